@@ -81,6 +81,10 @@ func GetConfig(rootDir, chainId string) cfg.Config {
 	mapConfig.SetDefault("pex_reactor", false)    // enable for peer exchange
 	mapConfig.SetDefault("priv_validator_file", filepath.Join(rootDir, chainId, "priv_validator.json"))
 	mapConfig.SetDefault("priv_validator_file_root", filepath.Join(rootDir, chainId, "priv_validator"))
+	mapConfig.SetDefault("priv_validator_laddr", "") // e.g. "tcp://10.0.0.2:46659", use the remote signer if set
+	mapConfig.SetDefault("priv_validator_tls_cert", filepath.Join(rootDir, "signer", "cert.pem"))
+	mapConfig.SetDefault("priv_validator_tls_key", filepath.Join(rootDir, "signer", "key.pem"))
+	mapConfig.SetDefault("priv_validator_tls_ca", filepath.Join(rootDir, "signer", "ca.pem"))
 	mapConfig.SetDefault("db_backend", "leveldb")
 	mapConfig.SetDefault("db_dir", filepath.Join(rootDir, chainId, defaultDataDir))
 	//mapConfig.SetDefault("rpc_laddr", "tcp://0.0.0.0:46657")
@@ -0,0 +1,153 @@
+package consensus
+
+import (
+	"crypto/ecdsa"
+	"errors"
+	"sync"
+
+	tmdcrypto "github.com/Gessiux/go-crypto"
+	ep "github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	ErrEvidenceTooOld   = errors.New("Evidence is older than the previous epoch")
+	ErrEvidenceInFuture = errors.New("Evidence is higher than the current height")
+	ErrEvidenceNil      = errors.New("Nil evidence")
+)
+
+// -----------------------------------------------------------------------------
+// EvidencePool keeps the double sign evidence seen by this node,
+// so that every evidence is only gossiped and reported once.
+type EvidencePool struct {
+	mtx      sync.Mutex
+	evidence map[common.Hash]*types.DuplicateVoteEvidence
+}
+
+func NewEvidencePool() *EvidencePool {
+	return &EvidencePool{
+		evidence: make(map[common.Hash]*types.DuplicateVoteEvidence),
+	}
+}
+
+// Add adds the evidence to the pool, returns false if the offence has been seen already
+func (evpool *EvidencePool) Add(evidence *types.DuplicateVoteEvidence) bool {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+
+	hash := evidence.Hash()
+	if _, exist := evpool.evidence[hash]; exist {
+		return false
+	}
+	evpool.evidence[hash] = evidence
+	return true
+}
+
+// Prune removes the evidence lower than the height
+func (evpool *EvidencePool) Prune(height uint64) {
+	evpool.mtx.Lock()
+	defer evpool.mtx.Unlock()
+
+	for hash, evidence := range evpool.evidence {
+		if evidence.Height() < height {
+			delete(evpool.evidence, hash)
+		}
+	}
+}
+
+//-----------------------------------------------------------------------------
+
+// handleEvidence verifies the evidence gossiped by peers against the validators of its epoch
+func (cs *ConsensusState) handleEvidence(evidence *types.DuplicateVoteEvidence) error {
+	if evidence == nil {
+		return ErrEvidenceNil
+	}
+
+	height := evidence.Height()
+	if height > cs.Height {
+		return ErrEvidenceInFuture
+	}
+
+	// only the evidence of the current and previous epoch could be punished
+	var evidenceEp *ep.Epoch
+	if height >= cs.Epoch.StartBlock {
+		evidenceEp = cs.Epoch
+	} else if prevEp := cs.Epoch.GetPreviousEpoch(); prevEp != nil && height >= prevEp.StartBlock {
+		evidenceEp = prevEp
+	}
+	if evidenceEp == nil {
+		return ErrEvidenceTooOld
+	}
+
+	if err := evidence.Verify(cs.state.NTCExtra.ChainID, evidenceEp.Validators); err != nil {
+		return err
+	}
+
+	cs.addEvidence(evidence)
+	return nil
+}
+
+// addEvidence keeps the verified evidence, the reactor gossips it to the peers.
+// If we're a validator, the evidence is reported to the chain as well
+func (cs *ConsensusState) addEvidence(evidence *types.DuplicateVoteEvidence) {
+	if !cs.evpool.Add(evidence) {
+		return
+	}
+	cs.logger.Warnf("Found double sign evidence %v", evidence)
+
+	types.FireEventEvidence(cs.evsw, types.EventDataEvidence{Evidence: evidence})
+
+	if cs.privValidator == nil || !cs.Validators.HasAddress(cs.privValidator.GetAddress()) {
+		return
+	}
+	if evidence.Address() == common.BytesToAddress(cs.privValidator.GetAddress()) {
+		return
+	}
+	go cs.reportEvidence(evidence)
+}
+
+// reportEvidence sends the evidence tx into the local tx pool, so that it'll be packed into the block we propose.
+// The tx is signed by the key derived from our BLS private key, the same as the data saved to the main chain
+func (cs *ConsensusState) reportEvidence(evidence *types.DuplicateVoteEvidence) {
+
+	var prv *ecdsa.PrivateKey
+	var err error
+	if prvValidator, ok := cs.privValidator.(*types.PrivValidator); ok {
+		blsPrivKey, ok := prvValidator.PrivKey.(tmdcrypto.BLSPrivKey)
+		if !ok {
+			cs.logger.Error("reportEvidence: the consensus key is held by a remote signer, PrivateKey not available")
+			return
+		}
+		prv, err = crypto.ToECDSA(blsPrivKey.Bytes())
+		if err != nil {
+			cs.logger.Error("reportEvidence: failed to get PrivateKey", "err", err)
+			return
+		}
+	} else {
+		cs.logger.Warn("reportEvidence: unexpected privValidator type, evidence not reported")
+		return
+	}
+
+	bs, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		cs.logger.Error("reportEvidence: failed to encode evidence", "evidence", evidence, "err", err)
+		return
+	}
+
+	data, err := neatAbi.ChainABI.Pack(neatAbi.ReportDoubleSign.String(), bs)
+	if err != nil {
+		cs.logger.Error("reportEvidence: failed to pack evidence", "err", err)
+		return
+	}
+
+	hash, err := cs.backend.GetBroadcaster().SendSystemTx(data, prv)
+	if err != nil {
+		cs.logger.Error("reportEvidence: failed to send evidence tx", "err", err)
+		return
+	}
+	cs.logger.Infof("reportEvidence: evidence %v reported, hash: %x", evidence, hash)
+}
@@ -224,6 +224,8 @@ func (conR *ConsensusReactor) Receive(chID uint64, src consensus.Peer, msgBytes
 		case *Maj23SignAggrMessage:
 			ps.SetHasMaj23SignAggr(msg.Maj23SignAggr)
 			conR.conS.peerMsgQueue <- msgInfo{msg, src.GetKey()}
+		case *EvidenceMessage:
+			conR.conS.peerMsgQueue <- msgInfo{msg, src.GetKey()}
 		default:
 			conR.logger.Warn(Fmt("Unknown message type %v", reflect.TypeOf(msg)))
 		}
@@ -305,6 +307,11 @@ func (conR *ConsensusReactor) registerEventCallbacks() {
 		conR.broadcastSignAggr(edv.SignAggr)
 	})
 
+	types.AddListenerForEvent(conR.evsw, "conR", types.EventStringEvidence(), func(data types.TMEventData) {
+		ede := data.(types.EventDataEvidence)
+		conR.broadcastEvidence(ede.Evidence)
+	})
+
 	types.AddListenerForEvent(conR.evsw, "conR", types.EventStringVote2Proposer(), func(data types.TMEventData) {
 		edv := data.(types.EventDataVote2Proposer)
 		conR.sendVote2Proposer(edv.Vote, edv.ProposerKey)
@@ -348,6 +355,13 @@ func (conR *ConsensusReactor) broadcastSignAggr(sign *types.SignAggr) {
 	}
 }
 
+func (conR *ConsensusReactor) broadcastEvidence(evidence *types.DuplicateVoteEvidence) {
+	if evidence != nil {
+		msg := &EvidenceMessage{Evidence: evidence}
+		conR.conS.backend.GetBroadcaster().BroadcastMessage(DataChannel, struct{ ConsensusMessage }{msg})
+	}
+}
+
 func (conR *ConsensusReactor) sendVote2Proposer(vote *types.Vote, proposerKey string) {
 	if vote != nil {
 		peerState, ok := conR.peerStates.Load(proposerKey)
@@ -973,6 +987,7 @@ const (
 	msgTypeVoteSetMaj23  = byte(0x16)
 	msgTypeVoteSetBits   = byte(0x17)
 	msgTypeMaj23SignAggr = byte(0x18)
+	msgTypeEvidence      = byte(0x19)
 )
 
 type ConsensusMessage interface{}
@@ -989,6 +1004,7 @@ var _ = wire.RegisterInterface(
 	wire.ConcreteType{&VoteSetMaj23Message{}, msgTypeVoteSetMaj23},
 	wire.ConcreteType{&VoteSetBitsMessage{}, msgTypeVoteSetBits},
 	wire.ConcreteType{&Maj23SignAggrMessage{}, msgTypeMaj23SignAggr},
+	wire.ConcreteType{&EvidenceMessage{}, msgTypeEvidence},
 )
 
 // TODO: check for unnecessary extra bytes at the end.
@@ -1084,6 +1100,16 @@ func (m *Maj23SignAggrMessage) String() string {
 
 //-------------------------------------
 
+type EvidenceMessage struct {
+	Evidence *types.DuplicateVoteEvidence
+}
+
+func (m *EvidenceMessage) String() string {
+	return fmt.Sprintf("[Evidence %v]", m.Evidence)
+}
+
+//-------------------------------------
+
 type HasVoteMessage struct {
 	Height uint64
 	Round  int
@@ -0,0 +1,76 @@
+package consensus
+
+import (
+	"reflect"
+
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+)
+
+// Functions for replaying the WAL to recover the round state after a crash
+
+// readReplayMessage feeds a single WAL message back into the state machine
+func (cs *ConsensusState) readReplayMessage(msg *TimedWALMessage) {
+
+	switch m := msg.Msg.(type) {
+	case types.EventDataRoundState:
+		// new steps are driven by the replayed messages, only log them
+		cs.logger.Infof("Replay: New Step, height: %v, round: %v, step: %v", m.Height, m.Round, m.Step)
+	case msgInfo:
+		peerKey := m.PeerKey
+		if peerKey == "" {
+			peerKey = "local"
+		}
+		switch msg := m.Msg.(type) {
+		case *ProposalMessage:
+			p := msg.Proposal
+			cs.logger.Infof("Replay: Proposal, height: %v, round: %v, header: %v, pol: %v, peer: %v",
+				p.Height, p.Round, p.BlockPartsHeader, p.POLRound, peerKey)
+		case *BlockPartMessage:
+			cs.logger.Infof("Replay: BlockPart, height: %v, round: %v, peer: %v", msg.Height, msg.Round, peerKey)
+		case *VoteMessage:
+			v := msg.Vote
+			cs.logger.Infof("Replay: Vote, height: %v, round: %v, type: %v, blockID: %v, peer: %v",
+				v.Height, v.Round, v.Type, v.BlockID, peerKey)
+		case *Maj23SignAggrMessage:
+			cs.logger.Infof("Replay: Maj23SignAggr, peer: %v", peerKey)
+		default:
+			cs.logger.Infof("Replay: %v, peer: %v", reflect.TypeOf(msg), peerKey)
+		}
+
+		cs.handleMsg(m, cs.RoundState)
+	case timeoutInfo:
+		cs.logger.Infof("Replay: Timeout, height: %v, round: %v, step: %v, dur: %v", m.Height, m.Round, m.Step, m.Duration)
+		cs.handleTimeout(m, cs.RoundState)
+	default:
+		cs.logger.Warnf("Replay: Unknown TimedWALMessage type: %v", reflect.TypeOf(msg.Msg))
+	}
+}
+
+// catchupReplay replays the messages of the current height from the WAL,
+// bringing the round state back to where it was before the node stopped.
+func (cs *ConsensusState) catchupReplay(csHeight uint64) error {
+
+	// set replayMode, messages are not written to the WAL again while replaying
+	cs.replayMode = true
+	defer func() { cs.replayMode = false }()
+
+	msgs, found, err := cs.wal.SearchForHeight(csHeight)
+	if err != nil {
+		// on ErrWALHeightExists the WAL is ahead of the state, a marker of the current height
+		// would make it go backwards, the marker is left to the start of the next height
+		return err
+	}
+	if !found {
+		// start the current height, so that following messages are found by the next replay
+		cs.wal.WriteHeight(csHeight)
+		cs.logger.Infof("Replay: no WAL messages for height %v", csHeight)
+		return nil
+	}
+
+	cs.logger.Infof("Catchup by replaying consensus messages, height: %v, messages: %v", csHeight, len(msgs))
+	for _, msg := range msgs {
+		cs.readReplayMessage(msg)
+	}
+	cs.logger.Infof("Replay: Done, height: %v, round: %v, step: %v", cs.Height, cs.Round, cs.Step)
+	return nil
+}
@@ -321,6 +321,13 @@ type ConsensusState struct {
 
 	evsw types.EventSwitch
 
+	wal        *WAL
+	walFile    string
+	walLight   bool
+	replayMode bool // messages are not saved to the WAL while replaying
+
+	evpool *EvidencePool // double sign evidence seen by us
+
 	nSteps int // used for testing to limit the number of transitions the state makes
 
 	// allow certain function to be overwritten for testing
@@ -347,6 +354,9 @@ func NewConsensusState(backend Backend, config cfg.Config, chainConfig *params.C
 		internalMsgQueue: make(chan msgInfo, msgQueueSize),
 		timeoutTicker:    NewTimeoutTicker(backend.GetLogger()),
 		timeoutParams:    InitTimeoutParamsFromConfig(config),
+		walFile:          config.GetString("cs_wal_file"),
+		walLight:         config.GetBool("cs_wal_light"),
+		evpool:           NewEvidencePool(),
 		//done:             make(chan struct{}),
 		blockFromMiner: nil,
 		backend:        backend,
@@ -593,20 +603,52 @@ func (cs *ConsensusState) OnStart() error {
 	//  to deal with them (by that point, at most one will be valid)
 	cs.timeoutTicker.Start()
 
+	cs.StartNewHeight()
+
+	// we may have lost some votes if the process crashed
+	// reload from consensus log to catchup
+	// NOTE: the WAL is opened after StartNewHeight, so the height marker
+	// written by it doesn't hide the messages saved before the crash
+	if cs.walFile != "" {
+		if err := cs.OpenWAL(cs.walFile); err != nil {
+			cs.logger.Errorf("Error loading ConsensusState wal: %v", err)
+			return err
+		}
+		if err := cs.catchupReplay(cs.Height); err != nil {
+			cs.logger.Errorf("Error on catchup replay. Proceeding to start ConsensusState anyway: %v", err)
+		}
+	}
+
 	// now start the receiveRoutine
 	go cs.receiveRoutine(0)
 
-	cs.StartNewHeight()
-
 	//cs.id = chain.GetNodeID()
 
 	return nil
 }
 
+// OpenWAL opens the write-ahead log in which all consensus messages are recorded before being processed
+func (cs *ConsensusState) OpenWAL(walFile string) error {
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+
+	wal, err := NewWAL(walFile, cs.walLight, cs.logger)
+	if err != nil {
+		return err
+	}
+	cs.wal = wal
+	return nil
+}
+
 func (cs *ConsensusState) OnStop() {
 
 	cs.BaseService.OnStop()
 	cs.timeoutTicker.Stop()
+
+	// Make BaseService.Wait() wait until cs.wal.Close()
+	cs.mtx.Lock()
+	defer cs.mtx.Unlock()
+	cs.wal.Close()
 }
 
 // NOTE: be sure to Stop() the event switch and drain
@@ -715,6 +757,9 @@ func (cs *ConsensusState) ReconstructLastCommit(state *sm.State) {
 func (cs *ConsensusState) newStep() {
 	rs := cs.RoundStateEvent()
 
+	if !cs.replayMode {
+		cs.wal.Save(rs)
+	}
 	cs.nSteps += 1
 	// newStep is called by updateToStep in NewConsensusState before the evsw is set!
 	if cs.evsw != nil {
@@ -743,15 +788,18 @@ func (cs *ConsensusState) receiveRoutine(maxSteps int) {
 
 		select {
 		case mi = <-cs.peerMsgQueue:
+			cs.wal.Save(mi)
 			// handles proposals, block parts, votes
 			// may generate internal events (votes, complete proposals, 2/3 majorities)
 			rs := cs.RoundState
 			cs.handleMsg(mi, rs)
 		case mi = <-cs.internalMsgQueue:
+			cs.wal.Save(mi)
 			// handles proposals, block parts, votes
 			rs := cs.RoundState
 			cs.handleMsg(mi, rs)
 		case ti := <-cs.timeoutTicker.Chan(): // tockChan:
+			cs.wal.Save(ti)
 			// if the timeout is relevant to the rs
 			// go to the next step
 			rs := cs.RoundState
@@ -805,6 +853,11 @@ func (cs *ConsensusState) handleMsg(mi msgInfo, rs RoundState) {
 
 		// NOTE: the vote is broadcast to peers by the reactor listening
 		// for vote events
+	case *EvidenceMessage:
+		// double sign evidence gossiped by peers, verify and report it
+		cs.mtx.Lock()
+		err = cs.handleEvidence(msg.Evidence)
+		cs.mtx.Unlock()
 	default:
 		cs.logger.Warnf("handleMsg. Unknown msg type %v", reflect.TypeOf(msg))
 	}
@@ -1006,6 +1059,17 @@ func (cs *ConsensusState) enterPropose(height uint64, round int) {
 		}
 	}
 
+	// The side chain closed by the main chain halts after its final block, the final block is
+	// saved to the main chain by the validators for the settlement
+	if closure := cs.sideChainClosure(); closure != nil && closure.Halted(height) {
+		if !closure.Settled() && cs.privValidator != nil {
+			cs.logger.Infof("enterPropose: save final block to main chain, height: %v", closure.HaltHeight)
+			cs.saveBlockToMainChain(cs.GetChainReader().GetBlockByNumber(closure.HaltHeight))
+		}
+		cs.logger.Infof("enterPropose: side chain closed, halt after height %v", closure.HaltHeight)
+		return
+	}
+
 	// If we don't get the proposal and all block parts quick enough, enterPrevote
 	cs.scheduleTimeout(cs.timeoutParams.Propose(round), height, round, RoundStepPropose)
 
@@ -1216,6 +1280,13 @@ func (cs *ConsensusState) defaultDoPrevote(height uint64, round int) {
 		return
 	}
 
+	// No block after the final block of a closed side chain
+	if closure := cs.sideChainClosure(); closure != nil && closure.Halted(height) {
+		cs.logger.Warnf("enterPrevote: side chain closed, halt after height %v", closure.HaltHeight)
+		cs.signAddVote(types.VoteTypePrevote, nil, types.PartSetHeader{})
+		return
+	}
+
 	// Validate TX4
 	err = cs.ValidateTX4(cs.ProposalBlock)
 	if err != nil {
@@ -1853,11 +1924,12 @@ func (cs *ConsensusState) tryAddVote(vote *types.Vote, peerKey string) error {
 		// If it's otherwise invalid, punish peer.
 		if err == ErrVoteHeightMismatch {
 			return err
-		} else if _, ok := err.(*types.ErrVoteConflictingVotes); ok {
+		} else if voteErr, ok := err.(*types.ErrVoteConflictingVotes); ok {
 			if peerKey == "" {
 				cs.logger.Warn("Found conflicting vote from ourselves. Did you unsafe_reset a validator?", "height", vote.Height, "round", vote.Round, "type", vote.Type)
 				return err
 			}
+			cs.addEvidence(types.NewDuplicateVoteEvidence(voteErr))
 			return err
 		} else {
 			// Probably an invalid signature. Bad peer.
@@ -2044,6 +2116,16 @@ func CompareHRS(h1 uint64, r1 int, s1 RoundStepType, h2 uint64, r2 int, s2 Round
 	return 1
 }
 
+// sideChainClosure returns the decommission of this side chain, nil for the main chain or the
+// side chain not closing
+func (cs *ConsensusState) sideChainClosure() *core.SideChainClosure {
+	chainId := cs.state.NTCExtra.ChainID
+	if cs.cch == nil || chainId == params.MainnetChainConfig.NeatChainId || chainId == params.TestnetChainConfig.NeatChainId {
+		return nil
+	}
+	return core.GetSideChainClosure(cs.cch.GetChainInfoDB(), chainId)
+}
+
 func (cs *ConsensusState) ValidateTX4(b *types.NCBlock) error {
 	var index int
 
@@ -2107,7 +2189,12 @@ func (cs *ConsensusState) saveBlockToMainChain(block *neatTypes.Block) {
 	// We use BLS Consensus PrivateKey to sign the digest data
 	var prv *ecdsa.PrivateKey
 	if prvValidator, ok := cs.privValidator.(*types.PrivValidator); ok {
-		prv, err = crypto.ToECDSA(prvValidator.PrivKey.(tmdcrypto.BLSPrivKey).Bytes())
+		blsPrivKey, ok := prvValidator.PrivKey.(tmdcrypto.BLSPrivKey)
+		if !ok {
+			cs.logger.Error("saveDataToMainChain: the consensus key is held by a remote signer, PrivateKey not available")
+			return
+		}
+		prv, err = crypto.ToECDSA(blsPrivKey.Bytes())
 		if err != nil {
 			cs.logger.Error("saveDataToMainChain: failed to get PrivateKey", "err", err)
 			return
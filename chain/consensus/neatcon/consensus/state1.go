@@ -36,6 +36,9 @@ func (cs *ConsensusState) StartNewHeight() {
 	state := cs.InitState(cs.Epoch)
 	cs.UpdateToState(state)
 
+	// the evidence of the previous epochs has been reported already
+	cs.evpool.Prune(cs.Epoch.StartBlock)
+
 	cs.newStep()
 	cs.scheduleRound0(cs.getRoundState()) //not use cs.GetRoundState to avoid dead-lock
 }
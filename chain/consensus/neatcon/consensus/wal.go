@@ -0,0 +1,369 @@
+package consensus
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+	"time"
+
+	. "github.com/Gessiux/go-common"
+	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/log"
+)
+
+//--------------------------------------------------------
+// types and functions for savings consensus messages
+
+const (
+	walHeightMarker = "#HEIGHT: "
+	walMaxFileSize  = 10 * 1024 * 1024 // rotate the WAL at new height once it grows past 10MB
+	walMaxLineSize  = 4 * 1024 * 1024  // a single message can not exceed 4MB
+)
+
+var (
+	ErrWALHeightExists = errors.New("WAL should not contain messages of the next height")
+)
+
+type TimedWALMessage struct {
+	Time time.Time  `json:"time"`
+	Msg  WALMessage `json:"msg"`
+}
+
+type WALMessage interface{}
+
+const (
+	walMsgTypeRoundState = byte(0x01)
+	walMsgTypeMsgInfo    = byte(0x02)
+	walMsgTypeTimeout    = byte(0x03)
+)
+
+var _ = wire.RegisterInterface(
+	struct{ WALMessage }{},
+	wire.ConcreteType{types.EventDataRoundState{}, walMsgTypeRoundState},
+	wire.ConcreteType{msgInfo{}, walMsgTypeMsgInfo},
+	wire.ConcreteType{timeoutInfo{}, walMsgTypeTimeout},
+)
+
+//--------------------------------------------------------
+// Simple write-ahead logger
+
+// Write ahead logger writes msgs to disk before they are processed.
+// Can be used for crash-recovery and deterministic replay.
+// Each line is a json encoded TimedWALMessage, every height starts with a
+// "#HEIGHT: N" marker line. Once the file grows past walMaxFileSize it is
+// rotated to "<path>.1" at the next height marker, so at most two files are kept.
+type WAL struct {
+	path  string
+	light bool // ignore block parts and other peers' messages
+
+	mtx  sync.Mutex
+	file *os.File
+	buf  *bufio.Writer
+	size int64
+
+	logger log.Logger
+}
+
+func NewWAL(path string, light bool, logger log.Logger) (*WAL, error) {
+	if err := EnsureDir(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+
+	wal := &WAL{
+		path:   path,
+		light:  light,
+		logger: logger,
+	}
+	if err := wal.openFile(); err != nil {
+		return nil, err
+	}
+	return wal, nil
+}
+
+func (wal *WAL) openFile() error {
+	file, err := os.OpenFile(wal.path, os.O_RDWR|os.O_CREATE|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	size, err := truncatePartialLine(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+	wal.file = file
+	wal.buf = bufio.NewWriter(file)
+	wal.size = size
+	return nil
+}
+
+// truncatePartialLine drops an incomplete last line left by a crash in the middle of a write,
+// so that new messages don't get appended to it. It returns the new file size.
+func truncatePartialLine(file *os.File) (int64, error) {
+	info, err := file.Stat()
+	if err != nil {
+		return 0, err
+	}
+
+	size := info.Size()
+	chunk := make([]byte, 4096)
+	for end := size; end > 0; {
+		start := end - int64(len(chunk))
+		if start < 0 {
+			start = 0
+		}
+		n, err := file.ReadAt(chunk[:end-start], start)
+		if err != nil && err != io.EOF {
+			return 0, err
+		}
+		if idx := bytes.LastIndexByte(chunk[:n], '\n'); idx >= 0 {
+			end = start + int64(idx) + 1
+			if end == size {
+				return size, nil
+			}
+			return end, file.Truncate(end)
+		}
+		end = start
+	}
+	// no complete line at all
+	if size > 0 {
+		return 0, file.Truncate(0)
+	}
+	return 0, nil
+}
+
+// Save writes the message to the WAL, called before the message is processed
+func (wal *WAL) Save(wmsg WALMessage) {
+	if wal == nil {
+		return
+	}
+
+	if wal.light {
+		// in light mode we only write new steps, timeouts, and our own votes (no proposals, block parts)
+		if mi, ok := wmsg.(msgInfo); ok {
+			if mi.PeerKey != "" {
+				return
+			}
+		}
+	}
+
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	if wal.file == nil {
+		return
+	}
+
+	// Write #HEIGHT: XYZ if new height
+	if edrs, ok := wmsg.(types.EventDataRoundState); ok {
+		if edrs.Step == RoundStepNewHeight.String() {
+			wal.writeHeight(edrs.Height)
+		}
+	}
+
+	// Write the wal message
+	wmsgBytes := wire.JSONBytes(TimedWALMessage{time.Now(), wmsg})
+	if err := wal.writeLine(wmsgBytes); err != nil {
+		PanicQ(Fmt("Error writing msg to consensus wal. Error: %v \n\nMessage: %v", err, wmsg))
+	}
+
+	// Our own messages are about to be sent to peers, make sure they hit the disk first
+	if mi, ok := wmsg.(msgInfo); ok && mi.PeerKey == "" {
+		if err := wal.sync(); err != nil {
+			PanicQ(Fmt("Error syncing consensus wal to disk. Error: %v", err))
+		}
+		return
+	}
+	if err := wal.buf.Flush(); err != nil {
+		PanicQ(Fmt("Error flushing consensus wal buf to file. Error: %v", err))
+	}
+}
+
+// WriteHeight writes a height marker, used to start a height which has no marker yet
+func (wal *WAL) WriteHeight(height uint64) {
+	if wal == nil {
+		return
+	}
+
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	if wal.file == nil {
+		return
+	}
+	wal.writeHeight(height)
+	if err := wal.sync(); err != nil {
+		PanicQ(Fmt("Error syncing consensus wal to disk. Error: %v", err))
+	}
+}
+
+func (wal *WAL) writeHeight(height uint64) {
+	if wal.size >= walMaxFileSize {
+		if err := wal.rotate(); err != nil {
+			wal.logger.Error("Failed to rotate consensus wal", "error", err)
+		}
+	}
+	if err := wal.writeLine([]byte(walHeightMarker + strconv.FormatUint(height, 10))); err != nil {
+		PanicQ(Fmt("Error writing height marker to consensus wal. Error: %v", err))
+	}
+}
+
+func (wal *WAL) writeLine(line []byte) error {
+	n, err := wal.buf.Write(line)
+	wal.size += int64(n)
+	if err != nil {
+		return err
+	}
+	err = wal.buf.WriteByte('\n')
+	wal.size++
+	return err
+}
+
+func (wal *WAL) sync() error {
+	if err := wal.buf.Flush(); err != nil {
+		return err
+	}
+	return wal.file.Sync()
+}
+
+// rotate moves the current file to "<path>.1" and starts a new one
+func (wal *WAL) rotate() error {
+	if err := wal.sync(); err != nil {
+		return err
+	}
+	if err := wal.file.Close(); err != nil {
+		return err
+	}
+	wal.file = nil
+	if err := os.Rename(wal.path, wal.path+".1"); err != nil {
+		return err
+	}
+	return wal.openFile()
+}
+
+func (wal *WAL) Close() {
+	if wal == nil {
+		return
+	}
+
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	if wal.file == nil {
+		return
+	}
+	if err := wal.sync(); err != nil {
+		wal.logger.Error("Failed to sync consensus wal", "error", err)
+	}
+	wal.file.Close()
+	wal.file = nil
+}
+
+// SearchForHeight returns the messages written after the last marker of the given height.
+// found is false if there's no marker for the height.
+// It returns ErrWALHeightExists if a marker for height+1 follows the last marker of the height.
+func (wal *WAL) SearchForHeight(height uint64) (msgs []*TimedWALMessage, found bool, err error) {
+	wal.mtx.Lock()
+	defer wal.mtx.Unlock()
+
+	if wal.file != nil {
+		if err = wal.buf.Flush(); err != nil {
+			return nil, false, err
+		}
+	}
+
+	nextHeight := false
+	// the rotated file holds older messages, so read it first
+	for _, path := range []string{wal.path + ".1", wal.path} {
+		err = ReadWALFile(path, func(marker uint64, isMarker bool, msg *TimedWALMessage) error {
+			if isMarker {
+				nextHeight = marker == height+1
+				found = marker == height
+				// messages of other heights don't belong to us
+				msgs = nil
+				return nil
+			}
+			if found {
+				msgs = append(msgs, msg)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, false, err
+		}
+	}
+	if nextHeight {
+		return nil, false, ErrWALHeightExists
+	}
+	return msgs, found, nil
+}
+
+// ReadWALFile iterates over a WAL file, calling cb for every height marker and message.
+// A missing file is treated as empty, a truncated last line (crash in the middle of a write) is skipped.
+func ReadWALFile(path string, cb func(height uint64, isMarker bool, msg *TimedWALMessage) error) error {
+	file, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	} else if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	reader := bufio.NewReaderSize(file, 64*1024)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			// incomplete last line, the write was interrupted
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if len(line) > walMaxLineSize {
+			return errors.New(Fmt("WAL line too large: %v bytes", len(line)))
+		}
+
+		line = bytes.TrimRight(line, "\n")
+		if len(line) == 0 {
+			continue
+		}
+
+		if bytes.HasPrefix(line, []byte(walHeightMarker)) {
+			height, err := strconv.ParseUint(string(line[len(walHeightMarker):]), 10, 64)
+			if err != nil {
+				return errors.New(Fmt("Invalid WAL height marker %q: %v", line, err))
+			}
+			if err := cb(height, true, nil); err != nil {
+				return err
+			}
+			continue
+		}
+		// skip other meta lines
+		if line[0] == '#' {
+			continue
+		}
+
+		msg, err := DecodeWALMessage(line)
+		if err != nil {
+			return err
+		}
+		if err := cb(0, false, msg); err != nil {
+			return err
+		}
+	}
+}
+
+// DecodeWALMessage decodes a single WAL line
+func DecodeWALMessage(line []byte) (*TimedWALMessage, error) {
+	var err error
+	var msg TimedWALMessage
+	wire.ReadJSON(&msg, line, &err)
+	if err != nil {
+		return nil, errors.New(Fmt("Error reading json data: %v", err))
+	}
+	return &msg, nil
+}
@@ -0,0 +1,108 @@
+package consensus
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/log"
+)
+
+func TestWALSearchForHeight(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cs_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	wal, err := NewWAL(filepath.Join(dir, "wal"), false, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	vote := &types.Vote{
+		ValidatorAddress: []byte{0x01},
+		Height:           2,
+		Type:             types.VoteTypePrevote,
+		BlockID:          types.BlockID{Hash: []byte{0x02}},
+		Signature:        crypto.BLSSignature([]byte{0x03}),
+	}
+
+	wal.Save(types.EventDataRoundState{Height: 1, Round: 0, Step: RoundStepNewHeight.String()})
+	wal.Save(timeoutInfo{time.Second, 1, 0, RoundStepNewHeight})
+	wal.Save(types.EventDataRoundState{Height: 2, Round: 0, Step: RoundStepNewHeight.String()})
+	wal.Save(msgInfo{&VoteMessage{vote}, ""})
+	wal.Save(timeoutInfo{time.Second, 2, 0, RoundStepPropose})
+	wal.Close()
+
+	wal, err = NewWAL(filepath.Join(dir, "wal"), false, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+
+	msgs, found, err := wal.SearchForHeight(2)
+	if err != nil || !found {
+		t.Fatalf("height 2 not found: %v", err)
+	}
+	if len(msgs) != 3 {
+		t.Fatalf("expected 3 messages, got %v", len(msgs))
+	}
+	mi, ok := msgs[1].Msg.(msgInfo)
+	if !ok {
+		t.Fatalf("expected msgInfo, got %T", msgs[1].Msg)
+	}
+	if v := mi.Msg.(*VoteMessage).Vote; v.Height != 2 || !v.Signature.Equals(vote.Signature) {
+		t.Errorf("unexpected vote %v", v)
+	}
+	if ti, ok := msgs[2].Msg.(timeoutInfo); !ok || ti.Step != RoundStepPropose {
+		t.Errorf("unexpected timeout %v", msgs[2].Msg)
+	}
+
+	if _, _, err := wal.SearchForHeight(1); err != ErrWALHeightExists {
+		t.Errorf("expected ErrWALHeightExists, got %v", err)
+	}
+	if _, found, _ := wal.SearchForHeight(3); found {
+		t.Errorf("height 3 should not be found")
+	}
+}
+
+func TestWALPartialLine(t *testing.T) {
+	dir, err := ioutil.TempDir("", "cs_wal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "wal")
+	wal, err := NewWAL(path, false, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	wal.WriteHeight(5)
+	wal.Close()
+
+	// simulate a crash in the middle of a write
+	file, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		t.Fatal(err)
+	}
+	file.Write([]byte(`{"time":"2020`))
+	file.Close()
+
+	wal, err = NewWAL(path, false, log.Root())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer wal.Close()
+	wal.Save(timeoutInfo{time.Second, 5, 0, RoundStepPropose})
+
+	msgs, found, err := wal.SearchForHeight(5)
+	if err != nil || !found || len(msgs) != 1 {
+		t.Fatalf("unexpected search result: %v, %v, %v", len(msgs), found, err)
+	}
+}
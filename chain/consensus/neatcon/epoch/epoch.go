@@ -26,6 +26,10 @@ var NextEpochNotEXPECTED = errors.New("next epoch parameters are not excepted, f
 
 var ForbiddenEpoch = big.NewInt(2) // forbid 2 epoch
 
+var DoubleSignForbiddenEpoch = big.NewInt(4) // forbid 4 epoch for double signing
+
+const DoubleSignSlashRate = 5 // slash 5% of the deposit for double signing
+
 const (
 	EPOCH_NOT_EXIST          = iota // value --> 0
 	EPOCH_PROPOSED_NOT_VOTED        // value --> 1
@@ -90,6 +94,16 @@ func InitEpoch(db dbm.DB, genDoc *tmTypes.GenesisDoc, logger log.Logger) *Epoch
 	}
 }
 
+// LoadLatestEpoch load the latest saved Epoch from DB, nil if no Epoch saved yet
+func LoadLatestEpoch(db dbm.DB, logger log.Logger) *Epoch {
+	epochNumber := db.Get([]byte(latestEpochKey))
+	if epochNumber == nil {
+		return nil
+	}
+	epNo, _ := strconv.ParseUint(string(epochNumber), 10, 64)
+	return LoadOneEpoch(db, epNo, logger)
+}
+
 // Load Full Epoch By EpochNumber (Epoch data, Reward Scheme, ValidatorVote, Previous Epoch, Next Epoch)
 func LoadOneEpoch(db dbm.DB, epochNumber uint64, logger log.Logger) *Epoch {
 	// Load Epoch Data from DB
@@ -305,15 +319,21 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 		epoch.nextEpoch = epoch.GetNextEpoch()
 		if epoch.nextEpoch != nil {
 
-			// Step 1: Refund the Delegate (subtract the pending refund / deposit proxied amount)
+			// Step 0: Tally the proposals and apply the chain parameter changes of the next epoch
+			epoch.tallyProposals(state)
+
+			// Step 1: Move the redelegated deposit to the new candidates, compound the rewards, then
+			// move the refund of the Delegate to the unbonding queue (subtract the pending refund / deposit proxied amount)
+			received := settleRedelegations(state)
+			compoundRewards(state, received)
+			matureEpoch := epoch.Number + epoch.unbondingPeriod(state)
 			for refundAddress := range state.GetDelegateAddressRefundSet() {
 				state.ForEachProxied(refundAddress, func(key common.Address, proxiedBalance, depositProxiedBalance, pendingRefundBalance *big.Int) bool {
 					if pendingRefundBalance.Sign() > 0 {
-						// Refund Pending Refund
+						// Unbond Pending Refund
 						state.SubDepositProxiedBalanceByUser(refundAddress, key, pendingRefundBalance)
 						state.SubPendingRefundBalanceByUser(refundAddress, key, pendingRefundBalance)
-						state.SubDelegateBalance(key, pendingRefundBalance)
-						state.AddBalance(key, pendingRefundBalance)
+						state.AddUnbonding(key, refundAddress, pendingRefundBalance, matureEpoch)
 					}
 					return true
 				})
@@ -372,6 +392,13 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 				fmt.Printf("Should enter new epoch, next epoch vote set is nil, %v\n", nextEpochVoteSet)
 			}
 
+			// the votes were made before the redelegated deposit and the compounded rewards arrived
+			for addr, amount := range received {
+				if vote, exist := nextEpochVoteSet.GetVoteByAddress(addr); exist && vote.Amount != nil {
+					vote.Amount = new(big.Int).Add(vote.Amount, amount)
+				}
+			}
+
 			// if has candidate and next epoch vote set not nil, add them to next epoch vote set
 			if len(candidateList) > 0 {
 				for addr := range candidateList {
@@ -466,7 +493,7 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 
 			// Update Validators with vote
 			//refundsUpdate, err := updateEpochValidatorSet(newValidators, epoch.nextEpoch.validatorVoteSet)
-			refundsUpdate, err := updateEpochValidatorSet(newValidators, nextEpochVoteSet)
+			refundsUpdate, err := updateEpochValidatorSet(newValidators, nextEpochVoteSet, maximumValidatorsSize(state))
 			if err != nil {
 				epoch.logger.Warn("Error changing validator set", "error", err)
 				return false, nil, err
@@ -489,12 +516,12 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 				}
 			}
 
-			// Step 4: For vote out Address, refund deposit (deposit amount -> balance, deposit proxied amount -> proxied amount)
+			// Step 4: For vote out Address, refund deposit (deposit amount -> unbonding queue, deposit proxied amount -> proxied amount)
 			for _, r := range refunds {
 				if !r.Voteout {
-					// Normal Refund, refund the deposit back to the self balance
+					// Normal Refund, unbond the deposit to the self balance
 					state.SubDepositBalance(r.Address, r.Amount)
-					state.AddBalance(r.Address, r.Amount)
+					state.AddUnbonding(r.Address, r.Address, r.Amount, matureEpoch)
 				} else {
 					// Voteout Refund, refund the deposit both to self and proxied (if available)
 					if state.IsCandidate(r.Address) {
@@ -506,13 +533,18 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 							return true
 						})
 					}
-					// Refund all the self deposit balance
+					// Unbond all the self deposit balance
 					depositBalance := state.GetDepositBalance(r.Address)
-					state.SubDepositBalance(r.Address, depositBalance)
-					state.AddBalance(r.Address, depositBalance)
+					if depositBalance.Sign() > 0 {
+						state.SubDepositBalance(r.Address, depositBalance)
+						state.AddUnbonding(r.Address, r.Address, depositBalance, matureEpoch)
+					}
 				}
 			}
 
+			// Step 5: Refund the mature unbondings
+			refundUnbondings(state, epoch.Number)
+
 			return true, newValidators, nil
 		} else {
 			return false, nil, NextEpochNotExist
@@ -521,6 +553,76 @@ func (epoch *Epoch) ShouldEnterNewEpoch(height uint64, state *state.StateDB) (bo
 	return false, nil, nil
 }
 
+// settleRedelegations moves the redelegated deposit proxied balance to the new candidates, what's left after
+// slashing is moved. It returns the amount received by each candidate
+func settleRedelegations(state *state.StateDB) map[common.Address]*big.Int {
+	received := make(map[common.Address]*big.Int)
+	for _, r := range state.GetRedelegations() {
+		amount := state.GetPendingRefundBalanceByUser(r.From, r.Delegator)
+		if amount.Cmp(r.Amount) > 0 {
+			amount = r.Amount
+		}
+		if amount.Sign() <= 0 {
+			continue
+		}
+		state.SubDepositProxiedBalanceByUser(r.From, r.Delegator, amount)
+		state.SubPendingRefundBalanceByUser(r.From, r.Delegator, amount)
+		if state.IsCandidate(r.To) {
+			state.AddProxiedBalanceByUser(r.To, r.Delegator, amount)
+			if received[r.To] == nil {
+				received[r.To] = new(big.Int)
+			}
+			received[r.To].Add(received[r.To], amount)
+		} else {
+			// the candidate has gone, refund like the unDelegate
+			state.SubDelegateBalance(r.Delegator, amount)
+			state.AddBalance(r.Delegator, amount)
+		}
+	}
+	state.ClearRedelegations()
+	return received
+}
+
+// compoundRewards moves the rewards of the delegators opted in to their deposit proxied balance of the same
+// candidate, the amount received by each candidate is added to received
+func compoundRewards(state *state.StateDB, received map[common.Address]*big.Int) {
+	for delegator := range state.GetAutoCompoundSet() {
+		rewards := make(map[common.Address]*big.Int)
+		state.ForEachReward(delegator, func(candidate common.Address, reward *big.Int) bool {
+			if reward.Sign() > 0 {
+				rewards[candidate] = new(big.Int).Set(reward)
+			}
+			return true
+		})
+		for candidate, reward := range rewards {
+			// the reward of the gone or forbidden candidate is left to be withdrawn
+			if !state.IsCandidate(candidate) || state.GetForbidden(candidate) {
+				continue
+			}
+			state.SubRewardBalanceByDelegateAddress(delegator, candidate, reward)
+			state.AddDelegateBalance(delegator, reward)
+			state.AddDepositProxiedBalanceByUser(candidate, delegator, reward)
+			if received[candidate] == nil {
+				received[candidate] = new(big.Int)
+			}
+			received[candidate].Add(received[candidate], reward)
+		}
+	}
+}
+
+// refundUnbondings refunds the unbondings mature at the epoch, what's left after slashing is refunded
+func refundUnbondings(state *state.StateDB, epoch uint64) {
+	for _, u := range state.RemoveMatureUnbondings(epoch) {
+		if u.Amount.Sign() > 0 {
+			// the self deposit has left the deposit balance once unbonded
+			if !u.IsSelfDeposit() {
+				state.SubDelegateBalance(u.Delegator, u.Amount)
+			}
+			state.AddBalance(u.Delegator, u.Amount)
+		}
+	}
+}
+
 func compareAddress(addrA, addrB []byte) bool {
 	if addrA[0] == addrB[0] {
 		return compareAddress(addrA[1:], addrB[1:])
@@ -577,13 +679,13 @@ func DryRunUpdateEpochValidatorSet(state *state.StateDB, validators *tmTypes.Val
 		}
 	}
 
-	_, err := updateEpochValidatorSet(validators, voteSet)
+	_, err := updateEpochValidatorSet(validators, voteSet, maximumValidatorsSize(state))
 	return err
 }
 
 // updateEpochValidatorSet Update the Current Epoch Validator by vote
 //
-func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochValidatorVoteSet) ([]*tmTypes.RefundValidatorAmount, error) {
+func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochValidatorVoteSet, maxValSize int) ([]*tmTypes.RefundValidatorAmount, error) {
 
 	// Refund List will be vaildators contain from Vote (exit validator or less amount than previous amount) and Knockout after sort by amount
 	var refund []*tmTypes.RefundValidatorAmount
@@ -633,8 +735,8 @@ func updateEpochValidatorSet(validators *tmTypes.ValidatorSet, voteSet *EpochVal
 	// Determine the Validator Size
 	//valSize := oldValSize + newValSize/2
 	valSize := oldValSize + newValSize
-	if valSize > MaximumValidatorsSize {
-		valSize = MaximumValidatorsSize
+	if valSize > maxValSize {
+		valSize = maxValSize
 	} else if valSize < MinimumValidatorsSize {
 		valSize = MinimumValidatorsSize
 	}
@@ -917,7 +1019,8 @@ func (epoch *Epoch) GetForbiddenDuration() time.Duration {
 	return ForbiddenDuration
 }
 
-// Update validator block time and set forbidden if this validator did not participate in consensus in one epoch
+// Update validator block time and set forbidden if this validator did not participate in consensus in one epoch,
+// or signed too few blocks within the signing window
 func (epoch *Epoch) UpdateForbiddenState(header *types.Header, prevHeader *types.Header, commit *tmTypes.Commit, state *state.StateDB) {
 	validators := epoch.Validators.Validators
 	height := header.Number.Uint64()
@@ -927,7 +1030,11 @@ func (epoch *Epoch) UpdateForbiddenState(header *types.Header, prevHeader *types
 
 	if height <= 1 || height == epoch.StartBlock {
 		return
-	} else if height == epoch.EndBlock {
+	}
+
+	epoch.updateSigningInfo(commit, state)
+
+	if height == epoch.EndBlock {
 		// epoch.logger.Debugf("Update validator forbidden state, epoch end block %v", height)
 		// epoch end block set all validators mined block times 0
 		for _, v := range validators {
@@ -941,9 +1048,9 @@ func (epoch *Epoch) UpdateForbiddenState(header *types.Header, prevHeader *types
 			addr := common.BytesToAddress(v.Address[:])
 			times := state.GetMinedBlocks(addr)
 			if times.Cmp(common.Big0) == 0 {
-				epoch.logger.Debugf("Update validator forbidden state, set %v forbidden, mined blocks %v, forbidden epoch %v", addr.String(), times, ForbiddenEpoch)
+				epoch.logger.Debugf("Update validator forbidden state, set %v forbidden, mined blocks %v, forbidden epoch %v", addr.String(), times, forbiddenEpoch(state))
 				state.SetForbidden(addr, true)
-				state.SetForbiddenTime(addr, ForbiddenEpoch)
+				state.SetForbiddenTime(addr, forbiddenEpoch(state))
 
 				state.MarkAddressForbidden(addr)
 			}
@@ -973,3 +1080,73 @@ func (epoch *Epoch) UpdateForbiddenState(header *types.Header, prevHeader *types
 	}
 
 }
+
+// updateSigningInfo moves the signing window of every validator forward with the commit,
+// the validator signed less than the minimum blocks within the window will be punished
+func (epoch *Epoch) updateSigningInfo(commit *tmTypes.Commit, state *state.StateDB) {
+	rs := epoch.rs
+	if rs == nil {
+		rs = LoadRewardScheme(epoch.db)
+	}
+	if rs == nil {
+		rs = &RewardScheme{}
+	}
+	window := governedUint64(state, ParamSignedBlocksWindow, rs.SignedBlocksWindow)
+	if window == 0 {
+		return
+	}
+
+	validators := epoch.Validators.Validators
+	if commit == nil || commit.BitArray == nil || commit.BitArray.Size() != uint64(len(validators)) {
+		return
+	}
+
+	minSigned := window * governedUint64(state, ParamMinSignedPerWindow, rs.MinSignedPerWindow) / 100
+	slashRate := governedUint64(state, ParamDowntimeSlashRate, rs.DowntimeSlashRate)
+	for i, v := range validators {
+		addr := common.BytesToAddress(v.Address)
+		info := state.GetSigningInfo(addr)
+		// the forbidden validator will be removed at the end of epoch, it starts over once it's back
+		if state.GetForbidden(addr) {
+			if info.IndexOffset > 0 {
+				state.ResetSigningInfo(addr)
+			}
+			continue
+		}
+
+		signed := info.Update(window, commit.BitArray.GetIndex(uint64(i)))
+		if info.IndexOffset >= window && signed < minSigned {
+			slashed := PunishDowntime(addr, uint8(slashRate), state)
+			epoch.logger.Infof("Update validator forbidden state, set %v forbidden, signed blocks %v within window %v, slashed %v", addr.String(), signed, window, slashed)
+			state.ResetSigningInfo(addr)
+			continue
+		}
+		state.SetSigningInfo(addr, info)
+	}
+}
+
+// PunishDowntime slashes rate percent of the deposit of the offline validator and its delegators, then forbids the validator.
+// It returns the total slashed amount
+func PunishDowntime(addr common.Address, rate uint8, state *state.StateDB) *big.Int {
+	return punish(addr, rate, forbiddenEpoch(state), state)
+}
+
+// PunishDoubleSign slashes the deposit of the double signing validator and its delegators, then forbids the validator.
+// It returns the total slashed amount
+func PunishDoubleSign(addr common.Address, state *state.StateDB) *big.Int {
+	return punish(addr, DoubleSignSlashRate, DoubleSignForbiddenEpoch, state)
+}
+
+func punish(addr common.Address, rate uint8, forbiddenEpoch *big.Int, state *state.StateDB) *big.Int {
+	slashed := state.SlashDepositBalance(addr, rate)
+	slashed.Add(slashed, state.SlashDepositProxiedBalance(addr, rate))
+	slashed.Add(slashed, state.SlashUnbondings(addr, rate))
+
+	state.SetForbidden(addr, true)
+	if state.GetForbiddenTime(addr).Cmp(forbiddenEpoch) < 0 {
+		state.SetForbiddenTime(addr, new(big.Int).Set(forbiddenEpoch))
+	}
+	state.MarkAddressForbidden(addr)
+
+	return slashed
+}
@@ -7,6 +7,11 @@ import (
 	"testing"
 	"time"
 
+	cmn "github.com/Gessiux/go-common"
+	tmTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/utilities/common"
 )
 
@@ -69,3 +74,196 @@ func TestVoteSetCompare(t *testing.T) {
 		fmt.Printf("address:%v, amount: %v\n", voteArr[i].Address.String(), voteArr[i].Amount)
 	}
 }
+
+func TestUpdateSigningInfo(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	online := common.BytesToAddress([]byte{1})
+	offline := common.BytesToAddress([]byte{2})
+	for _, addr := range []common.Address{online, offline} {
+		statedb.AddDepositBalance(addr, big.NewInt(1000))
+	}
+
+	epoch := &Epoch{
+		StartBlock: 0,
+		EndBlock:   100,
+		Validators: tmTypes.NewValidatorSet([]*tmTypes.Validator{
+			{Address: online.Bytes(), VotingPower: big.NewInt(1)},
+			{Address: offline.Bytes(), VotingPower: big.NewInt(1)},
+		}),
+		rs: &RewardScheme{
+			SignedBlocksWindow: 4,
+			MinSignedPerWindow: 50,
+			DowntimeSlashRate:  10,
+		},
+		logger: log.New(),
+	}
+	onlineIndex, _ := epoch.Validators.GetByAddress(online.Bytes())
+
+	// the offline validator signs the first 2 blocks only
+	for i := 0; i < 4; i++ {
+		bitArray := cmn.NewBitArray(2)
+		bitArray.SetIndex(uint64(onlineIndex), true)
+		bitArray.SetIndex(uint64(1-onlineIndex), i < 2)
+		epoch.updateSigningInfo(&tmTypes.Commit{BitArray: bitArray}, statedb)
+	}
+	if statedb.GetForbidden(offline) {
+		t.Fatalf("validator signed half of the window should not be forbidden")
+	}
+	if signed := statedb.GetSigningInfo(offline).SignedBlocks; signed != 2 {
+		t.Errorf("signed blocks %v, want 2", signed)
+	}
+
+	// the window slides, the first signed block is dropped
+	bitArray := cmn.NewBitArray(2)
+	bitArray.SetIndex(uint64(onlineIndex), true)
+	epoch.updateSigningInfo(&tmTypes.Commit{BitArray: bitArray}, statedb)
+
+	if !statedb.GetForbidden(offline) || statedb.GetForbiddenTime(offline).Cmp(ForbiddenEpoch) != 0 {
+		t.Errorf("offline validator should be forbidden")
+	}
+	if balance := statedb.GetDepositBalance(offline); balance.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("deposit balance %v, want 900", balance)
+	}
+	if info := statedb.GetSigningInfo(offline); info.IndexOffset != 0 {
+		t.Errorf("signing info should be reset after punishment")
+	}
+
+	if statedb.GetForbidden(online) || statedb.GetDepositBalance(online).Cmp(big.NewInt(1000)) != 0 {
+		t.Errorf("online validator should not be punished")
+	}
+	if signed := statedb.GetSigningInfo(online).SignedBlocks; signed != 4 {
+		t.Errorf("signed blocks %v, want 4", signed)
+	}
+}
+
+func TestSettleRedelegations(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	from := common.BytesToAddress([]byte{1})
+	to := common.BytesToAddress([]byte{2})
+	delegator := common.BytesToAddress([]byte{3})
+	statedb.ApplyForCandidate(to, "", 0)
+
+	statedb.AddDelegateBalance(delegator, big.NewInt(100))
+	statedb.AddDepositProxiedBalanceByUser(from, delegator, big.NewInt(100))
+	statedb.AddPendingRefundBalanceByUser(from, delegator, big.NewInt(60))
+	statedb.AddRedelegation(delegator, from, to, big.NewInt(60))
+	// the proxied trie is iterated by the slashing, so the delegations must be committed first
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+
+	// the redelegated deposit is still slashable at the source candidate
+	statedb.SlashDepositProxiedBalance(from, 50)
+
+	received := settleRedelegations(statedb)
+	if amount := received[to]; amount == nil || amount.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("received amount %v, want 50", amount)
+	}
+	if balance := statedb.GetProxiedBalanceByUser(to, delegator); balance.Cmp(big.NewInt(50)) != 0 {
+		t.Errorf("proxied balance %v, want 50", balance)
+	}
+	if balance := statedb.GetDepositProxiedBalanceByUser(from, delegator); balance.Sign() != 0 {
+		t.Errorf("deposit proxied balance %v, want 0", balance)
+	}
+	if balance := statedb.GetPendingRefundBalanceByUser(from, delegator); balance.Sign() != 0 {
+		t.Errorf("pending refund balance %v, want 0", balance)
+	}
+	if redelegations := statedb.GetRedelegations(); len(redelegations) != 0 {
+		t.Errorf("redelegations %v not cleared", redelegations)
+	}
+}
+
+func TestCompoundRewards(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	candidate := common.BytesToAddress([]byte{1})
+	gone := common.BytesToAddress([]byte{2})
+	delegator := common.BytesToAddress([]byte{3})
+	manual := common.BytesToAddress([]byte{4})
+	statedb.ApplyForCandidate(candidate, "", 0)
+
+	statedb.AddRewardBalanceByDelegateAddress(delegator, candidate, big.NewInt(30))
+	statedb.AddRewardBalanceByDelegateAddress(delegator, gone, big.NewInt(20))
+	statedb.AddRewardBalanceByDelegateAddress(manual, candidate, big.NewInt(10))
+	statedb.SetAutoCompound(delegator, true)
+	// the reward trie is iterated by the compounding, so the rewards must be committed first
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+
+	received := make(map[common.Address]*big.Int)
+	compoundRewards(statedb, received)
+	if amount := received[candidate]; amount == nil || amount.Cmp(big.NewInt(30)) != 0 {
+		t.Errorf("received amount %v, want 30", amount)
+	}
+	if balance := statedb.GetDepositProxiedBalanceByUser(candidate, delegator); balance.Cmp(big.NewInt(30)) != 0 {
+		t.Errorf("deposit proxied balance %v, want 30", balance)
+	}
+	if balance := statedb.GetDelegateBalance(delegator); balance.Cmp(big.NewInt(30)) != 0 {
+		t.Errorf("delegate balance %v, want 30", balance)
+	}
+	// the reward of the gone candidate is left to be withdrawn
+	if reward := statedb.GetRewardBalanceByDelegateAddress(delegator, gone); reward.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("reward balance %v, want 20", reward)
+	}
+	if reward := statedb.GetRewardBalanceByDelegateAddress(manual, candidate); reward.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("reward balance of the manual delegator %v, want 10", reward)
+	}
+}
+
+func TestRefundUnbondings(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	candidate := common.BytesToAddress([]byte{1})
+	delegator := common.BytesToAddress([]byte{2})
+	statedb.AddDelegateBalance(delegator, big.NewInt(200))
+	statedb.AddUnbonding(delegator, candidate, big.NewInt(100), 1)
+	statedb.AddUnbonding(delegator, candidate, big.NewInt(100), 2)
+
+	// the unbonding amount is still slashable
+	if slashed := statedb.SlashUnbondings(candidate, 10); slashed.Cmp(big.NewInt(20)) != 0 {
+		t.Errorf("slashed amount %v, want 20", slashed)
+	}
+
+	refundUnbondings(statedb, 1)
+	if balance := statedb.GetBalance(delegator); balance.Cmp(big.NewInt(90)) != 0 {
+		t.Errorf("balance %v, want 90", balance)
+	}
+	if balance := statedb.GetDelegateBalance(delegator); balance.Cmp(big.NewInt(90)) != 0 {
+		t.Errorf("delegate balance %v, want 90", balance)
+	}
+	if unbondings := statedb.GetUnbondings(delegator); len(unbondings) != 1 || unbondings[0].MatureEpoch != 2 {
+		t.Errorf("unbondings %v, want the one mature at epoch 2", unbondings)
+	}
+}
+
+func TestRefundSelfDepositUnbondings(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	candidate := common.BytesToAddress([]byte{1})
+	statedb.AddUnbonding(candidate, candidate, big.NewInt(100), 1)
+
+	// the self deposit is slashable, but out of the deposit balance and the voting power
+	if slashed := statedb.SlashUnbondings(candidate, 10); slashed.Cmp(big.NewInt(10)) != 0 {
+		t.Errorf("slashed amount %v, want 10", slashed)
+	}
+	if power := GovernanceVotingPower(statedb, candidate); power.Sign() != 0 {
+		t.Errorf("voting power %v, want 0", power)
+	}
+
+	refundUnbondings(statedb, 1)
+	if balance := statedb.GetBalance(candidate); balance.Cmp(big.NewInt(90)) != 0 {
+		t.Errorf("balance %v, want 90", balance)
+	}
+	if balance := statedb.GetDelegateBalance(candidate); balance.Sign() != 0 {
+		t.Errorf("delegate balance %v, want 0", balance)
+	}
+}
@@ -0,0 +1,172 @@
+package epoch
+
+import (
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/Gessiux/neatchain/chain/core/state"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/common/math"
+)
+
+// The chain parameters changeable by the governance, the required gas of the functions
+// are changed with neatAbi.FunctionType.GasParam
+const (
+	ParamMaximumValidatorsSize = "MaximumValidatorsSize"
+	ParamForbiddenEpoch        = "ForbiddenEpoch"
+	ParamSignedBlocksWindow    = "SignedBlocksWindow"
+	ParamMinSignedPerWindow    = "MinSignedPerWindow"
+	ParamDowntimeSlashRate     = "DowntimeSlashRate"
+	ParamUnbondingPeriod       = "UnbondingPeriod"
+)
+
+var (
+	MinProposalDeposit = math.MustParseBig256("1000000000000000000000") // 1000 * e18
+
+	// ProposalVotingPeriod is the number of epochs after the current one a new proposal stays open for voting
+	ProposalVotingPeriod uint64 = 1
+
+	// ProposalQuorum is the percentage of the total stake the votes must reach
+	ProposalQuorum int64 = 33
+
+	maxRequiredGas = big.NewInt(10000000)
+)
+
+// ValidateParamChange checks the parameter is changeable by the governance and the value is within its range
+func ValidateParamChange(param string, value *big.Int) error {
+	var min, max int64
+	switch param {
+	case ParamMaximumValidatorsSize:
+		min, max = MinimumValidatorsSize, 1000
+	case ParamForbiddenEpoch:
+		min, max = 1, 100
+	case ParamSignedBlocksWindow:
+		min, max = 0, 100000
+	case ParamMinSignedPerWindow, ParamDowntimeSlashRate, ParamUnbondingPeriod:
+		min, max = 0, 100
+	default:
+		if !strings.HasPrefix(param, neatAbi.RequiredGasParamPrefix) ||
+			neatAbi.StringToFunctionType(strings.TrimPrefix(param, neatAbi.RequiredGasParamPrefix)) == neatAbi.Unknown {
+			return fmt.Errorf("unknown chain parameter %v", param)
+		}
+		min, max = 0, maxRequiredGas.Int64()
+	}
+	if value == nil || value.Cmp(big.NewInt(min)) < 0 || value.Cmp(big.NewInt(max)) > 0 {
+		return fmt.Errorf("value of chain parameter %v out of range [%v, %v]", param, min, max)
+	}
+	return nil
+}
+
+// GovernanceVotingPower returns the stake of the address, the deposit plus the amount delegated to the candidates,
+// the unbonding amount is not counted
+func GovernanceVotingPower(state *state.StateDB, addr common.Address) *big.Int {
+	power := new(big.Int).Add(state.GetDelegateBalance(addr), state.GetDepositBalance(addr))
+	for _, u := range state.GetUnbondings(addr) {
+		// the unbonding self deposit is out of the deposit balance already
+		if !u.IsSelfDeposit() {
+			power.Sub(power, u.Amount)
+		}
+	}
+	return power
+}
+
+// totalGovernanceVotingPower returns the total stake the GovernanceVotingPower is measured from, the deposit
+// of the validators and the candidates plus the amount delegated to them
+func (epoch *Epoch) totalGovernanceVotingPower(statedb *state.StateDB) *big.Int {
+	stakers := make(map[common.Address]struct{})
+	for _, v := range epoch.Validators.Validators {
+		stakers[common.BytesToAddress(v.Address)] = struct{}{}
+	}
+	for addr := range statedb.GetCandidateSet() {
+		stakers[addr] = struct{}{}
+	}
+
+	total := new(big.Int)
+	for addr := range stakers {
+		total.Add(total, statedb.GetDepositBalance(addr))
+		total.Add(total, statedb.GetTotalProxiedBalance(addr))
+		total.Add(total, statedb.GetTotalDepositProxiedBalance(addr))
+	}
+	return total
+}
+
+// tallyProposals closes the voting of the proposals ending with the epoch, then puts the accepted changes
+// due at the next epoch into effect
+func (epoch *Epoch) tallyProposals(statedb *state.StateDB) {
+	quorum := epoch.totalGovernanceVotingPower(statedb)
+	quorum.Mul(quorum, big.NewInt(ProposalQuorum))
+	quorum.Div(quorum, big.NewInt(100))
+
+	for _, id := range statedb.GetActiveProposals() {
+		proposal := statedb.GetProposal(id)
+		if proposal == nil || proposal.VotingEndEpoch > epoch.Number {
+			continue
+		}
+		statedb.RemoveActiveProposal(id)
+
+		yes, no := new(big.Int), new(big.Int)
+		for _, vote := range proposal.Votes {
+			power := GovernanceVotingPower(statedb, vote.Voter)
+			if vote.Option == state.VoteYes {
+				yes.Add(yes, power)
+			} else {
+				no.Add(no, power)
+			}
+		}
+
+		refund := true
+		switch {
+		case proposal.TotalDeposit().Cmp(MinProposalDeposit) < 0:
+			proposal.Status = state.ProposalRejected
+		case new(big.Int).Add(yes, no).Cmp(quorum) < 0:
+			// not enough interest, the deposit is burned to keep the spam away
+			proposal.Status = state.ProposalDropped
+			refund = false
+		case yes.Cmp(no) > 0:
+			proposal.Status = state.ProposalAccepted
+			statedb.AddPendingParamChange(proposal.Param, proposal.Value, proposal.TargetEpoch)
+		default:
+			proposal.Status = state.ProposalRejected
+		}
+		if refund {
+			for _, d := range proposal.Deposits {
+				statedb.AddBalance(d.Depositor, d.Amount)
+			}
+		}
+		statedb.SetProposal(proposal)
+		epoch.logger.Infof("Tally proposal %v, param %v, value %v, yes %v, no %v, status %v", id, proposal.Param, proposal.Value, yes, no, proposal.Status)
+	}
+
+	for _, change := range statedb.ApplyParamChanges(epoch.Number + 1) {
+		epoch.logger.Infof("Chain parameter %v changed to %v from epoch %v", change.Param, change.Value, epoch.Number+1)
+	}
+}
+
+// governedUint64 returns the value of the parameter changed by the governance, or the default one
+func governedUint64(state *state.StateDB, param string, def uint64) uint64 {
+	if value, ok := state.GetChainParam(param); ok {
+		return value.Uint64()
+	}
+	return def
+}
+
+func maximumValidatorsSize(state *state.StateDB) int {
+	return int(governedUint64(state, ParamMaximumValidatorsSize, MaximumValidatorsSize))
+}
+
+func forbiddenEpoch(state *state.StateDB) *big.Int {
+	return new(big.Int).SetUint64(governedUint64(state, ParamForbiddenEpoch, ForbiddenEpoch.Uint64()))
+}
+
+func (epoch *Epoch) unbondingPeriod(state *state.StateDB) uint64 {
+	rs := epoch.rs
+	if rs == nil {
+		rs = LoadRewardScheme(epoch.db)
+	}
+	if rs == nil {
+		rs = &RewardScheme{}
+	}
+	return governedUint64(state, ParamUnbondingPeriod, rs.UnbondingPeriod)
+}
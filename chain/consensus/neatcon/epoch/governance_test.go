@@ -0,0 +1,78 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	tmTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+func TestTallyProposals(t *testing.T) {
+	statedb, _ := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+
+	validator := common.BytesToAddress([]byte{3})
+	staker := common.BytesToAddress([]byte{1})
+	idle := common.BytesToAddress([]byte{2})
+	statedb.AddDepositBalance(validator, big.NewInt(100))
+	statedb.AddDelegateBalance(staker, big.NewInt(50))
+	statedb.AddDepositProxiedBalanceByUser(validator, staker, big.NewInt(50))
+
+	epoch := &Epoch{
+		Number: 1,
+		Validators: tmTypes.NewValidatorSet([]*tmTypes.Validator{
+			{Address: validator.Bytes(), VotingPower: big.NewInt(1000)},
+		}),
+		logger: log.New(),
+	}
+
+	// the quorum is measured from the stake like the votes, not the voting power of the validators
+	if total := epoch.totalGovernanceVotingPower(statedb); total.Cmp(big.NewInt(150)) != 0 {
+		t.Errorf("total voting power %v, want 150", total)
+	}
+
+	// accepted, the change is applied from the next epoch
+	accepted := statedb.CreateProposal(staker, ParamMaximumValidatorsSize, big.NewInt(21), 1, 2)
+	// no vote, the quorum is not reached
+	dropped := statedb.CreateProposal(idle, ParamForbiddenEpoch, big.NewInt(3), 1, 2)
+	// the voting goes on
+	voting := statedb.CreateProposal(staker, ParamForbiddenEpoch, big.NewInt(5), 2, 3)
+
+	for _, id := range []uint64{accepted, dropped, voting} {
+		proposal := statedb.GetProposal(id)
+		proposal.AddDeposit(proposal.Proposer, MinProposalDeposit)
+		proposal.SetVote(staker, id != dropped)
+		if id == dropped {
+			proposal.Votes = nil
+		}
+		statedb.SetProposal(proposal)
+	}
+	epoch.tallyProposals(statedb)
+
+	if status := statedb.GetProposal(accepted).Status; status != state.ProposalAccepted {
+		t.Errorf("proposal status %v, want accepted", status)
+	}
+	if size := maximumValidatorsSize(statedb); size != 21 {
+		t.Errorf("maximum validators size %v, want 21", size)
+	}
+	if balance := statedb.GetBalance(staker); balance.Cmp(MinProposalDeposit) != 0 {
+		t.Errorf("deposit of the accepted proposal not refunded, balance %v", balance)
+	}
+
+	if status := statedb.GetProposal(dropped).Status; status != state.ProposalDropped {
+		t.Errorf("proposal status %v, want dropped", status)
+	}
+	if balance := statedb.GetBalance(idle); balance.Sign() != 0 {
+		t.Errorf("deposit of the dropped proposal refunded, balance %v", balance)
+	}
+	if forbidden := forbiddenEpoch(statedb); forbidden.Cmp(ForbiddenEpoch) != 0 {
+		t.Errorf("forbidden epoch %v, want default %v", forbidden, ForbiddenEpoch)
+	}
+
+	if active := statedb.GetActiveProposals(); len(active) != 1 || active[0] != voting {
+		t.Errorf("active proposals %v, want [%v]", active, voting)
+	}
+}
@@ -21,6 +21,32 @@ type RewardScheme struct {
 	RewardFirstYear    *big.Int
 	EpochNumberPerYear uint64
 	TotalYear          uint64
+
+	SignedBlocksWindow uint64
+	MinSignedPerWindow uint64
+	DowntimeSlashRate  uint64
+
+	UnbondingPeriod uint64
+}
+
+// rewardSchemeV2 is the Reward Scheme saved before the unbonding period was added
+type rewardSchemeV2 struct {
+	TotalReward        *big.Int
+	RewardFirstYear    *big.Int
+	EpochNumberPerYear uint64
+	TotalYear          uint64
+
+	SignedBlocksWindow uint64
+	MinSignedPerWindow uint64
+	DowntimeSlashRate  uint64
+}
+
+// rewardSchemeV1 is the Reward Scheme saved before the downtime slashing parameters were added
+type rewardSchemeV1 struct {
+	TotalReward        *big.Int
+	RewardFirstYear    *big.Int
+	EpochNumberPerYear uint64
+	TotalYear          uint64
 }
 
 // Load Reward Scheme
@@ -32,8 +58,31 @@ func LoadRewardScheme(db dbm.DB) *RewardScheme {
 		rs := &RewardScheme{}
 		err := wire.ReadBinaryBytes(buf, rs)
 		if err != nil {
-			log.Errorf("LoadRewardScheme Failed, error: %v", err)
-			return nil
+			// Fallback to the old Reward Schemes, the refunds are not unbonded
+			v2 := &rewardSchemeV2{}
+			if wire.ReadBinaryBytes(buf, v2) == nil {
+				return &RewardScheme{
+					TotalReward:        v2.TotalReward,
+					RewardFirstYear:    v2.RewardFirstYear,
+					EpochNumberPerYear: v2.EpochNumberPerYear,
+					TotalYear:          v2.TotalYear,
+					SignedBlocksWindow: v2.SignedBlocksWindow,
+					MinSignedPerWindow: v2.MinSignedPerWindow,
+					DowntimeSlashRate:  v2.DowntimeSlashRate,
+				}
+			}
+			// downtime slashing is disabled as well
+			v1 := &rewardSchemeV1{}
+			if wire.ReadBinaryBytes(buf, v1) != nil {
+				log.Errorf("LoadRewardScheme Failed, error: %v", err)
+				return nil
+			}
+			rs = &RewardScheme{
+				TotalReward:        v1.TotalReward,
+				RewardFirstYear:    v1.RewardFirstYear,
+				EpochNumberPerYear: v1.EpochNumberPerYear,
+				TotalYear:          v1.TotalYear,
+			}
 		}
 		return rs
 	}
@@ -48,6 +97,10 @@ func MakeRewardScheme(db dbm.DB, rsDoc *tmTypes.RewardSchemeDoc) *RewardScheme {
 		RewardFirstYear:    rsDoc.RewardFirstYear,
 		EpochNumberPerYear: rsDoc.EpochNumberPerYear,
 		TotalYear:          rsDoc.TotalYear,
+		SignedBlocksWindow: rsDoc.SignedBlocksWindow,
+		MinSignedPerWindow: rsDoc.MinSignedPerWindow,
+		DowntimeSlashRate:  rsDoc.DowntimeSlashRate,
+		UnbondingPeriod:    rsDoc.UnbondingPeriod,
 	}
 
 	return rs
@@ -66,8 +119,16 @@ func (rs *RewardScheme) String() string {
 		"totalReward : %v,\n"+
 		"rewardFirstYear : %v,\n"+
 		"epochNumberPerYear : %v,\n"+
+		"signedBlocksWindow : %v,\n"+
+		"minSignedPerWindow : %v,\n"+
+		"downtimeSlashRate : %v,\n"+
+		"unbondingPeriod : %v,\n"+
 		"}",
 		rs.TotalReward,
 		rs.RewardFirstYear,
-		rs.EpochNumberPerYear)
+		rs.EpochNumberPerYear,
+		rs.SignedBlocksWindow,
+		rs.MinSignedPerWindow,
+		rs.DowntimeSlashRate,
+		rs.UnbondingPeriod)
 }
@@ -0,0 +1,99 @@
+package epoch
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+func TestSlashCandidate(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+
+	candidate := common.BytesToAddress([]byte{1})
+	delegator := common.BytesToAddress([]byte{2})
+
+	statedb.AddDepositBalance(candidate, big.NewInt(1000))
+	for _, user := range []common.Address{candidate, delegator} {
+		statedb.AddDelegateBalance(user, big.NewInt(200))
+		statedb.AddDepositProxiedBalanceByUser(candidate, user, big.NewInt(200))
+	}
+	// the delegator wants 190 back, only 180 is left after slashing
+	statedb.AddPendingRefundBalanceByUser(candidate, delegator, big.NewInt(190))
+	// the proxied trie is iterated, so the delegations must be committed first
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+
+	if slashed := statedb.SlashDepositBalance(candidate, 10); slashed.Cmp(big.NewInt(100)) != 0 {
+		t.Errorf("slashed deposit %v, want 100", slashed)
+	}
+	if slashed := statedb.SlashDepositProxiedBalance(candidate, 10); slashed.Cmp(big.NewInt(40)) != 0 {
+		t.Errorf("slashed deposit proxied %v, want 40", slashed)
+	}
+
+	if balance := statedb.GetDepositBalance(candidate); balance.Cmp(big.NewInt(900)) != 0 {
+		t.Errorf("deposit balance %v, want 900", balance)
+	}
+	if balance := statedb.GetTotalDepositProxiedBalance(candidate); balance.Cmp(big.NewInt(360)) != 0 {
+		t.Errorf("total deposit proxied balance %v, want 360", balance)
+	}
+	if balance := statedb.GetDelegateBalance(delegator); balance.Cmp(big.NewInt(180)) != 0 {
+		t.Errorf("delegate balance %v, want 180", balance)
+	}
+	if balance := statedb.GetPendingRefundBalanceByUser(candidate, delegator); balance.Cmp(big.NewInt(180)) != 0 {
+		t.Errorf("pending refund balance %v, want 180", balance)
+	}
+
+	// the slashed evidence survives a commit
+	hash := common.BytesToHash([]byte{3})
+	statedb.MarkEvidenceSlashed(hash)
+	root, err = statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+	if !statedb.IsEvidenceSlashed(hash) {
+		t.Errorf("slashed evidence lost after commit")
+	}
+	if statedb.IsEvidenceSlashed(common.BytesToHash([]byte{4})) {
+		t.Errorf("unexpected slashed evidence")
+	}
+}
+
+func TestSigningInfo(t *testing.T) {
+	db := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, db)
+	validator := common.BytesToAddress([]byte{1})
+
+	info := statedb.GetSigningInfo(validator)
+	for _, signed := range []bool{true, true, false, true, false} {
+		info.Update(4, signed)
+	}
+	// the window holds the latest 4 blocks
+	if info.IndexOffset != 5 || info.SignedBlocks != 2 {
+		t.Errorf("signing info %v/%v, want 5/2", info.IndexOffset, info.SignedBlocks)
+	}
+	statedb.SetSigningInfo(validator, info)
+
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	statedb, _ = state.New(root, db)
+	if loaded := statedb.GetSigningInfo(validator); loaded.IndexOffset != 5 || loaded.SignedBlocks != 2 {
+		t.Errorf("signing info %v/%v after commit, want 5/2", loaded.IndexOffset, loaded.SignedBlocks)
+	}
+
+	statedb.ResetSigningInfo(validator)
+	root, _ = statedb.Commit(false)
+	statedb, _ = state.New(root, db)
+	if loaded := statedb.GetSigningInfo(validator); loaded.IndexOffset != 0 || loaded.SignedBlocks != 0 {
+		t.Errorf("signing info not reset")
+	}
+}
@@ -1,6 +1,7 @@
 package neatcon
 
 import (
+	"fmt"
 	"io/ioutil"
 	"os"
 	"strings"
@@ -14,6 +15,7 @@ import (
 	"github.com/Gessiux/neatchain/chain/core"
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/params"
+	"gopkg.in/urfave/cli.v1"
 )
 
 type Node struct {
@@ -36,10 +38,9 @@ type Node struct {
 
 func NewNodeNotStart(backend *backend, config cfg.Config, chainConfig *params.ChainConfig, cch core.CrossChainHelper, genDoc *types.GenesisDoc) *Node {
 	// Get PrivValidator
-	var privValidator *types.PrivValidator
-	privValidatorFile := config.GetString("priv_validator_file")
-	if _, err := os.Stat(privValidatorFile); err == nil {
-		privValidator = types.LoadPrivValidator(privValidatorFile)
+	privValidator, err := LoadPrivValidator(config)
+	if err != nil {
+		cmn.Exit(cmn.Fmt("Failed to load PrivValidator: %v", err))
 	}
 
 	// Initial Epoch
@@ -268,25 +269,76 @@ func ProtocolAndAddress(listenAddr string) (string, string) {
 	return protocol, address
 }
 
+// LoadPrivValidator loads the local node's validator key. If priv_validator_laddr is set the key is held
+// by the remote signer at that address, otherwise it's read from priv_validator_file, nil if the file not exist.
+// The encrypted key is unlocked by the passphrase set at startup, see SetValidatorPassphrase
+func LoadPrivValidator(config cfg.Config) (*types.PrivValidator, error) {
+	if signerAddr := config.GetString("priv_validator_laddr"); signerAddr != "" {
+		tlsConfig, err := types.LoadSignerTLSConfig(config.GetString("priv_validator_tls_cert"),
+			config.GetString("priv_validator_tls_key"), config.GetString("priv_validator_tls_ca"))
+		if err != nil {
+			return nil, err
+		}
+		return types.LoadRemotePrivValidator(signerAddr, tlsConfig)
+	}
+
+	privValidatorFile := config.GetString("priv_validator_file")
+	if _, err := os.Stat(privValidatorFile); err != nil {
+		return nil, nil
+	}
+	privValidator := types.LoadPrivValidator(privValidatorFile)
+	if privValidator.IsLocked() {
+		if err := privValidator.Unlock(validatorPassphrase); err != nil {
+			return nil, fmt.Errorf("failed to unlock %v: %v", privValidatorFile, err)
+		}
+	}
+	return privValidator, nil
+}
+
+// validatorPassphrase unlocks the encrypted consensus key, the main chain and
+// the side chains share the same key, so it's asked only once at startup
+var validatorPassphrase string
+
+// SetValidatorPassphrase sets the passphrase of the encrypted priv_validator_file
+func SetValidatorPassphrase(passphrase string) {
+	validatorPassphrase = passphrase
+}
+
 func MakeNeatConNode(backend *backend, config cfg.Config, chainConfig *params.ChainConfig, cch core.CrossChainHelper) *Node {
 
-	var genDoc *types.GenesisDoc
+	genDoc := loadGenesisDoc(config, chainConfig)
+	if genDoc == nil {
+		return nil
+	}
+	config.Set("chain_id", genDoc.ChainID)
+
+	return NewNodeNotStart(backend, config, chainConfig, cch, genDoc)
+}
+
+// GenesisEpoch returns the epoch of the NeatCon genesis, the light client trusts its validators
+func GenesisEpoch(chainConfig *params.ChainConfig, cliCtx *cli.Context) (*epoch.Epoch, error) {
+	config := GetNeatConConfig(chainConfig.NeatChainId, cliCtx)
+	genDoc := loadGenesisDoc(config, chainConfig)
+	if genDoc == nil {
+		return nil, fmt.Errorf("neatcon genesis of chain %s not found", chainConfig.NeatChainId)
+	}
+	return epoch.MakeOneEpoch(nil, &genDoc.CurrentEpoch, chainConfig.ChainLogger), nil
+}
+
+func loadGenesisDoc(config cfg.Config, chainConfig *params.ChainConfig) *types.GenesisDoc {
 	genDocFile := config.GetString("genesis_file")
 
 	if !cmn.FileExists(genDocFile) {
 		if chainConfig.NeatChainId == params.MainnetChainConfig.NeatChainId {
-			genDoc, _ = types.GenesisDocFromJSON([]byte(types.MainnetGenesisJSON))
+			genDoc, _ := types.GenesisDocFromJSON([]byte(types.MainnetGenesisJSON))
+			return genDoc
 		} else if chainConfig.NeatChainId == params.TestnetChainConfig.NeatChainId {
-			genDoc, _ = types.GenesisDocFromJSON([]byte(types.TestnetGenesisJSON))
-		} else {
-			return nil
+			genDoc, _ := types.GenesisDocFromJSON([]byte(types.TestnetGenesisJSON))
+			return genDoc
 		}
-	} else {
-		genDoc = readGenesisFromFile(genDocFile)
+		return nil
 	}
-	config.Set("chain_id", genDoc.ChainID)
-
-	return NewNodeNotStart(backend, config, chainConfig, cch, genDoc)
+	return readGenesisFromFile(genDocFile)
 }
 
 func readGenesisFromFile(genDocFile string) *types.GenesisDoc {
@@ -35,6 +35,7 @@ func EventStringVote2Proposer() string      { return "Vote2Proposer" }
 func EventStringProposal() string           { return "Proposal" }
 func EventStringBlockPart() string          { return "BlockPart" }
 func EventStringProposalBlockParts() string { return "Proposal_BlockParts" }
+func EventStringEvidence() string           { return "Evidence" }
 
 func EventStringRequest() string        { return "Request" }
 func EventStringMessage() string        { return "Message" }
@@ -58,6 +59,7 @@ const (
 	EventDataTypeVote          = byte(0x12)
 	EventDataTypeSignAggr      = byte(0x13)
 	EventDataTypeVote2Proposer = byte(0x14)
+	EventDataTypeEvidence      = byte(0x15)
 
 	EventDataTypeRequest        = byte(0x21)
 	EventDataTypeMessage        = byte(0x22)
@@ -74,6 +76,7 @@ var _ = wire.RegisterInterface(
 	wire.ConcreteType{EventDataVote{}, EventDataTypeVote},
 	wire.ConcreteType{EventDataSignAggr{}, EventDataTypeSignAggr},
 	wire.ConcreteType{EventDataVote2Proposer{}, EventDataTypeVote2Proposer},
+	wire.ConcreteType{EventDataEvidence{}, EventDataTypeEvidence},
 
 	wire.ConcreteType{EventDataRequest{}, EventDataTypeRequest},
 	wire.ConcreteType{EventDataMessage{}, EventDataTypeMessage},
@@ -124,6 +127,11 @@ type EventDataVote2Proposer struct {
 	ProposerKey string
 }
 
+// EventDataEvidence is posted when a new double sign evidence is found
+type EventDataEvidence struct {
+	Evidence *DuplicateVoteEvidence
+}
+
 // EventDataRequest is posted to propose a proposal
 type EventDataRequest struct {
 	Proposal *neatTypes.Block `json:"proposal"`
@@ -146,6 +154,7 @@ func (_ EventDataRoundState) AssertIsTMEventData()     {}
 func (_ EventDataVote) AssertIsTMEventData()           {}
 func (_ EventDataSignAggr) AssertIsTMEventData()       {}
 func (_ EventDataVote2Proposer) AssertIsTMEventData()  {}
+func (_ EventDataEvidence) AssertIsTMEventData()       {}
 
 func (_ EventDataRequest) AssertIsTMEventData()        {}
 func (_ EventDataMessage) AssertIsTMEventData()        {}
@@ -215,6 +224,10 @@ func FireEventVote2Proposer(fireable events.Fireable, vote EventDataVote2Propose
 	fireEvent(fireable, EventStringVote2Proposer(), vote)
 }
 
+func FireEventEvidence(fireable events.Fireable, evidence EventDataEvidence) {
+	fireEvent(fireable, EventStringEvidence(), evidence)
+}
+
 func FireEventTx(fireable events.Fireable, tx EventDataTx) {
 	fireEvent(fireable, EventStringTx(tx.Tx), tx)
 }
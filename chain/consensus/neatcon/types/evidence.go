@@ -0,0 +1,136 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+
+	. "github.com/Gessiux/go-common"
+	"github.com/Gessiux/neatchain/utilities/common"
+	neatCrypto "github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	ErrEvidenceMissingVote       = errors.New("Evidence is missing a vote")
+	ErrEvidenceMismatchedVotes   = errors.New("Evidence votes are not from the same validator and step")
+	ErrEvidenceSameBlockID       = errors.New("Evidence votes are for the same block")
+	ErrEvidenceInvalidSignature  = errors.New("Evidence has an invalid vote signature")
+	ErrEvidenceValidatorNotFound = errors.New("Evidence validator not found")
+	ErrEvidenceValidatorMismatch = errors.New("Evidence validator index and address mismatch")
+)
+
+// DuplicateVoteEvidence contains two conflicting votes signed by the same validator
+// at the same height, round and step, it's used to slash the validator.
+type DuplicateVoteEvidence struct {
+	VoteA *Vote `json:"vote_a"`
+	VoteB *Vote `json:"vote_b"`
+}
+
+// NewDuplicateVoteEvidence creates the evidence from conflicting votes,
+// the votes are ordered by block id so that the same conflict always has the same encoding.
+func NewDuplicateVoteEvidence(conflict *ErrVoteConflictingVotes) *DuplicateVoteEvidence {
+	voteA, voteB := conflict.VoteA, conflict.VoteB
+	if voteA.BlockID.Key() > voteB.BlockID.Key() {
+		voteA, voteB = voteB, voteA
+	}
+	return &DuplicateVoteEvidence{
+		VoteA: voteA,
+		VoteB: voteB,
+	}
+}
+
+// EncodeRLP serializes the evidence into the Ethereum RLP format.
+func (dve *DuplicateVoteEvidence) EncodeRLP(w io.Writer) error {
+	return rlp.Encode(w, []interface{}{
+		dve.VoteA,
+		dve.VoteB,
+	})
+}
+
+// DecodeRLP implements rlp.Decoder, and load the evidence fields from a RLP stream.
+func (dve *DuplicateVoteEvidence) DecodeRLP(s *rlp.Stream) error {
+	var ev struct {
+		VoteA *Vote
+		VoteB *Vote
+	}
+
+	if err := s.Decode(&ev); err != nil {
+		return err
+	}
+
+	dve.VoteA = ev.VoteA
+	dve.VoteB = ev.VoteB
+	return nil
+}
+
+// DecodeDuplicateVoteEvidence decodes the evidence from the RLP bytes
+func DecodeDuplicateVoteEvidence(bs []byte) (*DuplicateVoteEvidence, error) {
+	var dve DuplicateVoteEvidence
+	if err := rlp.DecodeBytes(bs, &dve); err != nil {
+		return nil, err
+	}
+	if dve.VoteA == nil || dve.VoteB == nil {
+		return nil, ErrEvidenceMissingVote
+	}
+	return &dve, nil
+}
+
+func (dve *DuplicateVoteEvidence) Height() uint64 {
+	return dve.VoteA.Height
+}
+
+func (dve *DuplicateVoteEvidence) Address() common.Address {
+	return common.BytesToAddress(dve.VoteA.ValidatorAddress)
+}
+
+// Hash identifies the offence, a validator is punished at most once per height
+func (dve *DuplicateVoteEvidence) Hash() common.Hash {
+	bs, _ := rlp.EncodeToBytes([]interface{}{dve.VoteA.ValidatorAddress, dve.VoteA.Height})
+	return neatCrypto.Keccak256Hash(bs)
+}
+
+// ValidateBasic checks the evidence votes are conflicting, without verifying the signatures
+func (dve *DuplicateVoteEvidence) ValidateBasic() error {
+	a, b := dve.VoteA, dve.VoteB
+	if a == nil || b == nil {
+		return ErrEvidenceMissingVote
+	}
+	if a.Height != b.Height || a.Round != b.Round || a.Type != b.Type ||
+		a.ValidatorIndex != b.ValidatorIndex || !bytes.Equal(a.ValidatorAddress, b.ValidatorAddress) {
+		return ErrEvidenceMismatchedVotes
+	}
+	if a.BlockID.Equals(b.BlockID) {
+		return ErrEvidenceSameBlockID
+	}
+	return nil
+}
+
+// Verify checks the evidence votes are conflicting and both signed by the validator
+func (dve *DuplicateVoteEvidence) Verify(chainID string, valSet *ValidatorSet) error {
+	if err := dve.ValidateBasic(); err != nil {
+		return err
+	}
+
+	addr, val := valSet.GetByIndex(int(dve.VoteA.ValidatorIndex))
+	if val == nil {
+		return ErrEvidenceValidatorNotFound
+	}
+	if !bytes.Equal(addr, dve.VoteA.ValidatorAddress) {
+		return ErrEvidenceValidatorMismatch
+	}
+
+	for _, vote := range []*Vote{dve.VoteA, dve.VoteB} {
+		if vote.Signature == nil || !val.PubKey.VerifyBytes(SignBytes(chainID, vote), vote.Signature) {
+			return ErrEvidenceInvalidSignature
+		}
+	}
+	return nil
+}
+
+func (dve *DuplicateVoteEvidence) String() string {
+	return fmt.Sprintf("DuplicateVoteEvidence{%X %v/%02d/%v %X %X}",
+		Fingerprint(dve.VoteA.ValidatorAddress), dve.VoteA.Height, dve.VoteA.Round, dve.VoteA.Type,
+		Fingerprint(dve.VoteA.BlockID.Hash), Fingerprint(dve.VoteB.BlockID.Hash))
+}
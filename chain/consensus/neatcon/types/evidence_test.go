@@ -0,0 +1,77 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+func signTestVote(chainID string, pv *PrivValidator, index int, hash []byte) *Vote {
+	vote := newTestVote(10, 0, VoteTypePrevote, hash)
+	vote.ValidatorAddress = pv.Address[:]
+	vote.ValidatorIndex = uint64(index)
+	vote.Signature = pv.PrivKey.Sign(SignBytes(chainID, vote))
+	return vote
+}
+
+func TestDuplicateVoteEvidence(t *testing.T) {
+	chainID := "neatchain"
+	pvA := GenPrivValidatorKey(common.StringToAddress("NEATaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	pvB := GenPrivValidatorKey(common.StringToAddress("NEATbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbbb"))
+	valSet := NewValidatorSet([]*Validator{
+		NewValidator(pvA.Address[:], pvA.PubKey, big.NewInt(1)),
+		NewValidator(pvB.Address[:], pvB.PubKey, big.NewInt(1)),
+	})
+	index, _ := valSet.GetByAddress(pvA.Address[:])
+
+	voteA := signTestVote(chainID, pvA, index, []byte{0x02})
+	voteB := signTestVote(chainID, pvA, index, []byte{0x01})
+	evidence := NewDuplicateVoteEvidence(&ErrVoteConflictingVotes{VoteA: voteA, VoteB: voteB})
+	if err := evidence.Verify(chainID, valSet); err != nil {
+		t.Fatalf("verify evidence failed: %v", err)
+	}
+
+	// The same conflict always has the same encoding
+	swapped := NewDuplicateVoteEvidence(&ErrVoteConflictingVotes{VoteA: voteB, VoteB: voteA})
+	bs, err := rlp.EncodeToBytes(evidence)
+	if err != nil {
+		t.Fatal(err)
+	}
+	swappedBs, _ := rlp.EncodeToBytes(swapped)
+	if string(bs) != string(swappedBs) {
+		t.Errorf("evidence encoding depends on the vote order")
+	}
+
+	decoded, err := DecodeDuplicateVoteEvidence(bs)
+	if err != nil {
+		t.Fatalf("decode evidence failed: %v", err)
+	}
+	if err := decoded.Verify(chainID, valSet); err != nil {
+		t.Errorf("verify decoded evidence failed: %v", err)
+	}
+	if decoded.Hash() != evidence.Hash() || decoded.Address() != evidence.Address() {
+		t.Errorf("decoded evidence mismatch")
+	}
+
+	// Votes for the same block are not a conflict
+	if err := (&DuplicateVoteEvidence{VoteA: voteA, VoteB: voteA}).Verify(chainID, valSet); err != ErrEvidenceSameBlockID {
+		t.Errorf("expected %v, got %v", ErrEvidenceSameBlockID, err)
+	}
+
+	// Votes of different validators are not a conflict
+	other := signTestVote(chainID, pvB, 1-index, []byte{0x01})
+	if err := (&DuplicateVoteEvidence{VoteA: voteA, VoteB: other}).Verify(chainID, valSet); err != ErrEvidenceMismatchedVotes {
+		t.Errorf("expected %v, got %v", ErrEvidenceMismatchedVotes, err)
+	}
+
+	// Signatures must come from the validator
+	forged := newTestVote(10, 0, VoteTypePrevote, []byte{0x03})
+	forged.ValidatorAddress = pvA.Address[:]
+	forged.ValidatorIndex = uint64(index)
+	forged.Signature = pvB.PrivKey.Sign(SignBytes(chainID, forged))
+	if err := (&DuplicateVoteEvidence{VoteA: voteA, VoteB: forged}).Verify(chainID, valSet); err != ErrEvidenceInvalidSignature {
+		t.Errorf("expected %v, got %v", ErrEvidenceInvalidSignature, err)
+	}
+}
@@ -48,6 +48,14 @@ type RewardSchemeDoc struct {
 	RewardFirstYear    *big.Int `json:"reward_first_year"`
 	EpochNumberPerYear uint64   `json:"epoch_no_per_year"`
 	TotalYear          uint64   `json:"total_year"`
+
+	// Downtime slashing, disabled if the window is 0
+	SignedBlocksWindow uint64 `json:"signed_blocks_window"`  // number of the latest blocks to check the validator liveness
+	MinSignedPerWindow uint64 `json:"min_signed_per_window"` // minimum percent of the window the validator must have signed
+	DowntimeSlashRate  uint64 `json:"downtime_slash_rate"`   // percent of the deposit to burn once the validator falls below the minimum
+
+	// Number of epochs the refunds wait in the unbonding queue, 0 refunds at the end of the epoch
+	UnbondingPeriod uint64 `json:"unbonding_period"`
 }
 
 type GenesisDoc struct {
@@ -420,12 +428,20 @@ func (rs RewardSchemeDoc) MarshalJSON() ([]byte, error) {
 		RewardFirstYear    *hexutil.Big   `json:"reward_first_year"`
 		EpochNumberPerYear hexutil.Uint64 `json:"epoch_no_per_year"`
 		TotalYear          hexutil.Uint64 `json:"total_year"`
+		SignedBlocksWindow hexutil.Uint64 `json:"signed_blocks_window,omitempty"`
+		MinSignedPerWindow hexutil.Uint64 `json:"min_signed_per_window,omitempty"`
+		DowntimeSlashRate  hexutil.Uint64 `json:"downtime_slash_rate,omitempty"`
+		UnbondingPeriod    hexutil.Uint64 `json:"unbonding_period,omitempty"`
 	}
 	var enc hexRewardScheme
 	enc.TotalReward = (*hexutil.Big)(rs.TotalReward)
 	enc.RewardFirstYear = (*hexutil.Big)(rs.RewardFirstYear)
 	enc.EpochNumberPerYear = hexutil.Uint64(rs.EpochNumberPerYear)
 	enc.TotalYear = hexutil.Uint64(rs.TotalYear)
+	enc.SignedBlocksWindow = hexutil.Uint64(rs.SignedBlocksWindow)
+	enc.MinSignedPerWindow = hexutil.Uint64(rs.MinSignedPerWindow)
+	enc.DowntimeSlashRate = hexutil.Uint64(rs.DowntimeSlashRate)
+	enc.UnbondingPeriod = hexutil.Uint64(rs.UnbondingPeriod)
 
 	return json.Marshal(&enc)
 }
@@ -436,6 +452,10 @@ func (rs *RewardSchemeDoc) UnmarshalJSON(input []byte) error {
 		RewardFirstYear    *hexutil.Big   `json:"reward_first_year"`
 		EpochNumberPerYear hexutil.Uint64 `json:"epoch_no_per_year"`
 		TotalYear          hexutil.Uint64 `json:"total_year"`
+		SignedBlocksWindow hexutil.Uint64 `json:"signed_blocks_window,omitempty"`
+		MinSignedPerWindow hexutil.Uint64 `json:"min_signed_per_window,omitempty"`
+		DowntimeSlashRate  hexutil.Uint64 `json:"downtime_slash_rate,omitempty"`
+		UnbondingPeriod    hexutil.Uint64 `json:"unbonding_period,omitempty"`
 	}
 	var dec hexRewardScheme
 	if err := json.Unmarshal(input, &dec); err != nil {
@@ -453,5 +473,16 @@ func (rs *RewardSchemeDoc) UnmarshalJSON(input []byte) error {
 	rs.EpochNumberPerYear = uint64(dec.EpochNumberPerYear)
 	rs.TotalYear = uint64(dec.TotalYear)
 
+	if dec.MinSignedPerWindow > 100 {
+		return errors.New("'min_signed_per_window' should not be greater than 100 for Genesis/reward_scheme")
+	}
+	if dec.DowntimeSlashRate > 100 {
+		return errors.New("'downtime_slash_rate' should not be greater than 100 for Genesis/reward_scheme")
+	}
+	rs.SignedBlocksWindow = uint64(dec.SignedBlocksWindow)
+	rs.MinSignedPerWindow = uint64(dec.MinSignedPerWindow)
+	rs.DowntimeSlashRate = uint64(dec.DowntimeSlashRate)
+	rs.UnbondingPeriod = uint64(dec.UnbondingPeriod)
+
 	return nil
 }
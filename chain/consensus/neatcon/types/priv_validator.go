@@ -2,6 +2,7 @@ package types
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"sync"
@@ -10,9 +11,38 @@ import (
 	. "github.com/Gessiux/go-common"
 	"github.com/Gessiux/go-crypto"
 	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/accounts/keystore"
+	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/utilities/common"
 )
 
+const (
+	stepNone      = 0 // Used to distinguish the initial state
+	stepPropose   = 1
+	stepPrevote   = 2
+	stepPrecommit = 3
+)
+
+var (
+	ErrHeightRegression = errors.New("Height regression")
+	ErrRoundRegression  = errors.New("Round regression")
+	ErrStepRegression   = errors.New("Step regression")
+	ErrConflictingSign  = errors.New("Conflicting data at the same height/round/step")
+	ErrSignerFailed     = errors.New("Signer failed to sign")
+)
+
+func voteToStep(vote *Vote) int8 {
+	switch vote.Type {
+	case VoteTypePrevote:
+		return stepPrevote
+	case VoteTypePrecommit:
+		return stepPrecommit
+	default:
+		PanicSanity("Unknown vote type")
+		return 0
+	}
+}
+
 type PrivValidator struct {
 	// NeatChain Account Address
 	Address common.Address `json:"address"`
@@ -22,8 +52,18 @@ type PrivValidator struct {
 	// PrivKey should be empty if a Signer other than the default is being used.
 	PrivKey crypto.PrivKey `json:"consensus_priv_key"`
 
+	// Last signed height/round/step, used to prevent double signing
+	LastHeight    uint64           `json:"last_height"`
+	LastRound     int              `json:"last_round"`
+	LastStep      int8             `json:"last_step"`
+	LastSignature crypto.Signature `json:"last_signature,omitempty"` // so we dont lose signatures
+	LastSignBytes []byte           `json:"last_signbytes,omitempty"` // so we dont lose signatures
+
 	Signer `json:"-"`
 
+	// Encrypted consensus private key, the key is saved encrypted if it's set
+	crypto *keystore.CryptoJSON
+
 	// For persistence.
 	// Overloaded for testing.
 	filePath string
@@ -36,6 +76,12 @@ type PrivV struct {
 	PubKey  crypto.PubKey  `json:"consensus_pub_key"`
 	PrivKey crypto.PrivKey `json:"consensus_priv_key"`
 
+	LastHeight    uint64           `json:"last_height"`
+	LastRound     int              `json:"last_round"`
+	LastStep      int8             `json:"last_step"`
+	LastSignature crypto.Signature `json:"last_signature,omitempty"`
+	LastSignBytes []byte           `json:"last_signbytes,omitempty"`
+
 	Signer `json:"-"`
 
 	// For persistence.
@@ -47,7 +93,8 @@ type PrivV struct {
 // This is used to sign votes.
 // It is the caller's duty to verify the msg before calling Sign,
 // eg. to avoid double signing.
-// Currently, the only callers are SignVote and SignProposal
+// Currently, the only caller is signBytesHRS, which guards against
+// signing conflicting data at the same or a lower height/round/step.
 type Signer interface {
 	Sign(msg []byte) crypto.Signature
 }
@@ -104,17 +151,30 @@ func LoadPrivValidator(filePath string) *PrivValidator {
 	if err != nil {
 		Exit(err.Error())
 	}
+	// The encrypted key stays locked until Unlock is called
+	privValJSONBytes, cryptoJson, err := decryptPrivValidatorJSON(privValJSONBytes)
+	if err != nil {
+		Exit(Fmt("Error reading PrivValidator from %v: %v\n", filePath, err))
+	}
 	privVal := wire.ReadJSON(&PrivV{}, privValJSONBytes, &err).(*PrivV)
 
 	if err != nil {
 		Exit(Fmt("Error reading PrivValidator from %v: %v\n", filePath, err))
 	}
 	privV := &PrivValidator{
-		Address:  common.StringToAddress(privVal.Address),
-		PubKey:   privVal.PubKey,
-		PrivKey:  privVal.PrivKey,
-		filePath: filePath,
-		Signer:   NewDefaultSigner(privVal.PrivKey),
+		Address:       common.StringToAddress(privVal.Address),
+		PubKey:        privVal.PubKey,
+		PrivKey:       privVal.PrivKey,
+		LastHeight:    privVal.LastHeight,
+		LastRound:     privVal.LastRound,
+		LastStep:      privVal.LastStep,
+		LastSignature: privVal.LastSignature,
+		LastSignBytes: privVal.LastSignBytes,
+		crypto:        cryptoJson,
+		filePath:      filePath,
+	}
+	if privV.PrivKey != nil {
+		privV.Signer = NewDefaultSigner(privV.PrivKey)
 	}
 
 	return privV
@@ -155,9 +215,22 @@ func (pv *PrivValidator) save() {
 	var priv PrivV
 	priv.Address = pv.Address.String()
 	priv.PubKey = pv.PubKey
-	priv.PrivKey = pv.PrivKey
+	if pv.crypto == nil {
+		priv.PrivKey = pv.PrivKey
+	}
+	priv.LastHeight = pv.LastHeight
+	priv.LastRound = pv.LastRound
+	priv.LastStep = pv.LastStep
+	priv.LastSignature = pv.LastSignature
+	priv.LastSignBytes = pv.LastSignBytes
 
 	jsonBytes := wire.JSONBytesPretty(priv)
+	if pv.crypto != nil {
+		var err error
+		if jsonBytes, err = encryptPrivValidatorJSON(jsonBytes, pv.crypto); err != nil {
+			PanicCrisis(err)
+		}
+	}
 	err := WriteFileAtomic(pv.filePath, jsonBytes, 0600)
 	if err != nil {
 		// `@; BOOM!!!
@@ -177,7 +250,10 @@ func (pv *PrivValidator) SignVote(chainID string, vote *Vote) error {
 	pv.mtx.Lock()
 	defer pv.mtx.Unlock()
 
-	signature := pv.Sign(SignBytes(chainID, vote))
+	signature, err := pv.signBytesHRS(vote.Height, int(vote.Round), voteToStep(vote), SignBytes(chainID, vote))
+	if err != nil {
+		return errors.New(Fmt("Error signing vote: %v", err))
+	}
 	vote.Signature = signature
 	return nil
 }
@@ -186,11 +262,86 @@ func (pv *PrivValidator) SignProposal(chainID string, proposal *Proposal) error
 	pv.mtx.Lock()
 	defer pv.mtx.Unlock()
 
-	signature := pv.Sign(SignBytes(chainID, proposal))
+	signature, err := pv.signBytesHRS(proposal.Height, proposal.Round, stepPropose, SignBytes(chainID, proposal))
+	if err != nil {
+		return errors.New(Fmt("Error signing proposal: %v", err))
+	}
 	proposal.Signature = signature
 	return nil
 }
 
+// signBytesHRS signs the given signBytes if the height/round/step (HRS)
+// are greater than the latest state. If the HRS are equal and the bytes
+// are identical, it returns the pv.LastSignature.
+func (pv *PrivValidator) signBytesHRS(height uint64, round int, step int8, signBytes []byte) (crypto.Signature, error) {
+
+	lastSignature, err := checkHRS(pv.LastHeight, pv.LastRound, pv.LastStep, pv.LastSignature, pv.LastSignBytes,
+		height, round, step, signBytes)
+	if err != nil {
+		return nil, err
+	}
+	if lastSignature != nil {
+		log.Info("Using PrivValidator LastSignature", "sig", lastSignature)
+		return lastSignature, nil
+	}
+
+	// Sign
+	signature := pv.Sign(signBytes)
+	if signature == nil {
+		return nil, ErrSignerFailed
+	}
+
+	// Persist height/round/step
+	pv.LastHeight = height
+	pv.LastRound = round
+	pv.LastStep = step
+	pv.LastSignature = signature
+	pv.LastSignBytes = signBytes
+	// In-memory validators (no file set) have nothing to persist
+	if pv.filePath != "" {
+		pv.save()
+	}
+
+	return signature, nil
+}
+
+// checkHRS compares the height/round/step (HRS) with the last signed one.
+// It returns an error if the HRS regresses or conflicting data is signed at the same HRS,
+// and the last signature if the identical bytes have been signed already.
+func checkHRS(lastHeight uint64, lastRound int, lastStep int8, lastSignature crypto.Signature, lastSignBytes []byte,
+	height uint64, round int, step int8, signBytes []byte) (crypto.Signature, error) {
+
+	// If height regression, err
+	if lastHeight > height {
+		return nil, ErrHeightRegression
+	}
+	// More cases for when the height matches
+	if lastHeight == height {
+		// If round regression, err
+		if lastRound > round {
+			return nil, ErrRoundRegression
+		}
+		if lastRound == round {
+			// If step regression, err
+			if lastStep > step {
+				return nil, ErrStepRegression
+			} else if lastStep == step {
+				if lastSignBytes != nil {
+					if lastSignature == nil || lastSignature.IsZero() {
+						PanicSanity("PrivValidator: LastSignature is nil but LastSignBytes is not!")
+					}
+					// so we dont sign a conflicting vote or proposal
+					if bytes.Equal(lastSignBytes, signBytes) {
+						return lastSignature, nil
+					}
+				}
+				return nil, ErrConflictingSign
+			}
+		}
+	}
+	return nil, nil
+}
+
 func (pv *PrivValidator) String() string {
 	return fmt.Sprintf("PrivValidator{%X}", pv.Address)
 }
@@ -0,0 +1,132 @@
+package types
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+
+	"github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/accounts/keystore"
+)
+
+// privValidatorCryptoKey is the json key of the encrypted consensus private key,
+// it replaces consensus_priv_key in the file of an encrypted PrivValidator
+const privValidatorCryptoKey = "crypto"
+
+var (
+	ErrPrivValidatorLocked    = errors.New("PrivValidator is locked")
+	ErrPrivValidatorNotLocked = errors.New("PrivValidator is not encrypted")
+)
+
+// IsLocked returns true if the consensus private key is encrypted and not unlocked yet
+func (pv *PrivValidator) IsLocked() bool {
+	return pv.crypto != nil && pv.PrivKey == nil
+}
+
+// IsEncrypted returns true if the consensus private key is saved encrypted
+func (pv *PrivValidator) IsEncrypted() bool {
+	return pv.crypto != nil
+}
+
+// Unlock decrypts the consensus private key with the passphrase
+func (pv *PrivValidator) Unlock(passphrase string) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	if pv.crypto == nil {
+		return ErrPrivValidatorNotLocked
+	}
+	keyBytes, err := keystore.DecryptDataV3(*pv.crypto, passphrase)
+	if err != nil {
+		return err
+	}
+	privKey, err := crypto.PrivKeyFromBytes(keyBytes)
+	if err != nil {
+		return err
+	}
+	pv.PrivKey = privKey
+	pv.Signer = NewDefaultSigner(privKey)
+	return nil
+}
+
+// Encrypt encrypts the consensus private key with the passphrase the same way as the account keystore,
+// the key is saved encrypted from now on. The key is encrypted once, so that saving the last signed
+// height/round/step doesn't cost a key derivation
+func (pv *PrivValidator) Encrypt(passphrase string, scryptN, scryptP int) error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	if pv.PrivKey == nil {
+		return ErrPrivValidatorLocked
+	}
+	// the key is encoded with its type, see crypto.PrivKeyFromBytes
+	keyBytes := wire.BinaryBytes(struct{ crypto.PrivKey }{pv.PrivKey})
+	cryptoJson, err := keystore.EncryptDataV3(keyBytes, []byte(passphrase), scryptN, scryptP)
+	if err != nil {
+		return err
+	}
+	pv.crypto = &cryptoJson
+	return nil
+}
+
+// RemoveEncryption saves the consensus private key as plain text from now on
+func (pv *PrivValidator) RemoveEncryption() error {
+	pv.mtx.Lock()
+	defer pv.mtx.Unlock()
+
+	if pv.PrivKey == nil {
+		return ErrPrivValidatorLocked
+	}
+	pv.crypto = nil
+	return nil
+}
+
+// encryptPrivValidatorJSON replaces consensus_priv_key of the PrivValidator json with the encrypted key
+func encryptPrivValidatorJSON(jsonBytes []byte, cryptoJson *keystore.CryptoJSON) ([]byte, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &fields); err != nil {
+		return nil, err
+	}
+	delete(fields, "consensus_priv_key")
+
+	cryptoBytes, err := json.Marshal(cryptoJson)
+	if err != nil {
+		return nil, err
+	}
+	fields[privValidatorCryptoKey] = cryptoBytes
+	return json.MarshalIndent(fields, "", "\t")
+}
+
+// decryptPrivValidatorJSON splits the encrypted key from the PrivValidator json, nil if not encrypted
+func decryptPrivValidatorJSON(jsonBytes []byte) ([]byte, *keystore.CryptoJSON, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(jsonBytes, &fields); err != nil {
+		return nil, nil, err
+	}
+	cryptoBytes, encrypted := fields[privValidatorCryptoKey]
+	if !encrypted {
+		return jsonBytes, nil, nil
+	}
+
+	cryptoJson := new(keystore.CryptoJSON)
+	if err := json.Unmarshal(cryptoBytes, cryptoJson); err != nil {
+		return nil, nil, err
+	}
+	delete(fields, privValidatorCryptoKey)
+	jsonBytes, err := json.Marshal(fields)
+	if err != nil {
+		return nil, nil, err
+	}
+	return jsonBytes, cryptoJson, nil
+}
+
+// IsPrivValidatorEncrypted checks whether the consensus private key in the file is encrypted
+func IsPrivValidatorEncrypted(filePath string) (bool, error) {
+	jsonBytes, err := ioutil.ReadFile(filePath)
+	if err != nil {
+		return false, err
+	}
+	_, cryptoJson, err := decryptPrivValidatorJSON(jsonBytes)
+	return cryptoJson != nil, err
+}
@@ -0,0 +1,502 @@
+package types
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	. "github.com/Gessiux/go-common"
+	"github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+const (
+	signerMsgTypePubKeyRequest  = byte(0x01)
+	signerMsgTypePubKeyResponse = byte(0x02)
+	signerMsgTypeSignRequest    = byte(0x03)
+	signerMsgTypeSignResponse   = byte(0x04)
+
+	maxSignerMessageSize = 1048576 // 1MB
+	remoteSignerTimeout  = 3 * time.Second
+)
+
+var (
+	ErrSignerUnexpectedResponse = errors.New("Unexpected response from remote signer")
+	ErrSignerUnknownSignBytes   = errors.New("Sign bytes are neither a vote nor a proposal")
+	ErrSignerInvalidChainID     = errors.New("Invalid chain id in sign bytes")
+)
+
+// SignerMessage is exchanged between the validator and the remote signer daemon
+type SignerMessage interface{}
+
+var _ = wire.RegisterInterface(
+	struct{ SignerMessage }{},
+	wire.ConcreteType{&PubKeyRequest{}, signerMsgTypePubKeyRequest},
+	wire.ConcreteType{&PubKeyResponse{}, signerMsgTypePubKeyResponse},
+	wire.ConcreteType{&SignRequest{}, signerMsgTypeSignRequest},
+	wire.ConcreteType{&SignResponse{}, signerMsgTypeSignResponse},
+)
+
+type PubKeyRequest struct{}
+
+type PubKeyResponse struct {
+	Address []byte
+	PubKey  crypto.PubKey
+}
+
+// SignRequest carries the sign bytes only, the signer daemon reads
+// the chain id and height/round/step from the sign bytes by itself
+type SignRequest struct {
+	SignBytes []byte
+}
+
+type SignResponse struct {
+	Signature crypto.Signature
+	Error     string
+}
+
+func writeSignerMessage(conn net.Conn, msg SignerMessage) error {
+	var n int
+	var err error
+	wire.WriteBinary(struct{ SignerMessage }{msg}, conn, &n, &err)
+	return err
+}
+
+func readSignerMessage(conn net.Conn) (SignerMessage, error) {
+	var n int
+	var err error
+	msg := wire.ReadBinary(struct{ SignerMessage }{}, conn, maxSignerMessageSize, &n, &err).(struct{ SignerMessage }).SignerMessage
+	return msg, err
+}
+
+// LoadSignerTLSConfig loads the certificate used by either side of the signer connection.
+// Both sides must present a certificate issued by the CA, any other peer is rejected.
+func LoadSignerTLSConfig(certFile, keyFile, caFile string) (*tls.Config, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, err
+	}
+	caPEM, err := ioutil.ReadFile(caFile)
+	if err != nil {
+		return nil, err
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(caPEM) {
+		return nil, fmt.Errorf("no certificate found in %v", caFile)
+	}
+
+	return &tls.Config{
+		Certificates: []tls.Certificate{cert},
+		MinVersion:   tls.VersionTLS12,
+		ClientAuth:   tls.RequireAnyClientCert,
+		// The peer is identified by the CA rather than the host name,
+		// the same check is used by both sides, see verifyPeerCertificate
+		InsecureSkipVerify:    true,
+		VerifyPeerCertificate: verifyPeerCertificate(pool),
+	}, nil
+}
+
+func verifyPeerCertificate(pool *x509.CertPool) func([][]byte, [][]*x509.Certificate) error {
+	return func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+		if len(rawCerts) == 0 {
+			return errors.New("peer certificate missing")
+		}
+		certs := make([]*x509.Certificate, len(rawCerts))
+		for i, raw := range rawCerts {
+			cert, err := x509.ParseCertificate(raw)
+			if err != nil {
+				return err
+			}
+			certs[i] = cert
+		}
+		intermediates := x509.NewCertPool()
+		for _, cert := range certs[1:] {
+			intermediates.AddCert(cert)
+		}
+		_, err := certs[0].Verify(x509.VerifyOptions{
+			Roots:         pool,
+			Intermediates: intermediates,
+			KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageAny},
+		})
+		return err
+	}
+}
+
+// protoAddr: e.g. "tcp://127.0.0.1:46659" or "unix:///tmp/signer.sock"
+func protocolAndAddress(protoAddr string) (string, string) {
+	parts := strings.SplitN(protoAddr, "://", 2)
+	if len(parts) == 1 {
+		return "tcp", parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+//-------------------------------------
+
+// Implements Signer, the consensus key is kept by a signer daemon
+// and every sign request is sent over a mutually authenticated connection.
+type RemoteSigner struct {
+	addr      string
+	tlsConfig *tls.Config
+
+	mtx  sync.Mutex
+	conn net.Conn
+}
+
+func NewRemoteSigner(addr string, tlsConfig *tls.Config) *RemoteSigner {
+	return &RemoteSigner{
+		addr:      addr,
+		tlsConfig: tlsConfig,
+	}
+}
+
+// LoadRemotePrivValidator creates the PrivValidator whose key is held by the signer daemon at addr.
+// The HRS of the validator is kept in memory only, the daemon guards against double signing on its own.
+func LoadRemotePrivValidator(addr string, tlsConfig *tls.Config) (*PrivValidator, error) {
+	signer := NewRemoteSigner(addr, tlsConfig)
+	address, pubKey, err := signer.GetPubKey()
+	if err != nil {
+		return nil, err
+	}
+	return &PrivValidator{
+		Address: common.BytesToAddress(address),
+		PubKey:  pubKey,
+		Signer:  signer,
+	}, nil
+}
+
+// Implements Signer, returns nil if the signer daemon fails to sign
+func (rs *RemoteSigner) Sign(msg []byte) crypto.Signature {
+	resp, err := rs.call(&SignRequest{SignBytes: msg})
+	if err != nil {
+		log.Error("RemoteSigner: failed to sign", "addr", rs.addr, "err", err)
+		return nil
+	}
+	signResp, ok := resp.(*SignResponse)
+	if !ok {
+		log.Error("RemoteSigner: failed to sign", "addr", rs.addr, "err", ErrSignerUnexpectedResponse)
+		return nil
+	}
+	if signResp.Error != "" {
+		log.Error("RemoteSigner: sign request rejected", "addr", rs.addr, "err", signResp.Error)
+		return nil
+	}
+	return signResp.Signature
+}
+
+// GetPubKey returns the account address and consensus public key of the signer daemon
+func (rs *RemoteSigner) GetPubKey() ([]byte, crypto.PubKey, error) {
+	resp, err := rs.call(&PubKeyRequest{})
+	if err != nil {
+		return nil, nil, err
+	}
+	pubKeyResp, ok := resp.(*PubKeyResponse)
+	if !ok || pubKeyResp.PubKey == nil {
+		return nil, nil, ErrSignerUnexpectedResponse
+	}
+	return pubKeyResp.Address, pubKeyResp.PubKey, nil
+}
+
+func (rs *RemoteSigner) Close() {
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+
+	rs.closeConn()
+}
+
+// call sends the request and waits for the response, the connection is
+// re-established once if it's broken, e.g. the signer daemon has restarted
+func (rs *RemoteSigner) call(req SignerMessage) (SignerMessage, error) {
+	rs.mtx.Lock()
+	defer rs.mtx.Unlock()
+
+	var err error
+	for i := 0; i < 2; i++ {
+		if rs.conn == nil {
+			conn, err := rs.dial()
+			if err != nil {
+				return nil, err
+			}
+			rs.conn = conn
+		}
+
+		var resp SignerMessage
+		rs.conn.SetDeadline(time.Now().Add(remoteSignerTimeout))
+		if err = writeSignerMessage(rs.conn, req); err == nil {
+			resp, err = readSignerMessage(rs.conn)
+		}
+		if err == nil {
+			return resp, nil
+		}
+		rs.closeConn()
+	}
+	return nil, err
+}
+
+func (rs *RemoteSigner) dial() (net.Conn, error) {
+	proto, address := protocolAndAddress(rs.addr)
+	dialer := &net.Dialer{Timeout: remoteSignerTimeout}
+	return tls.DialWithDialer(dialer, proto, address, rs.tlsConfig)
+}
+
+func (rs *RemoteSigner) closeConn() {
+	if rs.conn != nil {
+		rs.conn.Close()
+		rs.conn = nil
+	}
+}
+
+//-------------------------------------
+
+// signState is the last signed height/round/step of a chain, persisted by the signer daemon
+type signState struct {
+	Height    uint64           `json:"height"`
+	Round     int              `json:"round"`
+	Step      int8             `json:"step"`
+	Signature crypto.Signature `json:"signature,omitempty"`
+	SignBytes []byte           `json:"signbytes,omitempty"`
+}
+
+// SignerServer holds the consensus key and serves the sign requests of the validator.
+// The last signed height/round/step of every chain is persisted in stateDir, so that
+// the key never signs conflicting votes or proposals, even if the validator is compromised.
+type SignerServer struct {
+	laddr     string
+	tlsConfig *tls.Config
+	privVal   *PrivValidator
+	stateDir  string
+
+	mtx    sync.Mutex
+	states map[string]*signState
+
+	connMtx  sync.Mutex
+	conns    map[net.Conn]struct{}
+	listener net.Listener
+	quit     chan struct{}
+
+	logger log.Logger
+}
+
+func NewSignerServer(laddr string, tlsConfig *tls.Config, privVal *PrivValidator, stateDir string, logger log.Logger) *SignerServer {
+	return &SignerServer{
+		laddr:     laddr,
+		tlsConfig: tlsConfig,
+		privVal:   privVal,
+		stateDir:  stateDir,
+		states:    make(map[string]*signState),
+		conns:     make(map[net.Conn]struct{}),
+		quit:      make(chan struct{}),
+		logger:    logger,
+	}
+}
+
+func (ss *SignerServer) Start() error {
+	if err := EnsureDir(ss.stateDir, 0700); err != nil {
+		return err
+	}
+
+	proto, address := protocolAndAddress(ss.laddr)
+	if proto == "unix" {
+		os.Remove(address)
+	}
+	listener, err := net.Listen(proto, address)
+	if err != nil {
+		return err
+	}
+	ss.listener = tls.NewListener(listener, ss.tlsConfig)
+	ss.logger.Info("Signer started", "laddr", ss.laddr, "address", ss.privVal.Address.String())
+
+	go ss.acceptRoutine()
+	return nil
+}
+
+func (ss *SignerServer) Stop() {
+	close(ss.quit)
+	if ss.listener != nil {
+		ss.listener.Close()
+	}
+
+	ss.connMtx.Lock()
+	defer ss.connMtx.Unlock()
+	for conn := range ss.conns {
+		conn.Close()
+	}
+}
+
+// Addr returns the address the server is listening on
+func (ss *SignerServer) Addr() net.Addr {
+	return ss.listener.Addr()
+}
+
+func (ss *SignerServer) acceptRoutine() {
+	for {
+		conn, err := ss.listener.Accept()
+		if err != nil {
+			select {
+			case <-ss.quit:
+				return
+			default:
+			}
+			ss.logger.Error("Signer failed to accept connection", "err", err)
+			time.Sleep(100 * time.Millisecond)
+			continue
+		}
+		go ss.handleConn(conn)
+	}
+}
+
+func (ss *SignerServer) handleConn(conn net.Conn) {
+	ss.connMtx.Lock()
+	ss.conns[conn] = struct{}{}
+	ss.connMtx.Unlock()
+	defer func() {
+		ss.connMtx.Lock()
+		delete(ss.conns, conn)
+		ss.connMtx.Unlock()
+		conn.Close()
+	}()
+
+	for {
+		req, err := readSignerMessage(conn)
+		if err != nil {
+			ss.logger.Debug("Signer connection closed", "remote", conn.RemoteAddr(), "err", err)
+			return
+		}
+
+		var resp SignerMessage
+		switch req := req.(type) {
+		case *PubKeyRequest:
+			resp = &PubKeyResponse{Address: ss.privVal.Address[:], PubKey: ss.privVal.PubKey}
+		case *SignRequest:
+			signature, err := ss.sign(req.SignBytes)
+			if err != nil {
+				ss.logger.Warn("Signer rejected sign request", "err", err)
+				resp = &SignResponse{Error: err.Error()}
+			} else {
+				resp = &SignResponse{Signature: signature}
+			}
+		default:
+			ss.logger.Warn("Signer received unknown request", "type", fmt.Sprintf("%T", req))
+			return
+		}
+
+		if err := writeSignerMessage(conn, resp); err != nil {
+			ss.logger.Debug("Signer failed to write response", "err", err)
+			return
+		}
+	}
+}
+
+// sign checks the sign bytes against the last signed HRS of the chain,
+// the new HRS is persisted before the signature is returned
+func (ss *SignerServer) sign(signBytes []byte) (crypto.Signature, error) {
+	chainID, height, round, step, err := parseSignBytes(signBytes)
+	if err != nil {
+		return nil, err
+	}
+
+	ss.mtx.Lock()
+	defer ss.mtx.Unlock()
+
+	state, err := ss.loadState(chainID)
+	if err != nil {
+		return nil, err
+	}
+	lastSignature, err := checkHRS(state.Height, state.Round, state.Step, state.Signature, state.SignBytes,
+		height, round, step, signBytes)
+	if err != nil {
+		return nil, err
+	}
+	if lastSignature != nil {
+		return lastSignature, nil
+	}
+
+	signature := ss.privVal.Sign(signBytes)
+	newState := &signState{
+		Height:    height,
+		Round:     round,
+		Step:      step,
+		Signature: signature,
+		SignBytes: signBytes,
+	}
+	if err := WriteFileAtomic(ss.statePath(chainID), wire.JSONBytesPretty(newState), 0600); err != nil {
+		return nil, err
+	}
+	ss.states[chainID] = newState
+	return signature, nil
+}
+
+func (ss *SignerServer) loadState(chainID string) (*signState, error) {
+	if state, exist := ss.states[chainID]; exist {
+		return state, nil
+	}
+	state := &signState{}
+	jsonBytes, err := ioutil.ReadFile(ss.statePath(chainID))
+	if err == nil {
+		wire.ReadJSON(state, jsonBytes, &err)
+		if err != nil {
+			return nil, err
+		}
+	} else if !os.IsNotExist(err) {
+		return nil, err
+	}
+	ss.states[chainID] = state
+	return state, nil
+}
+
+func (ss *SignerServer) statePath(chainID string) string {
+	return filepath.Join(ss.stateDir, chainID+".json")
+}
+
+// parseSignBytes reads the chain id and height/round/step from the canonical json of a vote or proposal
+func parseSignBytes(signBytes []byte) (chainID string, height uint64, round int, step int8, err error) {
+	var once struct {
+		ChainID string `json:"chain_id"`
+		Vote    *struct {
+			Height uint64 `json:"height"`
+			Round  int    `json:"round"`
+			Type   byte   `json:"type"`
+		} `json:"vote"`
+		Proposal *struct {
+			Height uint64 `json:"height"`
+			Round  int    `json:"round"`
+		} `json:"proposal"`
+	}
+	if err = json.Unmarshal(signBytes, &once); err != nil {
+		return
+	}
+	chainID = once.ChainID
+	if chainID == "" || chainID != filepath.Base(chainID) || strings.HasPrefix(chainID, ".") {
+		err = ErrSignerInvalidChainID
+		return
+	}
+
+	switch {
+	case once.Vote != nil && once.Proposal == nil:
+		height, round = once.Vote.Height, once.Vote.Round
+		switch once.Vote.Type {
+		case VoteTypePrevote:
+			step = stepPrevote
+		case VoteTypePrecommit:
+			step = stepPrecommit
+		default:
+			err = ErrVoteUnexpectedStep
+		}
+	case once.Proposal != nil && once.Vote == nil:
+		height, round, step = once.Proposal.Height, once.Proposal.Round, stepPropose
+	default:
+		err = ErrSignerUnknownSignBytes
+	}
+	return
+}
@@ -0,0 +1,149 @@
+package types
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// writeTestCerts writes a CA and the certificates issued by it into dir
+func writeTestCerts(t *testing.T, dir string, names ...string) {
+	caKey, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	caTemplate := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+	caDER, err := x509.CreateCertificate(rand.Reader, caTemplate, caTemplate, &caKey.PublicKey, caKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	writePEM(t, filepath.Join(dir, "ca.pem"), "CERTIFICATE", caDER)
+
+	for i, name := range names {
+		key, _ := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+		template := &x509.Certificate{
+			SerialNumber: big.NewInt(int64(i + 2)),
+			Subject:      pkix.Name{CommonName: name},
+			NotBefore:    time.Now().Add(-time.Hour),
+			NotAfter:     time.Now().Add(time.Hour),
+			KeyUsage:     x509.KeyUsageDigitalSignature,
+			ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		}
+		der, err := x509.CreateCertificate(rand.Reader, template, caTemplate, &key.PublicKey, caKey)
+		if err != nil {
+			t.Fatal(err)
+		}
+		keyDER, _ := x509.MarshalECPrivateKey(key)
+		writePEM(t, filepath.Join(dir, name+".pem"), "CERTIFICATE", der)
+		writePEM(t, filepath.Join(dir, name+"_key.pem"), "EC PRIVATE KEY", keyDER)
+	}
+}
+
+func writePEM(t *testing.T, path, blockType string, der []byte) {
+	if err := ioutil.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRemoteSigner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "signer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	writeTestCerts(t, dir, "signer", "validator")
+	os.Mkdir(filepath.Join(dir, "other"), 0700)
+	writeTestCerts(t, filepath.Join(dir, "other"), "validator")
+
+	serverTLS, err := LoadSignerTLSConfig(filepath.Join(dir, "signer.pem"), filepath.Join(dir, "signer_key.pem"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	key := GenPrivValidatorKey(common.StringToAddress("NEATaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	server := NewSignerServer("tcp://127.0.0.1:0", serverTLS, key, filepath.Join(dir, "state"), log.New())
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { server.Stop() }()
+	laddr := "tcp://" + server.Addr().String()
+
+	// The validator with a certificate issued by the same CA
+	clientTLS, err := LoadSignerTLSConfig(filepath.Join(dir, "validator.pem"), filepath.Join(dir, "validator_key.pem"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	pv, err := LoadRemotePrivValidator(laddr, clientTLS)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if pv.Address != key.Address || !pv.PubKey.Equals(key.PubKey) {
+		t.Fatalf("remote key mismatch")
+	}
+
+	chainID := "neatchain"
+	vote := newTestVote(10, 0, VoteTypePrevote, []byte{0x01})
+	if err := pv.SignVote(chainID, vote); err != nil {
+		t.Fatal(err)
+	}
+	if !key.PubKey.VerifyBytes(SignBytes(chainID, vote), vote.Signature) {
+		t.Errorf("invalid remote signature")
+	}
+
+	// The signer refuses conflicting data even if the validator's own guard is bypassed
+	conflicting := newTestVote(10, 0, VoteTypePrevote, []byte{0x02})
+	if signature := pv.Sign(SignBytes(chainID, conflicting)); signature != nil {
+		t.Errorf("signer signed conflicting vote")
+	}
+	lower := newTestVote(9, 0, VoteTypePrecommit, []byte{0x02})
+	if signature := pv.Sign(SignBytes(chainID, lower)); signature != nil {
+		t.Errorf("signer signed vote of lower height")
+	}
+	// Every chain has its own guard
+	if signature := pv.Sign(SignBytes("side_0", conflicting)); signature == nil {
+		t.Errorf("signer refused vote of another chain")
+	}
+	// Only votes and proposals are signed
+	if signature := pv.Sign([]byte("hello")); signature != nil {
+		t.Errorf("signer signed arbitrary bytes")
+	}
+
+	// The guard survives a restart of the signer
+	server.Stop()
+	server = NewSignerServer(laddr, serverTLS, key, filepath.Join(dir, "state"), log.New())
+	if err := server.Start(); err != nil {
+		t.Fatal(err)
+	}
+	if signature := pv.Sign(SignBytes(chainID, conflicting)); signature != nil {
+		t.Errorf("signer signed conflicting vote after restart")
+	}
+	next := newTestVote(10, 0, VoteTypePrecommit, []byte{0x01})
+	if err := pv.SignVote(chainID, next); err != nil {
+		t.Errorf("sign after restart failed: %v", err)
+	}
+
+	// The validator with a certificate of another CA is rejected
+	otherTLS, err := LoadSignerTLSConfig(filepath.Join(dir, "other", "validator.pem"), filepath.Join(dir, "other", "validator_key.pem"), filepath.Join(dir, "ca.pem"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRemotePrivValidator(laddr, otherTLS); err == nil {
+		t.Errorf("expected the validator of another CA to be rejected")
+	}
+}
@@ -0,0 +1,144 @@
+package types
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/accounts/keystore"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+func newTestVote(height, round uint64, type_ byte, hash []byte) *Vote {
+	return &Vote{
+		Height:  height,
+		Round:   round,
+		Type:    type_,
+		BlockID: BlockID{Hash: hash},
+	}
+}
+
+func TestPrivValidatorDoubleSign(t *testing.T) {
+	dir, err := ioutil.TempDir("", "priv_validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "priv_validator.json")
+	pv := GenPrivValidatorKey(common.StringToAddress("NEATaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	pv.SetFile(file)
+	pv.Save()
+
+	chainID := "neatchain"
+	vote := newTestVote(10, 0, VoteTypePrevote, []byte{0x01})
+	if err := pv.SignVote(chainID, vote); err != nil {
+		t.Fatalf("sign vote failed: %v", err)
+	}
+
+	// Signing the identical vote again returns the identical signature
+	same := newTestVote(10, 0, VoteTypePrevote, []byte{0x01})
+	if err := pv.SignVote(chainID, same); err != nil {
+		t.Fatalf("re-sign vote failed: %v", err)
+	}
+	if !same.Signature.Equals(vote.Signature) {
+		t.Errorf("re-signed vote has a different signature")
+	}
+
+	// A conflicting vote at the same HRS is refused
+	if err := pv.SignVote(chainID, newTestVote(10, 0, VoteTypePrevote, []byte{0x02})); err == nil {
+		t.Errorf("expected conflicting vote to be refused")
+	}
+
+	// The last signed state survives a reload
+	loaded := LoadPrivValidator(file)
+	if loaded.LastHeight != 10 || loaded.LastRound != 0 || loaded.LastStep != stepPrevote {
+		t.Errorf("unexpected last signed state %v/%v/%v", loaded.LastHeight, loaded.LastRound, loaded.LastStep)
+	}
+
+	// Height, round and step regressions are refused
+	if err := loaded.SignVote(chainID, newTestVote(9, 0, VoteTypePrecommit, []byte{0x01})); err == nil {
+		t.Errorf("expected height regression to be refused")
+	}
+	proposal := NewProposal(10, 0, []byte{0x01}, PartSetHeader{}, -1, BlockID{}, "")
+	if err := loaded.SignProposal(chainID, proposal); err == nil {
+		t.Errorf("expected step regression to be refused")
+	}
+
+	// Moving forward is allowed
+	if err := loaded.SignVote(chainID, newTestVote(10, 0, VoteTypePrecommit, []byte{0x01})); err != nil {
+		t.Errorf("sign precommit failed: %v", err)
+	}
+	proposal = NewProposal(10, 1, []byte{0x01}, PartSetHeader{}, -1, BlockID{}, "")
+	if err := loaded.SignProposal(chainID, proposal); err != nil {
+		t.Errorf("sign proposal failed: %v", err)
+	}
+	if err := loaded.SignVote(chainID, newTestVote(10, 0, VoteTypePrecommit, []byte{0x01})); err == nil {
+		t.Errorf("expected round regression to be refused")
+	}
+}
+
+func TestPrivValidatorEncrypt(t *testing.T) {
+	dir, err := ioutil.TempDir("", "priv_validator")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "priv_validator.json")
+	pv := GenPrivValidatorKey(common.StringToAddress("NEATaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaaa"))
+	pv.SetFile(file)
+	if err := pv.Encrypt("foo", keystore.LightScryptN, keystore.LightScryptP); err != nil {
+		t.Fatal(err)
+	}
+	pv.Save()
+
+	if encrypted, err := IsPrivValidatorEncrypted(file); err != nil || !encrypted {
+		t.Fatalf("expected encrypted file, got %v %v", encrypted, err)
+	}
+	jsonBytes, _ := ioutil.ReadFile(file)
+	if strings.Contains(string(jsonBytes), "consensus_priv_key") {
+		t.Errorf("plain private key in encrypted file")
+	}
+
+	// The key is locked after loading, the public key is still known
+	loaded := LoadPrivValidator(file)
+	if !loaded.IsLocked() || loaded.PrivKey != nil || !loaded.PubKey.Equals(pv.PubKey) {
+		t.Fatalf("unexpected loaded PrivValidator")
+	}
+	if err := loaded.Unlock("bar"); err != keystore.ErrDecrypt {
+		t.Errorf("expected %v, got %v", keystore.ErrDecrypt, err)
+	}
+	if err := loaded.Unlock("foo"); err != nil {
+		t.Fatal(err)
+	}
+	if !loaded.PrivKey.Equals(pv.PrivKey) {
+		t.Fatalf("unlocked private key mismatch")
+	}
+
+	// Signing saves the last signed state, the key stays encrypted
+	chainID := "neatchain"
+	vote := newTestVote(10, 0, VoteTypePrevote, []byte{0x01})
+	if err := loaded.SignVote(chainID, vote); err != nil {
+		t.Fatal(err)
+	}
+	reloaded := LoadPrivValidator(file)
+	if !reloaded.IsLocked() || reloaded.LastHeight != 10 {
+		t.Errorf("unexpected reloaded PrivValidator")
+	}
+
+	// Save the key as plain text again
+	if err := reloaded.RemoveEncryption(); err != ErrPrivValidatorLocked {
+		t.Errorf("expected %v, got %v", ErrPrivValidatorLocked, err)
+	}
+	reloaded.Unlock("foo")
+	if err := reloaded.RemoveEncryption(); err != nil {
+		t.Fatal(err)
+	}
+	reloaded.Save()
+	if plain := LoadPrivValidator(file); plain.IsEncrypted() || !plain.PrivKey.Equals(pv.PrivKey) {
+		t.Errorf("unexpected plain PrivValidator")
+	}
+}
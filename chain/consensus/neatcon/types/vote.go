@@ -100,16 +100,8 @@ func (vote *Vote) DecodeRLP(s *rlp.Stream) error {
 	vote.Round = vt.Round
 	vote.Type = vt.Type
 	vote.BlockID = vt.BlockID
-
-	sig, err := crypto.SignatureFromBytes(vt.Signature)
-	if err != nil {
-		return err
-	}
-	vote.Signature = sig
-
-	if err != nil {
-		return err
-	}
+	// EncodeRLP writes the raw bytes of the BLS signature, without the go-wire type prefix
+	vote.Signature = crypto.BLSSignature(vt.Signature)
 
 	return nil
 }
@@ -18,6 +18,7 @@
 package consensus
 
 import (
+	"crypto/ecdsa"
 	"math/big"
 
 	"github.com/Gessiux/neatchain/chain/core/types"
@@ -60,6 +61,8 @@ type Broadcaster interface {
 	BroadcastMessage(msgcode uint64, data interface{})
 	// Find the Bad Preimages and send request to best peer for correction
 	TryFixBadPreimages()
+	// SendSystemTx signs the chain contract call data with the key and adds it into the local tx pool
+	SendSystemTx(data []byte, prv *ecdsa.PrivateKey) (common.Hash, error)
 }
 
 // Peer defines the interface to communicate with peer
@@ -77,6 +77,17 @@ type CacheConfig struct {
 	TrieDirtyLimit    int           // Memory limit (MB) at which to start flushing dirty trie nodes to disk
 	TrieDirtyDisabled bool          // Whether to disable trie write caching and GC altogether (archive node)
 	TrieTimeLimit     time.Duration // Time limit after which to flush the current in-memory trie to disk
+	TrieRetention     uint64        // Number of recent blocks whose state is kept on disk, 0 keeps all
+}
+
+// PruneStatus is the progress of the state pruning.
+type PruneStatus struct {
+	Running           bool   `json:"is_running"`
+	Retention         uint64 `json:"retention"`
+	LatestBlockNumber uint64 `json:"latest_block_number"`
+	LatestPruneNumber uint64 `json:"latest_prune_number"`
+	RetainedStates    int    `json:"retained_states"`
+	PrunedNodes       uint64 `json:"pruned_nodes"`
 }
 
 // BlockChain represents the canonical chain given a database with a genesis
@@ -101,6 +112,8 @@ type BlockChain struct {
 	triegc *prque.Prque    // Priority queue mapping block numbers to tries to gc
 	gcproc time.Duration   // Accumulates canonical block processing for trie dumping
 
+	prunedNodes uint64 // Trie nodes deleted by the state pruning, must be called atomically
+
 	hc                  *HeaderChain
 	rmLogsFeed          event.Feed
 	chainFeed           event.Feed
@@ -179,6 +192,9 @@ func NewBlockChain(db neatdb.Database, cacheConfig *CacheConfig, chainConfig *pa
 		cch:           cch,
 		logger:        chainConfig.ChainLogger,
 	}
+	if bc.pruning() || trie.RefcountEnabled(db) {
+		bc.stateCache.TrieDB().EnableRefcount()
+	}
 	bc.validator = NewBlockValidator(chainConfig, bc, engine)
 	bc.processor = NewStateProcessor(chainConfig, bc, engine, cch)
 
@@ -732,7 +748,7 @@ func (bc *BlockChain) Stop() {
 				recent := bc.GetBlockByNumber(number - offset)
 
 				bc.logger.Info("Writing cached state to disk", "block", recent.Number(), "hash", recent.Hash(), "root", recent.Root())
-				if err := triedb.Commit(recent.Root(), true); err != nil {
+				if err := bc.commitState(recent.NumberU64(), recent.Root(), true); err != nil {
 					bc.logger.Error("Failed to commit recent state trie", "err", err)
 				}
 			}
@@ -952,6 +968,68 @@ func (bc *BlockChain) MuUnLock() {
 	bc.chainmu.Unlock()
 }
 
+// pruning checks whether the states falling out of the retention window are pruned.
+func (bc *BlockChain) pruning() bool {
+	return !bc.cacheConfig.TrieDirtyDisabled && bc.cacheConfig.TrieRetention > 0
+}
+
+// commitState flushes the state trie of the block to disk. With pruning enabled the
+// trie is retained until the block falls out of the retention window.
+func (bc *BlockChain) commitState(number uint64, root common.Hash, report bool) error {
+	triedb := bc.stateCache.TrieDB()
+	if err := triedb.Commit(root, report); err != nil {
+		return err
+	}
+	if !bc.pruning() || rawdb.HasRetainedState(bc.db, number, root) {
+		return nil
+	}
+	if err := triedb.Retain(root); err != nil {
+		return err
+	}
+	rawdb.WriteRetainedState(bc.db, number, root)
+	return nil
+}
+
+// pruneStates releases the retained states of the blocks falling out of the retention
+// window. The newest retained state is always kept, the tries in memory are built on it.
+func (bc *BlockChain) pruneStates(head uint64) {
+	if !bc.pruning() || head <= bc.cacheConfig.TrieRetention {
+		return
+	}
+	var (
+		limit  = head - bc.cacheConfig.TrieRetention
+		states = rawdb.ReadRetainedStates(bc.db)
+		triedb = bc.stateCache.TrieDB()
+	)
+	for i := 0; i < len(states)-1 && states[i].Number < limit; i++ {
+		nodes, err := triedb.Release(states[i].Root)
+		if err != nil {
+			bc.logger.Error("Failed to prune state", "number", states[i].Number, "root", states[i].Root, "err", err)
+			return
+		}
+		rawdb.DeleteRetainedState(bc.db, states[i].Number, states[i].Root)
+		rawdb.WriteHeadPruneNumber(bc.db, states[i].Number)
+		atomic.AddUint64(&bc.prunedNodes, uint64(nodes))
+
+		bc.logger.Debug("Pruned state", "number", states[i].Number, "root", states[i].Root, "nodes", nodes)
+	}
+}
+
+// PruneStatus reports the progress of the state pruning.
+func (bc *BlockChain) PruneStatus() *PruneStatus {
+	status := &PruneStatus{
+		Running:           bc.pruning(),
+		Retention:         bc.cacheConfig.TrieRetention,
+		LatestBlockNumber: bc.CurrentBlock().NumberU64(),
+		RetainedStates:    len(rawdb.ReadRetainedStates(bc.db)),
+		PrunedNodes:       atomic.LoadUint64(&bc.prunedNodes),
+	}
+	if number := rawdb.ReadHeadPruneNumber(bc.db); number != nil {
+		status.LatestPruneNumber = *number
+	}
+	return status
+}
+
 // WriteBlockWithState writes the block and all associated state to the database.
 func (bc *BlockChain) WriteBlockWithState(block *types.Block, receipts []*types.Receipt, state *state.StateDB) (status WriteStatus, err error) {
 
@@ -1006,9 +1084,10 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 
 	// If we're running an archive node, always flush
 	if withinEpochSwitchWindow || bc.cacheConfig.TrieDirtyDisabled || meetFlushBlockInterval {
-		if err := triedb.Commit(root, false); err != nil {
+		if err := bc.commitState(curBlockNumber, root, false); err != nil {
 			return NonStatTy, err
 		}
+		bc.pruneStates(curBlockNumber)
 	} else {
 		// Full but not archive node, do proper garbage collection
 		triedb.Reference(root, common.Hash{}) // metadata reference to keep trie alive
@@ -1040,7 +1119,8 @@ func (bc *BlockChain) writeBlockWithState(block *types.Block, receipts []*types.
 						log.Info("State in memory for too long, committing", "time", bc.gcproc, "allowance", bc.cacheConfig.TrieTimeLimit, "optimum", float64(chosen-lastWrite)/triesInMemory)
 					}
 					// Flush an entire trie and restart the counters
-					triedb.Commit(header.Root, true)
+					bc.commitState(chosen, header.Root, true)
+					bc.pruneStates(current)
 					lastWrite = chosen
 					bc.gcproc = 0
 				}
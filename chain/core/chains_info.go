@@ -14,6 +14,7 @@ import (
 	ep "github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
 	"github.com/Gessiux/neatchain/chain/core/state"
 	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/params"
 	"github.com/Gessiux/neatchain/utilities/common"
 	"github.com/Gessiux/neatchain/utilities/common/math"
 )
@@ -70,6 +71,9 @@ const (
 	chainInfoKey  = "CHAIN"
 	ethGenesisKey = "ETH_GENESIS"
 	ntcGenesisKey = "NTC_GENESIS"
+
+	genesisEpochKey = "GENESIS_EPOCH"
+	forksKey        = "FORKS"
 )
 
 var allChainKey = []byte("AllChainID")
@@ -94,6 +98,14 @@ func calcNTCGenesisKey(chainId string) []byte {
 	return []byte(ntcGenesisKey + ":" + chainId)
 }
 
+func calcGenesisEpochKey(chainId string) []byte {
+	return []byte(genesisEpochKey + ":" + chainId)
+}
+
+func calcForksKey(chainId string) []byte {
+	return []byte(forksKey + ":" + chainId)
+}
+
 func GetChainInfo(db dbm.DB, chainId string) *ChainInfo {
 	mtx.RLock()
 	defer mtx.RUnlock()
@@ -316,6 +328,52 @@ func LoadChainGenesis(db dbm.DB, chainId string) (ethGenesis, ntcGenesis []byte)
 	return
 }
 
+// SaveGenesisEpoch anchors the epoch 0 of the side chain, the blocks of epoch 0 are verified
+// against its validators
+func SaveGenesisEpoch(db dbm.DB, chainId string, genesisEpoch *ep.Epoch) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	db.SetSync(calcGenesisEpochKey(chainId), genesisEpoch.Bytes())
+}
+
+// GetGenesisEpoch load the epoch 0 anchored for the side chain
+func GetGenesisEpoch(db dbm.DB, chainId string) *ep.Epoch {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	return ep.FromBytes(db.Get(calcGenesisEpochKey(chainId)))
+}
+
+// SaveSideChainForks save the fork heights chosen by the creator of the side chain, the genesis of
+// the side chain is generated with them
+func SaveSideChainForks(db dbm.DB, chainId string, forks *params.SideChainForks) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	db.SetSync(calcForksKey(chainId), wire.BinaryBytes(*forks))
+}
+
+// GetSideChainForks load the fork heights of the side chain, nil if the side chain was created without them
+func GetSideChainForks(db dbm.DB, chainId string) *params.SideChainForks {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	buf := db.Get(calcForksKey(chainId))
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var forks params.SideChainForks
+	r, n, err := bytes.NewReader(buf), new(int), new(error)
+	wire.ReadBinaryPtr(&forks, r, 0, n, err)
+	if *err != nil {
+		log.Errorf("GetSideChainForks: failed to decode the forks of side chain %s: %v", chainId, *err)
+		return nil
+	}
+	return &forks
+}
+
 // ---------------------
 // Pending Chain
 var pendingChainMtx sync.Mutex
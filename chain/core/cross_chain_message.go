@@ -0,0 +1,129 @@
+package core
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/core/vm"
+	"github.com/Gessiux/neatchain/chain/trie"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	errIncompleteMessageProof = errors.New("incomplete cross chain message proof")
+	errMessageNotInOutbox     = errors.New("cross chain message not found in the outbox")
+)
+
+// ProveCrossChainMessage proves the message is recorded in the outbox at the state of the header
+func ProveCrossChainMessage(db state.Database, header *types.Header, msg *types.CrossChainMessage) (*types.CrossChainMessageProof, error) {
+	tr, err := trie.NewSecure(header.Root, db.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	accountProof := types.MakeBSKeyValueSet()
+	if err := tr.Prove(crypto.Keccak256(vm.CrossChainOutboxAddr.Bytes()), 0, accountProof); err != nil {
+		return nil, err
+	}
+	enc, err := tr.TryGet(vm.CrossChainOutboxAddr.Bytes())
+	if err != nil {
+		return nil, err
+	}
+	if len(enc) == 0 {
+		return nil, errMessageNotInOutbox
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return nil, err
+	}
+
+	storage, err := trie.NewSecure(account.Root, db.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	hash := msg.Hash()
+	if value, err := storage.TryGet(hash.Bytes()); err != nil {
+		return nil, err
+	} else if len(value) == 0 {
+		return nil, errMessageNotInOutbox
+	}
+	storageProof := types.MakeBSKeyValueSet()
+	if err := storage.Prove(crypto.Keccak256(hash.Bytes()), 0, storageProof); err != nil {
+		return nil, err
+	}
+
+	return &types.CrossChainMessageProof{
+		Header:       header,
+		Message:      msg,
+		AccountProof: accountProof,
+		StorageProof: storageProof,
+	}, nil
+}
+
+// VerifyCrossChainMessageProof checks the message is recorded in the outbox at the state
+// root of the header. The header itself is checked by the CrossChainHelper.
+func VerifyCrossChainMessageProof(proof *types.CrossChainMessageProof) error {
+	if proof.Header == nil || proof.Message == nil || proof.AccountProof == nil || proof.StorageProof == nil {
+		return errIncompleteMessageProof
+	}
+
+	enc, _, err := trie.VerifyProof(proof.Header.Root, crypto.Keccak256(vm.CrossChainOutboxAddr.Bytes()), proof.AccountProof)
+	if err != nil {
+		return fmt.Errorf("invalid outbox account proof: %v", err)
+	}
+	if len(enc) == 0 {
+		return errMessageNotInOutbox
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return err
+	}
+
+	hash := proof.Message.Hash()
+	value, _, err := trie.VerifyProof(account.Root, crypto.Keccak256(hash.Bytes()), proof.StorageProof)
+	if err != nil {
+		return fmt.Errorf("invalid outbox storage proof: %v", err)
+	}
+	if len(value) == 0 {
+		return errMessageNotInOutbox
+	}
+	return nil
+}
+
+// DecodeCrossChainMessageProof returns the message proof carried by the DeliverMessage transaction
+func DecodeCrossChainMessageProof(tx *types.Transaction) (*types.CrossChainMessageProof, error) {
+	var args []byte
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.DeliverMessage.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	var proof types.CrossChainMessageProof
+	if err := rlp.DecodeBytes(args, &proof); err != nil {
+		return nil, err
+	}
+	if proof.Header == nil || proof.Message == nil {
+		return nil, errIncompleteMessageProof
+	}
+	return &proof, nil
+}
+
+// ApplyCrossChainMessage executes the delivered message on the receiving contract with the gas
+// left by the DeliverMessage transaction, the message is sent by the inbox. The gas used is
+// returned, and whether the execution failed.
+func ApplyCrossChainMessage(config *params.ChainConfig, bc ChainContext, author *common.Address, statedb *state.StateDB, header *types.Header,
+	msg Message, crossMsg *types.CrossChainMessage, gas uint64, cfg vm.Config) (uint64, bool) {
+
+	context := NewEVMContext(msg, header, bc, author)
+	context.CrossChainMessage = crossMsg
+	vmenv := vm.NewEVM(context, statedb, config, cfg)
+
+	_, leftOverGas, err := vmenv.Call(vm.AccountRef(vm.CrossChainInboxAddr), crossMsg.To, crossMsg.Payload, gas, new(big.Int))
+	return gas - leftOverGas, err != nil
+}
@@ -78,6 +78,21 @@ var (
 	// ErrCommission is returned if the request Commission value not between 0 and 100
 	ErrCommission = errors.New("commission percentage (between 0 and 100) out of range")
 
+	// ErrRedelegateAmount is returned if the redelegation amount is not positive
+	ErrRedelegateAmount = errors.New("redelegate amount must be positive")
+
+	// ErrRedelegateSameCandidate is returned if the redelegation moves to the source candidate
+	ErrRedelegateSameCandidate = errors.New("can not redelegate to the same candidate")
+
+	// ErrRedelegateForbidden is returned if either candidate of the redelegation is forbidden
+	ErrRedelegateForbidden = errors.New("can not redelegate from or to a forbidden candidate")
+
+	// ErrRedelegationHop is returned if the stake redelegated in the current epoch is redelegated again
+	ErrRedelegationHop = errors.New("can not redelegate the stake redelegated in the current epoch")
+
+	// ErrAutoCompoundNoDelegation is returned if the address enabling the auto compound has no delegation
+	ErrAutoCompoundNoDelegation = errors.New("no delegation to compound the rewards into")
+
 	// Vote Error
 	// ErrVoteAmountTooLow is returned if the vote amount less than proxied delegation amount
 	ErrVoteAmountTooLow = errors.New("vote amount too low")
@@ -93,4 +108,30 @@ var (
 
 	// ErrNotAllowedInSideChain is returned if the transaction with side flag = false be sent to side chain
 	ErrNotAllowedInSideChain = errors.New("transaction not allowed in side chain")
+
+	// ErrCrossChainMessageNotActive is returned if the cross chain message is delivered before the fork
+	ErrCrossChainMessageNotActive = errors.New("cross chain message not supported yet")
+
+	// Evidence Error
+	// ErrEvidenceSlashed is returned if the offence of the double sign evidence has been punished already
+	ErrEvidenceSlashed = errors.New("double sign evidence already punished")
+
+	// ErrEvidenceTooOld is returned if the double sign evidence is older than the previous epoch
+	ErrEvidenceTooOld = errors.New("double sign evidence too old")
+
+	// Governance Error
+	// ErrProposalNotFound is returned if the proposal does not exist
+	ErrProposalNotFound = errors.New("proposal not found")
+
+	// ErrProposalClosed is returned if the voting of the proposal has been closed
+	ErrProposalClosed = errors.New("proposal voting closed")
+
+	// ErrProposalTargetEpoch is returned if the proposal takes effect before its voting ends
+	ErrProposalTargetEpoch = errors.New("proposal target epoch must be after the voting period")
+
+	// ErrProposalDeposit is returned if the deposit amount is not positive
+	ErrProposalDeposit = errors.New("proposal deposit must be positive")
+
+	// ErrNoVotingPower is returned if the voter has no deposit or delegation
+	ErrNoVotingPower = errors.New("address has no stake to vote")
 )
@@ -27,6 +27,9 @@ type TxPreEvent struct{ Tx *types.Transaction }
 //Tx3ProofDataEvent is posted when a tx3ProofData enters
 type Tx3ProofDataEvent struct{ Tx3PrfDt *types.TX3ProofData }
 
+// CrossChainTransferEvent is posted when a withdraw from a side chain changes its stage
+type CrossChainTransferEvent struct{ Transfer *types.CrossChainTransfer }
+
 // PendingLogsEvent is posted pre mining and notifies of pending logs.
 type PendingLogsEvent struct {
 	Logs []*types.Log
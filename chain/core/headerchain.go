@@ -439,6 +439,13 @@ func (hc *HeaderChain) SetHead(head uint64, delFn DeleteCallback) {
 	}
 	batch.Write()
 
+	// Truncate the frozen blocks above the new head, they're not canonical anymore
+	if frozen, _ := hc.chainDb.Ancients(); frozen > head+1 {
+		if err := hc.chainDb.TruncateAncients(head + 1); err != nil {
+			log.Crit("Failed to truncate ancient data", "number", head, "err", err)
+		}
+	}
+
 	// Clear out any stale content from the caches
 	hc.headerCache.Purge()
 	hc.tdCache.Purge()
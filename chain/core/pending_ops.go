@@ -12,9 +12,11 @@ import (
 func ApplyOp(op types.PendingOp, bc *BlockChain, cch CrossChainHelper) error {
 	switch op := op.(type) {
 	case *types.CreateSideChainOp:
-		return cch.CreateSideChain(op.From, op.ChainId, op.MinValidators, op.MinDepositAmount, op.StartBlock, op.EndBlock)
+		return cch.CreateSideChain(op.From, op.ChainId, op.MinValidators, op.MinDepositAmount, op.StartBlock, op.EndBlock, op.Forks)
 	case *types.JoinSideChainOp:
 		return cch.JoinSideChain(op.From, op.PubKey, op.ChainId, op.DepositAmount)
+	case *types.CloseSideChainOp:
+		return cch.CloseSideChain(op.From, op.ChainId, op.HaltHeight)
 	case *types.LaunchSideChainsOp:
 		if len(op.SideChainIds) > 0 {
 			var events []interface{}
@@ -28,11 +28,39 @@ import (
 	"github.com/Gessiux/neatchain/utilities/rlp"
 )
 
+// readAncient retrieves an item of the block from the ancient store, nil if the
+// database has no ancient store or the block is not frozen as canonical.
+func readAncient(db neatdb.Reader, kind string, hash common.Hash, number uint64) []byte {
+	ancients, ok := db.(neatdb.AncientReader)
+	if !ok {
+		return nil
+	}
+	if frozen, _ := ancients.Ancient(freezerHashTable, number); common.BytesToHash(frozen) != hash {
+		return nil
+	}
+	data, _ := ancients.Ancient(kind, number)
+	return data
+}
+
+// hasAncient checks whether the block is frozen as canonical in the ancient store,
+// all the data of a block is frozen together.
+func hasAncient(db neatdb.Reader, hash common.Hash, number uint64) bool {
+	return len(readAncient(db, freezerHashTable, hash, number)) > 0
+}
+
 // ReadCanonicalHash retrieves the hash assigned to a canonical block number.
 func ReadCanonicalHash(db neatdb.Reader, number uint64) common.Hash {
 	data, _ := db.Get(headerHashKey(number))
 	if len(data) == 0 {
-		return common.Hash{}
+		// The canonical hashes of the frozen blocks are moved into the ancient store,
+		// it's checked after the key-value store as the freezer deletes the frozen
+		// data from the key-value store after writing it out
+		if ancients, ok := db.(neatdb.AncientReader); ok {
+			data, _ = ancients.Ancient(freezerHashTable, number)
+		}
+		if len(data) == 0 {
+			return common.Hash{}
+		}
 	}
 	return common.BytesToHash(data)
 }
@@ -51,6 +79,23 @@ func DeleteCanonicalHash(db neatdb.Writer, number uint64) {
 	}
 }
 
+// ReadAllHashes retrieves all the hashes assigned to blocks at a certain heights,
+// both canonical and reorged forks included, from the key-value store.
+func ReadAllHashes(db neatdb.Iteratee, number uint64) []common.Hash {
+	prefix := headerKeyPrefix(number)
+
+	hashes := make([]common.Hash, 0, 1)
+	it := db.NewIteratorWithPrefix(prefix)
+	defer it.Release()
+
+	for it.Next() {
+		if key := it.Key(); len(key) == len(prefix)+common.HashLength {
+			hashes = append(hashes, common.BytesToHash(key[len(key)-common.HashLength:]))
+		}
+	}
+	return hashes
+}
+
 // ReadHeaderNumber returns the header number assigned to a hash.
 func ReadHeaderNumber(db neatdb.Reader, hash common.Hash) *uint64 {
 	data, _ := db.Get(headerNumberKey(hash))
@@ -130,15 +175,18 @@ func WriteFastTrieProgress(db neatdb.Writer, count uint64) {
 // ReadHeaderRLP retrieves a block header in its raw RLP database encoding.
 func ReadHeaderRLP(db neatdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerKey(number, hash))
+	if len(data) == 0 {
+		data = readAncient(db, freezerHeaderTable, hash, number)
+	}
 	return data
 }
 
 // HasHeader verifies the existence of a block header corresponding to the hash.
 func HasHeader(db neatdb.Reader, hash common.Hash, number uint64) bool {
-	if has, err := db.Has(headerKey(number, hash)); !has || err != nil {
-		return false
+	if has, err := db.Has(headerKey(number, hash)); has && err == nil {
+		return true
 	}
-	return true
+	return hasAncient(db, hash, number)
 }
 
 // ReadHeader retrieves the block header corresponding to the hash.
@@ -198,6 +246,9 @@ func deleteHeaderWithoutNumber(db neatdb.Writer, hash common.Hash, number uint64
 // ReadBodyRLP retrieves the block body (transactions and uncles) in RLP encoding.
 func ReadBodyRLP(db neatdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockBodyKey(number, hash))
+	if len(data) == 0 {
+		data = readAncient(db, freezerBodiesTable, hash, number)
+	}
 	return data
 }
 
@@ -210,10 +261,10 @@ func WriteBodyRLP(db neatdb.Writer, hash common.Hash, number uint64, rlp rlp.Raw
 
 // HasBody verifies the existence of a block body corresponding to the hash.
 func HasBody(db neatdb.Reader, hash common.Hash, number uint64) bool {
-	if has, err := db.Has(blockBodyKey(number, hash)); !has || err != nil {
-		return false
+	if has, err := db.Has(blockBodyKey(number, hash)); has && err == nil {
+		return true
 	}
-	return true
+	return hasAncient(db, hash, number)
 }
 
 // ReadBody retrieves the block body corresponding to the hash.
@@ -249,6 +300,9 @@ func DeleteBody(db neatdb.Writer, hash common.Hash, number uint64) {
 // ReadTdRLP retrieves a block's total difficulty corresponding to the hash in RLP encoding.
 func ReadTdRLP(db neatdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(headerTDKey(number, hash))
+	if len(data) == 0 {
+		data = readAncient(db, freezerDifficultyTable, hash, number)
+	}
 	return data
 }
 
@@ -287,15 +341,18 @@ func DeleteTd(db neatdb.Writer, hash common.Hash, number uint64) {
 // HasReceipts verifies the existence of all the transaction receipts belonging
 // to a block.
 func HasReceipts(db neatdb.Reader, hash common.Hash, number uint64) bool {
-	if has, err := db.Has(blockReceiptsKey(number, hash)); !has || err != nil {
-		return false
+	if has, err := db.Has(blockReceiptsKey(number, hash)); has && err == nil {
+		return true
 	}
-	return true
+	return hasAncient(db, hash, number)
 }
 
 // ReadReceiptsRLP retrieves all the transaction receipts belonging to a block in RLP encoding.
 func ReadReceiptsRLP(db neatdb.Reader, hash common.Hash, number uint64) rlp.RawValue {
 	data, _ := db.Get(blockReceiptsKey(number, hash))
+	if len(data) == 0 {
+		data = readAncient(db, freezerReceiptTable, hash, number)
+	}
 	return data
 }
 
@@ -6,46 +6,53 @@ import (
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/neatdb"
 	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
 )
 
-// ReadDataPruneTrieRootHash retrieves the root hash of a data prune process trie
-func ReadDataPruneTrieRootHash(db neatdb.Reader, scan, prune uint64) common.Hash {
-	data, _ := db.Get(dataPruneNumberKey(scan, prune))
-	if len(data) == 0 {
-		return common.Hash{}
-	}
-	return common.BytesToHash(data)
+// RetainedState is a state trie committed to disk and kept until it falls out of the
+// pruning window.
+type RetainedState struct {
+	Number uint64
+	Root   common.Hash
 }
 
-// WriteCanonicalHash stores the hash assigned to a canonical block number.
-func WriteDataPruneTrieRootHash(db neatdb.Writer, hash common.Hash, scan, prune uint64) {
-	if err := db.Put(dataPruneNumberKey(scan, prune), hash.Bytes()); err != nil {
-		log.Crit("Failed to store number to hash mapping", "err", err)
-	}
+// HasRetainedState checks if the state root of the block is retained.
+func HasRetainedState(db neatdb.Reader, number uint64, root common.Hash) bool {
+	has, _ := db.Has(retainedStateKey(number, root))
+	return has
 }
 
-// DeleteCanonicalHash removes the number to hash canonical mapping.
-func DeleteDataPruneTrieRootHash(db neatdb.Writer, scan, prune uint64) {
-	if err := db.Delete(dataPruneNumberKey(scan, prune)); err != nil {
-		log.Crit("Failed to delete number to hash mapping", "err", err)
+// WriteRetainedState stores the state root of the block as retained.
+func WriteRetainedState(db neatdb.Writer, number uint64, root common.Hash) {
+	if err := db.Put(retainedStateKey(number, root), nil); err != nil {
+		log.Crit("Failed to store retained state", "err", err)
 	}
 }
 
-// ReadHeadScanNumber retrieves the latest scaned number.
-func ReadHeadScanNumber(db neatdb.Reader) *uint64 {
-	data, _ := db.Get(headDataScanKey)
-	if len(data) != 8 {
-		return nil
+// DeleteRetainedState removes the state root of the block from the retained ones.
+func DeleteRetainedState(db neatdb.Writer, number uint64, root common.Hash) {
+	if err := db.Delete(retainedStateKey(number, root)); err != nil {
+		log.Crit("Failed to delete retained state", "err", err)
 	}
-	number := binary.BigEndian.Uint64(data)
-	return &number
 }
 
-// WriteHeadScanNumber stores the number of the latest scaned block.
-func WriteHeadScanNumber(db neatdb.Writer, scan uint64) {
-	if err := db.Put(headDataScanKey, encodeBlockNumber(scan)); err != nil {
-		log.Crit("Failed to store last scan number", "err", err)
+// ReadRetainedStates retrieves all the retained states, ordered by block number.
+func ReadRetainedStates(db neatdb.Iteratee) []RetainedState {
+	var states []RetainedState
+
+	it := db.NewIteratorWithPrefix(retainedStatePrefix)
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != len(retainedStatePrefix)+8+common.HashLength {
+			continue
+		}
+		states = append(states, RetainedState{
+			Number: binary.BigEndian.Uint64(key[len(retainedStatePrefix) : len(retainedStatePrefix)+8]),
+			Root:   common.BytesToHash(key[len(retainedStatePrefix)+8:]),
+		})
 	}
+	return states
 }
 
 // ReadHeadPruneNumber retrieves the latest pruned number.
@@ -64,3 +71,36 @@ func WriteHeadPruneNumber(db neatdb.Writer, prune uint64) {
 		log.Crit("Failed to store last prune number", "err", err)
 	}
 }
+
+// ReadPruneStateMarker retrieves the state roots kept by an interrupted offline pruning.
+func ReadPruneStateMarker(db neatdb.Reader) []common.Hash {
+	data, _ := db.Get(pruneStateMarkerKey)
+	if len(data) == 0 {
+		return nil
+	}
+	var roots []common.Hash
+	if err := rlp.DecodeBytes(data, &roots); err != nil {
+		log.Error("Invalid prune state marker RLP", "err", err)
+		return nil
+	}
+	return roots
+}
+
+// WritePruneStateMarker stores the state roots kept by the offline pruning, the marker
+// stays until the pruning completes.
+func WritePruneStateMarker(db neatdb.Writer, roots []common.Hash) {
+	data, err := rlp.EncodeToBytes(roots)
+	if err != nil {
+		log.Crit("Failed to RLP encode prune state marker", "err", err)
+	}
+	if err := db.Put(pruneStateMarkerKey, data); err != nil {
+		log.Crit("Failed to store prune state marker", "err", err)
+	}
+}
+
+// DeletePruneStateMarker removes the marker of the completed offline pruning.
+func DeletePruneStateMarker(db neatdb.Writer) {
+	if err := db.Delete(pruneStateMarkerKey); err != nil {
+		log.Crit("Failed to delete prune state marker", "err", err)
+	}
+}
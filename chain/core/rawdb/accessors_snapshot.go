@@ -0,0 +1,81 @@
+package rawdb
+
+import (
+	"encoding/binary"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ReadLatestSnapshotNumber retrieves the block number of the latest state snapshot.
+func ReadLatestSnapshotNumber(db neatdb.Reader) *uint64 {
+	data, _ := db.Get(snapshotLatestKey)
+	if len(data) != 8 {
+		return nil
+	}
+	number := binary.BigEndian.Uint64(data)
+	return &number
+}
+
+// WriteLatestSnapshotNumber stores the block number of the latest state snapshot.
+func WriteLatestSnapshotNumber(db neatdb.Writer, number uint64) {
+	if err := db.Put(snapshotLatestKey, encodeBlockNumber(number)); err != nil {
+		log.Crit("Failed to store latest snapshot number", "err", err)
+	}
+}
+
+// ReadSnapshotManifestRLP retrieves the encoded manifest of the state snapshot taken at the block.
+func ReadSnapshotManifestRLP(db neatdb.Reader, number uint64) rlp.RawValue {
+	data, _ := db.Get(snapshotManifestKey(number))
+	return data
+}
+
+// WriteSnapshotManifestRLP stores the encoded manifest of the state snapshot taken at the block.
+func WriteSnapshotManifestRLP(db neatdb.Writer, number uint64, manifest rlp.RawValue) {
+	if err := db.Put(snapshotManifestKey(number), manifest); err != nil {
+		log.Crit("Failed to store snapshot manifest", "err", err)
+	}
+}
+
+// DeleteSnapshotManifest removes the manifest of the state snapshot taken at the block.
+func DeleteSnapshotManifest(db neatdb.Writer, number uint64) {
+	if err := db.Delete(snapshotManifestKey(number)); err != nil {
+		log.Crit("Failed to delete snapshot manifest", "err", err)
+	}
+}
+
+// ReadSnapshotChunk retrieves a snapshot chunk by its hash.
+func ReadSnapshotChunk(db neatdb.Reader, hash common.Hash) []byte {
+	data, _ := db.Get(snapshotChunkKey(hash))
+	return data
+}
+
+// WriteSnapshotChunk stores a snapshot chunk by its hash.
+func WriteSnapshotChunk(db neatdb.Writer, hash common.Hash, chunk []byte) {
+	if err := db.Put(snapshotChunkKey(hash), chunk); err != nil {
+		log.Crit("Failed to store snapshot chunk", "err", err)
+	}
+}
+
+// DeleteSnapshotChunk removes a snapshot chunk by its hash.
+func DeleteSnapshotChunk(db neatdb.Writer, hash common.Hash) {
+	if err := db.Delete(snapshotChunkKey(hash)); err != nil {
+		log.Crit("Failed to delete snapshot chunk", "err", err)
+	}
+}
+
+// ReadSnapshotNumbers retrieves the block numbers of all the state snapshots in ascending order.
+func ReadSnapshotNumbers(db neatdb.Iteratee) []uint64 {
+	it := db.NewIteratorWithPrefix(snapshotManifestPrefix)
+	defer it.Release()
+
+	var numbers []uint64
+	for it.Next() {
+		if key := it.Key(); len(key) == len(snapshotManifestPrefix)+8 {
+			numbers = append(numbers, binary.BigEndian.Uint64(key[len(snapshotManifestPrefix):]))
+		}
+	}
+	return numbers
+}
@@ -0,0 +1,80 @@
+package rawdb
+
+import (
+	"bytes"
+
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	transferPrefix        = []byte("x") // transferPrefix + chainId + txHash -> cross chain transfer
+	pendingTransferPrefix = []byte("o") // pendingTransferPrefix + from + chainId + txHash -> nil, transfers not claimed yet
+)
+
+func transferKey(chainId string, txHash common.Hash) []byte {
+	return append(append(append([]byte{}, transferPrefix...), []byte(chainId)...), txHash.Bytes()...)
+}
+
+func pendingTransferKey(from common.Address, chainId string, txHash common.Hash) []byte {
+	key := append(append([]byte{}, pendingTransferPrefix...), from.Bytes()...)
+	return append(append(key, []byte(chainId)...), txHash.Bytes()...)
+}
+
+// ReadCrossChainTransfer retrieves the transfer of the withdraw tx of the side chain
+func ReadCrossChainTransfer(db neatdb.Reader, chainId string, txHash common.Hash) *types.CrossChainTransfer {
+	data, _ := db.Get(transferKey(chainId, txHash))
+	if len(data) == 0 {
+		return nil
+	}
+	transfer := new(types.CrossChainTransfer)
+	if err := rlp.DecodeBytes(data, transfer); err != nil {
+		log.Error("Invalid cross chain transfer RLP", "chain", chainId, "hash", txHash, "err", err)
+		return nil
+	}
+	return transfer
+}
+
+// WriteCrossChainTransfer stores the transfer, the transfers not claimed are indexed by the sender
+func WriteCrossChainTransfer(db neatdb.Writer, transfer *types.CrossChainTransfer) {
+	data, err := rlp.EncodeToBytes(transfer)
+	if err != nil {
+		log.Crit("Failed to RLP encode cross chain transfer", "err", err)
+	}
+	if err := db.Put(transferKey(transfer.ChainId, transfer.TxHash), data); err != nil {
+		log.Crit("Failed to store cross chain transfer", "err", err)
+	}
+
+	pending := pendingTransferKey(transfer.From, transfer.ChainId, transfer.TxHash)
+	if transfer.Stage == types.TransferClaimed {
+		if err := db.Delete(pending); err != nil {
+			log.Crit("Failed to delete pending cross chain transfer", "err", err)
+		}
+	} else if err := db.Put(pending, nil); err != nil {
+		log.Crit("Failed to store pending cross chain transfer", "err", err)
+	}
+}
+
+// ReadPendingCrossChainTransfers retrieves the transfers of the sender not claimed yet
+func ReadPendingCrossChainTransfers(db neatdb.Database, from common.Address) []*types.CrossChainTransfer {
+	prefix := append(append([]byte{}, pendingTransferPrefix...), from.Bytes()...)
+
+	var transfers []*types.CrossChainTransfer
+	iter := db.NewIteratorWithPrefix(prefix)
+	defer iter.Release()
+	for iter.Next() {
+		key := iter.Key()
+		if !bytes.HasPrefix(key, prefix) || len(key) < len(prefix)+common.HashLength {
+			continue
+		}
+		chainId := string(key[len(prefix) : len(key)-common.HashLength])
+		txHash := common.BytesToHash(key[len(key)-common.HashLength:])
+		if transfer := ReadCrossChainTransfer(db, chainId, txHash); transfer != nil {
+			transfers = append(transfers, transfer)
+		}
+	}
+	return transfers
+}
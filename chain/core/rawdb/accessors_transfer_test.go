@@ -0,0 +1,44 @@
+package rawdb
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// Tests that the transfers not claimed are listed as pending for their sender.
+func TestCrossChainTransferStorage(t *testing.T) {
+	db := NewMemoryDatabase()
+
+	from := common.StringToAddress("NEATSenderAddressAAAAAAAAAAAAAAA")
+	other := common.StringToAddress("NEATSenderAddressBBBBBBBBBBBBBBB")
+	first := &types.CrossChainTransfer{ChainId: "side_1", TxHash: common.BytesToHash([]byte{0x01}), From: from, Amount: big.NewInt(1), SubmitBlock: 10}
+	second := &types.CrossChainTransfer{ChainId: "side_2", TxHash: common.BytesToHash([]byte{0x02}), From: from, Amount: big.NewInt(2), SubmitBlock: 20}
+	third := &types.CrossChainTransfer{ChainId: "side_1", TxHash: common.BytesToHash([]byte{0x03}), From: other, Amount: big.NewInt(3), SubmitBlock: 30}
+
+	if transfer := ReadCrossChainTransfer(db, first.ChainId, first.TxHash); transfer != nil {
+		t.Fatalf("non existent transfer returned: %v", transfer)
+	}
+	for _, transfer := range []*types.CrossChainTransfer{first, second, third} {
+		WriteCrossChainTransfer(db, transfer)
+	}
+	if transfer := ReadCrossChainTransfer(db, second.ChainId, second.TxHash); transfer == nil || transfer.Amount.Cmp(second.Amount) != 0 || transfer.SubmitBlock != second.SubmitBlock {
+		t.Fatalf("transfer mismatch: have %v, want %v", transfer, second)
+	}
+	if pending := ReadPendingCrossChainTransfers(db, from); len(pending) != 2 {
+		t.Fatalf("pending transfers mismatch: have %d, want 2", len(pending))
+	}
+
+	// Claimed transfers are not pending anymore
+	first.Stage, first.ClaimTxHash, first.ClaimBlock = types.TransferClaimed, common.BytesToHash([]byte{0x11}), 100
+	WriteCrossChainTransfer(db, first)
+	pending := ReadPendingCrossChainTransfers(db, from)
+	if len(pending) != 1 || pending[0].TxHash != second.TxHash {
+		t.Fatalf("pending transfers mismatch after claim: %v", pending)
+	}
+	if transfer := ReadCrossChainTransfer(db, first.ChainId, first.TxHash); transfer == nil || transfer.Stage != types.TransferClaimed || transfer.ClaimBlock != 100 {
+		t.Fatalf("claimed transfer mismatch: %v", transfer)
+	}
+}
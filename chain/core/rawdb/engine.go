@@ -0,0 +1,197 @@
+package rawdb
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/neatdb/boltdb"
+	"github.com/Gessiux/neatchain/neatdb/leveldb"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+const (
+	// EngineLevelDB is the default key-value store engine.
+	EngineLevelDB = "leveldb"
+
+	// EngineBoltDB is the key-value store engine keeping all the data in a single
+	// BoltDB file.
+	EngineBoltDB = "boltdb"
+
+	// engineFile is the file of the database directory recording its engine.
+	engineFile = "ENGINE"
+
+	// convertBatchSize is the size of the batches written during a conversion.
+	convertBatchSize = 16 * 1024 * 1024
+)
+
+// ReadDatabaseEngine retrieves the key-value store engine recorded in the database
+// directory, empty if there's no database. The databases created before the engine
+// got recorded are LevelDB ones.
+func ReadDatabaseEngine(dir string) string {
+	if data, err := ioutil.ReadFile(filepath.Join(dir, engineFile)); err == nil {
+		return strings.TrimSpace(string(data))
+	}
+	if _, err := os.Stat(filepath.Join(dir, "CURRENT")); err == nil {
+		return EngineLevelDB
+	}
+	return ""
+}
+
+// writeDatabaseEngine records the key-value store engine of the database directory,
+// replacing the previous one atomically.
+func writeDatabaseEngine(dir string, engine string) error {
+	tmp := filepath.Join(dir, engineFile+".tmp")
+	if err := ioutil.WriteFile(tmp, []byte(engine), 0644); err != nil {
+		return err
+	}
+	return os.Rename(tmp, filepath.Join(dir, engineFile))
+}
+
+// openKeyValueStore opens the key-value store of the engine in the directory.
+func openKeyValueStore(dir string, engine string, cache int, handles int, namespace string) (neatdb.KeyValueStore, error) {
+	switch engine {
+	case EngineLevelDB:
+		return leveldb.New(dir, cache, handles, namespace)
+	case EngineBoltDB:
+		return boltdb.New(dir)
+	default:
+		return nil, fmt.Errorf("unknown database engine %q", engine)
+	}
+}
+
+// NewKeyValueStore opens the key-value store of the database directory with the
+// engine it was created with, an empty engine accepts any. A new database is
+// created with the engine, LevelDB by default.
+func NewKeyValueStore(dir string, engine string, cache int, handles int, namespace string) (neatdb.KeyValueStore, error) {
+	existing := ReadDatabaseEngine(dir)
+	switch {
+	case existing != "" && engine != "" && existing != engine:
+		return nil, fmt.Errorf("database engine %s chosen but the database in %s is a %s one, convert it first", engine, dir, existing)
+	case existing != "":
+		engine = existing
+	case engine == "":
+		engine = EngineLevelDB
+	}
+	kvdb, err := openKeyValueStore(dir, engine, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := os.Stat(filepath.Join(dir, engineFile)); os.IsNotExist(err) {
+		if err := writeDatabaseEngine(dir, engine); err != nil {
+			kvdb.Close()
+			return nil, err
+		}
+	}
+	return kvdb, nil
+}
+
+// removeEngineFiles deletes the files of the engine's key-value store from the
+// database directory, the other files (e.g. the ancient store) are left untouched.
+func removeEngineFiles(dir string, engine string) error {
+	var patterns []string
+	switch engine {
+	case EngineLevelDB:
+		patterns = []string{"CURRENT", "CURRENT.bak", "LOCK", "LOG", "LOG.old", "MANIFEST-*", "*.ldb", "*.log", "*.sst"}
+	case EngineBoltDB:
+		patterns = []string{boltdb.FileName}
+	default:
+		return fmt.Errorf("unknown database engine %q", engine)
+	}
+	for _, pattern := range patterns {
+		files, err := filepath.Glob(filepath.Join(dir, pattern))
+		if err != nil {
+			return err
+		}
+		for _, file := range files {
+			if err := os.Remove(file); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// ConvertDatabase copies the key-value store of the database directory into a new
+// one of the engine, next to it in the same directory. The new store takes over
+// once the copy is complete, then the old one is deleted. An interrupted conversion
+// leaves the old store in use, and is started over when run again.
+func ConvertDatabase(dir string, engine string, cache int, handles int) error {
+	existing := ReadDatabaseEngine(dir)
+	switch {
+	case existing == "":
+		return fmt.Errorf("no database in %s", dir)
+	case existing == engine:
+		return fmt.Errorf("database in %s is a %s one already", dir, engine)
+	}
+	// Drop the leftovers of an interrupted conversion
+	if err := removeEngineFiles(dir, engine); err != nil {
+		return err
+	}
+	src, err := openKeyValueStore(dir, existing, cache, handles, "")
+	if err != nil {
+		return err
+	}
+	dst, err := openKeyValueStore(dir, engine, cache, handles, "")
+	if err != nil {
+		src.Close()
+		return err
+	}
+	start := time.Now()
+	count, size, err := copyKeyValueStore(src, dst, engine)
+	if cerr := dst.Close(); err == nil {
+		err = cerr
+	}
+	src.Close()
+	if err != nil {
+		return err
+	}
+	// Switch to the new store, the old one is garbage from now on
+	if err := writeDatabaseEngine(dir, engine); err != nil {
+		return err
+	}
+	if err := removeEngineFiles(dir, existing); err != nil {
+		log.Warn("Failed to remove the converted database", "engine", existing, "err", err)
+	}
+	log.Info("Converted database", "engine", engine, "items", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// copyKeyValueStore copies all the items of the source store into the destination.
+func copyKeyValueStore(src, dst neatdb.KeyValueStore, engine string) (int, common.StorageSize, error) {
+	var (
+		count  int
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = dst.NewBatch()
+	)
+	it := src.NewIterator()
+	defer it.Release()
+
+	for it.Next() {
+		count++
+		size += common.StorageSize(len(it.Key()) + len(it.Value()))
+		batch.Put(it.Key(), it.Value())
+
+		if batch.ValueSize() >= convertBatchSize {
+			if err := batch.Write(); err != nil {
+				return count, size, err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Converting database", "engine", engine, "items", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := it.Error(); err != nil {
+		return count, size, err
+	}
+	return count, size, batch.Write()
+}
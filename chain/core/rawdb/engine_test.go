@@ -0,0 +1,66 @@
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Tests that a database is converted between the engines without touching the
+// other files of the directory.
+func TestConvertDatabase(t *testing.T) {
+	dir, err := ioutil.TempDir("", "engine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	db, err := NewKeyValueStore(dir, "", 0, 0, "")
+	if err != nil {
+		t.Fatalf("failed to create database: %v", err)
+	}
+	for i := 0; i < 100; i++ {
+		db.Put([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	db.Close()
+
+	ancient := filepath.Join(dir, "ancient", "headers.cidx")
+	os.MkdirAll(filepath.Dir(ancient), 0755)
+	ioutil.WriteFile(ancient, []byte("ancient"), 0644)
+
+	for _, engine := range []string{EngineBoltDB, EngineLevelDB} {
+		if err := ConvertDatabase(dir, engine, 0, 0); err != nil {
+			t.Fatalf("failed to convert database to %s: %v", engine, err)
+		}
+		if have := ReadDatabaseEngine(dir); have != engine {
+			t.Fatalf("engine mismatch: have %s, want %s", have, engine)
+		}
+		if err := ConvertDatabase(dir, engine, 0, 0); err == nil {
+			t.Fatalf("database converted to its own engine")
+		}
+		other := EngineLevelDB
+		if engine == EngineLevelDB {
+			other = EngineBoltDB
+		}
+		if _, err := NewKeyValueStore(dir, other, 0, 0, ""); err == nil {
+			t.Fatalf("database opened with the %s engine", other)
+		}
+		db, err := NewKeyValueStore(dir, "", 0, 0, "")
+		if err != nil {
+			t.Fatalf("failed to open converted database: %v", err)
+		}
+		for i := 0; i < 100; i++ {
+			if value, _ := db.Get([]byte(fmt.Sprintf("key-%d", i))); !bytes.Equal(value, []byte(fmt.Sprintf("value-%d", i))) {
+				t.Fatalf("item %d mismatch after conversion to %s: %q", i, engine, value)
+			}
+		}
+		db.Close()
+
+		if data, _ := ioutil.ReadFile(ancient); !bytes.Equal(data, []byte("ancient")) {
+			t.Fatalf("ancient file changed by the conversion")
+		}
+	}
+}
@@ -0,0 +1,349 @@
+package rawdb
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+var (
+	// errUnknownTable is returned if the user attempts to read from a table that is
+	// not tracked by the freezer.
+	errUnknownTable = errors.New("unknown table")
+
+	// errOutOrderInsertion is returned if the user attempts to inject out-of-order
+	// binary blobs into the freezer.
+	errOutOrderInsertion = errors.New("the append operation is out-order")
+)
+
+const (
+	// freezerRecheckInterval is the frequency to check the key-value database for
+	// chain progression that might permit new blocks to be frozen into immutable
+	// storage.
+	freezerRecheckInterval = time.Minute
+
+	// freezerBatchLimit is the maximum number of blocks to freeze in one batch
+	// before doing an fsync and deleting it from the key-value store.
+	freezerBatchLimit = 30000
+)
+
+// freezer is an append-only store of the immutable chain data, the headers, bodies,
+// receipts, total difficulties and canonical hashes of the old blocks are moved out
+// of the key-value store into flat files, one table per kind. The items of the
+// tables are indexed by the block number, and always cover the same blocks.
+type freezer struct {
+	frozen uint64 // Number of blocks already frozen (atomic, first for alignment)
+
+	tables map[string]*freezerTable // Data tables for storing everything
+
+	quit      chan struct{}
+	wg        sync.WaitGroup
+	closeOnce sync.Once
+}
+
+// newFreezer creates a chain freezer that moves ancient chain data into
+// append-only flat file containers.
+func newFreezer(datadir string, namespace string) (*freezer, error) {
+	if info, err := os.Lstat(datadir); !os.IsNotExist(err) && err == nil && info.Mode()&os.ModeSymlink != 0 {
+		log.Warn("Symbolic link ancient database is not supported", "path", datadir)
+		return nil, fmt.Errorf("symbolic link datadir is not supported")
+	}
+	freezer := &freezer{
+		tables: make(map[string]*freezerTable),
+		quit:   make(chan struct{}),
+	}
+	for name, disableSnappy := range freezerNoSnappy {
+		table, err := newTable(datadir, name, disableSnappy)
+		if err != nil {
+			for _, table := range freezer.tables {
+				table.Close()
+			}
+			return nil, err
+		}
+		freezer.tables[name] = table
+	}
+	if err := freezer.repair(); err != nil {
+		freezer.Close()
+		return nil, err
+	}
+	log.Info("Opened ancient database", "database", datadir, "frozen", freezer.frozen)
+	return freezer, nil
+}
+
+// Close terminates the chain freezer, unmapping all the data files.
+func (f *freezer) Close() error {
+	var errs []error
+	f.closeOnce.Do(func() {
+		close(f.quit)
+		f.wg.Wait()
+		for _, table := range f.tables {
+			if err := table.Close(); err != nil {
+				errs = append(errs, err)
+			}
+		}
+	})
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// HasAncient returns an indicator whether the specified ancient data exists
+// in the freezer.
+func (f *freezer) HasAncient(kind string, number uint64) (bool, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.has(number), nil
+	}
+	return false, nil
+}
+
+// Ancient retrieves an ancient binary blob from the append-only immutable files.
+func (f *freezer) Ancient(kind string, number uint64) ([]byte, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.Retrieve(number)
+	}
+	return nil, errUnknownTable
+}
+
+// Ancients returns the length of the frozen items.
+func (f *freezer) Ancients() (uint64, error) {
+	return atomic.LoadUint64(&f.frozen), nil
+}
+
+// AncientSize returns the ancient size of the specified category.
+func (f *freezer) AncientSize(kind string) (uint64, error) {
+	if table := f.tables[kind]; table != nil {
+		return table.size()
+	}
+	return 0, errUnknownTable
+}
+
+// AppendAncient injects all binary blobs belong to block at the end of the
+// append-only immutable table files.
+//
+// Notably, this function is lock free but kind of thread-safe. All out-of-order
+// injection will be rejected. But if two injections with same number happen at
+// the same time, we can get into the trouble.
+func (f *freezer) AppendAncient(number uint64, hash, header, body, receipts, td []byte) (err error) {
+	if atomic.LoadUint64(&f.frozen) != number {
+		return errOutOrderInsertion
+	}
+	// Rollback all inserted data if any insertion below failed to ensure
+	// the tables won't out of sync.
+	defer func() {
+		if err != nil {
+			if rerr := f.repair(); rerr != nil {
+				log.Crit("Failed to repair freezer", "err", rerr)
+			}
+			log.Info("Append ancient failed", "number", number, "err", err)
+		}
+	}()
+	items := []struct {
+		kind string
+		blob []byte
+	}{
+		{freezerHashTable, hash},
+		{freezerHeaderTable, header},
+		{freezerBodiesTable, body},
+		{freezerReceiptTable, receipts},
+		{freezerDifficultyTable, td},
+	}
+	for _, item := range items {
+		if err := f.tables[item.kind].Append(f.frozen, item.blob); err != nil {
+			log.Error("Failed to append ancient "+item.kind, "number", f.frozen, "err", err)
+			return err
+		}
+	}
+	atomic.AddUint64(&f.frozen, 1) // Only modify atomically
+	return nil
+}
+
+// TruncateAncients discards any recent data above the provided threshold number.
+func (f *freezer) TruncateAncients(items uint64) error {
+	if atomic.LoadUint64(&f.frozen) <= items {
+		return nil
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(items); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, items)
+	return nil
+}
+
+// Sync flushes all data tables to disk.
+func (f *freezer) Sync() error {
+	var errs []error
+	for _, table := range f.tables {
+		if err := table.Sync(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// freeze is a background thread that periodically checks the blockchain for any
+// import progress and moves ancient data from the fast database into the freezer.
+//
+// This functionality is deliberately broken off from block importing to avoid
+// incurring additional data shuffling delays on block propagation.
+func (f *freezer) freeze(db neatdb.KeyValueStore) {
+	defer f.wg.Done()
+
+	nfdb := &nofreezedb{KeyValueStore: db}
+	for {
+		// Retrieve the freezing threshold, NeatCon blocks are final once committed
+		// so only the recent blocks are kept in the key-value store
+		var limit uint64
+		if hash := ReadHeadBlockHash(nfdb); hash != (common.Hash{}) {
+			if number := ReadHeaderNumber(nfdb, hash); number == nil {
+				log.Error("Current full block number unavailable", "hash", hash)
+			} else if *number > params.ImmutabilityThreshold {
+				limit = *number - params.ImmutabilityThreshold
+			}
+		}
+		first := atomic.LoadUint64(&f.frozen)
+		if limit <= first {
+			if !f.wait(freezerRecheckInterval) {
+				return
+			}
+			continue
+		}
+		if limit-first > freezerBatchLimit {
+			limit = first + freezerBatchLimit
+		}
+		// Move the canonical blocks into the freezer
+		var (
+			start    = time.Now()
+			ancients = make([]common.Hash, 0, limit-first)
+		)
+		for f.frozen < limit {
+			number := f.frozen
+			hash := ReadCanonicalHash(nfdb, number)
+			if hash == (common.Hash{}) {
+				log.Error("Canonical hash missing, can't freeze", "number", number)
+				break
+			}
+			header := ReadHeaderRLP(nfdb, hash, number)
+			if len(header) == 0 {
+				log.Error("Block header missing, can't freeze", "number", number, "hash", hash)
+				break
+			}
+			body := ReadBodyRLP(nfdb, hash, number)
+			if len(body) == 0 {
+				log.Error("Block body missing, can't freeze", "number", number, "hash", hash)
+				break
+			}
+			receipts := ReadReceiptsRLP(nfdb, hash, number)
+			if len(receipts) == 0 {
+				log.Error("Block receipts missing, can't freeze", "number", number, "hash", hash)
+				break
+			}
+			td := ReadTdRLP(nfdb, hash, number)
+			if len(td) == 0 {
+				log.Error("Total difficulty missing, can't freeze", "number", number, "hash", hash)
+				break
+			}
+			log.Trace("Deep froze ancient block", "number", number, "hash", hash)
+			if err := f.AppendAncient(number, hash[:], header, body, receipts, td); err != nil {
+				break
+			}
+			ancients = append(ancients, hash)
+		}
+		// Batch of blocks have been frozen, flush them before wiping from the
+		// key-value store
+		if err := f.Sync(); err != nil {
+			log.Crit("Failed to flush frozen tables", "err", err)
+		}
+		batch := db.NewBatch()
+		for i := 0; i < len(ancients); i++ {
+			// Always keep the genesis block in the key-value store
+			if first+uint64(i) != 0 {
+				deleteBlockWithoutNumber(batch, ancients[i], first+uint64(i))
+				DeleteCanonicalHash(batch, first+uint64(i))
+			}
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete frozen canonical blocks", "err", err)
+		}
+		batch.Reset()
+
+		// Wipe out the blocks of the forks at the frozen heights, they could never
+		// become canonical again
+		var dangling int
+		for i := 0; i < len(ancients); i++ {
+			number := first + uint64(i)
+			for _, hash := range ReadAllHashes(db, number) {
+				if hash != ancients[i] {
+					DeleteBlock(batch, hash, number)
+					dangling++
+				}
+			}
+			if batch.ValueSize() >= neatdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					log.Crit("Failed to delete dangling side blocks", "err", err)
+				}
+				batch.Reset()
+			}
+		}
+		if err := batch.Write(); err != nil {
+			log.Crit("Failed to delete dangling side blocks", "err", err)
+		}
+		if len(ancients) > 0 {
+			context := []interface{}{
+				"blocks", len(ancients), "elapsed", common.PrettyDuration(time.Since(start)), "number", f.frozen - 1,
+			}
+			if dangling > 0 {
+				context = append(context, []interface{}{"dangling", dangling}...)
+			}
+			log.Info("Deep froze chain segment", context...)
+		}
+		// Avoid database thrashing with tiny writes
+		if len(ancients) < freezerBatchLimit {
+			if !f.wait(freezerRecheckInterval) {
+				return
+			}
+		}
+	}
+}
+
+// wait sleeps for the interval, it returns false if the freezer is closed meanwhile.
+func (f *freezer) wait(interval time.Duration) bool {
+	select {
+	case <-f.quit:
+		log.Info("Freezer shutting down")
+		return false
+	case <-time.After(interval):
+		return true
+	}
+}
+
+// repair truncates all data tables to the same length.
+func (f *freezer) repair() error {
+	min := uint64(1<<64 - 1)
+	for _, table := range f.tables {
+		items := atomic.LoadUint64(&table.items)
+		if min > items {
+			min = items
+		}
+	}
+	for _, table := range f.tables {
+		if err := table.truncate(min); err != nil {
+			return err
+		}
+	}
+	atomic.StoreUint64(&f.frozen, min)
+	return nil
+}
@@ -0,0 +1,437 @@
+package rawdb
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/golang/snappy"
+)
+
+var (
+	// errClosed is returned if an operation attempts to read from or write to the
+	// freezer table after it has already been closed.
+	errClosed = errors.New("closed")
+
+	// errOutOfBounds is returned if the item requested is not contained within the
+	// freezer table.
+	errOutOfBounds = errors.New("out of bounds")
+)
+
+// indexEntrySize is the size of a serialized index entry, the file number is
+// stored as uint16 and the offset as uint32.
+const indexEntrySize = 6
+
+// indexEntry contains the number of the data file an item resides in, and the
+// offset within the file to the end of the item.
+type indexEntry struct {
+	filenum uint32
+	offset  uint32
+}
+
+func (i *indexEntry) unmarshalBinary(b []byte) {
+	i.filenum = uint32(binary.BigEndian.Uint16(b[:2]))
+	i.offset = binary.BigEndian.Uint32(b[2:6])
+}
+
+func (i *indexEntry) marshalBinary() []byte {
+	b := make([]byte, indexEntrySize)
+	binary.BigEndian.PutUint16(b[:2], uint16(i.filenum))
+	binary.BigEndian.PutUint32(b[2:6], i.offset)
+	return b
+}
+
+// freezerTable is a single append-only table of the freezer (e.g. headers). It
+// consists of data files holding the items one after the other (snappy encoded
+// unless disabled), and an index file holding the end of every item. The first
+// index entry is always zero, the bounds of the item n are in the entries n and
+// n+1.
+type freezerTable struct {
+	items uint64 // Number of items stored in the table (atomic, first for alignment)
+
+	noCompression bool   // Whether the items are stored without snappy encoding
+	maxFileSize   uint32 // Max file size of the data files
+	name          string
+	path          string
+
+	head      *os.File            // File descriptor of the data file being appended
+	headId    uint32              // Number of the data file being appended
+	headBytes uint32              // Number of bytes written to the head file
+	files     map[uint32]*os.File // Open data files
+	index     *os.File            // File descriptor of the index file
+
+	logger log.Logger
+	lock   sync.RWMutex // Mutex protecting the data files from concurrent access
+}
+
+// newTable opens a freezer table with the default data file size limit.
+func newTable(path string, name string, noCompression bool) (*freezerTable, error) {
+	return newCustomTable(path, name, 2*1000*1000*1000, noCompression)
+}
+
+// newCustomTable opens a freezer table, creating the files if missing, and repairs
+// the data left by an interrupted append.
+func newCustomTable(path string, name string, maxFileSize uint32, noCompression bool) (*freezerTable, error) {
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return nil, err
+	}
+	idxName := fmt.Sprintf("%s.cidx", name)
+	if noCompression {
+		idxName = fmt.Sprintf("%s.ridx", name)
+	}
+	index, err := os.OpenFile(filepath.Join(path, idxName), os.O_RDWR|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, err
+	}
+	tab := &freezerTable{
+		noCompression: noCompression,
+		maxFileSize:   maxFileSize,
+		name:          name,
+		path:          path,
+		files:         make(map[uint32]*os.File),
+		index:         index,
+		logger:        log.New("table", name),
+	}
+	if err := tab.repair(); err != nil {
+		tab.Close()
+		return nil, err
+	}
+	return tab, nil
+}
+
+// repair cross checks the head of the index and the data files, truncating them to
+// the last item written out completely.
+func (t *freezerTable) repair() error {
+	buffer := make([]byte, indexEntrySize)
+
+	// Ensure the index holds at least the zero entry and full entries only
+	stat, err := t.index.Stat()
+	if err != nil {
+		return err
+	}
+	if stat.Size() == 0 {
+		if _, err := t.index.Write(buffer); err != nil {
+			return err
+		}
+	}
+	if overflow := stat.Size() % indexEntrySize; overflow != 0 {
+		if err := t.index.Truncate(stat.Size() - overflow); err != nil {
+			return err
+		}
+	}
+	if stat, err = t.index.Stat(); err != nil {
+		return err
+	}
+	indexSize := stat.Size()
+
+	var last indexEntry
+	if _, err := t.index.ReadAt(buffer, indexSize-indexEntrySize); err != nil {
+		return err
+	}
+	last.unmarshalBinary(buffer)
+
+	if t.head, err = t.openFile(last.filenum, os.O_RDWR|os.O_CREATE|os.O_APPEND); err != nil {
+		return err
+	}
+	if stat, err = t.head.Stat(); err != nil {
+		return err
+	}
+	dataSize := stat.Size()
+
+	// Drop the data written after the last index entry, and the index entries of
+	// the data not written out
+	for int64(last.offset) != dataSize {
+		if int64(last.offset) < dataSize {
+			t.logger.Warn("Truncating dangling head", "indexed", last.offset, "stored", dataSize)
+			if err := t.head.Truncate(int64(last.offset)); err != nil {
+				return err
+			}
+			dataSize = int64(last.offset)
+			continue
+		}
+		t.logger.Warn("Truncating dangling indexes", "indexed", last.offset, "stored", dataSize)
+		indexSize -= indexEntrySize
+		if err := t.index.Truncate(indexSize); err != nil {
+			return err
+		}
+		var prev indexEntry
+		if _, err := t.index.ReadAt(buffer, indexSize-indexEntrySize); err != nil {
+			return err
+		}
+		prev.unmarshalBinary(buffer)
+
+		// The head could move back to the previous data file
+		if prev.filenum != last.filenum {
+			t.releaseFile(last.filenum)
+			if t.head, err = t.openFile(prev.filenum, os.O_RDWR|os.O_CREATE|os.O_APPEND); err != nil {
+				return err
+			}
+			if stat, err = t.head.Stat(); err != nil {
+				return err
+			}
+			dataSize = stat.Size()
+		}
+		last = prev
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	if err := t.head.Sync(); err != nil {
+		return err
+	}
+	atomic.StoreUint64(&t.items, uint64(indexSize/indexEntrySize-1))
+	t.headId = last.filenum
+	t.headBytes = uint32(dataSize)
+
+	// Open the data files before the head for reading, and drop the ones after it
+	for i := uint32(0); i < t.headId; i++ {
+		if _, err := t.openFile(i, os.O_RDONLY); err != nil {
+			return err
+		}
+	}
+	t.releaseFilesAfter(t.headId, true)
+
+	t.logger.Debug("Chain freezer table opened", "items", t.items, "size", t.headBytes)
+	return nil
+}
+
+// truncate discards any recent data above the provided threshold number.
+func (t *freezerTable) truncate(items uint64) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	existing := atomic.LoadUint64(&t.items)
+	if existing <= items {
+		return nil
+	}
+	t.logger.Warn("Truncating freezer table", "items", existing, "limit", items)
+	if err := t.index.Truncate(int64(items+1) * indexEntrySize); err != nil {
+		return err
+	}
+	buffer := make([]byte, indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, int64(items*indexEntrySize)); err != nil {
+		return err
+	}
+	var expected indexEntry
+	expected.unmarshalBinary(buffer)
+
+	// The head could move back to a previous data file, the files after it are removed
+	if expected.filenum != t.headId {
+		t.releaseFile(expected.filenum)
+		head, err := t.openFile(expected.filenum, os.O_RDWR|os.O_CREATE|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+		t.releaseFilesAfter(expected.filenum, true)
+		t.head = head
+		t.headId = expected.filenum
+	}
+	if err := t.head.Truncate(int64(expected.offset)); err != nil {
+		return err
+	}
+	t.headBytes = expected.offset
+	atomic.StoreUint64(&t.items, items)
+	return nil
+}
+
+// Close closes all opened files.
+func (t *freezerTable) Close() error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	var errs []error
+	if t.index != nil {
+		if err := t.index.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	t.index = nil
+
+	for num, f := range t.files {
+		if err := f.Close(); err != nil {
+			errs = append(errs, err)
+		}
+		delete(t.files, num)
+	}
+	t.head = nil
+
+	if errs != nil {
+		return fmt.Errorf("%v", errs)
+	}
+	return nil
+}
+
+// openFile assumes that the write-lock is held by the caller.
+func (t *freezerTable) openFile(num uint32, flag int) (*os.File, error) {
+	if f, exist := t.files[num]; exist {
+		return f, nil
+	}
+	name := fmt.Sprintf("%s.%04d.cdat", t.name, num)
+	if t.noCompression {
+		name = fmt.Sprintf("%s.%04d.rdat", t.name, num)
+	}
+	f, err := os.OpenFile(filepath.Join(t.path, name), flag, 0644)
+	if err != nil {
+		return nil, err
+	}
+	t.files[num] = f
+	return f, nil
+}
+
+// releaseFile closes a file, and removes it from the open file cache.
+// Assumes that the caller holds the write lock.
+func (t *freezerTable) releaseFile(num uint32) {
+	if f, exist := t.files[num]; exist {
+		delete(t.files, num)
+		f.Close()
+	}
+}
+
+// releaseFilesAfter closes all open files with a higher number, and optionally also
+// deletes the files.
+func (t *freezerTable) releaseFilesAfter(num uint32, remove bool) {
+	for fnum, f := range t.files {
+		if fnum > num {
+			delete(t.files, fnum)
+			f.Close()
+			if remove {
+				os.Remove(f.Name())
+			}
+		}
+	}
+	if remove {
+		// The files not opened yet, left by an interrupted truncation
+		for fnum := num + 1; ; fnum++ {
+			name := fmt.Sprintf("%s.%04d.cdat", t.name, fnum)
+			if t.noCompression {
+				name = fmt.Sprintf("%s.%04d.rdat", t.name, fnum)
+			}
+			if err := os.Remove(filepath.Join(t.path, name)); err != nil {
+				break
+			}
+		}
+	}
+}
+
+// Append injects a binary blob at the end of the freezer table. The item number
+// must be the next one of the table, the data is not synced to disk until Sync.
+func (t *freezerTable) Append(item uint64, blob []byte) error {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if items := atomic.LoadUint64(&t.items); items != item {
+		return fmt.Errorf("appending unexpected item: want %d, have %d", items, item)
+	}
+	if !t.noCompression {
+		blob = snappy.Encode(nil, blob)
+	}
+	size := uint32(len(blob))
+
+	// Continue in a new data file if the head would grow over the limit
+	if t.headBytes+size < size || t.headBytes+size > t.maxFileSize {
+		next := t.headId + 1
+		head, err := t.openFile(next, os.O_RDWR|os.O_CREATE|os.O_TRUNC|os.O_APPEND)
+		if err != nil {
+			return err
+		}
+		if err := t.head.Sync(); err != nil {
+			return err
+		}
+		t.head = head
+		t.headId = next
+		t.headBytes = 0
+	}
+	if _, err := t.head.Write(blob); err != nil {
+		return err
+	}
+	t.headBytes += size
+
+	entry := indexEntry{filenum: t.headId, offset: t.headBytes}
+	if _, err := t.index.Write(entry.marshalBinary()); err != nil {
+		return err
+	}
+	atomic.AddUint64(&t.items, 1)
+	return nil
+}
+
+// Retrieve looks up the data offset of an item with the given number and retrieves
+// the raw binary blob from the data file.
+func (t *freezerTable) Retrieve(item uint64) ([]byte, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil {
+		return nil, errClosed
+	}
+	if atomic.LoadUint64(&t.items) <= item {
+		return nil, errOutOfBounds
+	}
+	buffer := make([]byte, 2*indexEntrySize)
+	if _, err := t.index.ReadAt(buffer, int64(item*indexEntrySize)); err != nil {
+		return nil, err
+	}
+	var start, end indexEntry
+	start.unmarshalBinary(buffer[:indexEntrySize])
+	end.unmarshalBinary(buffer[indexEntrySize:])
+
+	// The first item of a data file starts at its beginning
+	if start.filenum != end.filenum {
+		start.offset = 0
+	}
+	data, exist := t.files[end.filenum]
+	if !exist {
+		return nil, fmt.Errorf("missing data file %d", end.filenum)
+	}
+	blob := make([]byte, end.offset-start.offset)
+	if _, err := data.ReadAt(blob, int64(start.offset)); err != nil {
+		return nil, err
+	}
+	if t.noCompression {
+		return blob, nil
+	}
+	return snappy.Decode(nil, blob)
+}
+
+// has returns an indicator whether the specified number data exists in the
+// freezer table.
+func (t *freezerTable) has(number uint64) bool {
+	return atomic.LoadUint64(&t.items) > number
+}
+
+// size returns the total data size in the freezer table.
+func (t *freezerTable) size() (uint64, error) {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil {
+		return 0, errClosed
+	}
+	stat, err := t.index.Stat()
+	if err != nil {
+		return 0, err
+	}
+	total := uint64(t.maxFileSize)*uint64(t.headId) + uint64(t.headBytes) + uint64(stat.Size())
+	return total, nil
+}
+
+// Sync pushes any pending data from memory out to disk.
+func (t *freezerTable) Sync() error {
+	t.lock.RLock()
+	defer t.lock.RUnlock()
+
+	if t.index == nil || t.head == nil {
+		return errClosed
+	}
+	if err := t.index.Sync(); err != nil {
+		return err
+	}
+	return t.head.Sync()
+}
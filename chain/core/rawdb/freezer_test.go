@@ -0,0 +1,137 @@
+package rawdb
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/neatdb/memorydb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// Tests that a freezer table spanning several data files is repaired after a crash
+// in the middle of an append, and could be truncated.
+func TestFreezerTableRepair(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	table, err := newCustomTable(dir, "test", 50, true)
+	if err != nil {
+		t.Fatalf("failed to open table: %v", err)
+	}
+	for i := uint64(0); i < 20; i++ {
+		if err := table.Append(i, bytes.Repeat([]byte{byte(i)}, 15)); err != nil {
+			t.Fatalf("failed to append item %d: %v", i, err)
+		}
+	}
+	table.Close()
+
+	// Cut the last item in half, it must be dropped on reopening
+	head := filepath.Join(dir, fmt.Sprintf("test.%04d.rdat", 19/3))
+	if err := os.Truncate(head, 15+7); err != nil {
+		t.Fatal(err)
+	}
+	if table, err = newCustomTable(dir, "test", 50, true); err != nil {
+		t.Fatalf("failed to reopen table: %v", err)
+	}
+	defer table.Close()
+
+	if table.items != 19 {
+		t.Fatalf("items mismatch after repair: have %d, want %d", table.items, 19)
+	}
+	for i := uint64(0); i < 19; i++ {
+		blob, err := table.Retrieve(i)
+		if err != nil {
+			t.Fatalf("failed to retrieve item %d: %v", i, err)
+		}
+		if !bytes.Equal(blob, bytes.Repeat([]byte{byte(i)}, 15)) {
+			t.Fatalf("item %d mismatch: %x", i, blob)
+		}
+	}
+	if err := table.truncate(4); err != nil {
+		t.Fatalf("failed to truncate table: %v", err)
+	}
+	if _, err := table.Retrieve(4); err != errOutOfBounds {
+		t.Fatalf("truncated item retrieved: %v", err)
+	}
+	if err := table.Append(4, []byte{0xff}); err != nil {
+		t.Fatalf("failed to append after truncation: %v", err)
+	}
+	if blob, _ := table.Retrieve(4); !bytes.Equal(blob, []byte{0xff}) {
+		t.Fatalf("appended item mismatch: %x", blob)
+	}
+}
+
+// Tests that the blocks moved into the ancient store are still retrieved by the
+// chain accessors.
+func TestAncientStorage(t *testing.T) {
+	dir, err := ioutil.TempDir("", "freezer")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	kvdb := memorydb.New()
+	db, err := NewDatabaseWithFreezer(kvdb, dir, "")
+	if err != nil {
+		t.Fatalf("failed to create database with freezer: %v", err)
+	}
+	defer db.Close()
+
+	block := types.NewBlockWithHeader(&types.Header{Number: big.NewInt(0), Extra: []byte("test block")})
+	receipts := []*types.Receipt{types.NewReceipt(nil, false, 21000)}
+	receipts[0].TxHash = common.BytesToHash([]byte{0x11})
+
+	hash, number := block.Hash(), block.NumberU64()
+	WriteBlock(db, block)
+	WriteCanonicalHash(db, hash, number)
+	WriteReceipts(db, hash, number, receipts)
+	WriteTd(db, hash, number, big.NewInt(7))
+
+	// Freeze the block and delete it from the key-value store
+	var (
+		header, _ = rlp.EncodeToBytes(block.Header())
+		body, _   = rlp.EncodeToBytes(block.Body())
+	)
+	if err := db.AppendAncient(number, hash[:], header, body, ReadReceiptsRLP(db, hash, number), ReadTdRLP(db, hash, number)); err != nil {
+		t.Fatalf("failed to freeze block: %v", err)
+	}
+	deleteBlockWithoutNumber(kvdb, hash, number)
+	DeleteCanonicalHash(kvdb, number)
+
+	if entry := ReadCanonicalHash(db, number); entry != hash {
+		t.Fatalf("frozen canonical hash mismatch: have %x, want %x", entry, hash)
+	}
+	if entry := ReadBlock(db, hash, number); entry == nil || entry.Hash() != hash {
+		t.Fatalf("frozen block not found")
+	}
+	if !HasHeader(db, hash, number) || !HasBody(db, hash, number) || !HasReceipts(db, hash, number) {
+		t.Fatalf("frozen block data missing")
+	}
+	if entry := ReadReceipts(db, hash, number); len(entry) != 1 || entry[0].TxHash != receipts[0].TxHash {
+		t.Fatalf("frozen receipts mismatch: %v", entry)
+	}
+	if entry := ReadTd(db, hash, number); entry == nil || entry.Cmp(big.NewInt(7)) != 0 {
+		t.Fatalf("frozen total difficulty mismatch: %v", entry)
+	}
+	// The frozen data is served for the canonical hash only
+	if entry := ReadHeader(db, common.Hash{0x01}, number); entry != nil {
+		t.Fatalf("frozen header returned for a side block")
+	}
+	// Truncating the ancients drops the frozen block
+	if err := db.TruncateAncients(0); err != nil {
+		t.Fatalf("failed to truncate ancients: %v", err)
+	}
+	if entry := ReadHeader(db, hash, number); entry != nil {
+		t.Fatalf("truncated header returned")
+	}
+}
@@ -1,20 +1,20 @@
 // Package rawdb contains a collection of low level database accessors.
 package rawdb
 
+import "github.com/Gessiux/neatchain/utilities/common"
+
 // The fields below define the low level database schema prefixing for data prune.
 var (
-	// headDataScanKey tracks the latest know scan header's number.
-	headDataScanKey = []byte("LastDataScanHeight")
-
 	// headDataPruneKey tracks the latest know prune header's number.
 	headDataPruneKey = []byte("LastDataPruneHeight")
 
-	// Data item prefixes (use single byte to avoid mixing data types, avoid `i`, used for indexes).
-	dataPruneProcessPrefix = []byte("p") // dataPruneProcessPrefix + scan num (uint64 big endian) + prune num (uint64 big endian) + dataPruneProcessSuffix -> trie root hash
-	dataPruneProcessSuffix = []byte("n")
+	// pruneStateMarkerKey tracks the state roots kept by a running offline state pruning.
+	pruneStateMarkerKey = []byte("PruneStateMarker")
+
+	retainedStatePrefix = []byte("prune-retained-") // retainedStatePrefix + num (uint64 big endian) + state root -> nil, committed state kept on disk
 )
 
-// dataPruneNumberKey = dataPruneProcessPrefix + scan (uint64 big endian) + prune (uint64 big endian) + dataPruneProcessSuffix
-func dataPruneNumberKey(scan, prune uint64) []byte {
-	return append(append(append(dataPruneProcessPrefix, encodeBlockNumber(scan)...), encodeBlockNumber(prune)...), dataPruneProcessSuffix...)
+// retainedStateKey = retainedStatePrefix + num (uint64 big endian) + state root
+func retainedStateKey(number uint64, root common.Hash) []byte {
+	return append(append(append([]byte{}, retainedStatePrefix...), encodeBlockNumber(number)...), root.Bytes()...)
 }
@@ -0,0 +1,123 @@
+package core
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"math/big"
+
+	dbm "github.com/Gessiux/go-db"
+	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// SideChainSettlementPeriod is the number of main chain blocks the final balances of a closed
+// side chain could be withdrawn after its final state root is anchored
+const SideChainSettlementPeriod = 201600
+
+const sideChainClosureKey = "CLOSURE"
+
+// SideChainClosure tracks the decommission of a side chain. The validators of the side chain
+// vote to halt it at a height, once the block of the halt height is anchored on the main chain
+// the balances of its final state could be withdrawn until the end of the settlement period.
+type SideChainClosure struct {
+	ChainId string
+	Votes   []CloseVote
+
+	HaltHeight uint64 // last block of the side chain, 0 until +2/3 of the validators agreed
+
+	FinalHash   common.Hash // hash of the block at the halt height
+	FinalRoot   common.Hash // state root of the block at the halt height
+	SettleBlock uint64      // main chain block the final root was anchored at
+}
+
+// CloseVote is the halt height voted by a validator of the side chain
+type CloseVote struct {
+	Address    common.Address
+	HaltHeight uint64
+}
+
+// Halted checks the side chain stops before the height
+func (c *SideChainClosure) Halted(height uint64) bool {
+	return c.HaltHeight != 0 && height > c.HaltHeight
+}
+
+// Settled checks the final state root of the side chain is anchored on the main chain
+func (c *SideChainClosure) Settled() bool {
+	return c.SettleBlock != 0
+}
+
+// SettlementEnd is the last main chain block the final balances could be withdrawn
+func (c *SideChainClosure) SettlementEnd() uint64 {
+	return c.SettleBlock + SideChainSettlementPeriod
+}
+
+func calcSideChainClosureKey(chainId string) []byte {
+	return []byte(sideChainClosureKey + ":" + chainId)
+}
+
+// GetSideChainClosure load the decommission of the side chain, nil if the side chain is not closing
+func GetSideChainClosure(db dbm.DB, chainId string) *SideChainClosure {
+	mtx.RLock()
+	defer mtx.RUnlock()
+
+	buf := db.Get(calcSideChainClosureKey(chainId))
+	if len(buf) == 0 {
+		return nil
+	}
+
+	var closure SideChainClosure
+	r, n, err := bytes.NewReader(buf), new(int), new(error)
+	wire.ReadBinaryPtr(&closure, r, 0, n, err)
+	if *err != nil {
+		log.Errorf("GetSideChainClosure: failed to decode the closure of side chain %s: %v", chainId, *err)
+		return nil
+	}
+	return &closure
+}
+
+// SaveSideChainClosure save the decommission of the side chain
+func SaveSideChainClosure(db dbm.DB, closure *SideChainClosure) {
+	mtx.Lock()
+	defer mtx.Unlock()
+
+	db.SetSync(calcSideChainClosureKey(closure.ChainId), wire.BinaryBytes(*closure))
+}
+
+// ProveFinalBalance proves the account of the address at the final state root of a closed side chain
+func ProveFinalBalance(db state.Database, root common.Hash, address common.Address) (*types.BSKeyValueSet, error) {
+	tr, err := trie.NewSecure(root, db.TrieDB())
+	if err != nil {
+		return nil, err
+	}
+	proof := types.MakeBSKeyValueSet()
+	if err := tr.Prove(crypto.Keccak256(address.Bytes()), 0, proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// VerifyFinalBalance returns the balance of the address proven at the final state root
+func VerifyFinalBalance(root common.Hash, address common.Address, proof *types.BSKeyValueSet) (*big.Int, error) {
+	enc, _, err := trie.VerifyProof(root, crypto.Keccak256(address.Bytes()), proof)
+	if err != nil {
+		return nil, fmt.Errorf("invalid account proof: %v", err)
+	}
+	if len(enc) == 0 {
+		return nil, errors.New("account not found in the final state")
+	}
+	var account state.Account
+	if err := rlp.DecodeBytes(enc, &account); err != nil {
+		return nil, err
+	}
+	if account.Balance == nil {
+		return new(big.Int), nil
+	}
+	return account.Balance, nil
+}
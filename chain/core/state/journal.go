@@ -19,6 +19,7 @@ package state
 import (
 	"math/big"
 
+	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/utilities/common"
 )
 
@@ -154,6 +155,10 @@ type (
 	addLogChange struct {
 		txhash common.Hash
 	}
+	discardLogsChange struct {
+		txhash common.Hash
+		prev   []*types.Log
+	}
 	addPreimageChange struct {
 		hash common.Hash
 	}
@@ -305,6 +310,11 @@ func (ch refundChange) undo(s *StateDB) {
 	s.refund = ch.prev
 }
 
+func (ch discardLogsChange) undo(s *StateDB) {
+	s.logs[ch.txhash] = ch.prev
+	s.logSize += uint(len(ch.prev))
+}
+
 func (ch addLogChange) undo(s *StateDB) {
 	logs := s.logs[ch.txhash]
 	if len(logs) == 1 {
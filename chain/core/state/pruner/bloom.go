@@ -0,0 +1,52 @@
+package pruner
+
+import (
+	"encoding/binary"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// bloomHashes is the number of bit positions set for each key, every position is taken
+// from 8 bytes of the key.
+const bloomHashes = common.HashLength / 8
+
+// stateBloom is a bloom filter of the trie node and code hashes of the kept states. The
+// keys are hashes already, so the positions are taken from their bytes directly.
+type stateBloom struct {
+	bits []uint64
+	size uint64 // Number of bits in the filter
+}
+
+// newStateBloom creates a bloom filter of the size in megabytes
+func newStateBloom(megabytes uint64) *stateBloom {
+	if megabytes == 0 {
+		megabytes = 1
+	}
+	size := megabytes * 1024 * 1024 * 8
+	return &stateBloom{
+		bits: make([]uint64, size/64),
+		size: size,
+	}
+}
+
+func (b *stateBloom) add(hash common.Hash) {
+	for i := 0; i < bloomHashes; i++ {
+		pos := binary.BigEndian.Uint64(hash[i*8:]) % b.size
+		b.bits[pos/64] |= 1 << (pos % 64)
+	}
+}
+
+// contains checks whether the key may be in the filter, the false positives are just
+// kept on disk
+func (b *stateBloom) contains(key []byte) bool {
+	if len(key) != common.HashLength {
+		return false
+	}
+	for i := 0; i < bloomHashes; i++ {
+		pos := binary.BigEndian.Uint64(key[i*8:]) % b.size
+		if b.bits[pos/64]&(1<<(pos%64)) == 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,195 @@
+package pruner
+
+import (
+	"bytes"
+	"errors"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	// emptyRoot is the known root hash of an empty trie.
+	emptyRoot = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+
+	// emptyCodeHash is the known hash of the empty EVM bytecode.
+	emptyCodeHash = crypto.Keccak256(nil)
+
+	// errNoRoot is returned if there is no state to keep
+	errNoRoot = errors.New("no state root to keep")
+)
+
+// Pruner deletes the trie nodes and the codes of all the states except the kept ones.
+// The kept states are walked into a bloom filter, then every trie node or code missing
+// from the filter is deleted. The false positives of the filter are just left on disk.
+type Pruner struct {
+	db        neatdb.Database
+	bloomSize uint64 // Size of the bloom filter in megabytes
+}
+
+// NewPruner creates a pruner of the chain database
+func NewPruner(db neatdb.Database, bloomSize uint64) *Pruner {
+	return &Pruner{
+		db:        db,
+		bloomSize: bloomSize,
+	}
+}
+
+// Prune keeps the states of the roots and deletes the others. The kept roots are saved
+// in a marker before anything is deleted, an interrupted pruning keeps them as well
+// when it's run again.
+func (p *Pruner) Prune(roots []common.Hash) error {
+	kept := make(map[common.Hash]struct{})
+	var keep []common.Hash
+	for _, root := range append(append([]common.Hash{}, roots...), rawdb.ReadPruneStateMarker(p.db)...) {
+		if _, ok := kept[root]; ok {
+			continue
+		}
+		if has, _ := p.db.Has(root[:]); !has {
+			log.Warn("Kept state missing, skipped", "root", root)
+			continue
+		}
+		kept[root] = struct{}{}
+		keep = append(keep, root)
+	}
+	if len(keep) == 0 {
+		return errNoRoot
+	}
+	rawdb.WritePruneStateMarker(p.db, keep)
+
+	// Walk all the kept states before deleting anything
+	start := time.Now()
+	bloom := newStateBloom(p.bloomSize)
+	for _, root := range keep {
+		if err := p.markState(bloom, root); err != nil {
+			return err
+		}
+		log.Info("Marked kept state", "root", root, "elapsed", common.PrettyDuration(time.Since(start)))
+	}
+	if err := p.sweep(bloom); err != nil {
+		return err
+	}
+
+	// The nodes got deleted without counting the references, the online pruning has to
+	// start over
+	if err := trie.ResetRefcount(p.db); err != nil {
+		return err
+	}
+	for _, retained := range rawdb.ReadRetainedStates(p.db) {
+		rawdb.DeleteRetainedState(p.db, retained.Number, retained.Root)
+	}
+
+	log.Info("Compacting database")
+	cstart := time.Now()
+	if err := p.db.Compact(nil, nil); err != nil {
+		// The pruned data is gone already, only the space isn't reclaimed yet
+		log.Warn("Database compaction failed", "err", err)
+	} else {
+		log.Info("Database compaction finished", "elapsed", common.PrettyDuration(time.Since(cstart)))
+	}
+
+	rawdb.DeletePruneStateMarker(p.db)
+	log.Info("State pruning successful", "kept", len(keep), "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
+
+// markState adds the nodes of the account trie, of the sub tries of every account and
+// the codes into the bloom filter
+func (p *Pruner) markState(bloom *stateBloom, root common.Hash) error {
+	sdb := state.NewDatabase(p.db)
+	accounts, err := sdb.OpenTrie(root)
+	if err != nil {
+		return err
+	}
+	it := accounts.NodeIterator(nil)
+	for it.Next(true) {
+		if hash := it.Hash(); hash != (common.Hash{}) {
+			bloom.add(hash)
+		}
+		if !it.Leaf() {
+			continue
+		}
+		var account state.Account
+		if err := rlp.DecodeBytes(it.LeafBlob(), &account); err != nil {
+			return err
+		}
+		addrHash := common.BytesToHash(it.LeafKey())
+		tries := []struct {
+			root common.Hash
+			open func(addrHash, root common.Hash) (state.Trie, error)
+		}{
+			{account.Root, sdb.OpenStorageTrie},
+			{account.TX1Root, sdb.OpenTX1Trie},
+			{account.TX3Root, sdb.OpenTX3Trie},
+			{account.ProxiedRoot, sdb.OpenProxiedTrie},
+			{account.RewardRoot, sdb.OpenRewardTrie},
+		}
+		for _, t := range tries {
+			if t.root == emptyRoot || t.root == (common.Hash{}) {
+				continue
+			}
+			tr, err := t.open(addrHash, t.root)
+			if err != nil {
+				return err
+			}
+			sub := tr.NodeIterator(nil)
+			for sub.Next(true) {
+				if hash := sub.Hash(); hash != (common.Hash{}) {
+					bloom.add(hash)
+				}
+			}
+			if sub.Error() != nil {
+				return sub.Error()
+			}
+		}
+		if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+			bloom.add(common.BytesToHash(account.CodeHash))
+		}
+	}
+	return it.Error()
+}
+
+// sweep deletes the trie nodes and codes missing from the bloom filter
+func (p *Pruner) sweep(bloom *stateBloom) error {
+	var (
+		count  int
+		size   common.StorageSize
+		start  = time.Now()
+		logged = time.Now()
+		batch  = p.db.NewBatch()
+	)
+	it := p.db.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		key := it.Key()
+		if len(key) != common.HashLength || bloom.contains(key) {
+			continue
+		}
+		count++
+		size += common.StorageSize(len(key) + len(it.Value()))
+		batch.Delete(common.CopyBytes(key))
+
+		if batch.ValueSize() >= neatdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return err
+			}
+			batch.Reset()
+		}
+		if time.Since(logged) > 8*time.Second {
+			log.Info("Pruning state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+			logged = time.Now()
+		}
+	}
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	log.Info("Pruned state data", "nodes", count, "size", size, "elapsed", common.PrettyDuration(time.Since(start)))
+	return nil
+}
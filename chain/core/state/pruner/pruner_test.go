@@ -0,0 +1,85 @@
+package pruner
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+)
+
+func TestPrune(t *testing.T) {
+	db := rawdb.NewMemoryDatabase()
+	sdb := state.NewDatabase(db)
+
+	// Commit two states, the second one updates half of the accounts
+	var roots []common.Hash
+	root := common.Hash{}
+	for round := int64(0); round < 2; round++ {
+		statedb, _ := state.New(root, sdb)
+		for i := int64(0); i < 100; i++ {
+			if round > 0 && i%2 == 0 {
+				continue
+			}
+			addr := common.BigToAddress(big.NewInt(i + 1))
+			statedb.SetBalance(addr, big.NewInt(i+round*1000))
+			if i%10 == 1 {
+				statedb.SetState(addr, common.BigToHash(big.NewInt(round)), common.HexToHash("0xbeef"))
+				statedb.SetCode(addr, []byte{0x60, byte(round), byte(i)})
+				statedb.AddTX1(addr, crypto.Keccak256Hash(addr[:], []byte{byte(round)}))
+			}
+		}
+		var err error
+		if root, err = statedb.Commit(false); err != nil {
+			t.Fatalf("failed to commit state: %v", err)
+		}
+		if err := sdb.TrieDB().Commit(root, false); err != nil {
+			t.Fatalf("failed to commit trie: %v", err)
+		}
+		roots = append(roots, root)
+	}
+	rawdb.WriteHeadPruneNumber(db, 1)
+
+	// An interrupted pruning keeps the roots of its marker too
+	rawdb.WritePruneStateMarker(db, roots[:1])
+	if err := NewPruner(db, 1).Prune(roots[1:]); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	if marker := rawdb.ReadPruneStateMarker(db); marker != nil {
+		t.Fatalf("marker left: %v", marker)
+	}
+	for _, root := range roots {
+		checkState(t, db, root)
+	}
+
+	if err := NewPruner(db, 1).Prune(roots[1:]); err != nil {
+		t.Fatalf("failed to prune: %v", err)
+	}
+	checkState(t, db, roots[1])
+	if has, _ := db.Has(roots[0][:]); has {
+		t.Fatalf("pruned state still on disk")
+	}
+	if number := rawdb.ReadHeadPruneNumber(db); number == nil || *number != 1 {
+		t.Fatalf("non state data deleted")
+	}
+}
+
+// checkState walks the whole state, including the sub tries and the codes
+func checkState(t *testing.T, db neatdb.Database, root common.Hash) {
+	t.Helper()
+	if err := NewPruner(db, 1).markState(newStateBloom(1), root); err != nil {
+		t.Fatalf("state %x broken: %v", root, err)
+	}
+	statedb, err := state.New(root, state.NewDatabase(db))
+	if err != nil {
+		t.Fatalf("failed to open state %x: %v", root, err)
+	}
+	for i := int64(1); i < 100; i += 10 {
+		if code := statedb.GetCode(common.BigToAddress(big.NewInt(i + 1))); len(code) != 3 {
+			t.Fatalf("code of state %x missing", root)
+		}
+	}
+}
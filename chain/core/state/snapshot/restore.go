@@ -0,0 +1,192 @@
+package snapshot
+
+import (
+	"bytes"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// Restorer rebuilds the state tries of a snapshot from its chunks. The chunks may be
+// processed in any order, the sub tries and codes of every account are checked as soon
+// as its chunk arrives, the account trie is checked against the root of the manifest
+// once all the chunks are processed.
+type Restorer struct {
+	manifest *Manifest
+	db       neatdb.Database
+	triedb   *trie.Database
+	accounts *trie.Trie
+
+	pending map[common.Hash]struct{}
+}
+
+// NewRestorer creates a restorer writing the state of the manifest into the database
+func NewRestorer(manifest *Manifest, db neatdb.Database) (*Restorer, error) {
+	triedb := trie.NewDatabase(db)
+	accounts, err := trie.New(common.Hash{}, triedb)
+	if err != nil {
+		return nil, err
+	}
+	pending := make(map[common.Hash]struct{}, len(manifest.Chunks))
+	for _, hash := range manifest.Chunks {
+		pending[hash] = struct{}{}
+	}
+	return &Restorer{
+		manifest: manifest,
+		db:       db,
+		triedb:   triedb,
+		accounts: accounts,
+		pending:  pending,
+	}, nil
+}
+
+// Missing returns the hashes of the chunks not processed yet, in manifest order
+func (r *Restorer) Missing() []common.Hash {
+	missing := make([]common.Hash, 0, len(r.pending))
+	for _, hash := range r.manifest.Chunks {
+		if _, ok := r.pending[hash]; ok {
+			missing = append(missing, hash)
+		}
+	}
+	return missing
+}
+
+// Done reports whether all the chunks are processed
+func (r *Restorer) Done() bool {
+	return len(r.pending) == 0
+}
+
+// Process verifies the chunk and writes its entries into the state tries. A chunk not
+// listed in the manifest, or with any invalid entry, is rejected as a whole.
+func (r *Restorer) Process(data []byte) error {
+	hash := crypto.Keccak256Hash(data)
+	if _, ok := r.pending[hash]; !ok {
+		return errUnknownChunk
+	}
+	var entries []*Entry
+	if err := rlp.DecodeBytes(data, &entries); err != nil {
+		return err
+	}
+
+	preimages := make(map[common.Hash][]byte)
+	batch := r.db.NewBatch()
+	for _, entry := range entries {
+		if err := r.restoreEntry(entry, preimages, batch); err != nil {
+			return err
+		}
+	}
+	for _, entry := range entries {
+		if err := r.accounts.TryUpdate(entry.Hash[:], entry.Value); err != nil {
+			return err
+		}
+	}
+	root, err := r.accounts.Commit(nil)
+	if err != nil {
+		return err
+	}
+	if err := r.triedb.Commit(root, false); err != nil {
+		return err
+	}
+	rawdb.WritePreimages(batch, preimages)
+	if err := batch.Write(); err != nil {
+		return err
+	}
+	delete(r.pending, hash)
+	return nil
+}
+
+// restoreEntry rebuilds the sub tries of an account entry and checks them against the
+// roots of the account
+func (r *Restorer) restoreEntry(entry *Entry, preimages map[common.Hash][]byte, batch neatdb.Batch) error {
+	if err := addPreimage(preimages, entry.Hash, entry.Preimage); err != nil {
+		return err
+	}
+	var account state.Account
+	if rlp.DecodeBytes(entry.Value, &account) != nil {
+		// Not an account, but one of the sets kept in the account trie
+		if len(entry.Code) > 0 || len(entry.Storage) > 0 || len(entry.TX1) > 0 || len(entry.TX3) > 0 || len(entry.Proxied) > 0 || len(entry.Reward) > 0 {
+			return errInvalidEntry
+		}
+		return nil
+	}
+
+	tries := []struct {
+		root  common.Hash
+		slots []Slot
+	}{
+		{account.Root, entry.Storage},
+		{account.TX1Root, entry.TX1},
+		{account.TX3Root, entry.TX3},
+		{account.ProxiedRoot, entry.Proxied},
+		{account.RewardRoot, entry.Reward},
+	}
+	for _, t := range tries {
+		want := t.root
+		if want == (common.Hash{}) {
+			want = emptyRoot
+		}
+		tr, err := trie.New(common.Hash{}, r.triedb)
+		if err != nil {
+			return err
+		}
+		for _, slot := range t.slots {
+			if err := addPreimage(preimages, slot.Key, slot.Preimage); err != nil {
+				return err
+			}
+			if err := tr.TryUpdate(slot.Key[:], slot.Value); err != nil {
+				return err
+			}
+		}
+		root, err := tr.Commit(nil)
+		if err != nil {
+			return err
+		}
+		if root != want {
+			return errInvalidEntry
+		}
+		if root != emptyRoot {
+			if err := r.triedb.Commit(root, false); err != nil {
+				return err
+			}
+		}
+	}
+
+	if bytes.Equal(account.CodeHash, emptyCodeHash) {
+		if len(entry.Code) > 0 {
+			return errInvalidCode
+		}
+		return nil
+	}
+	if !bytes.Equal(crypto.Keccak256(entry.Code), account.CodeHash) {
+		return errInvalidCode
+	}
+	return batch.Put(account.CodeHash, entry.Code)
+}
+
+// addPreimage checks the preimage of the hashed key, empty preimages are skipped
+func addPreimage(preimages map[common.Hash][]byte, hash common.Hash, preimage []byte) error {
+	if len(preimage) == 0 {
+		return nil
+	}
+	if crypto.Keccak256Hash(preimage) != hash {
+		return errInvalidPreimage
+	}
+	preimages[hash] = preimage
+	return nil
+}
+
+// Finish checks the restored account trie against the root of the manifest
+func (r *Restorer) Finish() error {
+	if !r.Done() {
+		return errIncomplete
+	}
+	if root := r.accounts.Hash(); root != r.manifest.Root {
+		return ErrRootMismatch
+	}
+	return nil
+}
@@ -0,0 +1,240 @@
+// Package snapshot dumps the state of a block into flat, hashed chunks and restores the
+// state tries from them, so a new node doesn't have to fetch the tries node by node.
+package snapshot
+
+import (
+	"bytes"
+	"errors"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ChunkSize is the target size of a snapshot chunk. The sub tries of an account are
+// never split, so a chunk holding an account with a huge storage may exceed it.
+const ChunkSize = 1024 * 1024
+
+var (
+	emptyRoot     = common.HexToHash("56e81f171bcc55a6ff8345e692c0f86e5b48e01b996cadc001622fb5e363b421")
+	emptyCodeHash = crypto.Keccak256(nil)
+
+	errUnknownChunk    = errors.New("unknown snapshot chunk")
+	errInvalidEntry    = errors.New("invalid snapshot entry")
+	errInvalidPreimage = errors.New("invalid snapshot preimage")
+	errInvalidCode     = errors.New("invalid snapshot code")
+	errIncomplete      = errors.New("snapshot chunks missing")
+
+	// ErrRootMismatch is returned if the restored state doesn't match the snapshot root
+	ErrRootMismatch = errors.New("snapshot state root mismatch")
+)
+
+// Manifest describes the state snapshot taken at a block. The chunks are listed in the
+// order of the account hashes, the state root is anchored by the header of the block.
+type Manifest struct {
+	Number    uint64
+	BlockHash common.Hash
+	Root      common.Hash
+	Chunks    []common.Hash
+}
+
+// Hash returns the hash of the manifest
+func (m *Manifest) Hash() common.Hash {
+	enc, _ := rlp.EncodeToBytes(m)
+	return crypto.Keccak256Hash(enc)
+}
+
+// Slot is a leaf of the storage trie or of any other sub trie of an account
+type Slot struct {
+	Key      common.Hash // hashed key of the leaf
+	Preimage []byte      // key before hashing, empty if unknown
+	Value    []byte
+}
+
+// Entry is a leaf of the account trie with the sub tries and the code of the account.
+// The account trie also stores the candidate, reward and other sets of the chain, these
+// entries don't decode as an account and carry no sub tries.
+type Entry struct {
+	Hash     common.Hash
+	Preimage []byte
+	Value    []byte
+	Code     []byte
+	Storage  []Slot
+	TX1      []Slot
+	TX3      []Slot
+	Proxied  []Slot
+	Reward   []Slot
+}
+
+func (e *Entry) size() int {
+	size := common.HashLength + len(e.Preimage) + len(e.Value) + len(e.Code)
+	for _, slots := range [][]Slot{e.Storage, e.TX1, e.TX3, e.Proxied, e.Reward} {
+		for _, slot := range slots {
+			size += common.HashLength + len(slot.Preimage) + len(slot.Value)
+		}
+	}
+	return size
+}
+
+// Generate dumps the state of the block into chunks and stores them with the manifest
+// into the database. The state must be kept available while the snapshot is generated.
+func Generate(sdb state.Database, db neatdb.Database, number uint64, blockHash, root common.Hash) (*Manifest, error) {
+	return generate(sdb, db, number, blockHash, root, ChunkSize)
+}
+
+func generate(sdb state.Database, db neatdb.Database, number uint64, blockHash, root common.Hash, chunkSize int) (*Manifest, error) {
+	accTrie, err := sdb.OpenTrie(root)
+	if err != nil {
+		return nil, err
+	}
+	manifest := &Manifest{Number: number, BlockHash: blockHash, Root: root}
+
+	var (
+		chunk []*Entry
+		size  int
+	)
+	flush := func() error {
+		enc, err := rlp.EncodeToBytes(chunk)
+		if err != nil {
+			return err
+		}
+		hash := crypto.Keccak256Hash(enc)
+		rawdb.WriteSnapshotChunk(db, hash, enc)
+		manifest.Chunks = append(manifest.Chunks, hash)
+		chunk, size = nil, 0
+		return nil
+	}
+
+	it := trie.NewIterator(accTrie.NodeIterator(nil))
+	for it.Next() {
+		entry := &Entry{
+			Hash:     common.BytesToHash(it.Key),
+			Preimage: common.CopyBytes(accTrie.GetKey(it.Key)),
+			Value:    common.CopyBytes(it.Value),
+		}
+		var account state.Account
+		if rlp.DecodeBytes(it.Value, &account) == nil {
+			if err := dumpAccount(sdb, entry, &account); err != nil {
+				return nil, err
+			}
+		}
+		chunk = append(chunk, entry)
+		if size += entry.size(); size >= chunkSize {
+			if err := flush(); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if it.Err != nil {
+		return nil, it.Err
+	}
+	if len(chunk) > 0 {
+		if err := flush(); err != nil {
+			return nil, err
+		}
+	}
+
+	enc, err := rlp.EncodeToBytes(manifest)
+	if err != nil {
+		return nil, err
+	}
+	rawdb.WriteSnapshotManifestRLP(db, number, enc)
+	rawdb.WriteLatestSnapshotNumber(db, number)
+	return manifest, nil
+}
+
+// dumpAccount fills the sub tries and the code of the account into the entry
+func dumpAccount(sdb state.Database, entry *Entry, account *state.Account) error {
+	addrHash := entry.Hash
+	tries := []struct {
+		root  common.Hash
+		open  func(addrHash, root common.Hash) (state.Trie, error)
+		slots *[]Slot
+	}{
+		{account.Root, sdb.OpenStorageTrie, &entry.Storage},
+		{account.TX1Root, sdb.OpenTX1Trie, &entry.TX1},
+		{account.TX3Root, sdb.OpenTX3Trie, &entry.TX3},
+		{account.ProxiedRoot, sdb.OpenProxiedTrie, &entry.Proxied},
+		{account.RewardRoot, sdb.OpenRewardTrie, &entry.Reward},
+	}
+	for _, t := range tries {
+		if t.root == emptyRoot || t.root == (common.Hash{}) {
+			continue
+		}
+		tr, err := t.open(addrHash, t.root)
+		if err != nil {
+			return err
+		}
+		it := trie.NewIterator(tr.NodeIterator(nil))
+		for it.Next() {
+			*t.slots = append(*t.slots, Slot{
+				Key:      common.BytesToHash(it.Key),
+				Preimage: common.CopyBytes(tr.GetKey(it.Key)),
+				Value:    common.CopyBytes(it.Value),
+			})
+		}
+		if it.Err != nil {
+			return it.Err
+		}
+	}
+	if !bytes.Equal(account.CodeHash, emptyCodeHash) {
+		code, err := sdb.ContractCode(addrHash, common.BytesToHash(account.CodeHash))
+		if err != nil {
+			return err
+		}
+		entry.Code = code
+	}
+	return nil
+}
+
+// ReadManifest retrieves the manifest of the snapshot taken at the block, or of the
+// latest snapshot if number is zero.
+func ReadManifest(db neatdb.Reader, number uint64) *Manifest {
+	if number == 0 {
+		latest := rawdb.ReadLatestSnapshotNumber(db)
+		if latest == nil {
+			return nil
+		}
+		number = *latest
+	}
+	enc := rawdb.ReadSnapshotManifestRLP(db, number)
+	if len(enc) == 0 {
+		return nil
+	}
+	manifest := new(Manifest)
+	if err := rlp.DecodeBytes(enc, manifest); err != nil {
+		return nil
+	}
+	return manifest
+}
+
+// Prune removes all but the latest kept snapshots with their chunks. The chunks still
+// listed by a kept snapshot are left in place.
+func Prune(db neatdb.Database, kept int) {
+	numbers := rawdb.ReadSnapshotNumbers(db)
+	if len(numbers) <= kept {
+		return
+	}
+	inUse := make(map[common.Hash]struct{})
+	for _, number := range numbers[len(numbers)-kept:] {
+		if manifest := ReadManifest(db, number); manifest != nil {
+			for _, hash := range manifest.Chunks {
+				inUse[hash] = struct{}{}
+			}
+		}
+	}
+	for _, number := range numbers[:len(numbers)-kept] {
+		if manifest := ReadManifest(db, number); manifest != nil {
+			for _, hash := range manifest.Chunks {
+				if _, ok := inUse[hash]; !ok {
+					rawdb.DeleteSnapshotChunk(db, hash)
+				}
+			}
+		}
+		rawdb.DeleteSnapshotManifest(db, number)
+	}
+}
@@ -0,0 +1,161 @@
+package snapshot
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+)
+
+// newTestState creates a committed state with storage, code and the sub tries of the chain
+func newTestState(t *testing.T) (state.Database, common.Hash) {
+	sdb := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, sdb)
+	for i := int64(0); i < 200; i++ {
+		addr := common.BigToAddress(big.NewInt(i + 1))
+		statedb.SetBalance(addr, big.NewInt(i))
+		if i%10 == 0 {
+			statedb.SetState(addr, common.BigToHash(big.NewInt(i)), common.HexToHash("0xbeef"))
+			statedb.SetCode(addr, []byte{0x60, 0x00, byte(i)})
+			statedb.AddTX1(addr, crypto.Keccak256Hash(addr[:]))
+			statedb.AddTX3(addr, common.BigToHash(big.NewInt(i+2000)))
+			statedb.AddRewardBalance(addr, big.NewInt(i+1))
+			statedb.AddProxiedBalanceByUser(addr, common.BigToAddress(big.NewInt(i+5000)), big.NewInt(i+1))
+		}
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return sdb, root
+}
+
+func readChunks(t *testing.T, db neatdb.Database, manifest *Manifest) [][]byte {
+	chunks := make([][]byte, len(manifest.Chunks))
+	for i, hash := range manifest.Chunks {
+		if chunks[i] = rawdb.ReadSnapshotChunk(db, hash); len(chunks[i]) == 0 {
+			t.Fatalf("chunk %d missing", i)
+		}
+	}
+	return chunks
+}
+
+func TestGenerateRestore(t *testing.T) {
+	sdb, root := newTestState(t)
+	db := rawdb.NewMemoryDatabase()
+	manifest, err := generate(sdb, db, 10, common.HexToHash("0x01"), root, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	if len(manifest.Chunks) < 2 {
+		t.Fatalf("snapshot not chunked: %d chunks", len(manifest.Chunks))
+	}
+	if stored := ReadManifest(db, 0); stored == nil || stored.Hash() != manifest.Hash() {
+		t.Fatalf("latest manifest mismatch: have %v, want %v", stored, manifest)
+	}
+
+	// Restore the chunks in reverse order
+	chunks := readChunks(t, db, manifest)
+	restoredb := rawdb.NewMemoryDatabase()
+	restorer, err := NewRestorer(manifest, restoredb)
+	if err != nil {
+		t.Fatalf("failed to create restorer: %v", err)
+	}
+	for i := len(chunks) - 1; i >= 0; i-- {
+		if err := restorer.Finish(); err != errIncomplete {
+			t.Fatalf("finished with %d chunks missing: %v", i+1, err)
+		}
+		if err := restorer.Process(chunks[i]); err != nil {
+			t.Fatalf("failed to process chunk %d: %v", i, err)
+		}
+	}
+	if err := restorer.Finish(); err != nil {
+		t.Fatalf("failed to finish restore: %v", err)
+	}
+
+	// The restored state is complete
+	statedb, err := state.New(root, state.NewDatabase(restoredb))
+	if err != nil {
+		t.Fatalf("failed to open restored state: %v", err)
+	}
+	addr := common.BigToAddress(big.NewInt(11))
+	if balance := statedb.GetBalance(addr); balance.Cmp(big.NewInt(10)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want %v", balance, 10)
+	}
+	if value := statedb.GetState(addr, common.BigToHash(big.NewInt(10))); value != common.HexToHash("0xbeef") {
+		t.Fatalf("storage mismatch: have %x", value)
+	}
+	if code := statedb.GetCode(addr); len(code) != 3 {
+		t.Fatalf("code mismatch: have %x", code)
+	}
+	if !statedb.HasTX1(addr, crypto.Keccak256Hash(addr[:])) {
+		t.Fatalf("tx1 missing")
+	}
+	if reward := statedb.GetTotalRewardBalance(addr); reward.Cmp(big.NewInt(11)) != 0 {
+		t.Fatalf("reward mismatch: have %v, want %v", reward, 11)
+	}
+}
+
+func TestRestoreInvalidChunk(t *testing.T) {
+	sdb, root := newTestState(t)
+	db := rawdb.NewMemoryDatabase()
+	manifest, err := generate(sdb, db, 10, common.HexToHash("0x01"), root, 1024)
+	if err != nil {
+		t.Fatalf("failed to generate snapshot: %v", err)
+	}
+	chunks := readChunks(t, db, manifest)
+
+	restorer, _ := NewRestorer(manifest, rawdb.NewMemoryDatabase())
+	tampered := common.CopyBytes(chunks[0])
+	tampered[len(tampered)-1]++
+	if err := restorer.Process(tampered); err != errUnknownChunk {
+		t.Fatalf("tampered chunk: have %v, want %v", err, errUnknownChunk)
+	}
+
+	// A manifest listing chunks of another state fails the root check
+	forged := &Manifest{Number: 10, Root: crypto.Keccak256Hash([]byte("root")), Chunks: manifest.Chunks}
+	restorer, _ = NewRestorer(forged, rawdb.NewMemoryDatabase())
+	for i, chunk := range chunks {
+		if err := restorer.Process(chunk); err != nil {
+			t.Fatalf("failed to process chunk %d: %v", i, err)
+		}
+	}
+	if err := restorer.Finish(); err != ErrRootMismatch {
+		t.Fatalf("forged manifest: have %v, want %v", err, ErrRootMismatch)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	sdb, root := newTestState(t)
+	db := rawdb.NewMemoryDatabase()
+	var manifests []*Manifest
+	for _, number := range []uint64{10, 20, 30} {
+		manifest, err := generate(sdb, db, number, common.HexToHash("0x01"), root, 1024)
+		if err != nil {
+			t.Fatalf("failed to generate snapshot: %v", err)
+		}
+		manifests = append(manifests, manifest)
+	}
+	Prune(db, 2)
+	if ReadManifest(db, 10) != nil {
+		t.Fatalf("old manifest not pruned")
+	}
+	for _, number := range []uint64{20, 30} {
+		if ReadManifest(db, number) == nil {
+			t.Fatalf("manifest %d pruned", number)
+		}
+	}
+	// The chunks of the same state are shared by the kept snapshots
+	for i, hash := range manifests[0].Chunks {
+		if len(rawdb.ReadSnapshotChunk(db, hash)) == 0 {
+			t.Fatalf("shared chunk %d pruned", i)
+		}
+	}
+}
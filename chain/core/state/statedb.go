@@ -72,6 +72,32 @@ type StateDB struct {
 	forbiddenSet      ForbiddenSet
 	forbiddenSetDirty bool
 
+	// Cache of slashed evidence
+	slashedEvidences      map[common.Hash]bool
+	slashedEvidencesDirty map[common.Hash]struct{}
+
+	// Cache of validator signing info
+	signingInfos      map[common.Address]*SigningInfo
+	signingInfosDirty map[common.Address]struct{}
+
+	// Cache of governance proposals and chain parameters
+	proposals       map[uint64]*Proposal
+	proposalsDirty  map[uint64]struct{}
+	governance      *Governance
+	governanceDirty bool
+
+	// Cache of the redelegations of the current epoch
+	redelegations      []*Redelegation
+	redelegationsDirty bool
+
+	// Cache of the delegators compounding the rewards
+	autoCompoundSet      AutoCompoundSet
+	autoCompoundSetDirty bool
+
+	// Cache of the unbonding queue
+	unbondings      []*Unbonding
+	unbondingsDirty bool
+
 	// Cache of Side Chain Reward Per Block
 	sideChainRewardPerBlock      *big.Int
 	sideChainRewardPerBlockDirty bool
@@ -120,6 +146,12 @@ func New(root common.Hash, db Database) (*StateDB, error) {
 		candidateSetDirty:            false,
 		forbiddenSet:                 make(ForbiddenSet),
 		forbiddenSetDirty:            false,
+		slashedEvidences:             make(map[common.Hash]bool),
+		slashedEvidencesDirty:        make(map[common.Hash]struct{}),
+		signingInfos:                 make(map[common.Address]*SigningInfo),
+		signingInfosDirty:            make(map[common.Address]struct{}),
+		proposals:                    make(map[uint64]*Proposal),
+		proposalsDirty:               make(map[uint64]struct{}),
 		sideChainRewardPerBlock:      nil,
 		sideChainRewardPerBlockDirty: false,
 		logs:                         make(map[common.Hash][]*types.Log),
@@ -152,6 +184,16 @@ func (self *StateDB) Reset(root common.Hash) error {
 	self.rewardSet = make(RewardSet)
 	self.candidateSet = make(CandidateSet)
 	self.forbiddenSet = make(ForbiddenSet)
+	self.slashedEvidences = make(map[common.Hash]bool)
+	self.slashedEvidencesDirty = make(map[common.Hash]struct{})
+	self.signingInfos = make(map[common.Address]*SigningInfo)
+	self.signingInfosDirty = make(map[common.Address]struct{})
+	self.proposals = make(map[uint64]*Proposal)
+	self.proposalsDirty = make(map[uint64]struct{})
+	self.governance = nil
+	self.redelegations = nil
+	self.autoCompoundSet = nil
+	self.unbondings = nil
 	self.sideChainRewardPerBlock = nil
 	self.thash = common.Hash{}
 	self.bhash = common.Hash{}
@@ -174,6 +216,17 @@ func (self *StateDB) AddLog(log *types.Log) {
 	self.logSize++
 }
 
+// DiscardLogs drops the logs of the transaction added so far
+func (self *StateDB) DiscardLogs(hash common.Hash) {
+	logs := self.logs[hash]
+	if len(logs) == 0 {
+		return
+	}
+	self.journal = append(self.journal, discardLogsChange{txhash: hash, prev: logs})
+	delete(self.logs, hash)
+	self.logSize -= uint(len(logs))
+}
+
 func (self *StateDB) GetLogs(hash common.Hash) []*types.Log {
 	return self.logs[hash]
 }
@@ -644,6 +697,16 @@ func (self *StateDB) Copy() *StateDB {
 		candidateSetDirty:            self.candidateSetDirty,
 		forbiddenSet:                 make(ForbiddenSet, len(self.forbiddenSet)),
 		forbiddenSetDirty:            self.forbiddenSetDirty,
+		slashedEvidences:             make(map[common.Hash]bool, len(self.slashedEvidences)),
+		slashedEvidencesDirty:        make(map[common.Hash]struct{}, len(self.slashedEvidencesDirty)),
+		signingInfos:                 make(map[common.Address]*SigningInfo, len(self.signingInfos)),
+		signingInfosDirty:            make(map[common.Address]struct{}, len(self.signingInfosDirty)),
+		proposals:                    make(map[uint64]*Proposal, len(self.proposals)),
+		proposalsDirty:               make(map[uint64]struct{}, len(self.proposalsDirty)),
+		governanceDirty:              self.governanceDirty,
+		redelegationsDirty:           self.redelegationsDirty,
+		autoCompoundSetDirty:         self.autoCompoundSetDirty,
+		unbondingsDirty:              self.unbondingsDirty,
 		sideChainRewardPerBlockDirty: self.sideChainRewardPerBlockDirty,
 		refund:                       self.refund,
 		logs:                         make(map[common.Hash][]*types.Log, len(self.logs)),
@@ -670,6 +733,45 @@ func (self *StateDB) Copy() *StateDB {
 		state.forbiddenSet[addr] = struct{}{}
 	}
 
+	for hash, slashed := range self.slashedEvidences {
+		state.slashedEvidences[hash] = slashed
+	}
+	for hash := range self.slashedEvidencesDirty {
+		state.slashedEvidencesDirty[hash] = struct{}{}
+	}
+
+	for addr, info := range self.signingInfos {
+		state.signingInfos[addr] = info.copy()
+	}
+	for addr := range self.signingInfosDirty {
+		state.signingInfosDirty[addr] = struct{}{}
+	}
+
+	for id, p := range self.proposals {
+		state.proposals[id] = p.copy()
+	}
+	for id := range self.proposalsDirty {
+		state.proposalsDirty[id] = struct{}{}
+	}
+	if self.governance != nil {
+		state.governance = self.governance.copy()
+	}
+	if self.redelegations != nil {
+		state.redelegations = self.GetRedelegations()
+	}
+	if self.autoCompoundSet != nil {
+		state.autoCompoundSet = make(AutoCompoundSet, len(self.autoCompoundSet))
+		for addr := range self.autoCompoundSet {
+			state.autoCompoundSet[addr] = struct{}{}
+		}
+	}
+	if self.unbondings != nil {
+		state.unbondings = make([]*Unbonding, len(self.unbondings))
+		for i, u := range self.unbondings {
+			state.unbondings[i] = &Unbonding{Delegator: u.Delegator, Candidate: u.Candidate, Amount: new(big.Int).Set(u.Amount), MatureEpoch: u.MatureEpoch}
+		}
+	}
+
 	if self.sideChainRewardPerBlock != nil {
 		state.sideChainRewardPerBlock = new(big.Int).Set(self.sideChainRewardPerBlock)
 	}
@@ -754,6 +856,34 @@ func (s *StateDB) Finalise(deleteEmptyObjects bool) {
 		s.commitForbiddenSet()
 	}
 
+	if len(s.slashedEvidencesDirty) > 0 {
+		s.commitSlashedEvidences()
+	}
+
+	if len(s.signingInfosDirty) > 0 {
+		s.commitSigningInfos()
+	}
+
+	if len(s.proposalsDirty) > 0 {
+		s.commitProposals()
+	}
+
+	if s.governanceDirty {
+		s.commitGovernance()
+	}
+
+	if s.redelegationsDirty {
+		s.commitRedelegations()
+	}
+
+	if s.autoCompoundSetDirty {
+		s.commitAutoCompoundSet()
+	}
+
+	if s.unbondingsDirty {
+		s.commitUnbondings()
+	}
+
 	// Update Side Chain Reward per Block if something changed
 	if s.sideChainRewardPerBlockDirty {
 		s.commitSideChainRewardPerBlock()
@@ -873,6 +1003,41 @@ func (s *StateDB) Commit(deleteEmptyObjects bool) (root common.Hash, err error)
 		s.forbiddenSetDirty = false
 	}
 
+	if len(s.slashedEvidencesDirty) > 0 {
+		s.commitSlashedEvidences()
+		s.slashedEvidencesDirty = make(map[common.Hash]struct{})
+	}
+
+	if len(s.signingInfosDirty) > 0 {
+		s.commitSigningInfos()
+		s.signingInfosDirty = make(map[common.Address]struct{})
+	}
+
+	if len(s.proposalsDirty) > 0 {
+		s.commitProposals()
+		s.proposalsDirty = make(map[uint64]struct{})
+	}
+
+	if s.governanceDirty {
+		s.commitGovernance()
+		s.governanceDirty = false
+	}
+
+	if s.redelegationsDirty {
+		s.commitRedelegations()
+		s.redelegationsDirty = false
+	}
+
+	if s.autoCompoundSetDirty {
+		s.commitAutoCompoundSet()
+		s.autoCompoundSetDirty = false
+	}
+
+	if s.unbondingsDirty {
+		s.commitUnbondings()
+		s.unbondingsDirty = false
+	}
+
 	// Commit Reward Per Block to the trie
 	if s.sideChainRewardPerBlockDirty {
 		s.commitSideChainRewardPerBlock()
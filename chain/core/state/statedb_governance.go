@@ -0,0 +1,309 @@
+package state
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ----- Proposal
+
+const (
+	ProposalVoting   uint8 = iota // open for deposits and votes
+	ProposalAccepted              // accepted, the change is applied from the target epoch
+	ProposalRejected              // not enough deposit or approval, the deposit is refunded
+	ProposalDropped               // quorum not reached, the deposit is burned
+)
+
+const (
+	VoteYes uint8 = iota + 1
+	VoteNo
+)
+
+// Proposal is a change of a chain parameter voted by the stakers
+type Proposal struct {
+	Id             uint64
+	Proposer       common.Address
+	Param          string
+	Value          *big.Int
+	VotingEndEpoch uint64 // the votes are tallied at the end of this epoch
+	TargetEpoch    uint64 // the accepted change is applied from this epoch
+	Status         uint8
+	Deposits       []*ProposalDeposit
+	Votes          []*ProposalVote
+}
+
+type ProposalDeposit struct {
+	Depositor common.Address
+	Amount    *big.Int
+}
+
+type ProposalVote struct {
+	Voter  common.Address
+	Option uint8
+}
+
+// TotalDeposit returns the sum of all the deposits of the proposal
+func (p *Proposal) TotalDeposit() *big.Int {
+	total := new(big.Int)
+	for _, d := range p.Deposits {
+		total.Add(total, d.Amount)
+	}
+	return total
+}
+
+// AddDeposit adds the amount to the deposit of the depositor
+func (p *Proposal) AddDeposit(depositor common.Address, amount *big.Int) {
+	for _, d := range p.Deposits {
+		if d.Depositor == depositor {
+			d.Amount = new(big.Int).Add(d.Amount, amount)
+			return
+		}
+	}
+	p.Deposits = append(p.Deposits, &ProposalDeposit{Depositor: depositor, Amount: new(big.Int).Set(amount)})
+}
+
+// IsVoting checks whether the proposal is open for deposits and votes
+func (p *Proposal) IsVoting() bool {
+	return p.Status == ProposalVoting
+}
+
+// SetVote records the vote of the voter, the previous vote of the voter is replaced
+func (p *Proposal) SetVote(voter common.Address, approve bool) {
+	option := VoteNo
+	if approve {
+		option = VoteYes
+	}
+	for _, v := range p.Votes {
+		if v.Voter == voter {
+			v.Option = option
+			return
+		}
+	}
+	p.Votes = append(p.Votes, &ProposalVote{Voter: voter, Option: option})
+}
+
+func (p *Proposal) copy() *Proposal {
+	cpy := *p
+	cpy.Value = new(big.Int).Set(p.Value)
+	cpy.Deposits = make([]*ProposalDeposit, len(p.Deposits))
+	for i, d := range p.Deposits {
+		cpy.Deposits[i] = &ProposalDeposit{Depositor: d.Depositor, Amount: new(big.Int).Set(d.Amount)}
+	}
+	cpy.Votes = make([]*ProposalVote, len(p.Votes))
+	for i, v := range p.Votes {
+		cpy.Votes[i] = &ProposalVote{Voter: v.Voter, Option: v.Option}
+	}
+	return &cpy
+}
+
+// GetProposal returns a copy of the proposal, nil if not exist
+func (self *StateDB) GetProposal(id uint64) *Proposal {
+	if p, exist := self.proposals[id]; exist {
+		return p.copy()
+	}
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(proposalKey(id))
+	if err != nil {
+		self.setError(err)
+		return nil
+	}
+	if len(enc) == 0 {
+		return nil
+	}
+	p := &Proposal{}
+	if err := rlp.DecodeBytes(enc, p); err != nil {
+		self.setError(err)
+		return nil
+	}
+	self.proposals[id] = p
+	return p.copy()
+}
+
+// SetProposal stores the proposal
+func (self *StateDB) SetProposal(p *Proposal) {
+	self.proposals[p.Id] = p.copy()
+	self.proposalsDirty[p.Id] = struct{}{}
+}
+
+// CreateProposal opens a new proposal for voting, returns the id of the proposal
+func (self *StateDB) CreateProposal(proposer common.Address, param string, value *big.Int, votingEndEpoch, targetEpoch uint64) uint64 {
+	gov := self.getGovernance()
+	p := &Proposal{
+		Id:             gov.NextProposalId,
+		Proposer:       proposer,
+		Param:          param,
+		Value:          new(big.Int).Set(value),
+		VotingEndEpoch: votingEndEpoch,
+		TargetEpoch:    targetEpoch,
+		Status:         ProposalVoting,
+	}
+	gov.NextProposalId++
+	gov.Active = append(gov.Active, p.Id)
+	self.governanceDirty = true
+
+	self.SetProposal(p)
+	return p.Id
+}
+
+func (self *StateDB) commitProposals() {
+	for id := range self.proposalsDirty {
+		data, err := rlp.EncodeToBytes(self.proposals[id])
+		if err != nil {
+			panic(fmt.Errorf("can't encode proposal %v : %v", id, err))
+		}
+		self.setError(self.trie.TryUpdate(proposalKey(id), data))
+	}
+}
+
+// Store the Proposals
+
+var proposalPrefix = []byte("Proposal")
+
+func proposalKey(id uint64) []byte {
+	key := make([]byte, len(proposalPrefix)+8)
+	copy(key, proposalPrefix)
+	binary.BigEndian.PutUint64(key[len(proposalPrefix):], id)
+	return key
+}
+
+// ----- Governance
+
+// ParamChange is the new value of a chain parameter, in effect from the epoch
+type ParamChange struct {
+	Param string
+	Value *big.Int
+	Epoch uint64
+}
+
+// Governance is the bookkeeping of the proposals and the chain parameters changed by them
+type Governance struct {
+	NextProposalId uint64
+	Active         []uint64       // proposals in voting
+	Pending        []*ParamChange // accepted changes waiting for the target epoch
+	Params         []*ParamChange // changes in effect, the latest one of each parameter
+}
+
+func (gov *Governance) copy() *Governance {
+	cpy := &Governance{
+		NextProposalId: gov.NextProposalId,
+		Active:         append([]uint64{}, gov.Active...),
+		Pending:        make([]*ParamChange, len(gov.Pending)),
+		Params:         make([]*ParamChange, len(gov.Params)),
+	}
+	for i, c := range gov.Pending {
+		cpy.Pending[i] = &ParamChange{Param: c.Param, Value: new(big.Int).Set(c.Value), Epoch: c.Epoch}
+	}
+	for i, c := range gov.Params {
+		cpy.Params[i] = &ParamChange{Param: c.Param, Value: new(big.Int).Set(c.Value), Epoch: c.Epoch}
+	}
+	return cpy
+}
+
+func (self *StateDB) getGovernance() *Governance {
+	if self.governance != nil {
+		return self.governance
+	}
+	// Try to get from Trie
+	self.governance = &Governance{}
+	enc, err := self.trie.TryGet(governanceKey)
+	if err != nil {
+		self.setError(err)
+		return self.governance
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, self.governance); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.governance
+}
+
+// GetActiveProposals returns the ids of the proposals in voting
+func (self *StateDB) GetActiveProposals() []uint64 {
+	return append([]uint64{}, self.getGovernance().Active...)
+}
+
+// RemoveActiveProposal closes the voting of the proposal
+func (self *StateDB) RemoveActiveProposal(id uint64) {
+	gov := self.getGovernance()
+	for i, active := range gov.Active {
+		if active == id {
+			gov.Active = append(gov.Active[:i:i], gov.Active[i+1:]...)
+			self.governanceDirty = true
+			return
+		}
+	}
+}
+
+// AddPendingParamChange schedules the accepted change for its epoch
+func (self *StateDB) AddPendingParamChange(param string, value *big.Int, epoch uint64) {
+	gov := self.getGovernance()
+	gov.Pending = append(gov.Pending, &ParamChange{Param: param, Value: new(big.Int).Set(value), Epoch: epoch})
+	self.governanceDirty = true
+}
+
+// GetPendingParamChanges returns the accepted changes waiting for their epoch
+func (self *StateDB) GetPendingParamChanges() []*ParamChange {
+	return self.getGovernance().copy().Pending
+}
+
+// GetParamChanges returns the changes in effect
+func (self *StateDB) GetParamChanges() []*ParamChange {
+	return self.getGovernance().copy().Params
+}
+
+// ApplyParamChanges puts the pending changes due at the epoch into effect, returns the applied changes
+func (self *StateDB) ApplyParamChanges(epoch uint64) []*ParamChange {
+	gov := self.getGovernance()
+
+	var applied, pending []*ParamChange
+	for _, change := range gov.Pending {
+		if change.Epoch > epoch {
+			pending = append(pending, change)
+			continue
+		}
+		replaced := false
+		for i, param := range gov.Params {
+			if param.Param == change.Param {
+				gov.Params[i] = change
+				replaced = true
+				break
+			}
+		}
+		if !replaced {
+			gov.Params = append(gov.Params, change)
+		}
+		applied = append(applied, change)
+	}
+	if len(applied) > 0 {
+		gov.Pending = pending
+		self.governanceDirty = true
+	}
+	return applied
+}
+
+// GetChainParam returns the value of the chain parameter changed by the governance, false if never changed
+func (self *StateDB) GetChainParam(param string) (*big.Int, bool) {
+	for _, change := range self.getGovernance().Params {
+		if change.Param == param {
+			return new(big.Int).Set(change.Value), true
+		}
+	}
+	return nil, false
+}
+
+func (self *StateDB) commitGovernance() {
+	data, err := rlp.EncodeToBytes(self.governance)
+	if err != nil {
+		panic(fmt.Errorf("can't encode governance : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(governanceKey, data))
+}
+
+// Store the Governance
+
+var governanceKey = []byte("Governance")
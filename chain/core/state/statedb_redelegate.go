@@ -0,0 +1,92 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ----- Redelegation
+
+// Redelegation is the deposit proxied balance moving to another candidate at the end of the epoch,
+// the amount stays slashable at the source candidate until then
+type Redelegation struct {
+	Delegator common.Address
+	From      common.Address
+	To        common.Address
+	Amount    *big.Int
+}
+
+// AddRedelegation records the delegator's deposit moving from one candidate to another
+func (self *StateDB) AddRedelegation(delegator, from, to common.Address, amount *big.Int) {
+	self.redelegations = append(self.getRedelegations(), &Redelegation{
+		Delegator: delegator,
+		From:      from,
+		To:        to,
+		Amount:    new(big.Int).Set(amount),
+	})
+	self.redelegationsDirty = true
+}
+
+// GetRedelegations returns the redelegations of the current epoch
+func (self *StateDB) GetRedelegations() []*Redelegation {
+	redelegations := self.getRedelegations()
+	cpy := make([]*Redelegation, len(redelegations))
+	for i, r := range redelegations {
+		cpy[i] = &Redelegation{Delegator: r.Delegator, From: r.From, To: r.To, Amount: new(big.Int).Set(r.Amount)}
+	}
+	return cpy
+}
+
+// IsRedelegatedTo checks whether the delegator has redelegated to the candidate in the current epoch
+func (self *StateDB) IsRedelegatedTo(delegator, candidate common.Address) bool {
+	for _, r := range self.getRedelegations() {
+		if r.Delegator == delegator && r.To == candidate {
+			return true
+		}
+	}
+	return false
+}
+
+// ClearRedelegations drops all the redelegations once they are settled at the end of the epoch
+func (self *StateDB) ClearRedelegations() {
+	self.redelegations = []*Redelegation{}
+	self.redelegationsDirty = true
+}
+
+func (self *StateDB) getRedelegations() []*Redelegation {
+	if self.redelegations != nil {
+		return self.redelegations
+	}
+	// Try to get from Trie
+	self.redelegations = []*Redelegation{}
+	enc, err := self.trie.TryGet(redelegationsKey)
+	if err != nil {
+		self.setError(err)
+		return self.redelegations
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.redelegations); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.redelegations
+}
+
+func (self *StateDB) commitRedelegations() {
+	if len(self.redelegations) == 0 {
+		self.setError(self.trie.TryDelete(redelegationsKey))
+		return
+	}
+	data, err := rlp.EncodeToBytes(self.redelegations)
+	if err != nil {
+		panic(fmt.Errorf("can't encode redelegations : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(redelegationsKey, data))
+}
+
+// Store the Redelegations of the current epoch
+
+var redelegationsKey = []byte("Redelegations")
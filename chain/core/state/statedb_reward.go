@@ -283,3 +283,82 @@ func (self *StateDB) commitSideChainRewardPerBlock() {
 // Side Chain Reward Per Block
 
 var sideChainRewardPerBlockKey = []byte("RewardPerBlock")
+
+// ----- Auto Compound Set
+
+// SetAutoCompound opts the delegator in or out of compounding the rewards at the end of every epoch
+func (self *StateDB) SetAutoCompound(addr common.Address, enable bool) {
+	set := self.GetAutoCompoundSet()
+	if _, exist := set[addr]; exist == enable {
+		return
+	}
+	if enable {
+		set[addr] = struct{}{}
+	} else {
+		delete(set, addr)
+	}
+	self.autoCompoundSetDirty = true
+}
+
+// IsAutoCompound checks whether the rewards of the delegator are compounded
+func (self *StateDB) IsAutoCompound(addr common.Address) bool {
+	_, exist := self.GetAutoCompoundSet()[addr]
+	return exist
+}
+
+func (self *StateDB) GetAutoCompoundSet() AutoCompoundSet {
+	if self.autoCompoundSet != nil {
+		return self.autoCompoundSet
+	}
+	// Try to get from Trie
+	self.autoCompoundSet = make(AutoCompoundSet)
+	enc, err := self.trie.TryGet(autoCompoundSetKey)
+	if err != nil {
+		self.setError(err)
+		return self.autoCompoundSet
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.autoCompoundSet); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.autoCompoundSet
+}
+
+func (self *StateDB) commitAutoCompoundSet() {
+	data, err := rlp.EncodeToBytes(self.autoCompoundSet)
+	if err != nil {
+		panic(fmt.Errorf("can't encode auto compound set : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(autoCompoundSetKey, data))
+}
+
+// Store the Auto Compound Delegator Set
+
+var autoCompoundSetKey = []byte("AutoCompoundSet")
+
+type AutoCompoundSet map[common.Address]struct{}
+
+func (set AutoCompoundSet) EncodeRLP(w io.Writer) error {
+	var list []common.Address
+	for addr := range set {
+		list = append(list, addr)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return bytes.Compare(list[i].Bytes(), list[j].Bytes()) == 1
+	})
+	return rlp.Encode(w, list)
+}
+
+func (set *AutoCompoundSet) DecodeRLP(s *rlp.Stream) error {
+	var list []common.Address
+	if err := s.Decode(&list); err != nil {
+		return err
+	}
+	autoCompoundSet := make(AutoCompoundSet, len(list))
+	for _, addr := range list {
+		autoCompoundSet[addr] = struct{}{}
+	}
+	*set = autoCompoundSet
+	return nil
+}
@@ -0,0 +1,198 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ----- Slash
+
+// SlashDepositBalance burns rate percent of the deposit balance of addr, returns the slashed amount
+func (self *StateDB) SlashDepositBalance(addr common.Address, rate uint8) *big.Int {
+	slashed := slashAmount(self.GetDepositBalance(addr), rate)
+	if slashed.Sign() > 0 {
+		self.SubDepositBalance(addr, slashed)
+	}
+	return slashed
+}
+
+// SlashDepositProxiedBalance burns rate percent of every delegator's deposit proxied balance of the candidate addr,
+// the delegate balance of the delegator is reduced accordingly, returns the total slashed amount
+func (self *StateDB) SlashDepositProxiedBalance(addr common.Address, rate uint8) *big.Int {
+	total := new(big.Int)
+	self.ForEachProxied(addr, func(key common.Address, proxiedBalance, depositProxiedBalance, pendingRefundBalance *big.Int) bool {
+		slashed := slashAmount(depositProxiedBalance, rate)
+		if slashed.Sign() > 0 {
+			self.SubDepositProxiedBalanceByUser(addr, key, slashed)
+			self.SubDelegateBalance(key, slashed)
+
+			// the pending refund can't be larger than what's left in the deposit
+			left := new(big.Int).Sub(depositProxiedBalance, slashed)
+			if pendingRefundBalance.Cmp(left) > 0 {
+				self.SubPendingRefundBalanceByUser(addr, key, new(big.Int).Sub(pendingRefundBalance, left))
+			}
+			total.Add(total, slashed)
+		}
+		return true
+	})
+	return total
+}
+
+func slashAmount(amount *big.Int, rate uint8) *big.Int {
+	if amount.Sign() <= 0 {
+		return new(big.Int)
+	}
+	slashed := new(big.Int).Mul(amount, big.NewInt(int64(rate)))
+	return slashed.Div(slashed, big.NewInt(100))
+}
+
+// ----- Slashed Evidence
+
+// MarkEvidenceSlashed records the evidence hash, so the same offence won't be punished twice
+func (self *StateDB) MarkEvidenceSlashed(hash common.Hash) {
+	if !self.IsEvidenceSlashed(hash) {
+		self.slashedEvidences[hash] = true
+		self.slashedEvidencesDirty[hash] = struct{}{}
+	}
+}
+
+// IsEvidenceSlashed checks whether the offence of the evidence hash has been punished
+func (self *StateDB) IsEvidenceSlashed(hash common.Hash) bool {
+	if slashed, exist := self.slashedEvidences[hash]; exist {
+		return slashed
+	}
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(slashedEvidenceKey(hash))
+	if err != nil {
+		self.setError(err)
+		return false
+	}
+	slashed := len(enc) > 0
+	self.slashedEvidences[hash] = slashed
+	return slashed
+}
+
+func (self *StateDB) commitSlashedEvidences() {
+	for hash := range self.slashedEvidencesDirty {
+		self.setError(self.trie.TryUpdate(slashedEvidenceKey(hash), slashedEvidenceMark))
+	}
+}
+
+// Store each Slashed Evidence Hash under its own key
+
+var (
+	slashedEvidencePrefix = []byte("SlashedEvidence")
+	slashedEvidenceMark   = []byte{1}
+)
+
+func slashedEvidenceKey(hash common.Hash) []byte {
+	return append(common.CopyBytes(slashedEvidencePrefix), hash.Bytes()...)
+}
+
+// ----- Signing Info
+
+// SigningInfo tracks the blocks signed by a validator within a sliding window of the latest blocks it should have signed
+type SigningInfo struct {
+	IndexOffset  uint64 // total number of blocks the validator should have signed
+	SignedBlocks uint64 // number of blocks signed within the window
+	Bitmap       []byte // signed flags of the window, the block is at IndexOffset % window
+}
+
+// Update moves the window forward by one block, returns the signed blocks within the window
+func (info *SigningInfo) Update(window uint64, signed bool) uint64 {
+	if window == 0 {
+		return info.SignedBlocks
+	}
+	// the window size has changed, start over
+	if uint64(len(info.Bitmap)) != (window+7)/8 {
+		info.IndexOffset = 0
+		info.SignedBlocks = 0
+		info.Bitmap = make([]byte, (window+7)/8)
+	}
+
+	index := info.IndexOffset % window
+	mask := byte(1) << (index % 8)
+	previous := info.Bitmap[index/8]&mask != 0
+	if previous && !signed {
+		info.Bitmap[index/8] &^= mask
+		info.SignedBlocks--
+	} else if !previous && signed {
+		info.Bitmap[index/8] |= mask
+		info.SignedBlocks++
+	}
+	info.IndexOffset++
+	return info.SignedBlocks
+}
+
+func (info *SigningInfo) copy() *SigningInfo {
+	if info == nil {
+		return nil
+	}
+	return &SigningInfo{
+		IndexOffset:  info.IndexOffset,
+		SignedBlocks: info.SignedBlocks,
+		Bitmap:       common.CopyBytes(info.Bitmap),
+	}
+}
+
+// GetSigningInfo returns a copy of the signing info of the validator addr
+func (self *StateDB) GetSigningInfo(addr common.Address) *SigningInfo {
+	if info, exist := self.signingInfos[addr]; exist {
+		if info == nil {
+			return &SigningInfo{}
+		}
+		return info.copy()
+	}
+	// Try to get from Trie
+	enc, err := self.trie.TryGet(signingInfoKey(addr))
+	if err != nil {
+		self.setError(err)
+		return &SigningInfo{}
+	}
+	info := &SigningInfo{}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, info); err != nil {
+			self.setError(err)
+			return &SigningInfo{}
+		}
+	}
+	self.signingInfos[addr] = info
+	return info.copy()
+}
+
+func (self *StateDB) SetSigningInfo(addr common.Address, info *SigningInfo) {
+	self.signingInfos[addr] = info.copy()
+	self.signingInfosDirty[addr] = struct{}{}
+}
+
+// ResetSigningInfo clears the signing info, the validator starts with an empty window
+func (self *StateDB) ResetSigningInfo(addr common.Address) {
+	self.signingInfos[addr] = nil
+	self.signingInfosDirty[addr] = struct{}{}
+}
+
+func (self *StateDB) commitSigningInfos() {
+	for addr := range self.signingInfosDirty {
+		info := self.signingInfos[addr]
+		if info == nil {
+			self.setError(self.trie.TryDelete(signingInfoKey(addr)))
+			continue
+		}
+		data, err := rlp.EncodeToBytes(info)
+		if err != nil {
+			panic(fmt.Errorf("can't encode signing info of %x : %v", addr, err))
+		}
+		self.setError(self.trie.TryUpdate(signingInfoKey(addr), data))
+	}
+}
+
+// Store the Signing Info of each Validator
+
+var signingInfoPrefix = []byte("SigningInfo")
+
+func signingInfoKey(addr common.Address) []byte {
+	return append(common.CopyBytes(signingInfoPrefix), addr.Bytes()...)
+}
@@ -0,0 +1,122 @@
+package state
+
+import (
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ----- Unbonding
+
+// Unbonding is the refunded deposit proxied balance waiting for the mature epoch, the amount stays in the
+// delegate balance of the delegator and is slashable for the offence of the candidate until then.
+// The refunded self deposit of the candidate is unbonded as well, the delegator is the candidate itself
+// and the amount is held by the unbonding only
+type Unbonding struct {
+	Delegator   common.Address
+	Candidate   common.Address
+	Amount      *big.Int
+	MatureEpoch uint64
+}
+
+// IsSelfDeposit returns whether the unbonding is the self deposit of the candidate
+func (u *Unbonding) IsSelfDeposit() bool {
+	return u.Delegator == u.Candidate
+}
+
+// AddUnbonding puts the refund of the delegator into the unbonding queue
+func (self *StateDB) AddUnbonding(delegator, candidate common.Address, amount *big.Int, matureEpoch uint64) {
+	self.unbondings = append(self.getUnbondings(), &Unbonding{
+		Delegator:   delegator,
+		Candidate:   candidate,
+		Amount:      new(big.Int).Set(amount),
+		MatureEpoch: matureEpoch,
+	})
+	self.unbondingsDirty = true
+}
+
+// GetUnbondings returns the unbondings of the delegator
+func (self *StateDB) GetUnbondings(delegator common.Address) []*Unbonding {
+	cpy := []*Unbonding{}
+	for _, u := range self.getUnbondings() {
+		if u.Delegator == delegator {
+			cpy = append(cpy, &Unbonding{Delegator: u.Delegator, Candidate: u.Candidate, Amount: new(big.Int).Set(u.Amount), MatureEpoch: u.MatureEpoch})
+		}
+	}
+	return cpy
+}
+
+// RemoveMatureUnbondings drops the unbondings mature at the epoch from the queue and returns them
+func (self *StateDB) RemoveMatureUnbondings(epoch uint64) []*Unbonding {
+	var mature, left []*Unbonding
+	for _, u := range self.getUnbondings() {
+		if u.MatureEpoch <= epoch {
+			mature = append(mature, u)
+		} else {
+			left = append(left, u)
+		}
+	}
+	if len(mature) > 0 {
+		self.unbondings = append([]*Unbonding{}, left...)
+		self.unbondingsDirty = true
+	}
+	return mature
+}
+
+// SlashUnbondings burns rate percent of every unbonding from the candidate addr, the delegate balance of the
+// delegator is reduced accordingly (except the self deposit), returns the total slashed amount
+func (self *StateDB) SlashUnbondings(addr common.Address, rate uint8) *big.Int {
+	total := new(big.Int)
+	for _, u := range self.getUnbondings() {
+		if u.Candidate != addr {
+			continue
+		}
+		slashed := slashAmount(u.Amount, rate)
+		if slashed.Sign() > 0 {
+			u.Amount = new(big.Int).Sub(u.Amount, slashed)
+			if !u.IsSelfDeposit() {
+				self.SubDelegateBalance(u.Delegator, slashed)
+			}
+			self.unbondingsDirty = true
+			total.Add(total, slashed)
+		}
+	}
+	return total
+}
+
+func (self *StateDB) getUnbondings() []*Unbonding {
+	if self.unbondings != nil {
+		return self.unbondings
+	}
+	// Try to get from Trie
+	self.unbondings = []*Unbonding{}
+	enc, err := self.trie.TryGet(unbondingsKey)
+	if err != nil {
+		self.setError(err)
+		return self.unbondings
+	}
+	if len(enc) > 0 {
+		if err := rlp.DecodeBytes(enc, &self.unbondings); err != nil {
+			self.setError(err)
+		}
+	}
+	return self.unbondings
+}
+
+func (self *StateDB) commitUnbondings() {
+	if len(self.unbondings) == 0 {
+		self.setError(self.trie.TryDelete(unbondingsKey))
+		return
+	}
+	data, err := rlp.EncodeToBytes(self.unbondings)
+	if err != nil {
+		panic(fmt.Errorf("can't encode unbondings : %v", err))
+	}
+	self.setError(self.trie.TryUpdate(unbondingsKey, data))
+}
+
+// Store the Unbonding queue
+
+var unbondingsKey = []byte("Unbondings")
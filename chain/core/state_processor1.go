@@ -97,6 +97,9 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		} else if !config.IsMainChain() && !function.AllowInSideChain() {
 			return nil, 0, ErrNotAllowedInSideChain
 		}
+		if function == neatAbi.DeliverMessage && !config.IsCrossChainMessage(header.Number) {
+			return nil, 0, ErrCrossChainMessageNotActive
+		}
 
 		from := msg.From()
 		// Make sure this transaction's nonce is correct
@@ -124,7 +127,7 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		//log.Infof("ApplyTransactionEx() 1, gas is %v, gasPrice is %v, gasValue is %v\n", gasLimit, tx.GasPrice(), gasValue)
 
 		// use gas
-		gas := function.RequiredGas()
+		gas := RequiredGas(statedb, function)
 		if gasLimit < gas {
 			return nil, 0, vm.ErrOutOfGas
 		}
@@ -158,6 +161,25 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 			}
 		}
 
+		// the chain functions log their events since the fork, the receipts of the blocks before are kept
+		if !config.IsChainEvent(header.Number) {
+			statedb.DiscardLogs(tx.Hash())
+		}
+
+		failed := false
+		if function == neatAbi.DeliverMessage {
+			// execute the message with the gas left, the call of a failed message is reverted by the EVM
+			// but the message stays delivered, it can not be replayed. The MessageDelivered event is kept
+			// as well, the failure is reported by the status of the receipt
+			proof, err := DecodeCrossChainMessageProof(tx)
+			if err != nil {
+				return nil, 0, err
+			}
+			var used uint64
+			used, failed = ApplyCrossChainMessage(config, bc, author, statedb, header, msg, proof.Message, gasLimit-gas, cfg)
+			gas += used
+		}
+
 		// refund gas
 		remainingGas := gasLimit - gas
 		remaining := new(big.Int).Mul(new(big.Int).SetUint64(remainingGas), tx.GasPrice())
@@ -175,12 +197,16 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		} else {
 			root = statedb.IntermediateRoot(config.IsEIP158(header.Number)).Bytes()
 		}
-		receipt := types.NewReceipt(root, false, *usedGas)
+		receipt := types.NewReceipt(root, failed, *usedGas)
 		receipt.TxHash = tx.Hash()
 		receipt.GasUsed = gas
 
 		// Set the receipt logs and create a bloom for filtering
 		receipt.Logs = statedb.GetLogs(tx.Hash())
+		// the events of the chain functions are logged without the block context of the EVM
+		for _, l := range receipt.Logs {
+			l.BlockNumber = header.Number.Uint64()
+		}
 		receipt.Bloom = types.CreateBloom(types.Receipts{receipt})
 		receipt.BlockHash = statedb.BlockHash()
 		receipt.BlockNumber = header.Number
@@ -192,3 +218,11 @@ func ApplyTransactionEx(config *params.ChainConfig, bc *BlockChain, author *comm
 		return receipt, 0, nil
 	}
 }
+
+// RequiredGas returns the gas required by the function, the governance may have changed the default one
+func RequiredGas(statedb *state.StateDB, function neatAbi.FunctionType) uint64 {
+	if gas, ok := statedb.GetChainParam(function.GasParam()); ok {
+		return gas.Uint64()
+	}
+	return function.RequiredGas()
+}
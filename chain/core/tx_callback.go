@@ -12,7 +12,9 @@ import (
 	"github.com/Gessiux/neatchain/chain/core/types"
 	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
 	"github.com/Gessiux/neatchain/neatcli"
+	"github.com/Gessiux/neatchain/params"
 	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/event"
 )
 
 type TX3LocalCache interface {
@@ -32,11 +34,13 @@ type CrossChainHelper interface {
 	GetChainInfoDB() dbm.DB
 
 	CanCreateSideChain(from common.Address, chainId string, minValidators uint16, minDepositAmount, startupCost *big.Int, startBlock, endBlock *big.Int) error
-	CreateSideChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error
+	CreateSideChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int, forks *params.SideChainForks) error
 	ValidateJoinSideChain(from common.Address, pubkey []byte, chainId string, depositAmount *big.Int, signature []byte) error
 	JoinSideChain(from common.Address, pubkey crypto.PubKey, chainId string, depositAmount *big.Int) error
 	ReadyForLaunchSideChain(height *big.Int, stateDB *state.StateDB) ([]string, []byte, []string)
 	ProcessPostPendingData(newPendingIdxBytes []byte, deleteSideChainIds []string)
+	ValidateCloseSideChain(from common.Address, chainId string, haltHeight uint64) error
+	CloseSideChain(from common.Address, chainId string, haltHeight uint64) error
 
 	VoteNextEpoch(ep *epoch.Epoch, from common.Address, voteHash common.Hash, txHash common.Hash) error
 	RevealVote(ep *epoch.Epoch, from common.Address, pubkey crypto.PubKey, depositAmount *big.Int, salt string, txHash common.Hash) error
@@ -56,6 +60,13 @@ type CrossChainHelper interface {
 	ValidateTX3ProofData(proofData *types.TX3ProofData) error
 	ValidateTX4WithInMemTX3ProofData(tx4 *types.Transaction, tx3ProofData *types.TX3ProofData) error
 
+	ValidateCrossChainMessageProof(proof *types.CrossChainMessageProof) error
+	ValidateReceiptProofData(proofData *types.ReceiptProofData) error
+
+	GetCrossChainTransfer(chainId string, txHash common.Hash) *types.CrossChainTransfer
+	GetPendingWithdrawals(from common.Address) []*types.CrossChainTransfer
+	SubscribeCrossChainTransferEvent(ch chan<- CrossChainTransferEvent) event.Subscription
+
 	////SaveDataToMainV1 acceps both epoch and tx3
 	//VerifySideChainProofDataV1(proofData *types.SideChainProofDataV1) error
 	//SaveSideChainProofDataToMainChainV1(proofData *types.SideChainProofDataV1) error
@@ -204,6 +204,9 @@ type TxPool struct {
 	pendingState  *state.ManagedState // Pending state tracking virtual nonces
 	currentMaxGas uint64              // Current gas limit for transaction caps
 
+	istanbul          bool // Fork indicator whether we are in the istanbul stage.
+	crossChainMessage bool // Fork indicator whether the cross chain messages are supported.
+
 	locals  *accountSet // Set of local transaction to exempt from eviction rules
 	journal *txJournal  // Journal of local transaction to back up to disk
 
@@ -419,6 +422,11 @@ func (pool *TxPool) reset(oldHead, newHead *types.Header) {
 	pool.pendingState = state.ManageState(statedb)
 	pool.currentMaxGas = newHead.GasLimit
 
+	// Update all fork indicator by next pending block number.
+	next := new(big.Int).Add(newHead.Number, big.NewInt(1))
+	pool.istanbul = pool.chainconfig.IsIstanbul(next)
+	pool.crossChainMessage = pool.chainconfig.IsCrossChainMessage(next)
+
 	// Inject any transactions discarded due to reorgs
 	log.Debug("Reinjecting stale transactions", "count", len(reinject))
 	pool.addTxsLocked(reinject, false)
@@ -604,7 +612,7 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 	}
 
 	if !neatAbi.IsNeatChainContractAddr(tx.To()) {
-		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true)
+		intrGas, err := IntrinsicGas(tx.Data(), tx.To() == nil, true, pool.istanbul)
 		if err != nil {
 			return err
 		}
@@ -625,6 +633,9 @@ func (pool *TxPool) validateTx(tx *types.Transaction, local bool) error {
 		} else if !pool.chainconfig.IsMainChain() && !function.AllowInSideChain() {
 			return ErrNotAllowedInSideChain
 		}
+		if function == neatAbi.DeliverMessage && !pool.crossChainMessage {
+			return ErrCrossChainMessageNotActive
+		}
 
 		log.Infof("validateTx Chain Function %v", function.String())
 		if validateCb := GetValidateCb(function); validateCb != nil {
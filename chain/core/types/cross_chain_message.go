@@ -0,0 +1,38 @@
+package types
+
+import (
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+)
+
+// CrossChainMessage is a payload sent by a contract of one chain to a contract of
+// another chain through the outbox. The chains are identified by the hash of their id.
+type CrossChainMessage struct {
+	SourceChain common.Hash
+	TargetChain common.Hash
+	Nonce       uint64
+	From        common.Address
+	To          common.Address
+	Payload     []byte
+}
+
+// Hash returns the hash the message is recorded by in the outbox and the inbox
+func (m *CrossChainMessage) Hash() common.Hash {
+	return rlpHash(m)
+}
+
+// ChainIdHash returns the hash identifying the chain in the cross chain messages
+func ChainIdHash(chainId string) common.Hash {
+	return crypto.Keccak256Hash([]byte(chainId))
+}
+
+// CrossChainMessageProof proves the message was sent by the source chain, the proofs
+// are the state trie nodes of the outbox account and of its storage slot of the message,
+// taken at the state root of the header.
+type CrossChainMessageProof struct {
+	Header  *Header
+	Message *CrossChainMessage
+
+	AccountProof *BSKeyValueSet
+	StorageProof *BSKeyValueSet
+}
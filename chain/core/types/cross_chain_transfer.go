@@ -0,0 +1,50 @@
+package types
+
+import (
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// TransferStage is the progress of a withdraw from a side chain to the main chain
+type TransferStage uint8
+
+const (
+	// TransferSubmitted is a withdraw (tx3) included in the side chain
+	TransferSubmitted TransferStage = iota
+	// TransferProofBroadcast is a withdraw whose proof reached the main chain
+	TransferProofBroadcast
+	// TransferClaimed is a withdraw claimed by a tx4 in the main chain
+	TransferClaimed
+	// TransferExpired is a withdraw of a side chain not existing anymore, it can't be claimed
+	TransferExpired
+)
+
+func (s TransferStage) String() string {
+	switch s {
+	case TransferSubmitted:
+		return "submitted"
+	case TransferProofBroadcast:
+		return "proof-broadcast"
+	case TransferClaimed:
+		return "claimed"
+	case TransferExpired:
+		return "expired"
+	default:
+		return "unknown"
+	}
+}
+
+// CrossChainTransfer links the withdraw tx (tx3) of a side chain to its claim tx (tx4) in
+// the main chain
+type CrossChainTransfer struct {
+	ChainId string
+	TxHash  common.Hash
+	From    common.Address
+	Amount  *big.Int
+	Stage   TransferStage
+
+	SubmitBlock uint64 // block of the tx3 in the side chain
+	ClaimTxHash common.Hash
+	ClaimBlock  uint64 // block of the tx4 in the main chain
+}
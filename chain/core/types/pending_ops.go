@@ -5,6 +5,7 @@ import (
 	"math/big"
 
 	"github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/neatchain/params"
 	"github.com/Gessiux/neatchain/utilities/common"
 )
 
@@ -42,6 +43,7 @@ type CreateSideChainOp struct {
 	MinDepositAmount *big.Int
 	StartBlock       *big.Int
 	EndBlock         *big.Int
+	Forks            *params.SideChainForks
 }
 
 func (op *CreateSideChainOp) Conflict(op1 PendingOp) bool {
@@ -52,8 +54,8 @@ func (op *CreateSideChainOp) Conflict(op1 PendingOp) bool {
 }
 
 func (op *CreateSideChainOp) String() string {
-	return fmt.Sprintf("CreateSideChainOp - From: %x, ChainId: %s, MinValidators: %d, MinDepositAmount: %x, StartBlock: %x, EndBlock: %x",
-		op.From, op.ChainId, op.MinValidators, op.MinDepositAmount, op.StartBlock, op.EndBlock)
+	return fmt.Sprintf("CreateSideChainOp - From: %x, ChainId: %s, MinValidators: %d, MinDepositAmount: %x, StartBlock: %x, EndBlock: %x, Forks: %v",
+		op.From, op.ChainId, op.MinValidators, op.MinDepositAmount, op.StartBlock, op.EndBlock, op.Forks)
 }
 
 // JoinSideChain op
@@ -76,6 +78,24 @@ func (op *JoinSideChainOp) String() string {
 		op.From, op.PubKey, op.ChainId, op.DepositAmount)
 }
 
+// CloseSideChain op
+type CloseSideChainOp struct {
+	From       common.Address
+	ChainId    string
+	HaltHeight uint64
+}
+
+func (op *CloseSideChainOp) Conflict(op1 PendingOp) bool {
+	if op1, ok := op1.(*CloseSideChainOp); ok {
+		return op.ChainId == op1.ChainId && op.From == op1.From
+	}
+	return false
+}
+
+func (op *CloseSideChainOp) String() string {
+	return fmt.Sprintf("CloseSideChainOp - From: %x, ChainId: %s, HaltHeight: %v", op.From, op.ChainId, op.HaltHeight)
+}
+
 // LaunchSideChain op
 type LaunchSideChainsOp struct {
 	SideChainIds       []string
@@ -0,0 +1,109 @@
+package types
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// ReceiptProofData proves the txs of a block together with their receipts, so the execution
+// result and the logs of a tx can be checked by another chain. The proofs are taken against
+// the tx root and the receipt root of the header.
+type ReceiptProofData struct {
+	Header *Header
+
+	TxIndexs      []uint
+	TxProofs      []*BSKeyValueSet
+	ReceiptProofs []*BSKeyValueSet
+}
+
+// NewReceiptProofData proves the txs of the indexes in the block and their receipts
+func NewReceiptProofData(block *Block, receipts Receipts, indexes []uint) (*ReceiptProofData, error) {
+	txs := block.Transactions()
+	if len(txs) != len(receipts) {
+		return nil, fmt.Errorf("block has %d txs and %d receipts", len(txs), len(receipts))
+	}
+
+	ret := &ReceiptProofData{
+		Header: block.Header(),
+	}
+
+	// build the Tries (see derive_sha.go)
+	txTrie, receiptTrie := new(trie.Trie), new(trie.Trie)
+	keybuf := new(bytes.Buffer)
+	for i := 0; i < len(txs); i++ {
+		keybuf.Reset()
+		rlp.Encode(keybuf, uint(i))
+		txTrie.Update(keybuf.Bytes(), txs.GetRlp(i))
+		receiptTrie.Update(keybuf.Bytes(), receipts.GetRlp(i))
+	}
+
+	for _, index := range indexes {
+		if int(index) >= len(txs) {
+			return nil, fmt.Errorf("tx index %d out of range", index)
+		}
+		keybuf.Reset()
+		rlp.Encode(keybuf, index)
+
+		txProof, receiptProof := MakeBSKeyValueSet(), MakeBSKeyValueSet()
+		if err := txTrie.Prove(keybuf.Bytes(), 0, txProof); err != nil {
+			return nil, err
+		}
+		if err := receiptTrie.Prove(keybuf.Bytes(), 0, receiptProof); err != nil {
+			return nil, err
+		}
+
+		ret.TxIndexs = append(ret.TxIndexs, index)
+		ret.TxProofs = append(ret.TxProofs, txProof)
+		ret.ReceiptProofs = append(ret.ReceiptProofs, receiptProof)
+	}
+	return ret, nil
+}
+
+// VerifyProofs checks the proofs against the header, the proven txs and receipts are returned
+// in the order of the indexes. The header itself is not checked.
+func (p *ReceiptProofData) VerifyProofs() ([]*Transaction, []*Receipt, error) {
+	if p.Header == nil {
+		return nil, nil, errors.New("receipt proof data without header")
+	}
+	if len(p.TxProofs) != len(p.TxIndexs) || len(p.ReceiptProofs) != len(p.TxIndexs) {
+		return nil, nil, errors.New("inconsistent receipt proof data")
+	}
+
+	txs := make([]*Transaction, len(p.TxIndexs))
+	receipts := make([]*Receipt, len(p.TxIndexs))
+	keybuf := new(bytes.Buffer)
+	for i, index := range p.TxIndexs {
+		keybuf.Reset()
+		rlp.Encode(keybuf, index)
+
+		txRlp, _, err := trie.VerifyProof(p.Header.TxHash, keybuf.Bytes(), p.TxProofs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(txRlp) == 0 {
+			return nil, nil, fmt.Errorf("tx %d not in the block", index)
+		}
+		receiptRlp, _, err := trie.VerifyProof(p.Header.ReceiptHash, keybuf.Bytes(), p.ReceiptProofs[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(receiptRlp) == 0 {
+			return nil, nil, fmt.Errorf("receipt %d not in the block", index)
+		}
+
+		txs[i], receipts[i] = new(Transaction), new(Receipt)
+		if err := rlp.DecodeBytes(txRlp, txs[i]); err != nil {
+			return nil, nil, err
+		}
+		if err := rlp.DecodeBytes(receiptRlp, receipts[i]); err != nil {
+			return nil, nil, err
+		}
+		receipts[i].TxHash = txs[i].Hash()
+		receipts[i].TransactionIndex = index
+	}
+	return txs, receipts, nil
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+func TestReceiptProofData(t *testing.T) {
+	to := common.StringToAddress("NEATReceiverAddressAAAAAAAAAAAAA")
+	var (
+		txs      []*Transaction
+		receipts []*Receipt
+	)
+	for i := 0; i < 5; i++ {
+		txs = append(txs, NewTransaction(uint64(i), to, big.NewInt(int64(i)), 21000, big.NewInt(1), nil))
+		receipt := NewReceipt(nil, i%2 == 1, uint64(i+1)*21000)
+		receipt.Logs = []*Log{{Address: to, Topics: []common.Hash{common.BigToHash(big.NewInt(int64(i)))}, Data: []byte{byte(i)}}}
+		receipts = append(receipts, receipt)
+	}
+	block := NewBlock(&Header{Number: big.NewInt(1)}, txs, nil, receipts)
+
+	proofData, err := NewReceiptProofData(block, receipts, []uint{1, 4})
+	if err != nil {
+		t.Fatalf("failed to prove receipts: %v", err)
+	}
+	enc, err := rlp.EncodeToBytes(proofData)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var decoded ReceiptProofData
+	if err := rlp.DecodeBytes(enc, &decoded); err != nil {
+		t.Fatal(err)
+	}
+
+	provenTxs, provenReceipts, err := decoded.VerifyProofs()
+	if err != nil {
+		t.Fatalf("failed to verify receipt proofs: %v", err)
+	}
+	for i, index := range []int{1, 4} {
+		if provenTxs[i].Hash() != txs[index].Hash() {
+			t.Errorf("tx %d mismatch", index)
+		}
+		if provenReceipts[i].Status != receipts[index].Status || provenReceipts[i].CumulativeGasUsed != receipts[index].CumulativeGasUsed {
+			t.Errorf("receipt %d mismatch", index)
+		}
+		if len(provenReceipts[i].Logs) != 1 || provenReceipts[i].Logs[0].Topics[0] != receipts[index].Logs[0].Topics[0] {
+			t.Errorf("logs of receipt %d mismatch", index)
+		}
+	}
+
+	// Proofs against another block are rejected
+	decoded.Header = NewBlock(&Header{Number: big.NewInt(1)}, txs[:4], nil, receipts[:4]).Header()
+	if _, _, err := decoded.VerifyProofs(); err == nil {
+		t.Fatalf("proofs verified against another block")
+	}
+}
@@ -21,6 +21,7 @@ import (
 	"math/big"
 	"testing"
 
+	"github.com/Gessiux/neatchain/params"
 	"github.com/Gessiux/neatchain/utilities/common"
 )
 
@@ -337,7 +338,14 @@ var bn256PairingTests = []precompiledTest{
 }
 
 func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
+	testPrecompiledContract(PrecompiledContractsByzantium[common.HexToAddress(addr)], test, t)
+}
+
+func testPrecompiledIstanbul(addr string, test precompiledTest, t *testing.T) {
+	testPrecompiledContract(PrecompiledContractsIstanbul[common.HexToAddress(addr)], test, t)
+}
+
+func testPrecompiledContract(p PrecompiledContract, test precompiledTest, t *testing.T) {
 	in := common.Hex2Bytes(test.input)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
 		nil, new(big.Int), p.RequiredGas(in))
@@ -351,10 +359,17 @@ func testPrecompiled(addr string, test precompiledTest, t *testing.T) {
 }
 
 func benchmarkPrecompiled(addr string, test precompiledTest, bench *testing.B) {
+	benchmarkPrecompiledContract(PrecompiledContractsByzantium[common.HexToAddress(addr)], test, bench)
+}
+
+func benchmarkPrecompiledIstanbul(addr string, test precompiledTest, bench *testing.B) {
+	benchmarkPrecompiledContract(PrecompiledContractsIstanbul[common.HexToAddress(addr)], test, bench)
+}
+
+func benchmarkPrecompiledContract(p PrecompiledContract, test precompiledTest, bench *testing.B) {
 	if test.noBenchmark {
 		return
 	}
-	p := PrecompiledContractsByzantium[common.HexToAddress(addr)]
 	in := common.Hex2Bytes(test.input)
 	reqGas := p.RequiredGas(in)
 	contract := NewContract(AccountRef(common.HexToAddress("1337")),
@@ -481,3 +496,68 @@ func BenchmarkPrecompiledBn256Pairing(bench *testing.B) {
 		benchmarkPrecompiled("08", test, bench)
 	}
 }
+
+// Tests the elliptic curve precompiles with the EIP 1108 gas prices of Istanbul.
+func TestPrecompiledBn256Istanbul(t *testing.T) {
+	for _, test := range bn256AddTests {
+		testPrecompiledIstanbul("06", test, t)
+	}
+	for _, test := range bn256ScalarMulTests {
+		testPrecompiledIstanbul("07", test, t)
+	}
+	for _, test := range bn256PairingTests {
+		testPrecompiledIstanbul("08", test, t)
+	}
+
+	add := PrecompiledContractsIstanbul[common.HexToAddress("06")]
+	if gas := add.RequiredGas(nil); gas != params.Bn256AddGasIstanbul {
+		t.Errorf("bn256Add gas mismatch: have %d, want %d", gas, params.Bn256AddGasIstanbul)
+	}
+	mul := PrecompiledContractsIstanbul[common.HexToAddress("07")]
+	if gas := mul.RequiredGas(nil); gas != params.Bn256ScalarMulGasIstanbul {
+		t.Errorf("bn256ScalarMul gas mismatch: have %d, want %d", gas, params.Bn256ScalarMulGasIstanbul)
+	}
+	pairing := PrecompiledContractsIstanbul[common.HexToAddress("08")]
+	for _, test := range bn256PairingTests {
+		in := common.Hex2Bytes(test.input)
+		want := params.Bn256PairingBaseGasIstanbul + uint64(len(in)/192)*params.Bn256PairingPerPointGasIstanbul
+		if gas := pairing.RequiredGas(in); gas != want {
+			t.Errorf("%s: bn256Pairing gas mismatch: have %d, want %d", test.name, gas, want)
+		}
+	}
+}
+
+// Benchmarks the elliptic curve precompiles with the EIP 1108 gas prices of Istanbul.
+func BenchmarkPrecompiledBn256Istanbul(bench *testing.B) {
+	for _, test := range bn256AddTests {
+		benchmarkPrecompiledIstanbul("06", test, bench)
+	}
+	for _, test := range bn256ScalarMulTests {
+		benchmarkPrecompiledIstanbul("07", test, bench)
+	}
+	for _, test := range bn256PairingTests {
+		benchmarkPrecompiledIstanbul("08", test, bench)
+	}
+}
+
+var blake2FTests = []precompiledTest{
+	{
+		input:    "0000000c48c9bdf267e6096a3ba7ca8485ae67bb2bf894fe72f36e3cf1361d5f3af54fa5d182e6ad7f520e511f6c3e2b8c68059b6bbd41fbabd9831f79217e1319cde05b61626300000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000000300000000000000000000000000000001",
+		expected: "ba80a53f981c4d0d6a2797b69f12f6e94c212f14685ac4b74b12bb6fdbffa2d17d87c5392aab792dc252d5de4533cc9518d38aa8dbf1925ab92386edd4009923",
+		name:     "vector 5",
+	},
+}
+
+// Tests the sample inputs from the blake2 F compression function EIP 152.
+func TestPrecompiledBlake2F(t *testing.T) {
+	for _, test := range blake2FTests {
+		testPrecompiledIstanbul("09", test, t)
+	}
+}
+
+// Benchmarks the sample inputs from the blake2 F compression function EIP 152.
+func BenchmarkPrecompiledBlake2F(bench *testing.B) {
+	for _, test := range blake2FTests {
+		benchmarkPrecompiledIstanbul("09", test, bench)
+	}
+}
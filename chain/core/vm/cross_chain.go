@@ -0,0 +1,146 @@
+package vm
+
+import (
+	"errors"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+var (
+	// CrossChainOutboxAddr is the system contract the contracts send the cross chain messages to
+	CrossChainOutboxAddr = common.StringToAddress("NEATCCCCCCCCCCCCCCCCCCCCCCCCCCCC")
+	// CrossChainInboxAddr is the caller of the delivered cross chain messages, the receiving
+	// contract calls it back to learn the source chain and the sender of the message
+	CrossChainInboxAddr = common.StringToAddress("NEATDDDDDDDDDDDDDDDDDDDDDDDDDDDD")
+
+	// CrossChainMessageTopic is the topic of the log of a message sent to the outbox
+	CrossChainMessageTopic = crypto.Keccak256Hash([]byte("CrossChainMessage(bytes32,bytes32)"))
+
+	// crossChainNonceKey is the storage slot of the outbox holding the next message nonce
+	crossChainNonceKey = common.Hash{}
+	// crossChainRecorded is the storage value of a message sent or delivered
+	crossChainRecorded = common.BytesToHash([]byte{1})
+)
+
+var (
+	errCrossChainReadOnly      = errors.New("cross chain message sent in read only call")
+	errCrossChainIndirect      = errors.New("cross chain message sent by delegate call")
+	errCrossChainInput         = errors.New("invalid cross chain message input")
+	errCrossChainValue         = errors.New("cross chain message can not carry value")
+	errCrossChainTarget        = errors.New("cross chain message sent to the same chain")
+	errCrossChainNotDelivering = errors.New("no cross chain message being delivered")
+)
+
+// IsCrossChainContract returns whether the address is one of the cross chain system contracts
+func IsCrossChainContract(addr common.Address) bool {
+	return addr == CrossChainOutboxAddr || addr == CrossChainInboxAddr
+}
+
+// isCrossChainContract returns whether the address is one of the cross chain system contracts
+// at the current block, before the fork they are plain accounts
+func (evm *EVM) isCrossChainContract(addr common.Address) bool {
+	return evm.ChainConfig().IsCrossChainMessage(evm.BlockNumber) && IsCrossChainContract(addr)
+}
+
+// runCrossChainContract runs the cross chain system contract of the code address
+func runCrossChainContract(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	switch *contract.CodeAddr {
+	case CrossChainOutboxAddr:
+		return sendCrossChainMessage(evm, contract, input, readOnly)
+	default:
+		return readCrossChainMessage(evm, contract)
+	}
+}
+
+// sendCrossChainMessage records the message of the input in the outbox, the input is
+// the hash of the target chain id (32 bytes), the receiving contract (32 bytes) and the
+// payload. The message hash is returned.
+func sendCrossChainMessage(evm *EVM, contract *Contract, input []byte, readOnly bool) ([]byte, error) {
+	if readOnly {
+		return nil, errCrossChainReadOnly
+	}
+	if contract.Address() != CrossChainOutboxAddr {
+		return nil, errCrossChainIndirect
+	}
+	if len(input) < 2*common.HashLength {
+		return nil, errCrossChainInput
+	}
+	if contract.Value().Sign() != 0 {
+		return nil, errCrossChainValue
+	}
+	gas := params.CrossChainMessageGas + uint64(len(input)+31)/32*params.CrossChainMessageWordGas
+	if !contract.UseGas(gas) {
+		return nil, ErrOutOfGas
+	}
+
+	msg := &types.CrossChainMessage{
+		SourceChain: types.ChainIdHash(evm.ChainConfig().NeatChainId),
+		TargetChain: common.BytesToHash(input[:common.HashLength]),
+		From:        contract.Caller(),
+		To:          common.BytesToAddress(input[common.HashLength : 2*common.HashLength]),
+		Payload:     common.CopyBytes(input[2*common.HashLength:]),
+	}
+	if msg.TargetChain == msg.SourceChain {
+		return nil, errCrossChainTarget
+	}
+
+	db := evm.StateDB
+	nonce := db.GetState(CrossChainOutboxAddr, crossChainNonceKey).Big().Uint64()
+	msg.Nonce = nonce
+	db.SetState(CrossChainOutboxAddr, crossChainNonceKey, common.BigToHash(new(big.Int).SetUint64(nonce+1)))
+
+	hash := msg.Hash()
+	db.SetState(CrossChainOutboxAddr, hash, crossChainRecorded)
+	// keep the system account from being deleted as an empty account
+	if db.GetNonce(CrossChainOutboxAddr) == 0 {
+		db.SetNonce(CrossChainOutboxAddr, 1)
+	}
+
+	data, err := rlp.EncodeToBytes(msg)
+	if err != nil {
+		return nil, err
+	}
+	db.AddLog(&types.Log{
+		Address:     CrossChainOutboxAddr,
+		Topics:      []common.Hash{CrossChainMessageTopic, hash},
+		Data:        data,
+		BlockNumber: evm.BlockNumber.Uint64(),
+	})
+	return hash.Bytes(), nil
+}
+
+// readCrossChainMessage returns the source chain and the sender of the message being
+// delivered, 32 bytes each
+func readCrossChainMessage(evm *EVM, contract *Contract) ([]byte, error) {
+	if !contract.UseGas(params.CrossChainInboxGas) {
+		return nil, ErrOutOfGas
+	}
+	msg := evm.CrossChainMessage
+	if msg == nil {
+		return nil, errCrossChainNotDelivering
+	}
+	return append(msg.SourceChain.Bytes(), msg.From.Bytes()...), nil
+}
+
+// IsCrossChainMessageSent returns whether the message is recorded in the outbox
+func IsCrossChainMessageSent(db StateDB, hash common.Hash) bool {
+	return db.GetState(CrossChainOutboxAddr, hash) == crossChainRecorded
+}
+
+// IsCrossChainMessageDelivered returns whether the message is recorded in the inbox
+func IsCrossChainMessageDelivered(db StateDB, hash common.Hash) bool {
+	return db.GetState(CrossChainInboxAddr, hash) == crossChainRecorded
+}
+
+// MarkCrossChainMessageDelivered records the message in the inbox, it won't be delivered again
+func MarkCrossChainMessageDelivered(db StateDB, hash common.Hash) {
+	db.SetState(CrossChainInboxAddr, hash, crossChainRecorded)
+	if db.GetNonce(CrossChainInboxAddr) == 0 {
+		db.SetNonce(CrossChainInboxAddr, 1)
+	}
+}
@@ -0,0 +1,135 @@
+package vm
+
+import (
+	"bytes"
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+func newCrossChainTestEVM(t *testing.T, msg *types.CrossChainMessage) (*EVM, *state.StateDB) {
+	statedb, err := state.New(common.Hash{}, state.NewDatabase(rawdb.NewMemoryDatabase()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	config := *params.TestChainConfig
+	config.NeatChainId = "neatchain"
+
+	context := Context{
+		CanTransfer:       func(db StateDB, addr common.Address, amount *big.Int) bool { return db.GetBalance(addr).Cmp(amount) >= 0 },
+		Transfer:          func(db StateDB, sender, recipient common.Address, amount *big.Int) {},
+		BlockNumber:       big.NewInt(1),
+		CrossChainMessage: msg,
+	}
+	return NewEVM(context, statedb, &config, Config{}), statedb
+}
+
+func TestSendCrossChainMessage(t *testing.T) {
+	evm, statedb := newCrossChainTestEVM(t, nil)
+
+	sender := common.StringToAddress("NEATSenderAddressAAAAAAAAAAAAAAA")
+	target := types.ChainIdHash("side_0")
+	to := common.StringToAddress("NEATReceiverAddressAAAAAAAAAAAAA")
+	input := append(append(target.Bytes(), to.Bytes()...), []byte("payload")...)
+
+	for i := 0; i < 2; i++ {
+		ret, _, err := evm.Call(AccountRef(sender), CrossChainOutboxAddr, input, 100000, new(big.Int))
+		if err != nil {
+			t.Fatalf("failed to send message: %v", err)
+		}
+		hash := common.BytesToHash(ret)
+		if !IsCrossChainMessageSent(statedb, hash) {
+			t.Fatalf("message %d not recorded in the outbox", i)
+		}
+
+		logs := statedb.Logs()
+		if len(logs) != i+1 || logs[i].Topics[1] != hash {
+			t.Fatalf("message %d not logged", i)
+		}
+		var msg types.CrossChainMessage
+		if err := rlp.DecodeBytes(logs[i].Data, &msg); err != nil {
+			t.Fatal(err)
+		}
+		if msg.Hash() != hash || msg.Nonce != uint64(i) || msg.From != sender || msg.To != to || msg.TargetChain != target {
+			t.Fatalf("message %d mismatch: %+v", i, msg)
+		}
+		if msg.SourceChain != types.ChainIdHash("neatchain") || !bytes.Equal(msg.Payload, []byte("payload")) {
+			t.Fatalf("message %d mismatch: %+v", i, msg)
+		}
+	}
+
+	// Messages to the sending chain and read only calls are rejected
+	self := append(append(types.ChainIdHash("neatchain").Bytes(), to.Bytes()...), []byte("payload")...)
+	if _, _, err := evm.Call(AccountRef(sender), CrossChainOutboxAddr, self, 100000, new(big.Int)); err != errCrossChainTarget {
+		t.Fatalf("message to the same chain: have %v, want %v", err, errCrossChainTarget)
+	}
+	if _, _, err := evm.StaticCall(AccountRef(sender), CrossChainOutboxAddr, input, 100000); err != errCrossChainReadOnly {
+		t.Fatalf("message in static call: have %v, want %v", err, errCrossChainReadOnly)
+	}
+}
+
+func TestReadCrossChainMessage(t *testing.T) {
+	caller := common.StringToAddress("NEATCallerAddressAAAAAAAAAAAAAAA")
+
+	evm, _ := newCrossChainTestEVM(t, nil)
+	if _, _, err := evm.Call(AccountRef(caller), CrossChainInboxAddr, nil, 100000, new(big.Int)); err != errCrossChainNotDelivering {
+		t.Fatalf("read without message: have %v, want %v", err, errCrossChainNotDelivering)
+	}
+
+	msg := &types.CrossChainMessage{
+		SourceChain: types.ChainIdHash("side_0"),
+		From:        common.StringToAddress("NEATSenderAddressAAAAAAAAAAAAAAA"),
+	}
+	evm, _ = newCrossChainTestEVM(t, msg)
+	ret, _, err := evm.Call(AccountRef(caller), CrossChainInboxAddr, nil, 100000, new(big.Int))
+	if err != nil {
+		t.Fatalf("failed to read message: %v", err)
+	}
+	if want := append(msg.SourceChain.Bytes(), msg.From.Bytes()...); !bytes.Equal(ret, want) {
+		t.Fatalf("read message mismatch: have %x, want %x", ret, want)
+	}
+}
+
+func TestCrossChainContractBeforeFork(t *testing.T) {
+	evm, statedb := newCrossChainTestEVM(t, nil)
+	evm.chainConfig.CrossChainMessageBlock = big.NewInt(2)
+
+	sender := common.StringToAddress("NEATSenderAddressAAAAAAAAAAAAAAA")
+	to := common.StringToAddress("NEATReceiverAddressAAAAAAAAAAAAA")
+	input := append(append(types.ChainIdHash("side_0").Bytes(), to.Bytes()...), []byte("payload")...)
+
+	// The outbox is a plain account before the fork
+	ret, _, err := evm.Call(AccountRef(sender), CrossChainOutboxAddr, input, 100000, new(big.Int))
+	if err != nil || len(ret) != 0 {
+		t.Fatalf("call before the fork: have (%x, %v), want no message", ret, err)
+	}
+	if logs := statedb.Logs(); len(logs) != 0 {
+		t.Fatalf("message logged before the fork: %v", logs)
+	}
+}
+
+func TestFailedCrossChainMessageStaysDelivered(t *testing.T) {
+	msg := &types.CrossChainMessage{
+		SourceChain: types.ChainIdHash("side_0"),
+		From:        common.StringToAddress("NEATSenderAddressAAAAAAAAAAAAAAA"),
+		To:          common.StringToAddress("NEATReceiverAddressAAAAAAAAAAAAA"),
+	}
+	evm, statedb := newCrossChainTestEVM(t, msg)
+	// PUSH1 0 PUSH1 0 REVERT
+	statedb.SetCode(msg.To, []byte{0x60, 0x00, 0x60, 0x00, 0xfd})
+
+	// The message is marked before the execution, as the DeliverMessage transaction does
+	MarkCrossChainMessageDelivered(statedb, msg.Hash())
+	if _, _, err := evm.Call(AccountRef(CrossChainInboxAddr), msg.To, msg.Payload, 100000, new(big.Int)); err == nil {
+		t.Fatal("reverting receiver succeeded")
+	}
+	if !IsCrossChainMessageDelivered(statedb, msg.Hash()) {
+		t.Fatal("failed message can be delivered again")
+	}
+}
@@ -7,7 +7,10 @@ import (
 	"github.com/Gessiux/neatchain/chain/accounts/keystore"
 	ntcTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
 	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatptc"
 	neatnode "github.com/Gessiux/neatchain/network/node"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/metrics/prometheus"
 	"github.com/Gessiux/neatchain/utilities/utils"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -61,6 +64,11 @@ func StartChain(ctx *cli.Context, chain *Chain, startDone chan<- struct{}) error
 	go func() {
 		utils.StartNode(ctx, chain.NeatNode)
 
+		// Report the metrics of the chain with its chain id
+		if neatChain, err := getNeatChainFromNode(chain.NeatNode); err == nil {
+			prometheus.RegisterChain(chain.Id, neatptc.NewChainRegistry(neatChain))
+		}
+
 		if startDone != nil {
 			startDone <- struct{}{}
 		}
@@ -69,7 +77,7 @@ func StartChain(ctx *cli.Context, chain *Chain, startDone chan<- struct{}) error
 	return nil
 }
 
-func CreateSideChain(ctx *cli.Context, chainId string, validator ntcTypes.PrivValidator, keyJson []byte, validators []ntcTypes.GenesisValidator) error {
+func CreateSideChain(ctx *cli.Context, chainId string, validator ntcTypes.PrivValidator, keyJson []byte, validators []ntcTypes.GenesisValidator, forks *params.SideChainForks) error {
 
 	config := utils.GetNeatConConfig(chainId, ctx)
 
@@ -82,11 +90,14 @@ func CreateSideChain(ctx *cli.Context, chainId string, validator ntcTypes.PrivVa
 		}
 	}
 
-	privValFile := config.GetString("priv_validator_file_root")
-	validator.SetFile(privValFile + ".json")
-	validator.Save()
+	// the key held by a remote signer is shared with the side chain through the signer
+	if validator.PrivKey != nil {
+		privValFile := config.GetString("priv_validator_file_root")
+		validator.SetFile(privValFile + ".json")
+		validator.Save()
+	}
 
-	err := initEthGenesisFromExistValidator(chainId, config, validators)
+	err := initEthGenesisFromExistValidator(chainId, config, validators, forks)
 	if err != nil {
 		return err
 	}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"encoding/json"
 	"io/ioutil"
 	"net"
 	"os"
@@ -12,6 +13,7 @@ import (
 	dbm "github.com/Gessiux/go-db"
 	"github.com/Gessiux/neatchain/chain/accounts"
 	"github.com/Gessiux/neatchain/chain/consensus"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon"
 	"github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
 	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
 	"github.com/Gessiux/neatchain/chain/core"
@@ -118,6 +120,11 @@ func (cm *ChainManager) LoadChains(sideIds []string) error {
 	//log.Infof("Start to load side chain: %v", readyToLoadChains)
 
 	for chainId := range readyToLoadChains {
+		if cm.sideChainArchived(chainId) {
+			log.Infof("Side chain %s is closed and archived, skip it", chainId)
+			continue
+		}
+
 		chain := LoadSideChain(cm.ctx, chainId)
 		if chain == nil {
 			log.Errorf("Load side chain: %s Failed.", chainId)
@@ -135,6 +142,8 @@ func (cm *ChainManager) InitCrossChainHelper() {
 		cm.mainChain.Config.GetString("db_backend"),
 		cm.ctx.GlobalString(utils.DataDirFlag.Name))
 	cm.cch.localTX3CacheDB, _ = rawdb.NewLevelDBDatabase(path.Join(cm.ctx.GlobalString(utils.DataDirFlag.Name), "tx3cache"), 0, 0, "neatchain/db/tx3/")
+	cm.cch.transfers = newTransferIndexer(cm.cch.localTX3CacheDB, cm.cch.chainInfoDB)
+	anchorGenesisEpochs(cm.cch.chainInfoDB)
 
 	chainId := MainChain
 	if cm.ctx.GlobalBool(utils.TestnetFlag.Name) {
@@ -184,6 +193,11 @@ func (cm *ChainManager) StartMainChain() error {
 	<-cm.mainStartDone
 	cm.mainQuit = cm.mainChain.NeatNode.StopChan()
 
+	if neatChain, err := getNeatChainFromNode(cm.mainChain.NeatNode); err == nil {
+		cm.cch.transfers.watchChain(cm.mainChain.Id, neatChain.BlockChain(), true)
+		cm.watchClosures(neatChain.BlockChain())
+	}
+
 	return err
 }
 
@@ -208,6 +222,10 @@ func (cm *ChainManager) StartChains() error {
 		StartChain(cm.ctx, chain, startDone)
 		<-startDone
 
+		if neatChain, err := getNeatChainFromNode(chain.NeatNode); err == nil {
+			cm.cch.transfers.watchChain(chain.Id, neatChain.BlockChain(), false)
+		}
+
 		cm.sideQuits[chain.Id] = chain.NeatNode.StopChan()
 
 		// Tell other peers that we have added into a new side chain
@@ -351,10 +369,13 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 	}
 
 	// side chain uses the same validator with the main chain.
-	privValidatorFile := cm.mainChain.Config.GetString("priv_validator_file")
-	self := types.LoadPrivValidator(privValidatorFile)
+	self, err := neatcon.LoadPrivValidator(cm.mainChain.Config)
+	if err != nil || self == nil {
+		log.Errorf("Create Side Chain %v failed! load PrivValidator failed, %v", chainId, err)
+		return
+	}
 
-	err := CreateSideChain(cm.ctx, chainId, *self, keyJson, validators)
+	err = CreateSideChain(cm.ctx, chainId, *self, keyJson, validators, core.GetSideChainForks(cm.cch.chainInfoDB, chainId))
 	if err != nil {
 		log.Errorf("Create Side Chain %v failed! %v", chainId, err)
 		return
@@ -393,6 +414,7 @@ func (cm *ChainManager) LoadSideChainInRT(chainId string) {
 
 	var sideEthereum *neatptc.NeatChain
 	chain.NeatNode.Service(&sideEthereum)
+	cm.cch.transfers.watchChain(chainId, sideEthereum.BlockChain(), false)
 	firstEpoch := sideEthereum.Engine().(consensus.NeatCon).GetEpoch()
 	// Side Chain start success, then delete the pending data in chain info db
 	cm.formalizeSideChain(chainId, *cci, firstEpoch)
@@ -428,6 +450,72 @@ func (cm *ChainManager) formalizeSideChain(chainId string, cci core.CoreChainInf
 	core.SaveChainInfo(cm.cch.chainInfoDB, &core.ChainInfo{CoreChainInfo: cci, Epoch: ep})
 }
 
+// watchClosures retires the local side chains closed once their settlement period is over
+func (cm *ChainManager) watchClosures(bc *core.BlockChain) {
+	ch := make(chan core.ChainHeadEvent, 16)
+	sub := bc.SubscribeChainHeadEvent(ch)
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-ch:
+				cm.retireSideChains(ev.Block.NumberU64())
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// retireSideChains stops the side chains whose settlement period is over at the main chain
+// height, and archives their data directory
+func (cm *ChainManager) retireSideChains(height uint64) {
+	cm.createSideChainLock.Lock()
+	defer cm.createSideChainLock.Unlock()
+
+	for chainId, chain := range cm.sideChains {
+		closure := core.GetSideChainClosure(cm.cch.chainInfoDB, chainId)
+		if closure == nil || !closure.Settled() || height <= closure.SettlementEnd() {
+			continue
+		}
+
+		log.Infof("Settlement of side chain %s is over, stop and archive it", chainId)
+		srv := cm.server.Server()
+		if address, ok := cm.getNodeValidator(chain.NeatNode); ok {
+			srv.RemoveLocalValidator(chainId, address)
+		}
+		srv.RemoveChildProtocolCaps(chain.NeatNode.GatherProtocols())
+		if err := chain.NeatNode.StopServices(); err != nil {
+			log.Error("Error when stopping side chain", "side id", chainId, "err", err)
+		}
+		delete(cm.sideChains, chainId)
+		delete(cm.sideQuits, chainId)
+
+		dataDir := cm.sideChainDataDir(chainId)
+		if err := os.Rename(dataDir, dataDir+archivedSuffix); err != nil {
+			log.Error("Failed to archive side chain data", "side id", chainId, "dir", dataDir, "err", err)
+		}
+	}
+}
+
+// archivedSuffix is appended to the data directory of the side chain retired
+const archivedSuffix = ".archived"
+
+func (cm *ChainManager) sideChainDataDir(chainId string) string {
+	return path.Join(cm.ctx.GlobalString(utils.DataDirFlag.Name), chainId)
+}
+
+// sideChainArchived checks the side chain is retired after its settlement
+func (cm *ChainManager) sideChainArchived(chainId string) bool {
+	closure := core.GetSideChainClosure(cm.cch.chainInfoDB, chainId)
+	if closure == nil || !closure.Settled() {
+		return false
+	}
+	_, err := os.Stat(cm.sideChainDataDir(chainId) + archivedSuffix)
+	return err == nil
+}
+
 func (cm *ChainManager) checkCoinbaseInSideChain(sideEpoch *epoch.Epoch) bool {
 	var neatchain *neatptc.NeatChain
 	cm.mainChain.NeatNode.Service(&neatchain)
@@ -469,6 +557,7 @@ func (cm *ChainManager) WaitChainsStop() {
 func (cm *ChainManager) Stop() {
 	utils.StopRPC()
 	cm.server.Stop()
+	cm.cch.transfers.close()
 	cm.cch.localTX3CacheDB.Close()
 	cm.cch.chainInfoDB.Close()
 
@@ -482,8 +571,11 @@ func (cm *ChainManager) Wait() {
 
 func (cm *ChainManager) getNodeValidator(neatNode *node.Node) (common.Address, bool) {
 
-	var neatchain *neatptc.NeatChain
-	neatNode.Service(&neatchain)
+	neatchain, err := getNeatChainFromNode(neatNode)
+	if err != nil {
+		// The light client does not validate
+		return common.Address{}, false
+	}
 
 	var coinbase common.Address
 	ntc := neatchain.Engine()
@@ -494,7 +586,32 @@ func (cm *ChainManager) getNodeValidator(neatNode *node.Node) (common.Address, b
 }
 
 func writeGenesisIntoChainInfoDB(db dbm.DB, sideChainId string, validators []types.GenesisValidator) {
-	ethByte, _ := generateETHGenesis(sideChainId, validators)
+	ethByte, _ := generateETHGenesis(sideChainId, validators, core.GetSideChainForks(db, sideChainId))
 	ntcByte, _ := generateNTCGenesis(sideChainId, validators)
 	core.SaveChainGenesis(db, sideChainId, ethByte, ntcByte)
+
+	// Anchor the genesis validators, the proofs of epoch 0 from the side chain are verified against them
+	genesisEpoch := epoch.MakeOneEpoch(nil, &types.OneEpochDoc{Number: 0, Validators: validators}, nil)
+	core.SaveGenesisEpoch(db, sideChainId, genesisEpoch)
+}
+
+// anchorGenesisEpochs anchors the genesis validators of the side chains launched before they were
+// saved along with the genesis, the proofs of epoch 0 are verified against them
+func anchorGenesisEpochs(db dbm.DB) {
+	for _, chainId := range core.GetSideChainIds(db) {
+		if core.GetGenesisEpoch(db, chainId) != nil {
+			continue
+		}
+		_, ntcGenesis := core.LoadChainGenesis(db, chainId)
+		if len(ntcGenesis) == 0 {
+			continue
+		}
+		var genDoc types.GenesisDoc
+		if err := json.Unmarshal(ntcGenesis, &genDoc); err != nil {
+			log.Errorf("Anchor genesis validators of side chain %s failed: %v", chainId, err)
+			continue
+		}
+		core.SaveGenesisEpoch(db, chainId, epoch.MakeOneEpoch(nil, &genDoc.CurrentEpoch, nil))
+		log.Infof("Genesis validators of side chain %s anchored", chainId)
+	}
 }
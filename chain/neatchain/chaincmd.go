@@ -101,8 +101,9 @@ It expects the genesis file as argument.`,
 		Usage:  "create-validator address", //create priv_validator.json for address
 		Flags: []cli.Flag{
 			utils.DataDirFlag,
+			utils.ValidatorPasswordFileFlag,
 		},
-		Description: "Create priv_validator.json for address",
+		Description: "Create priv_validator.json for address, the consensus key is encrypted if --validator.password is given",
 	}
 
 	importCommand = cli.Command{
@@ -115,6 +116,7 @@ It expects the genesis file as argument.`,
 			utils.CacheFlag,
 			utils.SyncModeFlag,
 			utils.GCModeFlag,
+			utils.PruneRetainFlag,
 			utils.CacheDatabaseFlag,
 			utils.CacheGCFlag,
 		},
@@ -7,6 +7,7 @@ import (
 
 	"github.com/Gessiux/go-crypto"
 	"github.com/Gessiux/go-wire"
+	"github.com/Gessiux/neatchain/chain/accounts/keystore"
 	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/params"
@@ -62,6 +63,12 @@ func CreatePrivateValidatorCmd(ctx *cli.Context) error {
 	}
 
 	fmt.Printf(string(wire.JSONBytesPretty(consolePrivVal)))
+	// Encrypt the consensus key if a passphrase is given
+	if passwords := utils.MakeValidatorPasswordList(ctx); len(passwords) > 0 {
+		if err := validator.Encrypt(passwords[0], keystore.StandardScryptN, keystore.StandardScryptP); err != nil {
+			utils.Fatalf("Failed to encrypt the consensus key: %v", err)
+		}
+	}
 	validator.SetFile(privValFile)
 	validator.Save()
 
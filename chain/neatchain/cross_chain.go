@@ -30,6 +30,7 @@ import (
 	"github.com/Gessiux/neatchain/params"
 	"github.com/Gessiux/neatchain/utilities/common"
 	"github.com/Gessiux/neatchain/utilities/common/math"
+	"github.com/Gessiux/neatchain/utilities/event"
 	"github.com/Gessiux/neatchain/utilities/rlp"
 )
 
@@ -40,6 +41,8 @@ type CrossChainHelper struct {
 	//the client does only connect to main chain
 	client      *neatcli.Client
 	mainChainId string
+
+	transfers *transferIndexer
 }
 
 func (cch *CrossChainHelper) GetMutex() *sync.Mutex {
@@ -122,7 +125,7 @@ func (cch *CrossChainHelper) CanCreateSideChain(from common.Address, chainId str
 }
 
 // CreateSideChain Save the Side Chain Data into the DB, the data will be used later during Block Commit Callback
-func (cch *CrossChainHelper) CreateSideChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int) error {
+func (cch *CrossChainHelper) CreateSideChain(from common.Address, chainId string, minValidators uint16, minDepositAmount *big.Int, startBlock, endBlock *big.Int, forks *params.SideChainForks) error {
 	log.Debug("CreateSideChain - start")
 
 	cci := &core.CoreChainInfo{
@@ -135,6 +138,9 @@ func (cch *CrossChainHelper) CreateSideChain(from common.Address, chainId string
 		JoinedValidators: make([]core.JoinedValidator, 0),
 	}
 	core.CreatePendingSideChainData(cch.chainInfoDB, cci)
+	if forks != nil {
+		core.SaveSideChainForks(cch.chainInfoDB, chainId, forks)
+	}
 
 	log.Debug("CreateSideChain - end")
 	return nil
@@ -234,6 +240,89 @@ func (cch *CrossChainHelper) ProcessPostPendingData(newPendingIdxBytes []byte, d
 	core.ProcessPostPendingData(cch.chainInfoDB, newPendingIdxBytes, deleteSideChainIds)
 }
 
+// ValidateCloseSideChain checks the validator of the side chain could vote to halt the side chain at the height
+func (cch *CrossChainHelper) ValidateCloseSideChain(from common.Address, chainId string, haltHeight uint64) error {
+	if haltHeight == 0 {
+		return errors.New("halt height must be greater than 0")
+	}
+
+	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
+	if ci == nil {
+		return fmt.Errorf("side chain %s not exist", chainId)
+	}
+	if closure := core.GetSideChainClosure(cch.chainInfoDB, chainId); closure != nil && closure.HaltHeight != 0 {
+		return fmt.Errorf("side chain %s already halted at height %v", chainId, closure.HaltHeight)
+	}
+
+	valSet, err := cch.closeValidators(ci)
+	if err != nil {
+		return err
+	}
+	if !valSet.HasAddress(from.Bytes()) {
+		return fmt.Errorf("%x is not a validator of side chain %s", from, chainId)
+	}
+	return nil
+}
+
+// CloseSideChain records the vote of the validator, the side chain halts at the height once
+// +2/3 of the voting power of its validators voted for it
+func (cch *CrossChainHelper) CloseSideChain(from common.Address, chainId string, haltHeight uint64) error {
+	log.Debug("CloseSideChain - start")
+
+	if err := cch.ValidateCloseSideChain(from, chainId, haltHeight); err != nil {
+		return err
+	}
+
+	closure := core.GetSideChainClosure(cch.chainInfoDB, chainId)
+	if closure == nil {
+		closure = &core.SideChainClosure{ChainId: chainId}
+	}
+
+	// The later vote of the validator replaces the former one
+	votes := closure.Votes[:0]
+	for _, vote := range closure.Votes {
+		if vote.Address != from {
+			votes = append(votes, vote)
+		}
+	}
+	closure.Votes = append(votes, core.CloseVote{Address: from, HaltHeight: haltHeight})
+
+	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
+	valSet, err := cch.closeValidators(ci)
+	if err != nil {
+		return err
+	}
+	votingPower := new(big.Int)
+	for _, vote := range closure.Votes {
+		if vote.HaltHeight != haltHeight {
+			continue
+		}
+		if _, val := valSet.GetByAddress(vote.Address.Bytes()); val != nil {
+			votingPower.Add(votingPower, val.VotingPower)
+		}
+	}
+	quorum := new(big.Int).Mul(valSet.TotalVotingPower(), big.NewInt(2))
+	quorum.Div(quorum, big.NewInt(3))
+	if votingPower.Cmp(quorum) > 0 {
+		closure.HaltHeight = haltHeight
+		log.Infof("Side chain %s will halt at height %v", chainId, haltHeight)
+	}
+
+	core.SaveSideChainClosure(cch.chainInfoDB, closure)
+
+	log.Debug("CloseSideChain - end")
+	return nil
+}
+
+// closeValidators returns the validators of the side chain voting for its closure
+func (cch *CrossChainHelper) closeValidators(ci *core.ChainInfo) (*ntcTypes.ValidatorSet, error) {
+	if ci.Epoch != nil && ci.Epoch.Validators != nil {
+		return ci.Epoch.Validators, nil
+	}
+	// Epochs of the side chain not learnt yet, the genesis validators are in charge
+	return cch.sideChainValidators(ci, 0)
+}
+
 func (cch *CrossChainHelper) VoteNextEpoch(ep *epoch.Epoch, from common.Address, voteHash common.Hash, txHash common.Hash) error {
 
 	voteSet := ep.GetNextEpoch().GetEpochValidatorVoteSet()
@@ -394,39 +483,14 @@ func (cch *CrossChainHelper) VerifySideChainProofData(bs []byte) error {
 		return errors.New("invalid difficulty")
 	}
 
-	// special case: epoch 0 update
-	// TODO: how to verify this block which includes epoch 0?
-	if ncExtra.EpochBytes != nil && len(ncExtra.EpochBytes) != 0 {
-		ep := epoch.FromBytes(ncExtra.EpochBytes)
-		if ep != nil && ep.Number == 0 {
-			return nil
-		}
+	if cch.sideChainProofForked() {
+		err = cch.verifySideChainHeader(header, ncExtra)
+	} else if chainId != "side_0" {
+		// Bypass the validator check for official side chain 0 before the fork
+		err = cch.verifySideChainHeaderLegacy(ncExtra)
 	}
-
-	// Bypass the validator check for official side chain 0
-	// TODO where need
-	if chainId != "side_0" {
-		ci := core.GetChainInfo(cch.chainInfoDB, chainId)
-		if ci == nil {
-			return fmt.Errorf("chain info %s not found", chainId)
-		}
-		epoch := ci.GetEpochByBlockNumber(ncExtra.Height)
-		if epoch == nil {
-			return fmt.Errorf("could not get epoch for block height %v", ncExtra.Height)
-		}
-		valSet := epoch.Validators
-		if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
-			return errors.New("inconsistent validator set")
-		}
-
-		seenCommit := ncExtra.SeenCommit
-		if !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
-			return errors.New("invalid committed seals")
-		}
-
-		if err = valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
-			return err
-		}
+	if err != nil {
+		return err
 	}
 
 	log.Debug("VerifySideChainProofData - end")
@@ -453,6 +517,16 @@ func (cch *CrossChainHelper) SaveSideChainProofDataToMainChain(bs []byte) error
 		return fmt.Errorf("invalid side chain id: %s", chainId)
 	}
 
+	// the final block of a closed side chain, its state root is anchored for the settlement
+	if closure := core.GetSideChainClosure(cch.chainInfoDB, chainId); closure != nil &&
+		closure.HaltHeight == ncExtra.Height && !closure.Settled() {
+		closure.FinalHash = header.Hash()
+		closure.FinalRoot = header.Root
+		closure.SettleBlock = cch.GetHeightFromMainChain().Uint64()
+		core.SaveSideChainClosure(cch.chainInfoDB, closure)
+		log.Infof("Final state of side chain %s anchored, root: %x, settlement ends at block %v", chainId, closure.FinalRoot, closure.SettlementEnd())
+	}
+
 	// here is epoch update; should be a more general mechanism
 	if len(ncExtra.EpochBytes) != 0 {
 		ep := epoch.FromBytes(ncExtra.EpochBytes)
@@ -524,34 +598,12 @@ func (cch *CrossChainHelper) ValidateTX3ProofData(proofData *types.TX3ProofData)
 		return errors.New("invalid difficulty")
 	}
 
-	// special case: epoch 0 update
-	// TODO: how to verify this block which includes epoch 0?
-	if ncExtra.EpochBytes != nil && len(ncExtra.EpochBytes) != 0 {
-		ep := epoch.FromBytes(ncExtra.EpochBytes)
-		if ep != nil && ep.Number == 0 {
-			return nil
-		}
-	}
-
-	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
-	if ci == nil {
-		return fmt.Errorf("chain info %s not found", chainId)
-	}
-	epoch := ci.GetEpochByBlockNumber(ncExtra.Height)
-	if epoch == nil {
-		return fmt.Errorf("could not get epoch for block height %v", ncExtra.Height)
-	}
-	valSet := epoch.Validators
-	if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
-		return errors.New("inconsistent validator set")
-	}
-
-	seenCommit := ncExtra.SeenCommit
-	if !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
-		return errors.New("invalid committed seals")
+	if cch.sideChainProofForked() {
+		err = cch.verifySideChainHeader(header, ncExtra)
+	} else {
+		err = cch.verifySideChainHeaderLegacy(ncExtra)
 	}
-
-	if err = valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
+	if err != nil {
 		return err
 	}
 
@@ -802,6 +854,155 @@ func (cch *CrossChainHelper) ValidateTX4WithInMemTX3ProofData(tx4 *types.Transac
 //	return nil
 //}
 
+// ValidateCrossChainMessageProof checks the header of the proof is committed by the source
+// chain of the message, and the message is in the outbox at the state of the header.
+// Headers of the main chain are checked against the local main chain, headers of the side
+// chains against the validators of their epoch.
+func (cch *CrossChainHelper) ValidateCrossChainMessageProof(proof *types.CrossChainMessageProof) error {
+	if proof.Header == nil || proof.Message == nil {
+		return errors.New("incomplete cross chain message proof")
+	}
+
+	header := proof.Header
+	ncExtra, err := ntcTypes.ExtractNeatConExtra(header)
+	if err != nil {
+		return err
+	}
+	if types.ChainIdHash(ncExtra.ChainID) != proof.Message.SourceChain {
+		return fmt.Errorf("header of chain %s is not the source of the message", ncExtra.ChainID)
+	}
+
+	if ncExtra.ChainID == cch.mainChainId {
+		neatchain := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode)
+		canonical := neatchain.BlockChain().GetHeaderByNumber(header.Number.Uint64())
+		if canonical == nil || canonical.Hash() != header.Hash() {
+			return errors.New("header not in the main chain")
+		}
+	} else if err := cch.verifySideChainHeader(header, ncExtra); err != nil {
+		return err
+	}
+
+	return core.VerifyCrossChainMessageProof(proof)
+}
+
+// ValidateReceiptProofData checks the header of the side chain is committed by its validators,
+// and the txs and receipts of the proof are in the block
+func (cch *CrossChainHelper) ValidateReceiptProofData(proofData *types.ReceiptProofData) error {
+	if proofData.Header == nil {
+		return errors.New("receipt proof data without header")
+	}
+
+	ncExtra, err := ntcTypes.ExtractNeatConExtra(proofData.Header)
+	if err != nil {
+		return err
+	}
+	if err := cch.verifySideChainHeader(proofData.Header, ncExtra); err != nil {
+		return err
+	}
+
+	_, _, err = proofData.VerifyProofs()
+	return err
+}
+
+// verifySideChainHeader checks the header of the side chain is committed by the validators
+// of its epoch
+func (cch *CrossChainHelper) verifySideChainHeader(header *types.Header, ncExtra *ntcTypes.NeatConExtra) error {
+	chainId := ncExtra.ChainID
+	if chainId == "" || chainId == MainChain || chainId == TestnetChain {
+		return fmt.Errorf("invalid side chain id: %s", chainId)
+	}
+	if ncExtra.Height != header.Number.Uint64() {
+		return fmt.Errorf("wrong height %v in extra data of header %v", ncExtra.Height, header.Number)
+	}
+
+	ci := core.GetChainInfo(cch.chainInfoDB, chainId)
+	if ci == nil {
+		return fmt.Errorf("chain info %s not found", chainId)
+	}
+	valSet, err := cch.sideChainValidators(ci, ncExtra.Height)
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
+		return errors.New("inconsistent validator set")
+	}
+
+	seenCommit := ncExtra.SeenCommit
+	if seenCommit == nil || !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
+		return errors.New("invalid committed seals")
+	}
+	// NeedToSave and NeedToBroadcast are set once the block is committed,
+	// the commit signs the extra data as it was proposed
+	proposed := ncExtra.Copy()
+	proposed.NeedToSave, proposed.NeedToBroadcast = false, false
+	if !bytes.Equal(seenCommit.BlockID.Hash, proposed.Hash()) {
+		return errors.New("invalid committed seals")
+	}
+
+	return valSet.VerifyCommit(chainId, ncExtra.Height, seenCommit)
+}
+
+// sideChainProofForked tells whether the block on top of the main chain passed the SideChainProof
+// fork, the proofs of epoch 0 and side_0 are verified against the anchored genesis validators after it
+func (cch *CrossChainHelper) sideChainProofForked() bool {
+	bc := MustGetNeatChainFromNode(chainMgr.mainChain.NeatNode).BlockChain()
+	return bc.Config().IsSideChainProof(new(big.Int).Add(bc.CurrentBlock().Number(), common.Big1))
+}
+
+// verifySideChainHeaderLegacy is the check of the side chain headers before the SideChainProof fork
+func (cch *CrossChainHelper) verifySideChainHeaderLegacy(ncExtra *ntcTypes.NeatConExtra) error {
+	// special case: epoch 0 update
+	if len(ncExtra.EpochBytes) != 0 {
+		ep := epoch.FromBytes(ncExtra.EpochBytes)
+		if ep != nil && ep.Number == 0 {
+			return nil
+		}
+	}
+
+	ci := core.GetChainInfo(cch.chainInfoDB, ncExtra.ChainID)
+	if ci == nil {
+		return fmt.Errorf("chain info %s not found", ncExtra.ChainID)
+	}
+	ep := ci.GetEpochByBlockNumber(ncExtra.Height)
+	if ep == nil {
+		return fmt.Errorf("could not get epoch for block height %v", ncExtra.Height)
+	}
+	valSet := ep.Validators
+	if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
+		return errors.New("inconsistent validator set")
+	}
+
+	seenCommit := ncExtra.SeenCommit
+	if !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
+		return errors.New("invalid committed seals")
+	}
+
+	return valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit)
+}
+
+// sideChainValidators returns the validators committing the block of the side chain. The main
+// chain only learns the epochs of the side chain from its proofs, so the blocks of epoch 0 are
+// checked against the genesis validators anchored when the side chain was launched.
+func (cch *CrossChainHelper) sideChainValidators(ci *core.ChainInfo, height uint64) (*ntcTypes.ValidatorSet, error) {
+	ep := ci.GetEpochByBlockNumber(height)
+	if ep != nil && ep.Number > 0 {
+		if ep.Validators == nil {
+			return nil, fmt.Errorf("could not get epoch for block height %v", height)
+		}
+		return ep.Validators, nil
+	}
+	// A block after the start of the known epoch is never in epoch 0
+	if ep == nil && ci.Epoch != nil && ci.Epoch.Number > 0 && height >= ci.Epoch.StartBlock {
+		return nil, fmt.Errorf("could not get epoch for block height %v", height)
+	}
+
+	genesisEpoch := core.GetGenesisEpoch(cch.chainInfoDB, ci.ChainId)
+	if genesisEpoch == nil || genesisEpoch.Validators == nil || genesisEpoch.Validators.Size() == 0 {
+		return nil, fmt.Errorf("genesis validators of side chain %s not found", ci.ChainId)
+	}
+	return genesisEpoch.Validators, nil
+}
+
 // TX3LocalCache start
 func (cch *CrossChainHelper) GetTX3(chainId string, txHash common.Hash) *types.Transaction {
 	return rawdb.GetTX3(cch.localTX3CacheDB, chainId, txHash)
@@ -812,7 +1013,13 @@ func (cch *CrossChainHelper) DeleteTX3(chainId string, txHash common.Hash) {
 }
 
 func (cch *CrossChainHelper) WriteTX3ProofData(proofData *types.TX3ProofData) error {
-	return rawdb.WriteTX3ProofData(cch.localTX3CacheDB, proofData)
+	if err := rawdb.WriteTX3ProofData(cch.localTX3CacheDB, proofData); err != nil {
+		return err
+	}
+	if ncExtra, err := ntcTypes.ExtractNeatConExtra(proofData.Header); err == nil {
+		cch.transfers.indexProofData(ncExtra.ChainID, proofData)
+	}
+	return nil
 }
 
 func (cch *CrossChainHelper) GetTX3ProofData(chainId string, txHash common.Hash) *types.TX3ProofData {
@@ -825,6 +1032,21 @@ func (cch *CrossChainHelper) GetAllTX3ProofData() []*types.TX3ProofData {
 
 // TX3LocalCache end
 
+// GetCrossChainTransfer returns the stage of the withdraw (tx3) of the side chain
+func (cch *CrossChainHelper) GetCrossChainTransfer(chainId string, txHash common.Hash) *types.CrossChainTransfer {
+	return cch.transfers.get(chainId, txHash)
+}
+
+// GetPendingWithdrawals returns the withdraws of the address not claimed in the main chain
+func (cch *CrossChainHelper) GetPendingWithdrawals(from common.Address) []*types.CrossChainTransfer {
+	return cch.transfers.pending(from)
+}
+
+// SubscribeCrossChainTransferEvent registers a subscription of CrossChainTransferEvent
+func (cch *CrossChainHelper) SubscribeCrossChainTransferEvent(ch chan<- core.CrossChainTransferEvent) event.Subscription {
+	return cch.transfers.subscribe(ch)
+}
+
 func MustGetNeatChainFromNode(node *node.Node) *neatptc.NeatChain {
 	neatChain, err := getNeatChainFromNode(node)
 	if err != nil {
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/utilities/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	dbCommand = cli.Command{
+		Name:     "db",
+		Usage:    "Low level database operations",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "convert",
+				Usage:     "Convert the chain database to another engine",
+				ArgsUsage: "<chainname>",
+				Action:    utils.MigrateFlags(convertDB),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+					utils.DBEngineFlag,
+				},
+				Description: `
+neatchain db convert --db.engine boltdb <chainname>
+copies the chaindata of the chain into a new database of the engine, then deletes
+the old one. The ancient store is kept as is. The node must be stopped first.
+
+An interrupted conversion leaves the old database in use, it's started over when
+the command is run again.`,
+			},
+		},
+	}
+)
+
+func convertDB(ctx *cli.Context) error {
+	chainName := ctx.Args().First()
+	if chainName == "" {
+		utils.Fatalf("This command requires chain name specified.")
+	}
+	engine := ctx.GlobalString(utils.DBEngineFlag.Name)
+	if engine != rawdb.EngineLevelDB && engine != rawdb.EngineBoltDB {
+		utils.Fatalf("--%s must be either '%s' or '%s'", utils.DBEngineFlag.Name, rawdb.EngineLevelDB, rawdb.EngineBoltDB)
+	}
+
+	stack, _ := makeConfigNode(ctx, chainName)
+	defer stack.Close()
+
+	var (
+		dir   = stack.ResolvePath("chaindata")
+		cache = ctx.GlobalInt(utils.CacheFlag.Name) * ctx.GlobalInt(utils.CacheDatabaseFlag.Name) / 100
+		start = time.Now()
+	)
+	if err := rawdb.ConvertDatabase(dir, engine, cache, 0); err != nil {
+		utils.Fatalf("Failed to convert database: %v", err)
+	}
+	fmt.Printf("Database converted to %s in %v\n", engine, time.Since(start))
+	return nil
+}
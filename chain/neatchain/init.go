@@ -38,6 +38,8 @@ const (
 
 	TotalYear = 10
 
+	UnbondingPeriod = 2 // epochs the refunds wait in the unbonding queue
+
 	DefaultAccountPassword = "neatchain"
 )
 
@@ -198,10 +200,11 @@ func init_neatchain(chainId string, neatGenesisPath string, ctx *cli.Context) {
 	dbPath := filepath.Join(utils.MakeDataDir(ctx), chainId, clientIdentifier, "/chaindata")
 	log.Infof("init_neatchain 0 with dbPath: %s", dbPath)
 
-	chainDb, err := rawdb.NewLevelDBDatabase(filepath.Join(utils.MakeDataDir(ctx), chainId, clientIdentifier, "/chaindata"), 0, 0, "neatchain/db/chaindata/")
+	kvdb, err := rawdb.NewKeyValueStore(dbPath, ctx.GlobalString(utils.DBEngineFlag.Name), 0, 0, "neatchain/db/chaindata/")
 	if err != nil {
 		utils.Fatalf("could not open database: %v", err)
 	}
+	chainDb := rawdb.NewDatabase(kvdb)
 	defer chainDb.Close()
 
 	log.Info("init_neatchain 1")
@@ -292,6 +295,7 @@ func createGenesisDoc(config cfg.Config, chainId string, coreGenesis *core.Genes
 				RewardFirstYear:    rewardFirstYear,
 				EpochNumberPerYear: 4380,
 				TotalYear:          uint64(totalYear),
+				UnbondingPeriod:    UnbondingPeriod,
 			}
 		} else {
 			rewardScheme = types.RewardSchemeDoc{
@@ -299,6 +303,7 @@ func createGenesisDoc(config cfg.Config, chainId string, coreGenesis *core.Genes
 				RewardFirstYear:    big.NewInt(0),
 				EpochNumberPerYear: 12,
 				TotalYear:          0,
+				UnbondingPeriod:    UnbondingPeriod,
 			}
 		}
 
@@ -350,6 +355,7 @@ func generateNTCGenesis(sideChainID string, validators []types.GenesisValidator)
 		RewardFirstYear:    big.NewInt(0),
 		EpochNumberPerYear: 12,
 		TotalYear:          0,
+		UnbondingPeriod:    UnbondingPeriod,
 	}
 
 	genDoc := types.GenesisDoc{
@@ -449,9 +455,9 @@ func checkAccount(coreGenesis core.Genesis) (common.Address, *big.Int, error) {
 	return act, amount, nil
 }
 
-func initEthGenesisFromExistValidator(sideChainID string, sideConfig cfg.Config, validators []types.GenesisValidator) error {
+func initEthGenesisFromExistValidator(sideChainID string, sideConfig cfg.Config, validators []types.GenesisValidator, forks *params.SideChainForks) error {
 
-	contents, err := generateETHGenesis(sideChainID, validators)
+	contents, err := generateETHGenesis(sideChainID, validators, forks)
 	if err != nil {
 		return err
 	}
@@ -463,9 +469,9 @@ func initEthGenesisFromExistValidator(sideChainID string, sideConfig cfg.Config,
 	return nil
 }
 
-func generateETHGenesis(sideChainID string, validators []types.GenesisValidator) ([]byte, error) {
+func generateETHGenesis(sideChainID string, validators []types.GenesisValidator, forks *params.SideChainForks) ([]byte, error) {
 	var coreGenesis = core.Genesis{
-		Config:     params.NewSideChainConfig(sideChainID),
+		Config:     params.NewSideChainConfig(sideChainID, forks),
 		Nonce:      0xdeadbeefdeadbeef,
 		Timestamp:  0x0,
 		ParentHash: common.Hash{},
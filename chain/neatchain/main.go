@@ -33,10 +33,13 @@ var (
 		utils.IdentityFlag,
 		utils.UnlockedAccountFlag,
 		utils.PasswordFileFlag,
+		utils.ValidatorPasswordFileFlag,
 		utils.BootnodesFlag,
 		utils.BootnodesV4Flag,
 		utils.BootnodesV5Flag,
 		utils.DataDirFlag,
+		utils.AncientFlag,
+		utils.DBEngineFlag,
 		utils.KeyStoreDirFlag,
 		utils.NoUSBFlag,
 		utils.TxPoolNoLocalsFlag,
@@ -51,7 +54,11 @@ var (
 		utils.TxPoolLifetimeFlag,
 		//utils.FastSyncFlag,
 		utils.SyncModeFlag,
+		utils.LightPeersFlag,
+		utils.SnapshotFlag,
 		utils.GCModeFlag,
+		utils.PruneFlag,
+		utils.PruneRetainFlag,
 		utils.CacheFlag,
 		utils.CacheDatabaseFlag,
 		utils.CacheTrieFlag,
@@ -78,6 +85,8 @@ var (
 		utils.RPCVirtualHostsFlag,
 		//utils.EthStatsURLFlag,
 		utils.MetricsEnabledFlag,
+		utils.MetricsHTTPFlag,
+		utils.MetricsPortFlag,
 		utils.NoCompactionFlag,
 		utils.GpoBlocksFlag,
 		utils.GpoPercentileFlag,
@@ -119,6 +128,10 @@ func init() {
 		copydbCommand,
 		removedbCommand,
 		dumpCommand,
+		// See dbcmd.go:
+		dbCommand,
+		// See snapshotcmd.go:
+		snapshotCommand,
 		// See monitorcmd.go:
 		monitorCommand,
 		// See accountcmd.go:
@@ -131,6 +144,10 @@ func init() {
 		// See misccmd.go:
 
 		bugCommand,
+		// See walcmd.go
+		wal2jsonCommand,
+		signerCommand,
+		validatorCommand,
 		// See config.go
 		dumpConfigCommand,
 		versionCommand,
@@ -156,6 +173,8 @@ func init() {
 		// Start system runtime metrics collection
 		go metrics.CollectProcessMetrics(3 * time.Second)
 
+		utils.SetupMetrics(ctx)
+
 		return nil
 	}
 
@@ -10,6 +10,7 @@ import (
 	"github.com/Gessiux/neatchain/chain/consensus/neatcon/consensus"
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/internal/debug"
+	"github.com/Gessiux/neatchain/neatptc/downloader"
 	"github.com/Gessiux/neatchain/utilities/utils"
 	"gopkg.in/urfave/cli.v1"
 )
@@ -28,6 +29,13 @@ func neatchainCmd(ctx *cli.Context) error {
 	// SideChainFlag flag
 	requestSideChain := strings.Split(ctx.GlobalString(utils.SideChainFlag.Name), ",")
 
+	// The light client follows the main chain headers only
+	lightSync := ctx.GlobalIsSet(utils.SyncModeFlag.Name) &&
+		*utils.GlobalTextMarshaler(ctx, utils.SyncModeFlag.Name).(*downloader.SyncMode) == downloader.LightSync
+
+	// Unlock the encrypted consensus key before the chains start
+	unlockPrivValidator(ctx)
+
 	// Initial P2P Server
 	chainMgr.InitP2P()
 
@@ -52,18 +60,20 @@ func neatchainCmd(ctx *cli.Context) error {
 	// Start Main Chain
 	err = chainMgr.StartMainChain()
 
-	// Load Side Chain
-	err = chainMgr.LoadChains(requestSideChain)
-	if err != nil {
-		log.Errorf("Load Side Chains failed. %v", err)
-		return err
-	}
+	if !lightSync {
+		// Load Side Chain
+		err = chainMgr.LoadChains(requestSideChain)
+		if err != nil {
+			log.Errorf("Load Side Chains failed. %v", err)
+			return err
+		}
 
-	// Start Side Chain
-	err = chainMgr.StartChains()
-	if err != nil {
-		log.Error("start chains failed")
-		return err
+		// Start Side Chain
+		err = chainMgr.StartChains()
+		if err != nil {
+			log.Error("start chains failed")
+			return err
+		}
 	}
 
 	err = chainMgr.StartRPC()
@@ -72,7 +82,9 @@ func neatchainCmd(ctx *cli.Context) error {
 		return err
 	}
 
-	chainMgr.StartInspectEvent()
+	if !lightSync {
+		chainMgr.StartInspectEvent()
+	}
 
 	go func() {
 		sigc := make(chan os.Signal, 1)
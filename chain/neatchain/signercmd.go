@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/utilities/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	signerListenAddrFlag = cli.StringFlag{
+		Name:  "signer.laddr",
+		Usage: `Signer listening address, "tcp://host:port" or "unix:///path/to/socket"`,
+		Value: "tcp://127.0.0.1:46659",
+	}
+	signerKeyFileFlag = cli.StringFlag{
+		Name:  "signer.key",
+		Usage: "Private validator file holding the consensus key (default = <datadir>/<chainname>/priv_validator.json)",
+	}
+	signerStateDirFlag = cli.StringFlag{
+		Name:  "signer.statedir",
+		Usage: "Directory of the last signed height/round/step of every chain (default = <datadir>/signer)",
+	}
+	signerTLSCertFlag = cli.StringFlag{
+		Name:  "signer.tlscert",
+		Usage: "TLS certificate of the signer (default = <datadir>/signer/cert.pem)",
+	}
+	signerTLSKeyFlag = cli.StringFlag{
+		Name:  "signer.tlskey",
+		Usage: "TLS private key of the signer (default = <datadir>/signer/key.pem)",
+	}
+	signerTLSCAFlag = cli.StringFlag{
+		Name:  "signer.tlsca",
+		Usage: "CA certificate the validators' TLS certificates are issued by (default = <datadir>/signer/ca.pem)",
+	}
+
+	signerCommand = cli.Command{
+		Action:    utils.MigrateFlags(signer),
+		Name:      "signer",
+		Usage:     "Run a remote signer holding the consensus key",
+		ArgsUsage: " ",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The signer command keeps the consensus key off the validator host. It serves the sign
+requests of the validator over a mutually authenticated TLS connection, both sides must
+present a certificate issued by the same CA. The last signed height/round/step of every
+chain is persisted, conflicting votes or proposals are never signed.
+
+The validator connects to the signer if priv_validator_laddr is set in config.toml, the
+certificate is set by priv_validator_tls_cert, priv_validator_tls_key and priv_validator_tls_ca.`,
+		Flags: []cli.Flag{
+			utils.DataDirFlag,
+			utils.TestnetFlag,
+			utils.ValidatorPasswordFileFlag,
+			signerListenAddrFlag,
+			signerKeyFileFlag,
+			signerStateDirFlag,
+			signerTLSCertFlag,
+			signerTLSKeyFlag,
+			signerTLSCAFlag,
+		},
+	}
+)
+
+func signer(ctx *cli.Context) error {
+	datadir := utils.MakeDataDir(ctx)
+	chainId := mainChainId(ctx)
+	orDefault := func(flag cli.StringFlag, def string) string {
+		if value := ctx.String(flag.Name); value != "" {
+			return value
+		}
+		return def
+	}
+
+	keyFile := orDefault(signerKeyFileFlag, filepath.Join(datadir, chainId, "priv_validator.json"))
+	if _, err := os.Stat(keyFile); err != nil {
+		utils.Fatalf("Failed to open private validator file: %v", err)
+	}
+	privValidator := types.LoadPrivValidator(keyFile)
+	if privValidator.IsLocked() {
+		unlockValidatorKey(privValidator, utils.MakeValidatorPasswordList(ctx))
+	}
+
+	tlsConfig, err := types.LoadSignerTLSConfig(
+		orDefault(signerTLSCertFlag, filepath.Join(datadir, "signer", "cert.pem")),
+		orDefault(signerTLSKeyFlag, filepath.Join(datadir, "signer", "key.pem")),
+		orDefault(signerTLSCAFlag, filepath.Join(datadir, "signer", "ca.pem")))
+	if err != nil {
+		utils.Fatalf("Failed to load TLS certificate: %v", err)
+	}
+
+	stateDir := orDefault(signerStateDirFlag, filepath.Join(datadir, "signer"))
+	server := types.NewSignerServer(ctx.String(signerListenAddrFlag.Name), tlsConfig, privValidator, stateDir, log.New("module", "signer"))
+	if err := server.Start(); err != nil {
+		utils.Fatalf("Failed to start signer: %v", err)
+	}
+
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, syscall.SIGINT, syscall.SIGTERM)
+	<-sigc
+	log.Info("Got interrupt, shutting down signer...")
+	server.Stop()
+	return nil
+}
@@ -0,0 +1,139 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	dbm "github.com/Gessiux/go-db"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state/pruner"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	bloomFilterSizeFlag = cli.Uint64Flag{
+		Name:  "bloomfilter.size",
+		Usage: "Megabytes of memory allocated to the bloom filter of the kept state",
+		Value: 2048,
+	}
+	pruneEpochsFlag = cli.Uint64Flag{
+		Name:  "prune.epochs",
+		Usage: "Number of previous epochs whose last state is kept as well",
+		Value: 0,
+	}
+
+	snapshotCommand = cli.Command{
+		Name:     "snapshot",
+		Usage:    "A set of commands based on the state of the chain",
+		Category: "BLOCKCHAIN COMMANDS",
+		Subcommands: []cli.Command{
+			{
+				Name:      "prune-state",
+				Usage:     "Prune the stale state data offline",
+				ArgsUsage: "<chainname>",
+				Action:    utils.MigrateFlags(pruneState),
+				Category:  "BLOCKCHAIN COMMANDS",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.CacheFlag,
+					utils.CacheDatabaseFlag,
+					bloomFilterSizeFlag,
+					pruneEpochsFlag,
+				},
+				Description: `
+neatchain snapshot prune-state <chainname>
+will keep the state of the head block, and the last state of the previous epochs
+specified by --prune.epochs, then delete all the other trie nodes and compact the
+database. The node must be stopped first.
+
+An interrupted pruning could be resumed by running the command again, the states
+kept by the interrupted run are kept as well.`,
+			},
+		},
+	}
+)
+
+func pruneState(ctx *cli.Context) error {
+	chainName := ctx.Args().First()
+	if chainName == "" {
+		utils.Fatalf("This command requires chain name specified.")
+	}
+
+	stack, _ := makeConfigNode(ctx, chainName)
+	defer stack.Close()
+
+	db := utils.MakeChainDatabase(ctx, stack)
+	defer db.Close()
+
+	head := headStateNumber(db)
+	roots := []common.Hash{stateRoot(db, head)}
+	if epochs := ctx.GlobalUint64(pruneEpochsFlag.Name); epochs > 0 {
+		roots = append(roots, epochStateRoots(ctx, db, chainName, head, epochs)...)
+	}
+
+	start := time.Now()
+	if err := pruner.NewPruner(db, ctx.GlobalUint64(bloomFilterSizeFlag.Name)).Prune(roots); err != nil {
+		utils.Fatalf("Failed to prune state: %v", err)
+	}
+	fmt.Printf("State pruning done in %v\n", time.Since(start))
+	return nil
+}
+
+// headStateNumber finds the latest block whose state is on disk, the states of the
+// latest blocks may not be flushed before the node stopped.
+func headStateNumber(db neatdb.Database) uint64 {
+	hash := rawdb.ReadHeadBlockHash(db)
+	number := rawdb.ReadHeaderNumber(db, hash)
+	if number == nil {
+		utils.Fatalf("Head block missing, the chain is not initialized")
+	}
+	for n := *number; ; n-- {
+		if root := stateRoot(db, n); root != (common.Hash{}) {
+			if has, _ := db.Has(root[:]); has {
+				return n
+			}
+		}
+		if n == 0 {
+			utils.Fatalf("No state of the canonical chain on disk")
+		}
+	}
+}
+
+func stateRoot(db neatdb.Database, number uint64) common.Hash {
+	hash := rawdb.ReadCanonicalHash(db, number)
+	if header := rawdb.ReadHeader(db, hash, number); header != nil {
+		return header.Root
+	}
+	return common.Hash{}
+}
+
+// epochStateRoots retrieves the state roots at the end of the epochs before the head
+func epochStateRoots(ctx *cli.Context, db neatdb.Database, chainName string, head, epochs uint64) []common.Hash {
+	config := utils.GetNeatConConfig(chainName, ctx)
+	epochDB := dbm.NewDB("epoch", config.GetString("db_backend"), config.GetString("db_dir"))
+	defer epochDB.Close()
+
+	latest := epoch.LoadLatestEpoch(epochDB, log.Root())
+	if latest == nil {
+		log.Warn("No epoch found, only the head state is kept")
+		return nil
+	}
+	cur := latest.GetEpochByBlockNumber(head)
+
+	var roots []common.Hash
+	for i := uint64(0); i < epochs && cur != nil && cur.StartBlock > 0; i++ {
+		cur = latest.GetEpochByBlockNumber(cur.StartBlock - 1)
+		if cur == nil {
+			break
+		}
+		if root := stateRoot(db, cur.EndBlock); root != (common.Hash{}) {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
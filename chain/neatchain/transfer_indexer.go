@@ -0,0 +1,198 @@
+package main
+
+import (
+	"bytes"
+	"sync"
+
+	dbm "github.com/Gessiux/go-db"
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/chain/trie"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/event"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// transferIndexer follows the withdraws from the side chains to the main chain. It links
+// each tx3 of a side chain to the tx4 claiming it in the main chain, the withdraws are
+// indexed from the blocks of the chains running locally and from the tx3 proof data
+// received by the main chain.
+type transferIndexer struct {
+	db          neatdb.Database
+	chainInfoDB dbm.DB
+
+	mu    sync.Mutex // Serializes the stage updates
+	feed  event.Feed
+	scope event.SubscriptionScope
+}
+
+func newTransferIndexer(db neatdb.Database, chainInfoDB dbm.DB) *transferIndexer {
+	return &transferIndexer{
+		db:          db,
+		chainInfoDB: chainInfoDB,
+	}
+}
+
+// watchChain indexes the claims (tx4) of the blocks of the main chain, or the withdraws
+// (tx3) of the blocks of a side chain
+func (ti *transferIndexer) watchChain(chainId string, bc *core.BlockChain, main bool) {
+	ch := make(chan core.ChainEvent, 64)
+	sub := bc.SubscribeChainEvent(ch)
+
+	go func() {
+		defer sub.Unsubscribe()
+		for {
+			select {
+			case ev := <-ch:
+				if main {
+					ti.indexClaims(ev.Block)
+				} else {
+					ti.indexWithdraws(chainId, ev.Block)
+				}
+			case <-sub.Err():
+				return
+			}
+		}
+	}()
+}
+
+// indexWithdraws marks the withdraws of the side chain block as submitted
+func (ti *transferIndexer) indexWithdraws(chainId string, block *types.Block) {
+	for _, tx := range block.Transactions() {
+		if !isChainFunction(tx, neatAbi.WithdrawFromSideChain) {
+			continue
+		}
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			continue
+		}
+		ti.update(&types.CrossChainTransfer{
+			ChainId:     chainId,
+			TxHash:      tx.Hash(),
+			From:        from,
+			Amount:      tx.Value(),
+			Stage:       types.TransferSubmitted,
+			SubmitBlock: block.NumberU64(),
+		})
+	}
+}
+
+// indexProofData marks the withdraws proven by the tx3 proof data as broadcast
+func (ti *transferIndexer) indexProofData(chainId string, proofData *types.TX3ProofData) {
+	header := proofData.Header
+	keybuf := new(bytes.Buffer)
+	for i, txIndex := range proofData.TxIndexs {
+		keybuf.Reset()
+		rlp.Encode(keybuf, txIndex)
+		val, _, err := trie.VerifyProof(header.TxHash, keybuf.Bytes(), proofData.TxProofs[i])
+		if err != nil {
+			continue
+		}
+		var tx types.Transaction
+		if err := rlp.DecodeBytes(val, &tx); err != nil || !isChainFunction(&tx, neatAbi.WithdrawFromSideChain) {
+			continue
+		}
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), &tx)
+		if err != nil {
+			continue
+		}
+		ti.update(&types.CrossChainTransfer{
+			ChainId:     chainId,
+			TxHash:      tx.Hash(),
+			From:        from,
+			Amount:      tx.Value(),
+			Stage:       types.TransferProofBroadcast,
+			SubmitBlock: header.Number.Uint64(),
+		})
+	}
+}
+
+// indexClaims marks the withdraws claimed by the main chain block
+func (ti *transferIndexer) indexClaims(block *types.Block) {
+	for _, tx := range block.Transactions() {
+		if !isChainFunction(tx, neatAbi.WithdrawFromMainChain) {
+			continue
+		}
+		var args neatAbi.WithdrawFromMainChainArgs
+		if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.WithdrawFromMainChain.String(), tx.Data()[4:]); err != nil {
+			continue
+		}
+		from, err := types.Sender(types.NewEIP155Signer(tx.ChainId()), tx)
+		if err != nil {
+			continue
+		}
+		ti.update(&types.CrossChainTransfer{
+			ChainId:     args.ChainId,
+			TxHash:      args.TxHash,
+			From:        from,
+			Amount:      args.Amount,
+			Stage:       types.TransferClaimed,
+			ClaimTxHash: tx.Hash(),
+			ClaimBlock:  block.NumberU64(),
+		})
+	}
+}
+
+// update stores the transfer if it moves the indexed one forward, the stage never goes back
+func (ti *transferIndexer) update(transfer *types.CrossChainTransfer) {
+	ti.mu.Lock()
+	if current := rawdb.ReadCrossChainTransfer(ti.db, transfer.ChainId, transfer.TxHash); current != nil {
+		if current.Stage >= transfer.Stage {
+			ti.mu.Unlock()
+			return
+		}
+		if transfer.SubmitBlock == 0 {
+			transfer.SubmitBlock = current.SubmitBlock
+		}
+	}
+	rawdb.WriteCrossChainTransfer(ti.db, transfer)
+	ti.mu.Unlock()
+
+	log.Debug("Cross chain transfer updated", "chain", transfer.ChainId, "hash", transfer.TxHash, "stage", transfer.Stage)
+	ti.feed.Send(core.CrossChainTransferEvent{Transfer: transfer})
+}
+
+// expire marks the withdraw not claimed as expired if its side chain doesn't exist anymore
+func (ti *transferIndexer) expire(transfer *types.CrossChainTransfer) *types.CrossChainTransfer {
+	if transfer.Stage != types.TransferClaimed && core.GetChainInfo(ti.chainInfoDB, transfer.ChainId) == nil {
+		transfer.Stage = types.TransferExpired
+	}
+	return transfer
+}
+
+func (ti *transferIndexer) get(chainId string, txHash common.Hash) *types.CrossChainTransfer {
+	transfer := rawdb.ReadCrossChainTransfer(ti.db, chainId, txHash)
+	if transfer == nil {
+		return nil
+	}
+	return ti.expire(transfer)
+}
+
+func (ti *transferIndexer) pending(from common.Address) []*types.CrossChainTransfer {
+	transfers := rawdb.ReadPendingCrossChainTransfers(ti.db, from)
+	for _, transfer := range transfers {
+		ti.expire(transfer)
+	}
+	return transfers
+}
+
+func (ti *transferIndexer) subscribe(ch chan<- core.CrossChainTransferEvent) event.Subscription {
+	return ti.scope.Track(ti.feed.Subscribe(ch))
+}
+
+func (ti *transferIndexer) close() {
+	ti.scope.Close()
+}
+
+// isChainFunction checks the tx calls the function of the chain contract
+func isChainFunction(tx *types.Transaction, function neatAbi.FunctionType) bool {
+	if !neatAbi.IsNeatChainContractAddr(tx.To()) || len(tx.Data()) < 4 {
+		return false
+	}
+	f, err := neatAbi.FunctionTypeFromId(tx.Data()[:4])
+	return err == nil && f == function
+}
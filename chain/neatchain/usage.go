@@ -67,12 +67,17 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			configFileFlag,
 			utils.DataDirFlag,
+			utils.AncientFlag,
+			utils.DBEngineFlag,
 			utils.KeyStoreDirFlag,
 			utils.NoUSBFlag,
 			utils.NetworkIdFlag,
 			utils.TestnetFlag,
 			utils.SyncModeFlag,
+			utils.LightPeersFlag,
+			utils.SnapshotFlag,
 			utils.GCModeFlag,
+			utils.PruneRetainFlag,
 			utils.EthStatsURLFlag,
 			utils.IdentityFlag,
 		},
@@ -106,6 +111,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Flags: []cli.Flag{
 			utils.UnlockedAccountFlag,
 			utils.PasswordFileFlag,
+			utils.ValidatorPasswordFileFlag,
 		},
 	},
 	{
@@ -175,6 +181,8 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "LOGGING AND DEBUGGING",
 		Flags: append([]cli.Flag{
 			utils.MetricsEnabledFlag,
+			utils.MetricsHTTPFlag,
+			utils.MetricsPortFlag,
 			utils.NoCompactionFlag,
 		}, debug.Flags...),
 	},
@@ -182,6 +190,7 @@ var AppHelpFlagGroups = []flagGroup{
 		Name: "DEPRECATED",
 		Flags: []cli.Flag{
 			utils.FastSyncFlag,
+			utils.PruneFlag,
 		},
 	},
 }
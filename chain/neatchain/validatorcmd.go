@@ -0,0 +1,185 @@
+package main
+
+import (
+	"os"
+
+	"github.com/Gessiux/neatchain/chain/accounts/keystore"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon"
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/utilities/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	validatorCommand = cli.Command{
+		Name:     "validator",
+		Usage:    "Manage the encryption of the consensus key",
+		Category: "ACCOUNT COMMANDS",
+		Description: `
+The consensus private key in priv_validator.json could be encrypted the same way as the
+account keystore (scrypt and aes-128-ctr). The passphrase of the encrypted key is asked
+when neatchain starts, it can be given non-interactively by --validator.password.
+
+By default the priv_validator.json of the main chain is used, the side chains have their
+own copy of the file under <datadir>/<chainname>.`,
+		Subcommands: []cli.Command{
+			{
+				Name:      "encrypt",
+				Usage:     "Encrypt the consensus private key",
+				Action:    utils.MigrateFlags(validatorEncrypt),
+				ArgsUsage: "[<priv_validator file>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					utils.ValidatorPasswordFileFlag,
+				},
+				Description: `
+    neatchain validator encrypt [<priv_validator file>]
+
+Encrypts the plain consensus private key, you are prompted for a passphrase.
+
+For non-interactive use the passphrase can be specified with the --validator.password flag.`,
+			},
+			{
+				Name:      "decrypt",
+				Usage:     "Save the consensus private key as plain text",
+				Action:    utils.MigrateFlags(validatorDecrypt),
+				ArgsUsage: "[<priv_validator file>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					utils.ValidatorPasswordFileFlag,
+				},
+				Description: `
+    neatchain validator decrypt [<priv_validator file>]
+
+Decrypts the consensus private key and saves it as plain text, you are prompted for the passphrase.`,
+			},
+			{
+				Name:      "change-password",
+				Usage:     "Change the passphrase of the encrypted consensus private key",
+				Action:    utils.MigrateFlags(validatorChangePassword),
+				ArgsUsage: "[<priv_validator file>]",
+				Flags: []cli.Flag{
+					utils.DataDirFlag,
+					utils.TestnetFlag,
+					utils.ValidatorPasswordFileFlag,
+				},
+				Description: `
+    neatchain validator change-password [<priv_validator file>]
+
+You are prompted for the current passphrase and the new one.
+
+For non-interactive use the passphrases can be specified with the --validator.password flag,
+the first line is the current passphrase and the second line the new one.`,
+			},
+		},
+	}
+)
+
+// mainChainId returns the chain id of the main chain the node runs
+func mainChainId(ctx *cli.Context) string {
+	if ctx.GlobalBool(utils.TestnetFlag.Name) {
+		return TestnetChain
+	}
+	return MainChain
+}
+
+// loadPrivValidatorArg loads the priv_validator file given by the first argument,
+// or the one of the main chain
+func loadPrivValidatorArg(ctx *cli.Context) *types.PrivValidator {
+	privValFile := ctx.Args().First()
+	if privValFile == "" {
+		privValFile = utils.GetNeatConConfig(mainChainId(ctx), ctx).GetString("priv_validator_file")
+	}
+	if _, err := os.Stat(privValFile); err != nil {
+		utils.Fatalf("Failed to open private validator file: %v", err)
+	}
+	return types.LoadPrivValidator(privValFile)
+}
+
+// unlockValidatorKey decrypts the consensus key, the passphrase is tried up to 3 times
+func unlockValidatorKey(privVal *types.PrivValidator, passwords []string) string {
+	var err error
+	for trials := 0; trials < 3; trials++ {
+		prompt := "Unlocking the consensus key"
+		if trials > 0 {
+			prompt = "Wrong passphrase, try again"
+		}
+		password := getPassPhrase(prompt, false, 0, passwords)
+		if err = privVal.Unlock(password); err == nil {
+			return password
+		}
+		if err != keystore.ErrDecrypt || len(passwords) > 0 {
+			break
+		}
+	}
+	utils.Fatalf("Failed to unlock the consensus key: %v", err)
+	return ""
+}
+
+func validatorEncrypt(ctx *cli.Context) error {
+	privVal := loadPrivValidatorArg(ctx)
+	if privVal.IsEncrypted() {
+		utils.Fatalf("The consensus key is encrypted already")
+	}
+
+	password := getPassPhrase("Your consensus key is encrypted with a passphrase. Please give a passphrase. Do not forget this passphrase.", true, 0, utils.MakeValidatorPasswordList(ctx))
+	if err := privVal.Encrypt(password, keystore.StandardScryptN, keystore.StandardScryptP); err != nil {
+		utils.Fatalf("Failed to encrypt the consensus key: %v", err)
+	}
+	privVal.Save()
+	log.Info("The consensus key is encrypted")
+	return nil
+}
+
+func validatorDecrypt(ctx *cli.Context) error {
+	privVal := loadPrivValidatorArg(ctx)
+	if !privVal.IsEncrypted() {
+		utils.Fatalf("The consensus key is not encrypted")
+	}
+
+	unlockValidatorKey(privVal, utils.MakeValidatorPasswordList(ctx))
+	if err := privVal.RemoveEncryption(); err != nil {
+		utils.Fatalf("Failed to decrypt the consensus key: %v", err)
+	}
+	privVal.Save()
+	log.Info("The consensus key is saved as plain text")
+	return nil
+}
+
+func validatorChangePassword(ctx *cli.Context) error {
+	privVal := loadPrivValidatorArg(ctx)
+	if !privVal.IsEncrypted() {
+		utils.Fatalf("The consensus key is not encrypted")
+	}
+
+	passwords := utils.MakeValidatorPasswordList(ctx)
+	unlockValidatorKey(privVal, passwords)
+	newPassword := getPassPhrase("Please give a new passphrase. Do not forget this passphrase.", true, 1, passwords)
+	if err := privVal.Encrypt(newPassword, keystore.StandardScryptN, keystore.StandardScryptP); err != nil {
+		utils.Fatalf("Failed to encrypt the consensus key: %v", err)
+	}
+	privVal.Save()
+	log.Info("The passphrase of the consensus key is changed")
+	return nil
+}
+
+// unlockPrivValidator asks for the passphrase of the encrypted consensus key before the chains start,
+// the key of the side chains is unlocked by the same passphrase
+func unlockPrivValidator(ctx *cli.Context) {
+	config := utils.GetNeatConConfig(mainChainId(ctx), ctx)
+	if config.GetString("priv_validator_laddr") != "" {
+		return
+	}
+	privValFile := config.GetString("priv_validator_file")
+	if _, err := os.Stat(privValFile); err != nil {
+		return
+	}
+	privVal := types.LoadPrivValidator(privValFile)
+	if !privVal.IsLocked() {
+		return
+	}
+	neatcon.SetValidatorPassphrase(unlockValidatorKey(privVal, utils.MakeValidatorPasswordList(ctx)))
+}
@@ -0,0 +1,60 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon/consensus"
+	"github.com/Gessiux/neatchain/utilities/utils"
+	"gopkg.in/urfave/cli.v1"
+)
+
+var (
+	wal2jsonCommand = cli.Command{
+		Action:    utils.MigrateFlags(wal2json),
+		Name:      "wal2json",
+		Usage:     "Convert a consensus write-ahead log to json",
+		ArgsUsage: "<walfile>",
+		Category:  "MISCELLANEOUS COMMANDS",
+		Description: `
+The wal2json command reads the consensus write-ahead log (by default located at
+<datadir>/<chainname>/data/cs.wal/wal) offline and prints one json object per line,
+including the height markers, for inspection.`,
+	}
+)
+
+type walJSONLine struct {
+	Height *uint64     `json:"height,omitempty"`
+	Time   string      `json:"time,omitempty"`
+	Type   string      `json:"type,omitempty"`
+	Msg    interface{} `json:"msg,omitempty"`
+}
+
+func wal2json(ctx *cli.Context) error {
+	if len(ctx.Args()) < 1 {
+		utils.Fatalf("This command requires an argument.")
+	}
+
+	walFile := ctx.Args().First()
+	if _, err := os.Stat(walFile); err != nil {
+		utils.Fatalf("Failed to open wal file: %v", err)
+	}
+
+	encoder := json.NewEncoder(os.Stdout)
+	err := consensus.ReadWALFile(walFile, func(height uint64, isMarker bool, msg *consensus.TimedWALMessage) error {
+		if isMarker {
+			return encoder.Encode(walJSONLine{Height: &height})
+		}
+		return encoder.Encode(walJSONLine{
+			Time: msg.Time.String(),
+			Type: strings.TrimPrefix(fmt.Sprintf("%T", msg.Msg), "consensus."),
+			Msg:  msg.Msg,
+		})
+	})
+	if err != nil {
+		utils.Fatalf("Failed to read wal file: %v", err)
+	}
+	return nil
+}
@@ -87,6 +87,10 @@ type Database struct {
 	dirtiesSize   common.StorageSize // Storage size of the dirty node cache (exc. flushlist)
 	preimagesSize common.StorageSize // Storage size of the preimages cache
 
+	refcount bool                     // Whether the references of the nodes on disk are counted
+	refs     map[common.Hash]nodeRef  // Reference counts pending in the current batch
+	flushing map[common.Hash]struct{} // Nodes pending in the current batch
+
 	lock sync.RWMutex
 }
 
@@ -486,9 +490,18 @@ func (db *Database) Reference(side common.Hash, parent common.Hash) {
 
 // reference is the private locked version of Reference.
 func (db *Database) reference(side common.Hash, parent common.Hash) {
-	// If the node does not exist, it's a node pulled from disk, skip
+	// If the node does not exist, it's a node pulled from disk, skip. The reference
+	// counting still needs the link to count the reference once the parent is flushed.
 	node, ok := db.dirties[side]
 	if !ok {
+		if db.refcount && parent != (common.Hash{}) && db.dirties[parent] != nil {
+			if db.dirties[parent].sideren == nil {
+				db.dirties[parent].sideren = make(map[common.Hash]uint16)
+			}
+			if _, ok := db.dirties[parent].sideren[side]; !ok {
+				db.dirties[parent].sideren[side] = 1
+			}
+		}
 		return
 	}
 	// If the reference already exists, only duplicate for roots
@@ -612,6 +625,9 @@ func (db *Database) Cap(limit common.StorageSize) error {
 	for size > limit && oldest != (common.Hash{}) {
 		// Fetch the oldest referenced node and push into the batch
 		node := db.dirties[oldest]
+		if err := db.track(oldest, node, batch, true); err != nil {
+			return err
+		}
 		if err := batch.Put(oldest[:], node.rlp()); err != nil {
 			return err
 		}
@@ -622,6 +638,7 @@ func (db *Database) Cap(limit common.StorageSize) error {
 				return err
 			}
 			batch.Reset()
+			db.resetRefs()
 		}
 		// Iterate to the next flush item, or abort if the size cap was achieved. Size
 		// is the total size, including both the useful cached data (hash -> blob), as
@@ -635,6 +652,7 @@ func (db *Database) Cap(limit common.StorageSize) error {
 		log.Error("Failed to write flush list to disk", "err", err)
 		return err
 	}
+	db.resetRefs()
 	// Write successful, clear out the flushed data
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -715,6 +733,7 @@ func (db *Database) Commit(node common.Hash, report bool) error {
 		log.Error("Failed to write trie to disk", "err", err)
 		return err
 	}
+	db.resetRefs()
 	// Uncache any leftovers in the last batch
 	db.lock.Lock()
 	defer db.lock.Unlock()
@@ -756,6 +775,9 @@ func (db *Database) commit(hash common.Hash, batch neatdb.Batch, uncacher *clean
 			return err
 		}
 	}
+	if err := db.track(hash, node, batch, false); err != nil {
+		return err
+	}
 	if err := batch.Put(hash[:], node.rlp()); err != nil {
 		return err
 	}
@@ -764,6 +786,7 @@ func (db *Database) commit(hash common.Hash, batch neatdb.Batch, uncacher *clean
 		if err := batch.Write(); err != nil {
 			return err
 		}
+		db.resetRefs()
 		db.lock.Lock()
 		batch.Replay(uncacher)
 		batch.Reset()
@@ -0,0 +1,259 @@
+package trie
+
+import (
+	"encoding/binary"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+var (
+	// refcountEnabledKey marks the database as tracking the references of its trie nodes.
+	refcountEnabledKey = []byte("TrieRefcount")
+
+	refcountPrefix     = []byte("trie-rc-")  // refcountPrefix + hash -> references (uint32 big endian) + blob flag
+	externalRefsPrefix = []byte("trie-ext-") // externalRefsPrefix + hash -> hashes of the external children
+)
+
+// nodeRef is the persisted reference count of a node written to disk. Nodes written
+// before the reference counting got enabled are not tracked and never deleted.
+type nodeRef struct {
+	count   uint32
+	blob    bool // Raw blob (contract code) rather than a trie node
+	tracked bool
+}
+
+func refcountKey(hash common.Hash) []byte {
+	return append(append([]byte{}, refcountPrefix...), hash[:]...)
+}
+
+func externalRefsKey(hash common.Hash) []byte {
+	return append(append([]byte{}, externalRefsPrefix...), hash[:]...)
+}
+
+// RefcountEnabled checks whether the reference counting was ever enabled on the database.
+func RefcountEnabled(db neatdb.Reader) bool {
+	has, _ := db.Has(refcountEnabledKey)
+	return has
+}
+
+// EnableRefcount turns on the reference counting of the trie nodes written to disk,
+// the tries committed afterwards could be retained and released to delete the nodes
+// not referenced anymore. Once enabled the database keeps counting the references,
+// the counts would be wrong otherwise.
+func (db *Database) EnableRefcount() {
+	if err := db.diskdb.Put(refcountEnabledKey, []byte{1}); err != nil {
+		log.Error("Failed to enable trie reference counting", "err", err)
+		return
+	}
+	db.refcount = true
+	db.refs = make(map[common.Hash]nodeRef)
+	db.flushing = make(map[common.Hash]struct{})
+}
+
+// readRef retrieves the reference count of the node, the pending writes come first.
+func (db *Database) readRef(hash common.Hash) nodeRef {
+	if ref, ok := db.refs[hash]; ok {
+		return ref
+	}
+	enc, _ := db.diskdb.Get(refcountKey(hash))
+	if len(enc) != 5 {
+		return nodeRef{}
+	}
+	return nodeRef{count: binary.BigEndian.Uint32(enc), blob: enc[4] == 1, tracked: true}
+}
+
+// writeRef queues the reference count of the node into the batch.
+func (db *Database) writeRef(batch neatdb.Batch, hash common.Hash, ref nodeRef) error {
+	enc := make([]byte, 5)
+	binary.BigEndian.PutUint32(enc, ref.count)
+	if ref.blob {
+		enc[4] = 1
+	}
+	ref.tracked = true
+	db.refs[hash] = ref
+	return batch.Put(refcountKey(hash), enc)
+}
+
+// resetRefs drops the pending reference counts once the batch is written out.
+func (db *Database) resetRefs() {
+	if db.refcount {
+		db.refs = make(map[common.Hash]nodeRef)
+		db.flushing = make(map[common.Hash]struct{})
+	}
+}
+
+// track counts the references of a node flushed to disk. A node flushed ahead of the
+// trie it belongs to (by Cap) which is already on disk is pinned, as the nodes in memory
+// referencing it are not counted.
+func (db *Database) track(hash common.Hash, node *cachedNode, batch neatdb.Batch, ahead bool) error {
+	if !db.refcount {
+		return nil
+	}
+	ref := db.readRef(hash)
+
+	_, flushing := db.flushing[hash]
+	if has, _ := db.diskdb.Has(hash[:]); flushing || has {
+		if ahead && ref.tracked {
+			ref.count++
+			return db.writeRef(batch, hash, ref)
+		}
+		return nil
+	}
+	db.flushing[hash] = struct{}{}
+
+	_, ref.blob = node.node.(rawNode)
+	if err := db.writeRef(batch, hash, ref); err != nil {
+		return err
+	}
+	for _, child := range node.sides() {
+		if child == (common.Hash{}) {
+			continue
+		}
+		if childRef := db.readRef(child); childRef.tracked {
+			childRef.count++
+			if err := db.writeRef(batch, child, childRef); err != nil {
+				return err
+			}
+		}
+	}
+	if len(node.sideren) > 0 {
+		external := make([]byte, 0, len(node.sideren)*common.HashLength)
+		for child := range node.sideren {
+			external = append(external, child[:]...)
+		}
+		return batch.Put(externalRefsKey(hash), external)
+	}
+	return nil
+}
+
+// Retain adds a reference to the committed trie root, keeping the trie on disk until
+// it's released.
+//
+// Note, this method is a non-synchronized mutator. It is unsafe to call this
+// concurrently with other mutators.
+func (db *Database) Retain(root common.Hash) error {
+	if !db.refcount {
+		return nil
+	}
+	ref := db.readRef(root)
+	if !ref.tracked {
+		return nil
+	}
+	ref.count++
+
+	batch := db.diskdb.NewBatch()
+	if err := db.writeRef(batch, root, ref); err != nil {
+		return err
+	}
+	defer db.resetRefs()
+	return batch.Write()
+}
+
+// Release drops a reference of the retained trie root and deletes the nodes not
+// referenced by any other retained trie. It returns the number of deleted nodes.
+//
+// Note, this method is a non-synchronized mutator. It is unsafe to call this
+// concurrently with other mutators.
+func (db *Database) Release(root common.Hash) (int, error) {
+	if !db.refcount {
+		return 0, nil
+	}
+	defer db.resetRefs()
+
+	var (
+		batch   = db.diskdb.NewBatch()
+		deleted int
+		queue   = []common.Hash{root}
+	)
+	for len(queue) > 0 {
+		hash := queue[len(queue)-1]
+		queue = queue[:len(queue)-1]
+
+		ref := db.readRef(hash)
+		if !ref.tracked || ref.count == 0 {
+			continue
+		}
+		ref.count--
+
+		// Keep the node if it's still referenced, or alive in the memory cache
+		db.lock.RLock()
+		_, dirty := db.dirties[hash]
+		db.lock.RUnlock()
+
+		if ref.count > 0 || dirty {
+			if err := db.writeRef(batch, hash, ref); err != nil {
+				return deleted, err
+			}
+			continue
+		}
+		children, err := db.persistedChildren(hash, ref.blob)
+		if err != nil {
+			return deleted, err
+		}
+		queue = append(queue, children...)
+
+		batch.Delete(hash[:])
+		batch.Delete(refcountKey(hash))
+		batch.Delete(externalRefsKey(hash))
+		db.refs[hash] = nodeRef{}
+		if db.cleans != nil {
+			db.cleans.Delete(string(hash[:]))
+		}
+		deleted++
+
+		if batch.ValueSize() >= neatdb.IdealBatchSize {
+			if err := batch.Write(); err != nil {
+				return deleted, err
+			}
+			batch.Reset()
+			db.resetRefs()
+		}
+	}
+	return deleted, batch.Write()
+}
+
+// persistedChildren retrieves the children of a node on disk, both the ones embedded
+// in the node and the external ones.
+func (db *Database) persistedChildren(hash common.Hash, blob bool) ([]common.Hash, error) {
+	var children []common.Hash
+	if !blob {
+		enc, err := db.diskdb.Get(hash[:])
+		if err != nil {
+			return nil, err
+		}
+		n, err := decodeNode(hash[:], enc)
+		if err != nil {
+			return nil, err
+		}
+		gatherChildren(simplifyNode(n), &children)
+	}
+	external, _ := db.diskdb.Get(externalRefsKey(hash))
+	for i := 0; i+common.HashLength <= len(external); i += common.HashLength {
+		children = append(children, common.BytesToHash(external[i:i+common.HashLength]))
+	}
+	return children, nil
+}
+
+// ResetRefcount drops the reference counts of the database, the nodes on disk are not
+// tracked anymore. It's used once the nodes got deleted without counting the references.
+func ResetRefcount(db neatdb.KeyValueStore) error {
+	batch := db.NewBatch()
+	for _, prefix := range [][]byte{refcountPrefix, externalRefsPrefix} {
+		it := db.NewIteratorWithPrefix(prefix)
+		for it.Next() {
+			batch.Delete(common.CopyBytes(it.Key()))
+			if batch.ValueSize() >= neatdb.IdealBatchSize {
+				if err := batch.Write(); err != nil {
+					it.Release()
+					return err
+				}
+				batch.Reset()
+			}
+		}
+		it.Release()
+	}
+	batch.Delete(refcountEnabledKey)
+	return batch.Write()
+}
@@ -0,0 +1,89 @@
+package trie
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+
+	"github.com/Gessiux/neatchain/neatdb/memorydb"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// Tests that releasing a retained trie deletes only the nodes not referenced by the
+// other retained tries, including the tries referenced from the leaves.
+func TestDatabaseRelease(t *testing.T) {
+	diskdb := memorydb.New()
+	triedb := NewDatabase(diskdb)
+	triedb.EnableRefcount()
+
+	// Create a storage trie referenced from a leaf of the main trie
+	storage, _ := New(common.Hash{}, triedb)
+	for i := 0; i < 50; i++ {
+		storage.Update([]byte(fmt.Sprintf("slot-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	storageRoot, _ := storage.Commit(nil)
+
+	main, _ := New(common.Hash{}, triedb)
+	for i := 0; i < 100; i++ {
+		main.Update([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("value-%d", i)))
+	}
+	main.Update([]byte("account"), storageRoot[:])
+	onleaf := func(leaf []byte, parent common.Hash) error {
+		if bytes.HasPrefix(leaf, storageRoot[:]) {
+			triedb.Reference(storageRoot, parent)
+		}
+		return nil
+	}
+	root1, _ := main.Commit(onleaf)
+	if err := triedb.Commit(root1, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	triedb.Retain(root1)
+
+	// Update the main trie and the account without touching the storage trie, it's on
+	// disk now
+	for i := 0; i < 10; i++ {
+		main.Update([]byte(fmt.Sprintf("key-%d", i)), []byte(fmt.Sprintf("updated-%d", i)))
+	}
+	main.Update([]byte("account"), append(storageRoot[:], 1))
+	root2, _ := main.Commit(onleaf)
+	if err := triedb.Commit(root2, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	triedb.Retain(root2)
+
+	deleted, err := triedb.Release(root1)
+	if err != nil {
+		t.Fatalf("failed to release trie: %v", err)
+	}
+	if deleted == 0 {
+		t.Fatalf("no node deleted")
+	}
+	if has, _ := diskdb.Has(root1[:]); has {
+		t.Fatalf("released root still on disk")
+	}
+	for _, root := range []common.Hash{root2, storageRoot} {
+		tr, err := New(root, NewDatabase(diskdb))
+		if err != nil {
+			t.Fatalf("failed to open retained trie %x: %v", root, err)
+		}
+		it := tr.NodeIterator(nil)
+		for it.Next(true) {
+		}
+		if it.Error() != nil {
+			t.Fatalf("retained trie %x broken: %v", root, it.Error())
+		}
+	}
+
+	// Releasing the last trie leaves nothing behind
+	if _, err := triedb.Release(root2); err != nil {
+		t.Fatalf("failed to release trie: %v", err)
+	}
+	it := diskdb.NewIterator()
+	defer it.Release()
+	for it.Next() {
+		if len(it.Key()) == common.HashLength {
+			t.Fatalf("dangling node %x", it.Key())
+		}
+	}
+}
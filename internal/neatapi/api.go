@@ -27,6 +27,7 @@ import (
 
 	"github.com/Gessiux/neatchain/chain/consensus"
 	"github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	tmTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
 	"github.com/Gessiux/neatchain/chain/core/state"
 
 	goCrypto "github.com/Gessiux/go-crypto"
@@ -1232,6 +1233,29 @@ func (s *PublicTransactionPoolAPI) GetTransactionReceipt(ctx context.Context, ha
 	return fields, nil
 }
 
+// GetReceiptProof returns the RLP encoded proof of the transaction and its receipt against the
+// header of the block, to be validated by ValidateReceiptProofData on another chain.
+func (s *PublicTransactionPoolAPI) GetReceiptProof(ctx context.Context, hash common.Hash) (hexutil.Bytes, error) {
+	tx, blockHash, _, index := rawdb.ReadTransaction(s.b.ChainDb(), hash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", hash)
+	}
+	block, err := s.b.GetBlock(ctx, blockHash)
+	if block == nil || err != nil {
+		return nil, fmt.Errorf("block %x not found", blockHash)
+	}
+	receipts, err := s.b.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+
+	proofData, err := types.NewReceiptProofData(block, receipts, []uint{uint(index)})
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(proofData)
+}
+
 // sign is a helper function that signs a transaction with the private key of the given address.
 func (s *PublicTransactionPoolAPI) sign(addr common.Address, tx *types.Transaction) (*types.Transaction, error) {
 	// Look up the wallet containing the requested signer
@@ -1698,6 +1722,15 @@ func NewPublicNEATAPI(b Backend, nonceLock *AddrLocker) *PublicNEATAPI {
 	return &PublicNEATAPI{b.AccountManager(), b, nonceLock}
 }
 
+// requiredGas returns the gas required by the function in the latest state
+func requiredGas(ctx context.Context, b Backend, function neatAbi.FunctionType) uint64 {
+	state, _, err := b.StateAndHeaderByNumber(ctx, rpc.LatestBlockNumber)
+	if state == nil || err != nil {
+		return function.RequiredGas()
+	}
+	return core.RequiredGas(state, function)
+}
+
 func (s *PublicNEATAPI) SignAddress(from common.Address, consensusPrivateKey hexutil.Bytes) (goCrypto.Signature, error) {
 	if len(consensusPrivateKey) != 32 {
 		return nil, errors.New("invalid consensus private key")
@@ -1717,7 +1750,28 @@ func (api *PublicNEATAPI) WithdrawReward(ctx context.Context, from common.Addres
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.WithdrawReward.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.WithdrawReward)
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PublicNEATAPI) SetAutoCompound(ctx context.Context, from common.Address, enable bool, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(neatAbi.SetAutoCompound.String(), enable)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, neatAbi.SetAutoCompound)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1732,6 +1786,24 @@ func (api *PublicNEATAPI) WithdrawReward(ctx context.Context, from common.Addres
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
+func (api *PublicNEATAPI) GetUnbondings(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) ([]*state.Unbonding, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	return state.GetUnbondings(address), state.Error()
+}
+
+func (api *PublicNEATAPI) GetAutoCompound(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (bool, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return false, err
+	}
+
+	return state.IsAutoCompound(address), state.Error()
+}
+
 func (api *PublicNEATAPI) Delegate(ctx context.Context, from, candidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
 
 	input, err := neatAbi.ChainABI.Pack(neatAbi.Delegate.String(), candidate)
@@ -1739,7 +1811,7 @@ func (api *PublicNEATAPI) Delegate(ctx context.Context, from, candidate common.A
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.Delegate.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.Delegate)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1760,7 +1832,29 @@ func (api *PublicNEATAPI) UnDelegate(ctx context.Context, from, candidate common
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.UnDelegate.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.UnDelegate)
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PublicNEATAPI) ReDelegate(ctx context.Context, from, fromCandidate, toCandidate common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+
+	input, err := neatAbi.ChainABI.Pack(neatAbi.ReDelegate.String(), fromCandidate, toCandidate, (*big.Int)(amount))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, neatAbi.ReDelegate)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1782,7 +1876,7 @@ func (api *PublicNEATAPI) Register(ctx context.Context, from common.Address, reg
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.Register.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.Register)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1803,7 +1897,7 @@ func (api *PublicNEATAPI) UnRegister(ctx context.Context, from common.Address, g
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.UnRegister.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.UnRegister)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1850,7 +1944,7 @@ func (api *PublicNEATAPI) SetCommission(ctx context.Context, from common.Address
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.SetCommission.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.SetCommission)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1871,7 +1965,7 @@ func (api *PublicNEATAPI) EditValidator(ctx context.Context, from common.Address
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.EditValidator.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.EditValidator)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1892,7 +1986,49 @@ func (api *PublicNEATAPI) UnForbidden(ctx context.Context, from common.Address,
 		return common.Hash{}, err
 	}
 
-	defaultGas := neatAbi.UnForbidden.RequiredGas()
+	defaultGas := requiredGas(ctx, api.b, neatAbi.UnForbidden)
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    nil,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PublicNEATAPI) SubmitProposal(ctx context.Context, from common.Address, param string, value *hexutil.Big, targetEpoch hexutil.Uint64, deposit *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(neatAbi.SubmitProposal.String(), param, (*big.Int)(value), uint64(targetEpoch))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, neatAbi.SubmitProposal)
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    deposit,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PublicNEATAPI) VoteProposal(ctx context.Context, from common.Address, id hexutil.Uint64, approve bool, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(neatAbi.VoteProposal.String(), uint64(id), approve)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, neatAbi.VoteProposal)
 
 	args := SendTxArgs{
 		From:     from,
@@ -1907,11 +2043,118 @@ func (api *PublicNEATAPI) UnForbidden(ctx context.Context, from common.Address,
 	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
 }
 
+func (api *PublicNEATAPI) DepositProposal(ctx context.Context, from common.Address, id hexutil.Uint64, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(neatAbi.DepositProposal.String(), uint64(id))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, neatAbi.DepositProposal)
+
+	args := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    amount,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, args, api.am, api.b, api.nonceLock)
+}
+
+func (api *PublicNEATAPI) GetProposal(ctx context.Context, id hexutil.Uint64, blockNr rpc.BlockNumber) (*state.Proposal, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	proposal := state.GetProposal(uint64(id))
+	if proposal == nil {
+		return nil, core.ErrProposalNotFound
+	}
+	return proposal, state.Error()
+}
+
+func (api *PublicNEATAPI) GetChainParams(ctx context.Context, blockNr rpc.BlockNumber) (map[string]interface{}, error) {
+	state, _, err := api.b.StateAndHeaderByNumber(ctx, blockNr)
+	if state == nil || err != nil {
+		return nil, err
+	}
+
+	fields := map[string]interface{}{
+		"params":          state.GetParamChanges(),
+		"pending":         state.GetPendingParamChanges(),
+		"activeProposals": state.GetActiveProposals(),
+	}
+	return fields, state.Error()
+}
+
 func init() {
+	// Create Side Chain
+	core.RegisterValidateCb(neatAbi.CreateSideChain, createSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.CreateSideChain, createSideChainApplyCb)
+
+	core.RegisterValidateCb(neatAbi.CreateSideChainWithForks, createSideChainWithForksValidateCb)
+	core.RegisterApplyCb(neatAbi.CreateSideChainWithForks, createSideChainWithForksApplyCb)
+
+	// Join Side Chain
+	core.RegisterValidateCb(neatAbi.JoinSideChain, joinSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.JoinSideChain, joinSideChainApplyCb)
+
+	// Deposit - Main Chain
+	core.RegisterValidateCb(neatAbi.DepositInMainChain, depositInMainChainValidateCb)
+	core.RegisterApplyCb(neatAbi.DepositInMainChain, depositInMainChainApplyCb)
+
+	// Deposit - Side Chain
+	core.RegisterValidateCb(neatAbi.DepositInSideChain, depositInSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.DepositInSideChain, depositInSideChainApplyCb)
+
+	// Withdraw - Side Chain
+	core.RegisterValidateCb(neatAbi.WithdrawFromSideChain, withdrawFromSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.WithdrawFromSideChain, withdrawFromSideChainApplyCb)
+
+	// Withdraw - Main Chain
+	core.RegisterValidateCb(neatAbi.WithdrawFromMainChain, withdrawFromMainChainValidateCb)
+	core.RegisterApplyCb(neatAbi.WithdrawFromMainChain, withdrawFromMainChainApplyCb)
+
+	// Save Data to Main Chain
+	core.RegisterValidateCb(neatAbi.SaveDataToMainChain, saveDataToMainChainValidateCb)
+	core.RegisterApplyCb(neatAbi.SaveDataToMainChain, saveDataToMainChainApplyCb)
+
+	// Deliver Cross Chain Message
+	core.RegisterValidateCb(neatAbi.DeliverMessage, deliverMessageValidateCb)
+	core.RegisterApplyCb(neatAbi.DeliverMessage, deliverMessageApplyCb)
+
+	// Close Side Chain
+	core.RegisterValidateCb(neatAbi.CloseSideChain, closeSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.CloseSideChain, closeSideChainApplyCb)
+
+	// Withdraw from Closed Side Chain
+	core.RegisterValidateCb(neatAbi.WithdrawFromClosedSideChain, withdrawFromClosedSideChainValidateCb)
+	core.RegisterApplyCb(neatAbi.WithdrawFromClosedSideChain, withdrawFromClosedSideChainApplyCb)
+
+	// Set Block Reward
+	core.RegisterValidateCb(neatAbi.SetBlockReward, setBlockRewardValidateCb)
+	core.RegisterApplyCb(neatAbi.SetBlockReward, setBlockRewardApplyCb)
+
+	// Vote Next Epoch
+	core.RegisterValidateCb(neatAbi.VoteNextEpoch, voteNextEpochValidateCb)
+	core.RegisterApplyCb(neatAbi.VoteNextEpoch, voteNextEpochApplyCb)
+
+	// Reveal Vote
+	core.RegisterValidateCb(neatAbi.RevealVote, revealVoteValidateCb)
+	core.RegisterApplyCb(neatAbi.RevealVote, revealVoteApplyCb)
+
 	// Withdraw reward
 	core.RegisterValidateCb(neatAbi.WithdrawReward, withdrawRewardValidateCb)
 	core.RegisterApplyCb(neatAbi.WithdrawReward, withdrawRewardApplyCb)
 
+	// Auto Compound reward
+	core.RegisterValidateCb(neatAbi.SetAutoCompound, setAutoCompoundValidateCb)
+	core.RegisterApplyCb(neatAbi.SetAutoCompound, setAutoCompoundApplyCb)
+
 	// Delegate
 	core.RegisterValidateCb(neatAbi.Delegate, delegateValidateCb)
 	core.RegisterApplyCb(neatAbi.Delegate, delegateApplyCb)
@@ -1920,6 +2163,10 @@ func init() {
 	core.RegisterValidateCb(neatAbi.UnDelegate, unDelegateValidateCb)
 	core.RegisterApplyCb(neatAbi.UnDelegate, unDelegateApplyCb)
 
+	// Move Delegate
+	core.RegisterValidateCb(neatAbi.ReDelegate, reDelegateValidateCb)
+	core.RegisterApplyCb(neatAbi.ReDelegate, reDelegateApplyCb)
+
 	// Register
 	core.RegisterValidateCb(neatAbi.Register, registerValidateCb)
 	core.RegisterApplyCb(neatAbi.Register, registerApplyCb)
@@ -1934,10 +2181,25 @@ func init() {
 
 	// Edit Validator
 	core.RegisterValidateCb(neatAbi.EditValidator, editValidatorValidateCb)
+	core.RegisterApplyCb(neatAbi.EditValidator, editValidatorApplyCb)
 
 	// UnForbidden
 	core.RegisterValidateCb(neatAbi.UnForbidden, unForbiddenValidateCb)
 	core.RegisterApplyCb(neatAbi.UnForbidden, unForbiddenApplyCb)
+
+	// Report Double Sign
+	core.RegisterValidateCb(neatAbi.ReportDoubleSign, reportDoubleSignValidateCb)
+	core.RegisterApplyCb(neatAbi.ReportDoubleSign, reportDoubleSignApplyCb)
+
+	// Governance Proposal
+	core.RegisterValidateCb(neatAbi.SubmitProposal, submitProposalValidateCb)
+	core.RegisterApplyCb(neatAbi.SubmitProposal, submitProposalApplyCb)
+
+	core.RegisterValidateCb(neatAbi.VoteProposal, voteProposalValidateCb)
+	core.RegisterApplyCb(neatAbi.VoteProposal, voteProposalApplyCb)
+
+	core.RegisterValidateCb(neatAbi.DepositProposal, depositProposalValidateCb)
+	core.RegisterApplyCb(neatAbi.DepositProposal, depositProposalApplyCb)
 }
 
 func withdrawRewardValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
@@ -1962,6 +2224,10 @@ func withdrawRewardApplyCb(tx *types.Transaction, state *state.StateDB, bc *core
 	state.SubRewardBalanceByDelegateAddress(from, args.DelegateAddress, reward)
 	state.AddBalance(from, reward)
 
+	if err := addChainLog(state, neatAbi.WithdrawReward, from, args.DelegateAddress, reward); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -1985,6 +2251,47 @@ func withDrawRewardValidation(from common.Address, tx *types.Transaction, state
 	return &args, nil
 }
 
+func setAutoCompoundValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setAutoCompoundValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setAutoCompoundApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setAutoCompoundValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	state.SetAutoCompound(from, args.Enable)
+
+	if err := addChainLog(state, neatAbi.SetAutoCompound, from, args.Enable); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setAutoCompoundValidation(from common.Address, tx *types.Transaction, state *state.StateDB) (*neatAbi.SetAutoCompoundArgs, error) {
+
+	var args neatAbi.SetAutoCompoundArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.SetAutoCompound.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if args.Enable && state.GetDelegateBalance(from).Sign() == 0 {
+		return nil, core.ErrAutoCompoundNoDelegation
+	}
+
+	return &args, nil
+}
+
 // register and unregister
 func registerValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
@@ -2033,6 +2340,10 @@ func registerApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.Block
 		return verror
 	}
 
+	if verror = addChainLog(state, neatAbi.Register, from, amount, args.Commission); verror != nil {
+		return verror
+	}
+
 	return nil
 }
 
@@ -2124,6 +2435,10 @@ func unRegisterApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.Blo
 	fmt.Printf("candidate set bug, unregiser clear candidate after\n")
 	fmt.Printf("candidate set bug, unregiser clear candidate after %v\n", state.GetCandidateSet())
 
+	if verror = addChainLog(state, neatAbi.UnRegister, from); verror != nil {
+		return verror
+	}
+
 	return nil
 }
 
@@ -2195,6 +2510,10 @@ func delegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.Block
 		}
 	}
 
+	if verror = addChainLog(state, neatAbi.Delegate, from, args.Candidate, amount); verror != nil {
+		return verror
+	}
+
 	return nil
 }
 
@@ -2290,6 +2609,10 @@ func unDelegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.Blo
 	//	return verror
 	//}
 
+	if verror = addChainLog(state, neatAbi.UnDelegate, from, args.Candidate, args.Amount); verror != nil {
+		return verror
+	}
+
 	return nil
 }
 
@@ -2341,6 +2664,140 @@ func unDelegateValidation(from common.Address, tx *types.Transaction, state *sta
 	return &args, nil
 }
 
+func reDelegateValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, verror := reDelegateValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+	return nil
+}
+
+func reDelegateApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	// Validate first
+	from := derivedAddressFromTx(tx)
+	args, verror := reDelegateValidation(from, tx, state, bc)
+	if verror != nil {
+		return verror
+	}
+
+	// block height validation
+	verror = updateValidation(bc)
+	if verror != nil {
+		return verror
+	}
+
+	// Apply Logic
+	// the proxied amount is not locked yet, move it to the new candidate immediately
+	// the deposit proxied amount is locked in the current epoch, it stays slashable at the source candidate
+	// and is moved to the new candidate at the end of the epoch
+	proxiedBalance := state.GetProxiedBalanceByUser(args.FromCandidate, from)
+	immediately := args.Amount
+	if args.Amount.Cmp(proxiedBalance) > 0 {
+		immediately = proxiedBalance
+		rest := new(big.Int).Sub(args.Amount, proxiedBalance)
+		state.AddPendingRefundBalanceByUser(args.FromCandidate, from, rest)
+		state.MarkDelegateAddressRefund(args.FromCandidate)
+		state.AddRedelegation(from, args.FromCandidate, args.ToCandidate, rest)
+	} else {
+		// record the redelegation for the hop check
+		state.AddRedelegation(from, args.FromCandidate, args.ToCandidate, common.Big0)
+	}
+
+	if immediately.Sign() > 0 {
+		state.SubProxiedBalanceByUser(args.FromCandidate, from, immediately)
+		state.AddProxiedBalanceByUser(args.ToCandidate, from, immediately)
+	}
+
+	verror = updateNextEpochValidatorVoteSet(tx, state, bc, args.ToCandidate, ops)
+	if verror != nil {
+		return verror
+	}
+
+	if verror = addChainLog(state, neatAbi.ReDelegate, from, args.FromCandidate, args.ToCandidate, args.Amount); verror != nil {
+		return verror
+	}
+
+	return nil
+}
+
+func reDelegateValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatAbi.ReDelegateArgs, error) {
+
+	var args neatAbi.ReDelegateArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.ReDelegate.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if args.Amount.Sign() <= 0 {
+		return nil, core.ErrRedelegateAmount
+	}
+
+	// Check Self Address
+	if from == args.FromCandidate {
+		return nil, core.ErrCancelSelfDelegate
+	}
+
+	if args.FromCandidate == args.ToCandidate {
+		return nil, core.ErrRedelegateSameCandidate
+	}
+
+	// Check Candidate
+	if !state.IsCandidate(args.ToCandidate) {
+		return nil, core.ErrNotCandidate
+	}
+
+	// the forbidden candidate's delegators can't run away from the penalty, and no one can join it
+	if state.GetForbidden(args.FromCandidate) || state.GetForbidden(args.ToCandidate) {
+		return nil, core.ErrRedelegateForbidden
+	}
+
+	// the stake redelegated in the current epoch may still be slashed at its previous candidate,
+	// it can't hop again before the epoch ends
+	if state.IsRedelegatedTo(from, args.FromCandidate) {
+		return nil, core.ErrRedelegationHop
+	}
+
+	// Super node Candidate can't decrease balance, and only accepts the existing delegators
+	var ep *epoch.Epoch
+	if nc, ok := bc.Engine().(consensus.NeatCon); ok {
+		ep = nc.GetEpoch().GetEpochByBlockNumber(bc.CurrentBlock().NumberU64())
+	}
+	if _, supernode := ep.Validators.GetByAddress(args.FromCandidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		return nil, core.ErrCannotUnBond
+	}
+	depositBalance := state.GetDepositProxiedBalanceByUser(args.ToCandidate, from)
+	if _, supernode := ep.Validators.GetByAddress(args.ToCandidate.Bytes()); supernode != nil && supernode.RemainingEpoch > 0 {
+		if depositBalance.Sign() == 0 {
+			return nil, core.ErrCannotDelegate
+		}
+	}
+
+	// Check if exceed the limit of delegated addresses
+	if depositBalance.Sign() == 0 && state.GetProxiedBalanceByUser(args.ToCandidate, from).Sign() == 0 {
+		if state.GetProxiedAddressNumber(args.ToCandidate) >= maxDelegationAddresses {
+			return nil, core.ErrExceedDelegationAddressLimit
+		}
+	}
+
+	// Check Proxied Amount in Candidate Balance
+	proxiedBalance := state.GetProxiedBalanceByUser(args.FromCandidate, from)
+	depositProxiedBalance := state.GetDepositProxiedBalanceByUser(args.FromCandidate, from)
+	pendingRefundBalance := state.GetPendingRefundBalanceByUser(args.FromCandidate, from)
+	// available = proxied + deposit - pending refund
+	availableBalance := new(big.Int).Sub(new(big.Int).Add(proxiedBalance, depositProxiedBalance), pendingRefundBalance)
+	if args.Amount.Cmp(availableBalance) == 1 {
+		return nil, core.ErrInsufficientProxiedBalance
+	}
+
+	// Check Epoch Height
+	if _, err := getEpoch(bc); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
 // set commission
 func setCommisstionValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
@@ -2361,6 +2818,10 @@ func setCommisstionApplyCb(tx *types.Transaction, state *state.StateDB, bc *core
 
 	state.SetCommission(from, args.Commission)
 
+	if err := addChainLog(state, neatAbi.SetCommission, from, args.Commission); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2384,14 +2845,38 @@ func setCommissionValidation(from common.Address, tx *types.Transaction, state *
 
 func editValidatorValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
 	from := derivedAddressFromTx(tx)
+	_, err := editValidatorValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func editValidatorApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := editValidatorValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	// the validator info is kept by the indexers only
+	if err := addChainLog(state, neatAbi.EditValidator, from, args.Moniker, args.Website, args.Identity, args.Details); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func editValidatorValidation(from common.Address, tx *types.Transaction, state *state.StateDB) (*neatAbi.EditValidatorArgs, error) {
 	if !state.IsCandidate(from) {
-		return errors.New("you are not a validator or candidate")
+		return nil, errors.New("you are not a validator or candidate")
 	}
 
 	var args neatAbi.EditValidatorArgs
 	data := tx.Data()
 	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.EditValidator.String(), data[4:]); err != nil {
-		return err
+		return nil, err
 	}
 
 	if len([]byte(args.Details)) > maxEditValidatorLength ||
@@ -2399,10 +2884,10 @@ func editValidatorValidateCb(tx *types.Transaction, state *state.StateDB, bc *co
 		len([]byte(args.Moniker)) > maxEditValidatorLength ||
 		len([]byte(args.Website)) > maxEditValidatorLength {
 		//fmt.Printf("args details length %v, identity length %v, moniker lenth %v, website length %v\n", len([]byte(args.Details)),len([]byte(args.Identity)),len([]byte(args.Moniker)),len([]byte(args.Website)))
-		return fmt.Errorf("args length too long, more than %v", maxEditValidatorLength)
+		return nil, fmt.Errorf("args length too long, more than %v", maxEditValidatorLength)
 	}
 
-	return nil
+	return &args, nil
 }
 
 func unForbiddenValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
@@ -2428,6 +2913,10 @@ func unForbiddenApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.Bl
 	// remove address from forbidden set
 	state.ClearForbiddenSetByAddress(from)
 
+	if err := addChainLog(state, neatAbi.UnForbidden, from); err != nil {
+		return err
+	}
+
 	return nil
 }
 
@@ -2461,6 +2950,230 @@ func unForbiddenValidation(from common.Address, state *state.StateDB, bc *core.B
 	return nil
 }
 
+func reportDoubleSignValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	_, err := reportDoubleSignValidation(tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func reportDoubleSignApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	evidence, err := reportDoubleSignValidation(tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	slashed := epoch.PunishDoubleSign(evidence.Address(), state)
+	state.MarkEvidenceSlashed(evidence.Hash())
+	log.Infof("Double sign evidence %v punished, slashed amount %v", evidence, slashed)
+
+	if err := addChainLog(state, neatAbi.ReportDoubleSign, derivedAddressFromTx(tx), evidence.Address(), slashed); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func reportDoubleSignValidation(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*tmTypes.DuplicateVoteEvidence, error) {
+
+	var args neatAbi.ReportDoubleSignArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.ReportDoubleSign.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	evidence, err := tmTypes.DecodeDuplicateVoteEvidence(args.Evidence)
+	if err != nil {
+		return nil, err
+	}
+
+	if state.IsEvidenceSlashed(evidence.Hash()) {
+		return nil, core.ErrEvidenceSlashed
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return nil, err
+	}
+
+	// the deposit of the offender is still locked only if the evidence comes from the current or previous epoch
+	if evidence.Height() > bc.CurrentBlock().NumberU64() {
+		return nil, fmt.Errorf("double sign evidence height %v higher than current height", evidence.Height())
+	}
+	evidenceEp := ep.GetEpochByBlockNumber(evidence.Height())
+	if evidenceEp == nil || evidenceEp.Number+1 < ep.Number {
+		return nil, core.ErrEvidenceTooOld
+	}
+
+	if err := evidence.Verify(bc.Config().NeatChainId, evidenceEp.Validators); err != nil {
+		return nil, err
+	}
+
+	return evidence, nil
+}
+
+// governance proposal
+func submitProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	_, _, err := submitProposalValidation(tx, bc)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func submitProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, ep, err := submitProposalValidation(tx, bc)
+	if err != nil {
+		return err
+	}
+
+	id := state.CreateProposal(from, args.Param, args.Value, ep.Number+epoch.ProposalVotingPeriod, args.TargetEpoch)
+	if deposit := tx.Value(); deposit.Sign() > 0 {
+		state.SubBalance(from, deposit)
+		proposal := state.GetProposal(id)
+		proposal.AddDeposit(from, deposit)
+		state.SetProposal(proposal)
+	}
+
+	if err := addChainLog(state, neatAbi.SubmitProposal, id, from, args.Param, args.Value, args.TargetEpoch); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func submitProposalValidation(tx *types.Transaction, bc *core.BlockChain) (*neatAbi.SubmitProposalArgs, *epoch.Epoch, error) {
+
+	var args neatAbi.SubmitProposalArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.SubmitProposal.String(), data[4:]); err != nil {
+		return nil, nil, err
+	}
+
+	if err := epoch.ValidateParamChange(args.Param, args.Value); err != nil {
+		return nil, nil, err
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// the change can't take effect before the votes are tallied
+	if args.TargetEpoch <= ep.Number+epoch.ProposalVotingPeriod {
+		return nil, nil, core.ErrProposalTargetEpoch
+	}
+
+	return &args, ep, nil
+}
+
+func voteProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, _, err := voteProposalValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func voteProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, proposal, err := voteProposalValidation(from, tx, state)
+	if err != nil {
+		return err
+	}
+
+	// the voting power is counted when the votes are tallied
+	proposal.SetVote(from, args.Approve)
+	state.SetProposal(proposal)
+
+	if err := addChainLog(state, neatAbi.VoteProposal, proposal.Id, from, args.Approve); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func voteProposalValidation(from common.Address, tx *types.Transaction, state *state.StateDB) (*neatAbi.VoteProposalArgs, *state.Proposal, error) {
+
+	var args neatAbi.VoteProposalArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.VoteProposal.String(), data[4:]); err != nil {
+		return nil, nil, err
+	}
+
+	proposal, err := votingProposal(args.Id, state)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if epoch.GovernanceVotingPower(state, from).Sign() == 0 {
+		return nil, nil, core.ErrNoVotingPower
+	}
+
+	return &args, proposal, nil
+}
+
+func depositProposalValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	_, err := depositProposalValidation(tx, state)
+	if err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func depositProposalApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	proposal, err := depositProposalValidation(tx, state)
+	if err != nil {
+		return err
+	}
+
+	deposit := tx.Value()
+	state.SubBalance(from, deposit)
+	proposal.AddDeposit(from, deposit)
+	state.SetProposal(proposal)
+
+	if err := addChainLog(state, neatAbi.DepositProposal, proposal.Id, from, deposit); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func depositProposalValidation(tx *types.Transaction, state *state.StateDB) (*state.Proposal, error) {
+
+	var args neatAbi.DepositProposalArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.DepositProposal.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if tx.Value().Sign() <= 0 {
+		return nil, core.ErrProposalDeposit
+	}
+
+	return votingProposal(args.Id, state)
+}
+
+// votingProposal returns the proposal open for voting
+func votingProposal(id uint64, state *state.StateDB) (*state.Proposal, error) {
+	proposal := state.GetProposal(id)
+	if proposal == nil {
+		return nil, core.ErrProposalNotFound
+	}
+	if !proposal.IsVoting() {
+		return nil, core.ErrProposalClosed
+	}
+	return proposal, nil
+}
+
 func concatCopyPreAllocate(slices [][]byte) []byte {
 	var totalLen int
 	for _, s := range slices {
@@ -2487,6 +3200,21 @@ func getEpoch(bc *core.BlockChain) (*epoch.Epoch, error) {
 	return ep, nil
 }
 
+// addChainLog logs the event of the function under the ChainContractMagicAddr, the block number of the
+// log is filled in by core.ApplyTransactionEx, which also drops the log before the ChainEventBlock fork
+func addChainLog(state *state.StateDB, function neatAbi.FunctionType, args ...interface{}) error {
+	topics, data, err := neatAbi.PackEvent(function, args...)
+	if err != nil {
+		return err
+	}
+	state.AddLog(&types.Log{
+		Address: neatAbi.ChainContractMagicAddr,
+		Topics:  topics,
+		Data:    data,
+	})
+	return nil
+}
+
 func derivedAddressFromTx(tx *types.Transaction) (from common.Address) {
 	signer := types.NewEIP155Signer(tx.ChainId())
 	from, _ = types.Sender(signer, tx)
@@ -148,6 +148,11 @@ func GetAPIs(apiBackend Backend, solcPath string) []rpc.API {
 			Version:   "1.0",
 			Service:   NewPublicNEATAPI(apiBackend, nonceLock),
 			Public:    true,
+		}, {
+			Namespace: "chain",
+			Version:   "1.0",
+			Service:   NewPublicChainAPI(apiBackend, nonceLock),
+			Public:    true,
 		},
 	}
 	return append(compiler, all...)
@@ -0,0 +1,1183 @@
+package neatapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/big"
+	"strings"
+
+	goCrypto "github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/neatchain/chain/accounts"
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/core/vm"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
+	"github.com/Gessiux/neatchain/network/rpc"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/common/hexutil"
+	"github.com/Gessiux/neatchain/utilities/common/math"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+	"github.com/Gessiux/neatchain/utilities/rlp"
+)
+
+// PublicChainAPI provides an API to create, join and transfer between the side chains.
+type PublicChainAPI struct {
+	am        *accounts.Manager
+	b         Backend
+	nonceLock *AddrLocker
+}
+
+// NewPublicChainAPI creates a new side chain API instance.
+func NewPublicChainAPI(b Backend, nonceLock *AddrLocker) *PublicChainAPI {
+	return &PublicChainAPI{b.AccountManager(), b, nonceLock}
+}
+
+// sendChainTx sends the chain contract function call, the gas is the one required by the function
+func (api *PublicChainAPI) sendChainTx(ctx context.Context, from common.Address, function neatAbi.FunctionType, value, gasPrice *hexutil.Big, args ...interface{}) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(function.String(), args...)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	defaultGas := requiredGas(ctx, api.b, function)
+
+	txArgs := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      (*hexutil.Uint64)(&defaultGas),
+		GasPrice: gasPrice,
+		Value:    value,
+		Input:    (*hexutil.Bytes)(&input),
+		Nonce:    nil,
+	}
+
+	return SendTransaction(ctx, txArgs, api.am, api.b, api.nonceLock)
+}
+
+// CreateSideChain applies for a new side chain, the official startup cost is locked from the owner
+func (api *PublicChainAPI) CreateSideChain(ctx context.Context, from common.Address, chainId string,
+	minValidators hexutil.Uint, minDepositAmount *hexutil.Big, startBlock, endBlock *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+
+	if chainId == "" || strings.Contains(chainId, ";") {
+		return common.Hash{}, errors.New("chainId is nil or empty, or contains ';', should be meaningful")
+	}
+	if minDepositAmount == nil || startBlock == nil || endBlock == nil {
+		return common.Hash{}, errors.New("minDepositAmount, startBlock and endBlock are required")
+	}
+
+	startupCost := (*hexutil.Big)(math.MustParseBig256(core.OFFICIAL_MINIMUM_DEPOSIT))
+	return api.sendChainTx(ctx, from, neatAbi.CreateSideChain, startupCost, gasPrice,
+		chainId, uint16(minValidators), (*big.Int)(minDepositAmount), (*big.Int)(startBlock), (*big.Int)(endBlock))
+}
+
+// CreateSideChainWithForks applies for a new side chain like CreateSideChain, the EVM forks of the
+// side chain are activated at the given heights, 0 activates them from the genesis
+func (api *PublicChainAPI) CreateSideChainWithForks(ctx context.Context, from common.Address, chainId string,
+	minValidators hexutil.Uint, minDepositAmount *hexutil.Big, startBlock, endBlock *hexutil.Big,
+	constantinopleBlock, petersburgBlock, istanbulBlock *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+
+	if chainId == "" || strings.Contains(chainId, ";") {
+		return common.Hash{}, errors.New("chainId is nil or empty, or contains ';', should be meaningful")
+	}
+	if minDepositAmount == nil || startBlock == nil || endBlock == nil {
+		return common.Hash{}, errors.New("minDepositAmount, startBlock and endBlock are required")
+	}
+	if constantinopleBlock == nil || petersburgBlock == nil || istanbulBlock == nil {
+		return common.Hash{}, errors.New("constantinopleBlock, petersburgBlock and istanbulBlock are required")
+	}
+
+	startupCost := (*hexutil.Big)(math.MustParseBig256(core.OFFICIAL_MINIMUM_DEPOSIT))
+	return api.sendChainTx(ctx, from, neatAbi.CreateSideChainWithForks, startupCost, gasPrice,
+		chainId, uint16(minValidators), (*big.Int)(minDepositAmount), (*big.Int)(startBlock), (*big.Int)(endBlock),
+		(*big.Int)(constantinopleBlock), (*big.Int)(petersburgBlock), (*big.Int)(istanbulBlock))
+}
+
+// JoinSideChain joins the pending side chain as a validator with the deposit amount
+func (api *PublicChainAPI) JoinSideChain(ctx context.Context, from common.Address, pubkey goCrypto.BLSPubKey, chainId string,
+	depositAmount *hexutil.Big, signature hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+
+	if chainId == "" || strings.Contains(chainId, ";") {
+		return common.Hash{}, errors.New("chainId is nil or empty, or contains ';', should be meaningful")
+	}
+
+	return api.sendChainTx(ctx, from, neatAbi.JoinSideChain, depositAmount, gasPrice, pubkey.Bytes(), chainId, []byte(signature))
+}
+
+// DepositInMainChain locks the amount in the main chain, to be claimed in the side chain by DepositInSideChain
+func (api *PublicChainAPI) DepositInMainChain(ctx context.Context, from common.Address, chainId string, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	if chainId == "" || strings.Contains(chainId, ";") {
+		return common.Hash{}, errors.New("chainId is nil or empty, or contains ';', should be meaningful")
+	}
+
+	return api.sendChainTx(ctx, from, neatAbi.DepositInMainChain, amount, gasPrice, chainId)
+}
+
+// DepositInSideChain claims the amount deposited by the main chain tx in this side chain
+func (api *PublicChainAPI) DepositInSideChain(ctx context.Context, from common.Address, txHash common.Hash) (common.Hash, error) {
+	chainId := api.b.ChainConfig().NeatChainId
+	return api.sendChainTx(ctx, from, neatAbi.DepositInSideChain, nil, nil, chainId, txHash)
+}
+
+// WithdrawFromSideChain burns the amount in this side chain, to be claimed in the main chain by WithdrawFromMainChain
+func (api *PublicChainAPI) WithdrawFromSideChain(ctx context.Context, from common.Address, amount *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	chainId := api.b.ChainConfig().NeatChainId
+	return api.sendChainTx(ctx, from, neatAbi.WithdrawFromSideChain, amount, gasPrice, chainId)
+}
+
+// WithdrawFromMainChain claims the amount withdrawn by the side chain tx in the main chain
+func (api *PublicChainAPI) WithdrawFromMainChain(ctx context.Context, from common.Address, amount *hexutil.Big, chainId string, txHash common.Hash) (common.Hash, error) {
+	if amount == nil {
+		return common.Hash{}, errors.New("amount is required")
+	}
+
+	return api.sendChainTx(ctx, from, neatAbi.WithdrawFromMainChain, nil, nil, chainId, (*big.Int)(amount), txHash)
+}
+
+// VoteNextEpoch sends the hash of the vote for the next epoch, the vote is revealed by RevealVote
+func (api *PublicChainAPI) VoteNextEpoch(ctx context.Context, from common.Address, voteHash common.Hash, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendChainTx(ctx, from, neatAbi.VoteNextEpoch, nil, gasPrice, voteHash)
+}
+
+// RevealVote reveals the vote of the next epoch matching the vote hash sent before
+func (api *PublicChainAPI) RevealVote(ctx context.Context, from common.Address, pubkey goCrypto.BLSPubKey, amount *hexutil.Big,
+	salt string, signature hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+
+	if amount == nil {
+		return common.Hash{}, errors.New("amount is required")
+	}
+
+	return api.sendChainTx(ctx, from, neatAbi.RevealVote, nil, gasPrice, pubkey.Bytes(), (*big.Int)(amount), salt, []byte(signature))
+}
+
+// SetBlockReward sets the reward per block of this side chain, only the owner is allowed
+func (api *PublicChainAPI) SetBlockReward(ctx context.Context, from common.Address, reward *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	if reward == nil {
+		return common.Hash{}, errors.New("reward is required")
+	}
+
+	chainId := api.b.ChainConfig().NeatChainId
+	return api.sendChainTx(ctx, from, neatAbi.SetBlockReward, nil, gasPrice, chainId, (*big.Int)(reward))
+}
+
+// BroadcastTX3ProofData saves the proof of the withdraw txs of a side chain block and
+// broadcasts it to the main chain peers
+func (api *PublicChainAPI) BroadcastTX3ProofData(ctx context.Context, bs hexutil.Bytes) error {
+	if !api.b.ChainConfig().IsMainChain() {
+		return errors.New("this api can only be called in the main chain")
+	}
+
+	var proofData types.TX3ProofData
+	if err := rlp.DecodeBytes(bs, &proofData); err != nil {
+		return err
+	}
+
+	cch := api.b.GetCrossChainHelper()
+	if err := cch.ValidateTX3ProofData(&proofData); err != nil {
+		return err
+	}
+	if err := cch.WriteTX3ProofData(&proofData); err != nil {
+		return err
+	}
+
+	api.b.BroadcastTX3ProofData(&proofData)
+	return nil
+}
+
+// GetCrossChainMessageProof returns the proof of the index-th cross chain message sent by the
+// tx, to be delivered to the target chain by DeliverCrossChainMessage
+func (api *PublicChainAPI) GetCrossChainMessageProof(ctx context.Context, txHash common.Hash, index hexutil.Uint) (hexutil.Bytes, error) {
+	tx, blockHash, blockNumber, txIndex := rawdb.ReadTransaction(api.b.ChainDb(), txHash)
+	if tx == nil {
+		return nil, fmt.Errorf("transaction %x not found", txHash)
+	}
+	receipts, err := api.b.GetReceipts(ctx, blockHash)
+	if err != nil {
+		return nil, err
+	}
+	if uint64(len(receipts)) <= txIndex {
+		return nil, fmt.Errorf("receipt of transaction %x not found", txHash)
+	}
+
+	var msgs []*types.CrossChainMessage
+	for _, l := range receipts[txIndex].Logs {
+		if l.Address != vm.CrossChainOutboxAddr || len(l.Topics) != 2 || l.Topics[0] != vm.CrossChainMessageTopic {
+			continue
+		}
+		msg := new(types.CrossChainMessage)
+		if err := rlp.DecodeBytes(l.Data, msg); err != nil {
+			return nil, err
+		}
+		msgs = append(msgs, msg)
+	}
+	if int(index) >= len(msgs) {
+		return nil, fmt.Errorf("transaction %x sent %d cross chain messages", txHash, len(msgs))
+	}
+
+	statedb, header, err := api.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(blockNumber))
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if header.Hash() != blockHash {
+		return nil, fmt.Errorf("block %x is not canonical", blockHash)
+	}
+	proof, err := core.ProveCrossChainMessage(statedb.Database(), header, msgs[index])
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(proof)
+}
+
+// DeliverCrossChainMessage delivers the message of the proof to this chain, the gas is
+// available to the receiving contract on top of the gas required by the delivery
+func (api *PublicChainAPI) DeliverCrossChainMessage(ctx context.Context, from common.Address, proof hexutil.Bytes, gas hexutil.Uint64, gasPrice *hexutil.Big) (common.Hash, error) {
+	input, err := neatAbi.ChainABI.Pack(neatAbi.DeliverMessage.String(), []byte(proof))
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	totalGas := hexutil.Uint64(requiredGas(ctx, api.b, neatAbi.DeliverMessage)) + gas
+
+	txArgs := SendTxArgs{
+		From:     from,
+		To:       &neatAbi.ChainContractMagicAddr,
+		Gas:      &totalGas,
+		GasPrice: gasPrice,
+		Input:    (*hexutil.Bytes)(&input),
+	}
+
+	return SendTransaction(ctx, txArgs, api.am, api.b, api.nonceLock)
+}
+
+// CloseSideChain votes to halt the side chain at the height, only the validators of the side chain
+// are allowed. The side chain halts once +2/3 of its voting power voted for the same height.
+func (api *PublicChainAPI) CloseSideChain(ctx context.Context, from common.Address, chainId string, haltHeight *hexutil.Big, gasPrice *hexutil.Big) (common.Hash, error) {
+	if haltHeight == nil {
+		return common.Hash{}, errors.New("halt height is required")
+	}
+
+	return api.sendChainTx(ctx, from, neatAbi.CloseSideChain, nil, gasPrice, chainId, (*big.Int)(haltHeight))
+}
+
+// GetSideChainClosure returns the decommission of the side chain, nil if the side chain is not closing
+func (api *PublicChainAPI) GetSideChainClosure(ctx context.Context, chainId string) (map[string]interface{}, error) {
+	closure := core.GetSideChainClosure(api.b.GetCrossChainHelper().GetChainInfoDB(), chainId)
+	if closure == nil {
+		return nil, nil
+	}
+
+	votes := make([]map[string]interface{}, len(closure.Votes))
+	for i, vote := range closure.Votes {
+		votes[i] = map[string]interface{}{
+			"address":    vote.Address.String(),
+			"haltHeight": hexutil.Uint64(vote.HaltHeight),
+		}
+	}
+	fields := map[string]interface{}{
+		"chainId":    closure.ChainId,
+		"votes":      votes,
+		"haltHeight": hexutil.Uint64(closure.HaltHeight),
+		"settled":    closure.Settled(),
+	}
+	if closure.Settled() {
+		fields["finalHash"] = closure.FinalHash
+		fields["finalRoot"] = closure.FinalRoot
+		fields["settleBlock"] = hexutil.Uint64(closure.SettleBlock)
+		fields["settlementEnd"] = hexutil.Uint64(closure.SettlementEnd())
+	}
+	return fields, nil
+}
+
+// GetFinalBalanceProof returns the proof of the account at the final state of this closed side
+// chain, to be withdrawn in the main chain by WithdrawFromClosedSideChain
+func (api *PublicChainAPI) GetFinalBalanceProof(ctx context.Context, address common.Address) (hexutil.Bytes, error) {
+	chainId := api.b.ChainConfig().NeatChainId
+	closure := core.GetSideChainClosure(api.b.GetCrossChainHelper().GetChainInfoDB(), chainId)
+	if closure == nil || closure.HaltHeight == 0 {
+		return nil, fmt.Errorf("side chain %s is not closed", chainId)
+	}
+
+	statedb, header, err := api.b.StateAndHeaderByNumber(ctx, rpc.BlockNumber(closure.HaltHeight))
+	if statedb == nil || err != nil {
+		return nil, err
+	}
+	if closure.Settled() && header.Hash() != closure.FinalHash {
+		return nil, fmt.Errorf("block %v is not the final block anchored in the main chain", closure.HaltHeight)
+	}
+	proof, err := core.ProveFinalBalance(statedb.Database(), header.Root, address)
+	if err != nil {
+		return nil, err
+	}
+	return rlp.EncodeToBytes(proof)
+}
+
+// WithdrawFromClosedSideChain withdraws the balance proven at the final state of the closed side
+// chain in the main chain, it is only allowed until the end of the settlement period
+func (api *PublicChainAPI) WithdrawFromClosedSideChain(ctx context.Context, from common.Address, chainId string, proof hexutil.Bytes, gasPrice *hexutil.Big) (common.Hash, error) {
+	return api.sendChainTx(ctx, from, neatAbi.WithdrawFromClosedSideChain, nil, gasPrice, chainId, []byte(proof))
+}
+
+// RPCCrossChainTransfer is the stage of a withdraw from a side chain to the main chain
+type RPCCrossChainTransfer struct {
+	ChainId     string          `json:"chainId"`
+	TxHash      common.Hash     `json:"txHash"`
+	From        string          `json:"from"`
+	Amount      *hexutil.Big    `json:"amount"`
+	Stage       string          `json:"stage"`
+	SubmitBlock *hexutil.Uint64 `json:"submitBlock"`
+	ClaimTxHash *common.Hash    `json:"claimTxHash"`
+	ClaimBlock  *hexutil.Uint64 `json:"claimBlock"`
+}
+
+func newRPCCrossChainTransfer(t *types.CrossChainTransfer) *RPCCrossChainTransfer {
+	result := &RPCCrossChainTransfer{
+		ChainId: t.ChainId,
+		TxHash:  t.TxHash,
+		From:    t.From.String(),
+		Amount:  (*hexutil.Big)(t.Amount),
+		Stage:   t.Stage.String(),
+	}
+	if t.SubmitBlock != 0 {
+		result.SubmitBlock = (*hexutil.Uint64)(&t.SubmitBlock)
+	}
+	if t.Stage == types.TransferClaimed {
+		result.ClaimTxHash = &t.ClaimTxHash
+		result.ClaimBlock = (*hexutil.Uint64)(&t.ClaimBlock)
+	}
+	return result
+}
+
+// GetCrossChainTransfer returns the stage of the withdraw (tx3) of the side chain: submitted,
+// proof-broadcast, claimed or expired. Nil is returned for the withdraws not known locally.
+func (api *PublicChainAPI) GetCrossChainTransfer(ctx context.Context, chainId string, txHash common.Hash) (*RPCCrossChainTransfer, error) {
+	transfer := api.b.GetCrossChainHelper().GetCrossChainTransfer(chainId, txHash)
+	if transfer == nil {
+		return nil, nil
+	}
+	return newRPCCrossChainTransfer(transfer), nil
+}
+
+// ListPendingWithdrawals returns the withdraws of the address not claimed in the main chain yet
+func (api *PublicChainAPI) ListPendingWithdrawals(ctx context.Context, address common.Address) ([]*RPCCrossChainTransfer, error) {
+	transfers := api.b.GetCrossChainHelper().GetPendingWithdrawals(address)
+	result := make([]*RPCCrossChainTransfer, len(transfers))
+	for i, transfer := range transfers {
+		result[i] = newRPCCrossChainTransfer(transfer)
+	}
+	return result, nil
+}
+
+// CrossChainTransfers creates a subscription fired each time a withdraw moves to the next
+// stage, only the withdraws of the address are notified if given
+func (api *PublicChainAPI) CrossChainTransfers(ctx context.Context, address *common.Address) (*rpc.Subscription, error) {
+	notifier, supported := rpc.NotifierFromContext(ctx)
+	if !supported {
+		return &rpc.Subscription{}, rpc.ErrNotificationsUnsupported
+	}
+
+	rpcSub := notifier.CreateSubscription()
+
+	go func() {
+		transfers := make(chan core.CrossChainTransferEvent, 16)
+		transfersSub := api.b.GetCrossChainHelper().SubscribeCrossChainTransferEvent(transfers)
+
+		for {
+			select {
+			case ev := <-transfers:
+				if address == nil || ev.Transfer.From == *address {
+					notifier.Notify(rpcSub.ID, newRPCCrossChainTransfer(ev.Transfer))
+				}
+			case <-rpcSub.Err():
+				transfersSub.Unsubscribe()
+				return
+			case <-notifier.Closed():
+				transfersSub.Unsubscribe()
+				return
+			}
+		}
+	}()
+
+	return rpcSub, nil
+}
+
+// isTxOfChain checks the tx was signed for the chain
+func isTxOfChain(tx *types.Transaction, chainId string) bool {
+	digest := crypto.Keccak256([]byte(chainId))
+	return tx.ChainId().Cmp(new(big.Int).SetBytes(digest)) == 0
+}
+
+// create side chain
+func createSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := createSideChainValidation(from, tx, cch)
+	return err
+}
+
+func createSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := createSideChainValidation(from, tx, cch)
+	if err != nil {
+		return err
+	}
+
+	return applyCreateSideChain(neatAbi.CreateSideChain, from, tx, state, ops, args, nil)
+}
+
+func createSideChainValidation(from common.Address, tx *types.Transaction, cch core.CrossChainHelper) (*neatAbi.CreateSideChainArgs, error) {
+	var args neatAbi.CreateSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.CreateSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if err := cch.CanCreateSideChain(from, args.ChainId, args.MinValidators, args.MinDepositAmount, tx.Value(), args.StartBlock, args.EndBlock); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
+// create side chain with forks
+func createSideChainWithForksValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, _, err := createSideChainWithForksValidation(from, tx, cch)
+	return err
+}
+
+func createSideChainWithForksApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, forks, err := createSideChainWithForksValidation(from, tx, cch)
+	if err != nil {
+		return err
+	}
+
+	return applyCreateSideChain(neatAbi.CreateSideChainWithForks, from, tx, state, ops, args, forks)
+}
+
+func createSideChainWithForksValidation(from common.Address, tx *types.Transaction, cch core.CrossChainHelper) (*neatAbi.CreateSideChainArgs, *params.SideChainForks, error) {
+	var args neatAbi.CreateSideChainWithForksArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.CreateSideChainWithForks.String(), data[4:]); err != nil {
+		return nil, nil, err
+	}
+
+	if err := cch.CanCreateSideChain(from, args.ChainId, args.MinValidators, args.MinDepositAmount, tx.Value(), args.StartBlock, args.EndBlock); err != nil {
+		return nil, nil, err
+	}
+
+	// the forks are built on each other
+	if args.PetersburgBlock.Cmp(args.ConstantinopleBlock) < 0 || args.IstanbulBlock.Cmp(args.PetersburgBlock) < 0 {
+		return nil, nil, errors.New("fork heights must be in the order of constantinople, petersburg and istanbul")
+	}
+
+	return &neatAbi.CreateSideChainArgs{
+		ChainId:          args.ChainId,
+		MinValidators:    args.MinValidators,
+		MinDepositAmount: args.MinDepositAmount,
+		StartBlock:       args.StartBlock,
+		EndBlock:         args.EndBlock,
+	}, &params.SideChainForks{
+		ConstantinopleBlock: args.ConstantinopleBlock,
+		PetersburgBlock:     args.PetersburgBlock,
+		IstanbulBlock:       args.IstanbulBlock,
+	}, nil
+}
+
+// applyCreateSideChain records the side chain to be created once the block is committed, the side chain
+// created without the forks never activates them
+func applyCreateSideChain(function neatAbi.FunctionType, from common.Address, tx *types.Transaction, state *state.StateDB, ops *types.PendingOps,
+	args *neatAbi.CreateSideChainArgs, forks *params.SideChainForks) error {
+
+	op := types.CreateSideChainOp{
+		From:             from,
+		ChainId:          args.ChainId,
+		MinValidators:    args.MinValidators,
+		MinDepositAmount: args.MinDepositAmount,
+		StartBlock:       args.StartBlock,
+		EndBlock:         args.EndBlock,
+		Forks:            forks,
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	// Lock the startup cost, refunded if the side chain fails to launch
+	state.SubBalance(from, tx.Value())
+	state.AddChainBalance(from, tx.Value())
+
+	if err := addChainLog(state, function, from, args.ChainId, args.MinValidators, args.MinDepositAmount, args.StartBlock, args.EndBlock); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// join side chain
+func joinSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := joinSideChainValidation(from, tx, cch)
+	return err
+}
+
+func joinSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := joinSideChainValidation(from, tx, cch)
+	if err != nil {
+		return err
+	}
+
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.PubKey)
+
+	op := types.JoinSideChainOp{
+		From:          from,
+		PubKey:        blsPK,
+		ChainId:       args.ChainId,
+		DepositAmount: tx.Value(),
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	// Lock the deposit, refunded if the side chain fails to launch
+	state.SubBalance(from, tx.Value())
+	state.AddSideChainDepositBalance(from, args.ChainId, tx.Value())
+
+	if err := addChainLog(state, neatAbi.JoinSideChain, from, args.ChainId, tx.Value()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func joinSideChainValidation(from common.Address, tx *types.Transaction, cch core.CrossChainHelper) (*neatAbi.JoinSideChainArgs, error) {
+	var args neatAbi.JoinSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.JoinSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if err := cch.ValidateJoinSideChain(from, args.PubKey, args.ChainId, tx.Value(), args.Signature); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
+// deposit in main chain (tx1)
+func depositInMainChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	_, err := depositInMainChainValidation(tx, cch)
+	return err
+}
+
+func depositInMainChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	ci, err := depositInMainChainValidation(tx, cch)
+	if err != nil {
+		return err
+	}
+
+	// The deposit is locked under the side chain owner until withdrawn from the side chain
+	state.SubBalance(from, tx.Value())
+	state.AddChainBalance(ci.Owner, tx.Value())
+	state.AddTX1(from, tx.Hash())
+
+	if err := addChainLog(state, neatAbi.DepositInMainChain, from, ci.ChainId, tx.Value()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func depositInMainChainValidation(tx *types.Transaction, cch core.CrossChainHelper) (*core.ChainInfo, error) {
+	var args neatAbi.DepositInMainChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.DepositInMainChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if tx.Value().Sign() < 1 {
+		return nil, errors.New("deposit amount must be greater than 0")
+	}
+
+	ci := core.GetChainInfo(cch.GetChainInfoDB(), args.ChainId)
+	if ci == nil {
+		return nil, fmt.Errorf("side chain %s not exist", args.ChainId)
+	}
+	if core.GetSideChainClosure(cch.GetChainInfoDB(), args.ChainId) != nil {
+		return nil, fmt.Errorf("side chain %s is closing", args.ChainId)
+	}
+
+	return ci, nil
+}
+
+// deposit in side chain (tx2)
+func depositInSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := depositInSideChainValidation(from, tx, state, cch)
+	return err
+}
+
+func depositInSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := depositInSideChainValidation(from, tx, state, cch)
+	if err != nil {
+		return err
+	}
+
+	tx1 := cch.GetTxFromMainChain(args.TxHash)
+	state.AddBalance(from, tx1.Value())
+	state.AddTX1(from, args.TxHash)
+
+	if err := addChainLog(state, neatAbi.DepositInSideChain, from, args.ChainId, args.TxHash, tx1.Value()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func depositInSideChainValidation(from common.Address, tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) (*neatAbi.DepositInSideChainArgs, error) {
+	var args neatAbi.DepositInSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.DepositInSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if !isTxOfChain(tx, args.ChainId) {
+		return nil, fmt.Errorf("tx is not signed for side chain %s", args.ChainId)
+	}
+
+	// Each deposit of the main chain could only be claimed once
+	if state.HasTX1(from, args.TxHash) {
+		return nil, fmt.Errorf("tx %x has already been deposited", args.TxHash)
+	}
+
+	tx1 := cch.GetTxFromMainChain(args.TxHash)
+	if tx1 == nil {
+		return nil, fmt.Errorf("tx %x not found in main chain", args.TxHash)
+	}
+	if derivedAddressFromTx(tx1) != from {
+		return nil, errors.New("tx in main chain is not sent by the same address")
+	}
+	if !neatAbi.IsNeatChainContractAddr(tx1.To()) || len(tx1.Data()) < 4 {
+		return nil, errors.New("tx in main chain is not a deposit")
+	}
+	function, err := neatAbi.FunctionTypeFromId(tx1.Data()[:4])
+	if err != nil || function != neatAbi.DepositInMainChain {
+		return nil, errors.New("tx in main chain is not a deposit")
+	}
+
+	var tx1Args neatAbi.DepositInMainChainArgs
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&tx1Args, neatAbi.DepositInMainChain.String(), tx1.Data()[4:]); err != nil {
+		return nil, err
+	}
+	if tx1Args.ChainId != args.ChainId {
+		return nil, fmt.Errorf("tx in main chain is a deposit to side chain %s", tx1Args.ChainId)
+	}
+
+	return &args, nil
+}
+
+// withdraw from side chain (tx3)
+func withdrawFromSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	_, err := withdrawFromSideChainValidation(tx)
+	return err
+}
+
+func withdrawFromSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := withdrawFromSideChainValidation(tx)
+	if err != nil {
+		return err
+	}
+
+	state.SubBalance(from, tx.Value())
+	state.AddTX3(from, tx.Hash())
+
+	if err := addChainLog(state, neatAbi.WithdrawFromSideChain, from, args.ChainId, tx.Value()); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func withdrawFromSideChainValidation(tx *types.Transaction) (*neatAbi.WithdrawFromSideChainArgs, error) {
+	var args neatAbi.WithdrawFromSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.WithdrawFromSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if !isTxOfChain(tx, args.ChainId) {
+		return nil, fmt.Errorf("tx is not signed for side chain %s", args.ChainId)
+	}
+
+	if tx.Value().Sign() < 1 {
+		return nil, errors.New("withdraw amount must be greater than 0")
+	}
+
+	return &args, nil
+}
+
+// withdraw from main chain (tx4)
+func withdrawFromMainChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, _, err := withdrawFromMainChainValidation(from, tx, state, cch, true)
+	return err
+}
+
+func withdrawFromMainChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	// The tx3 of the blocks received is proved by the block, only the miner checks the local cache
+	args, ci, err := withdrawFromMainChainValidation(from, tx, state, cch, mining)
+	if err != nil {
+		return err
+	}
+
+	state.SubChainBalance(ci.Owner, args.Amount)
+	state.AddBalance(from, args.Amount)
+	state.AddTX3(from, args.TxHash)
+
+	if err := addChainLog(state, neatAbi.WithdrawFromMainChain, from, args.ChainId, args.TxHash, args.Amount); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func withdrawFromMainChainValidation(from common.Address, tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper, checkTX3 bool) (*neatAbi.WithdrawFromMainChainArgs, *core.ChainInfo, error) {
+	var args neatAbi.WithdrawFromMainChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.WithdrawFromMainChain.String(), data[4:]); err != nil {
+		return nil, nil, err
+	}
+
+	if args.Amount.Sign() < 1 {
+		return nil, nil, errors.New("withdraw amount must be greater than 0")
+	}
+
+	// Each withdraw of the side chain could only be claimed once
+	if state.HasTX3(from, args.TxHash) {
+		return nil, nil, fmt.Errorf("tx %x has already been withdrawn", args.TxHash)
+	}
+
+	ci := core.GetChainInfo(cch.GetChainInfoDB(), args.ChainId)
+	if ci == nil {
+		return nil, nil, fmt.Errorf("side chain %s not exist", args.ChainId)
+	}
+	if state.GetChainBalance(ci.Owner).Cmp(args.Amount) < 0 {
+		return nil, nil, fmt.Errorf("side chain %s balance not enough for the withdraw", args.ChainId)
+	}
+
+	if checkTX3 {
+		tx3 := cch.GetTX3(args.ChainId, args.TxHash)
+		if tx3 == nil {
+			return nil, nil, fmt.Errorf("tx %x not found in side chain %s", args.TxHash, args.ChainId)
+		}
+		var tx3Args neatAbi.WithdrawFromSideChainArgs
+		if err := neatAbi.ChainABI.UnpackMethodInputs(&tx3Args, neatAbi.WithdrawFromSideChain.String(), tx3.Data()[4:]); err != nil {
+			return nil, nil, err
+		}
+		if derivedAddressFromTx(tx3) != from || tx3Args.ChainId != args.ChainId || tx3.Value().Cmp(args.Amount) != 0 {
+			return nil, nil, errors.New("params are not consistent with tx in side chain")
+		}
+	}
+
+	return &args, ci, nil
+}
+
+// save data to main chain
+func saveDataToMainChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	var args []byte
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.SaveDataToMainChain.String(), data[4:]); err != nil {
+		return err
+	}
+
+	return cch.VerifySideChainProofData(args)
+}
+
+func saveDataToMainChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	var args []byte
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.SaveDataToMainChain.String(), data[4:]); err != nil {
+		return err
+	}
+
+	if err := cch.VerifySideChainProofData(args); err != nil {
+		return err
+	}
+
+	op := types.SaveDataToMainChainOp{
+		Data: args,
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	if err := addChainLog(state, neatAbi.SaveDataToMainChain, derivedAddressFromTx(tx), crypto.Keccak256Hash(args)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// set block reward
+func setBlockRewardValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := setBlockRewardValidation(from, tx, cch)
+	return err
+}
+
+func setBlockRewardApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := setBlockRewardValidation(from, tx, cch)
+	if err != nil {
+		return err
+	}
+
+	state.SetSideChainRewardPerBlock(args.Reward)
+
+	if err := addChainLog(state, neatAbi.SetBlockReward, from, args.ChainId, args.Reward); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func setBlockRewardValidation(from common.Address, tx *types.Transaction, cch core.CrossChainHelper) (*neatAbi.SetBlockRewardArgs, error) {
+	var args neatAbi.SetBlockRewardArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.SetBlockReward.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if !isTxOfChain(tx, args.ChainId) {
+		return nil, fmt.Errorf("tx is not signed for side chain %s", args.ChainId)
+	}
+
+	ci := core.GetChainInfo(cch.GetChainInfoDB(), args.ChainId)
+	if ci == nil || ci.Owner != from {
+		return nil, core.ErrNotOwner
+	}
+
+	if args.Reward.Sign() == -1 {
+		return nil, core.ErrNegativeValue
+	}
+
+	return &args, nil
+}
+
+// deliver cross chain message
+func deliverMessageValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	_, err := deliverMessageValidation(tx, state, cch)
+	return err
+}
+
+func deliverMessageApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	proof, err := deliverMessageValidation(tx, state, cch)
+	if err != nil {
+		return err
+	}
+
+	// the message is executed once marked as delivered, see core.ApplyCrossChainMessage
+	vm.MarkCrossChainMessageDelivered(state, proof.Message.Hash())
+
+	if err := addChainLog(state, neatAbi.DeliverMessage, proof.Message.Hash(), proof.Message.SourceChain); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func deliverMessageValidation(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) (*types.CrossChainMessageProof, error) {
+	if tx.Value().Sign() != 0 {
+		return nil, errors.New("cross chain message can not carry value")
+	}
+
+	proof, err := core.DecodeCrossChainMessageProof(tx)
+	if err != nil {
+		return nil, err
+	}
+
+	// the tx is signed for this chain, the message must target it
+	msg := proof.Message
+	if msg.TargetChain != common.BigToHash(tx.ChainId()) {
+		return nil, errors.New("cross chain message is not sent to this chain")
+	}
+	if vm.IsCrossChainMessageDelivered(state, msg.Hash()) {
+		return nil, fmt.Errorf("cross chain message %x already delivered", msg.Hash())
+	}
+
+	if err := cch.ValidateCrossChainMessageProof(proof); err != nil {
+		return nil, err
+	}
+	return proof, nil
+}
+
+// close side chain
+func closeSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, err := closeSideChainValidation(from, tx, cch)
+	return err
+}
+
+func closeSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	args, err := closeSideChainValidation(from, tx, cch)
+	if err != nil {
+		return err
+	}
+
+	op := types.CloseSideChainOp{
+		From:       from,
+		ChainId:    args.ChainId,
+		HaltHeight: args.HaltHeight.Uint64(),
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	if err := addChainLog(state, neatAbi.CloseSideChain, from, args.ChainId, args.HaltHeight); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func closeSideChainValidation(from common.Address, tx *types.Transaction, cch core.CrossChainHelper) (*neatAbi.CloseSideChainArgs, error) {
+	var args neatAbi.CloseSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.CloseSideChain.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	if !args.HaltHeight.IsUint64() {
+		return nil, errors.New("invalid halt height")
+	}
+
+	if err := cch.ValidateCloseSideChain(from, args.ChainId, args.HaltHeight.Uint64()); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
+// withdraw from closed side chain
+func withdrawFromClosedSideChainValidateCb(tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) error {
+	from := derivedAddressFromTx(tx)
+	_, _, _, err := withdrawFromClosedSideChainValidation(from, tx, state, cch)
+	return err
+}
+
+func withdrawFromClosedSideChainApplyCb(tx *types.Transaction, state *state.StateDB, ops *types.PendingOps, cch core.CrossChainHelper, mining bool) error {
+	from := derivedAddressFromTx(tx)
+	ci, closure, balance, err := withdrawFromClosedSideChainValidation(from, tx, state, cch)
+	if err != nil {
+		return err
+	}
+
+	// The final balance could only be withdrawn once, marked by the hash of the final block
+	state.SubChainBalance(ci.Owner, balance)
+	state.AddBalance(from, balance)
+	state.AddTX3(from, closure.FinalHash)
+
+	if err := addChainLog(state, neatAbi.WithdrawFromClosedSideChain, from, ci.ChainId, balance); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func withdrawFromClosedSideChainValidation(from common.Address, tx *types.Transaction, state *state.StateDB, cch core.CrossChainHelper) (*core.ChainInfo, *core.SideChainClosure, *big.Int, error) {
+	var args neatAbi.WithdrawFromClosedSideChainArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.WithdrawFromClosedSideChain.String(), data[4:]); err != nil {
+		return nil, nil, nil, err
+	}
+
+	ci := core.GetChainInfo(cch.GetChainInfoDB(), args.ChainId)
+	if ci == nil {
+		return nil, nil, nil, fmt.Errorf("side chain %s not exist", args.ChainId)
+	}
+	closure := core.GetSideChainClosure(cch.GetChainInfoDB(), args.ChainId)
+	if closure == nil || !closure.Settled() {
+		return nil, nil, nil, fmt.Errorf("final state of side chain %s not anchored", args.ChainId)
+	}
+	if cch.GetHeightFromMainChain().Uint64() > closure.SettlementEnd() {
+		return nil, nil, nil, fmt.Errorf("settlement period of side chain %s is over", args.ChainId)
+	}
+	if state.HasTX3(from, closure.FinalHash) {
+		return nil, nil, nil, fmt.Errorf("final balance of side chain %s has already been withdrawn", args.ChainId)
+	}
+
+	var proof types.BSKeyValueSet
+	if err := rlp.DecodeBytes(args.Proof, &proof); err != nil {
+		return nil, nil, nil, err
+	}
+	balance, err := core.VerifyFinalBalance(closure.FinalRoot, from, &proof)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if balance.Sign() < 1 {
+		return nil, nil, nil, errors.New("no final balance to withdraw")
+	}
+	if state.GetChainBalance(ci.Owner).Cmp(balance) < 0 {
+		return nil, nil, nil, fmt.Errorf("side chain %s balance not enough for the withdraw", args.ChainId)
+	}
+
+	return ci, closure, balance, nil
+}
+
+// vote next epoch
+func voteNextEpochValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	_, err := voteNextEpochValidation(tx, bc)
+	return err
+}
+
+func voteNextEpochApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := voteNextEpochValidation(tx, bc)
+	if err != nil {
+		return err
+	}
+
+	op := types.VoteNextEpochOp{
+		From:     from,
+		VoteHash: args.VoteHash,
+		TxHash:   tx.Hash(),
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	if err := addChainLog(state, neatAbi.VoteNextEpoch, from, args.VoteHash); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func voteNextEpochValidation(tx *types.Transaction, bc *core.BlockChain) (*neatAbi.VoteNextEpochArgs, error) {
+	var args neatAbi.VoteNextEpochArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.VoteNextEpoch.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return nil, err
+	}
+	if ep.GetNextEpoch() == nil {
+		return nil, errors.New("next epoch not proposed yet, please retry later")
+	}
+
+	if err := updateValidation(bc); err != nil {
+		return nil, err
+	}
+
+	return &args, nil
+}
+
+// reveal vote
+func revealVoteValidateCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) error {
+	from := derivedAddressFromTx(tx)
+	_, err := revealVoteValidation(from, tx, state, bc)
+	return err
+}
+
+func revealVoteApplyCb(tx *types.Transaction, state *state.StateDB, bc *core.BlockChain, ops *types.PendingOps) error {
+	from := derivedAddressFromTx(tx)
+	args, err := revealVoteValidation(from, tx, state, bc)
+	if err != nil {
+		return err
+	}
+
+	// Lock the part of the vote amount not deposited yet
+	deposit := state.GetDepositBalance(from)
+	if args.Amount.Cmp(deposit) == 1 {
+		difference := new(big.Int).Sub(args.Amount, deposit)
+		state.SubBalance(from, difference)
+		state.AddDepositBalance(from, difference)
+	}
+
+	var blsPK goCrypto.BLSPubKey
+	copy(blsPK[:], args.PubKey)
+
+	op := types.RevealVoteOp{
+		From:   from,
+		Pubkey: blsPK,
+		Amount: args.Amount,
+		Salt:   args.Salt,
+		TxHash: tx.Hash(),
+	}
+	if ok := ops.Append(&op); !ok {
+		return fmt.Errorf("pending ops conflict: %v", op)
+	}
+
+	if err := addChainLog(state, neatAbi.RevealVote, from, args.Amount); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func revealVoteValidation(from common.Address, tx *types.Transaction, state *state.StateDB, bc *core.BlockChain) (*neatAbi.RevealVoteArgs, error) {
+	var args neatAbi.RevealVoteArgs
+	data := tx.Data()
+	if err := neatAbi.ChainABI.UnpackMethodInputs(&args, neatAbi.RevealVote.String(), data[4:]); err != nil {
+		return nil, err
+	}
+
+	ep, err := getEpoch(bc)
+	if err != nil {
+		return nil, err
+	}
+	if err := updateValidation(bc); err != nil {
+		return nil, err
+	}
+
+	// Check Signature of the PubKey matched against the Address
+	if err := goCrypto.CheckConsensusPubKey(from, args.PubKey, args.Signature); err != nil {
+		return nil, err
+	}
+
+	nextEp := ep.GetNextEpoch()
+	if nextEp == nil || nextEp.GetEpochValidatorVoteSet() == nil {
+		return nil, errors.New("the vote set of next epoch is empty")
+	}
+	vote, exist := nextEp.GetEpochValidatorVoteSet().GetVoteByAddress(from)
+	if !exist || vote.VoteHash == (common.Hash{}) {
+		return nil, fmt.Errorf("can not find the vote hash of address %x", from)
+	}
+
+	// VoteHash = Sha3(Address + PubKey + Amount + Salt)
+	voteHash := crypto.Keccak256Hash(concatCopyPreAllocate([][]byte{
+		from.Bytes(),
+		args.PubKey,
+		args.Amount.Bytes(),
+		[]byte(args.Salt),
+	}))
+	if vote.VoteHash != voteHash {
+		return nil, errors.New("your vote doesn't match your vote hash, please check your vote")
+	}
+
+	// The vote amount of a new validator can't be 0
+	if !ep.Validators.HasAddress(from.Bytes()) && args.Amount.Sign() == 0 {
+		return nil, errors.New("vote amount of new validator must be greater than 0")
+	}
+
+	deposit := state.GetDepositBalance(from)
+	if args.Amount.Cmp(deposit) == 1 {
+		difference := new(big.Int).Sub(args.Amount, deposit)
+		if state.GetBalance(from).Cmp(difference) == -1 {
+			return nil, fmt.Errorf("balance not enough for the vote amount %v", args.Amount)
+		}
+	}
+
+	return &args, nil
+}
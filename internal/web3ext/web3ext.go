@@ -19,6 +19,7 @@ package web3ext
 
 var Modules = map[string]string{
 	"admin":      Admin_JS,
+	"chain":      Chain_JS,
 	"chequebook": Chequebook_JS,
 	"clique":     Clique_JS,
 	"debug":      Debug_JS,
@@ -162,8 +163,8 @@ web3._extend({
 			call: 'admin_stopWS'
 		}),
 		new web3._extend.Method({
-			name: 'startScanAndPrune',
-			call: 'admin_startScanAndPrune'
+			name: 'latestPruneState',
+			call: 'admin_latestPruneState'
 		}),
 	],
 	properties: [
@@ -443,6 +444,11 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getReceiptProof',
+			call: 'eth_getReceiptProof',
+			params: 1
+		}),
 	],
 	properties: [
 		new web3._extend.Property({
@@ -508,6 +514,11 @@ web3._extend({
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter, null]
 		}),
+		new web3._extend.Method({
+			name: 'getReceiptProof',
+			call: 'neat_getReceiptProof',
+			params: 1
+		}),
 		new web3._extend.Method({
 			name: 'signAddress',
 			call: 'neat_signAddress',
@@ -632,6 +643,12 @@ web3._extend({
 			call: 'neat_setCommission',
 			params: 3,
 			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getUnbondings',
+			call: 'neat_getUnbondings',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, web3._extend.formatters.inputDefaultBlockNumberFormatter]
 		})
 	],
 	properties: [
@@ -651,6 +668,122 @@ web3._extend({
 });
 `
 
+const Chain_JS = `
+web3._extend({
+	property: 'chain',
+	methods: [
+		new web3._extend.Method({
+			name: 'createSideChain',
+			call: 'chain_createSideChain',
+			params: 7,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'createSideChainWithForks',
+			call: 'chain_createSideChainWithForks',
+			params: 10,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'joinSideChain',
+			call: 'chain_joinSideChain',
+			params: 6,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'depositInMainChain',
+			call: 'chain_depositInMainChain',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'depositInSideChain',
+			call: 'chain_depositInSideChain',
+			params: 2,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null]
+		}),
+		new web3._extend.Method({
+			name: 'withdrawFromSideChain',
+			call: 'chain_withdrawFromSideChain',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'withdrawFromMainChain',
+			call: 'chain_withdrawFromMainChain',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'voteNextEpoch',
+			call: 'chain_voteNextEpoch',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'revealVote',
+			call: 'chain_revealVote',
+			params: 6,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'setBlockReward',
+			call: 'chain_setBlockReward',
+			params: 3,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'broadcastTX3ProofData',
+			call: 'chain_broadcastTX3ProofData',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getCrossChainMessageProof',
+			call: 'chain_getCrossChainMessageProof',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'deliverCrossChainMessage',
+			call: 'chain_deliverCrossChainMessage',
+			params: 4
+		}),
+		new web3._extend.Method({
+			name: 'closeSideChain',
+			call: 'chain_closeSideChain',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getSideChainClosure',
+			call: 'chain_getSideChainClosure',
+			params: 1
+		}),
+		new web3._extend.Method({
+			name: 'getFinalBalanceProof',
+			call: 'chain_getFinalBalanceProof',
+			params: 1,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter]
+		}),
+		new web3._extend.Method({
+			name: 'withdrawFromClosedSideChain',
+			call: 'chain_withdrawFromClosedSideChain',
+			params: 4,
+			inputFormatter: [web3._extend.formatters.inputAddressFormatter, null, null, null]
+		}),
+		new web3._extend.Method({
+			name: 'getCrossChainTransfer',
+			call: 'chain_getCrossChainTransfer',
+			params: 2
+		}),
+		new web3._extend.Method({
+			name: 'listPendingWithdrawals',
+			call: 'chain_listPendingWithdrawals',
+			params: 1
+		})
+	]
+});
+`
+
 const Miner_JS = `
 web3._extend({
 	property: 'miner',
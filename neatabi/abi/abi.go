@@ -17,25 +17,35 @@ type FunctionType struct {
 
 var (
 	// Cross Chain Function
-	CreateSideChain       = FunctionType{0, true, true, false}
-	JoinSideChain         = FunctionType{1, true, true, false}
-	DepositInMainChain    = FunctionType{2, true, true, false}
-	DepositInSideChain    = FunctionType{3, true, false, true}
-	WithdrawFromSideChain = FunctionType{4, true, false, true}
-	WithdrawFromMainChain = FunctionType{5, true, true, false}
-	SaveDataToMainChain   = FunctionType{6, true, true, false}
-	SetBlockReward        = FunctionType{7, true, false, true}
+	CreateSideChain             = FunctionType{0, true, true, false}
+	JoinSideChain               = FunctionType{1, true, true, false}
+	DepositInMainChain          = FunctionType{2, true, true, false}
+	DepositInSideChain          = FunctionType{3, true, false, true}
+	WithdrawFromSideChain       = FunctionType{4, true, false, true}
+	WithdrawFromMainChain       = FunctionType{5, true, true, false}
+	SaveDataToMainChain         = FunctionType{6, true, true, false}
+	SetBlockReward              = FunctionType{7, true, false, true}
+	DeliverMessage              = FunctionType{8, true, true, true}
+	CloseSideChain              = FunctionType{9, true, true, false}
+	WithdrawFromClosedSideChain = FunctionType{21, true, true, false}
+	CreateSideChainWithForks    = FunctionType{27, true, true, false}
 	// Non-Cross Chain Function
-	VoteNextEpoch  = FunctionType{10, false, true, true}
-	RevealVote     = FunctionType{11, false, true, true}
-	Delegate       = FunctionType{12, false, true, true}
-	UnDelegate     = FunctionType{13, false, true, true}
-	Register       = FunctionType{14, false, true, true}
-	UnRegister     = FunctionType{15, false, true, true}
-	EditValidator  = FunctionType{16, false, true, true}
-	WithdrawReward = FunctionType{17, false, true, true}
-	UnForbidden    = FunctionType{18, false, true, true}
-	SetCommission  = FunctionType{19, false, true, true}
+	VoteNextEpoch    = FunctionType{10, false, true, true}
+	RevealVote       = FunctionType{11, false, true, true}
+	Delegate         = FunctionType{12, false, true, true}
+	UnDelegate       = FunctionType{13, false, true, true}
+	Register         = FunctionType{14, false, true, true}
+	UnRegister       = FunctionType{15, false, true, true}
+	EditValidator    = FunctionType{16, false, true, true}
+	WithdrawReward   = FunctionType{17, false, true, true}
+	UnForbidden      = FunctionType{18, false, true, true}
+	SetCommission    = FunctionType{19, false, true, true}
+	ReportDoubleSign = FunctionType{20, false, true, true}
+	SubmitProposal   = FunctionType{22, false, true, true}
+	VoteProposal     = FunctionType{23, false, true, true}
+	DepositProposal  = FunctionType{24, false, true, true}
+	ReDelegate       = FunctionType{25, false, true, true}
+	SetAutoCompound  = FunctionType{26, false, true, true}
 	// Unknown
 	Unknown = FunctionType{-1, false, false, false}
 )
@@ -52,9 +62,12 @@ func (t FunctionType) AllowInSideChain() bool {
 	return t.side
 }
 
+// RequiredGasParamPrefix prefixes the governance parameters of the required gas table
+const RequiredGasParamPrefix = "RequiredGas."
+
 func (t FunctionType) RequiredGas() uint64 {
 	switch t {
-	case CreateSideChain:
+	case CreateSideChain, CreateSideChainWithForks:
 		return 200000
 	case JoinSideChain:
 		return 100000
@@ -76,6 +89,12 @@ func (t FunctionType) RequiredGas() uint64 {
 		return 100000
 	case SetBlockReward:
 		return 100000
+	case DeliverMessage:
+		return 100000
+	case CloseSideChain:
+		return 100000
+	case WithdrawFromClosedSideChain:
+		return 200000
 	case EditValidator:
 		return 100000
 	case WithdrawReward:
@@ -84,11 +103,26 @@ func (t FunctionType) RequiredGas() uint64 {
 		return 100000
 	case SetCommission:
 		return 100000
+	case ReportDoubleSign:
+		return 0
+	case SubmitProposal:
+		return 200000
+	case VoteProposal, DepositProposal:
+		return 100000
+	case ReDelegate:
+		return 100000
+	case SetAutoCompound:
+		return 100000
 	default:
 		return 0
 	}
 }
 
+// GasParam returns the name of the governance parameter overriding the required gas of the function
+func (t FunctionType) GasParam() string {
+	return RequiredGasParamPrefix + t.String()
+}
+
 func (t FunctionType) String() string {
 	switch t {
 	case CreateSideChain:
@@ -119,6 +153,14 @@ func (t FunctionType) String() string {
 		return "UnRegister"
 	case SetBlockReward:
 		return "SetBlockReward"
+	case DeliverMessage:
+		return "DeliverMessage"
+	case CloseSideChain:
+		return "CloseSideChain"
+	case WithdrawFromClosedSideChain:
+		return "WithdrawFromClosedSideChain"
+	case CreateSideChainWithForks:
+		return "CreateSideChainWithForks"
 	case EditValidator:
 		return "EditValidator"
 	case WithdrawReward:
@@ -127,6 +169,18 @@ func (t FunctionType) String() string {
 		return "UnForbidden"
 	case SetCommission:
 		return "SetCommission"
+	case ReportDoubleSign:
+		return "ReportDoubleSign"
+	case SubmitProposal:
+		return "SubmitProposal"
+	case VoteProposal:
+		return "VoteProposal"
+	case DepositProposal:
+		return "DepositProposal"
+	case ReDelegate:
+		return "ReDelegate"
+	case SetAutoCompound:
+		return "SetAutoCompound"
 	default:
 		return "UnKnown"
 	}
@@ -162,6 +216,14 @@ func StringToFunctionType(s string) FunctionType {
 		return UnRegister
 	case "SetBlockReward":
 		return SetBlockReward
+	case "DeliverMessage":
+		return DeliverMessage
+	case "CloseSideChain":
+		return CloseSideChain
+	case "WithdrawFromClosedSideChain":
+		return WithdrawFromClosedSideChain
+	case "CreateSideChainWithForks":
+		return CreateSideChainWithForks
 	case "EditValidator":
 		return EditValidator
 	case "WithdrawReward":
@@ -170,6 +232,18 @@ func StringToFunctionType(s string) FunctionType {
 		return UnForbidden
 	case "SetCommission":
 		return SetCommission
+	case "ReportDoubleSign":
+		return ReportDoubleSign
+	case "SubmitProposal":
+		return SubmitProposal
+	case "VoteProposal":
+		return VoteProposal
+	case "DepositProposal":
+		return DepositProposal
+	case "ReDelegate":
+		return ReDelegate
+	case "SetAutoCompound":
+		return SetAutoCompound
 	default:
 		return Unknown
 	}
@@ -183,6 +257,17 @@ type CreateSideChainArgs struct {
 	EndBlock         *big.Int
 }
 
+type CreateSideChainWithForksArgs struct {
+	ChainId             string
+	MinValidators       uint16
+	MinDepositAmount    *big.Int
+	StartBlock          *big.Int
+	EndBlock            *big.Int
+	ConstantinopleBlock *big.Int
+	PetersburgBlock     *big.Int
+	IstanbulBlock       *big.Int
+}
+
 type JoinSideChainArgs struct {
 	PubKey    []byte
 	ChainId   string
@@ -208,6 +293,16 @@ type WithdrawFromMainChainArgs struct {
 	TxHash  common.Hash
 }
 
+type CloseSideChainArgs struct {
+	ChainId    string
+	HaltHeight *big.Int
+}
+
+type WithdrawFromClosedSideChainArgs struct {
+	ChainId string
+	Proof   []byte
+}
+
 type VoteNextEpochArgs struct {
 	VoteHash common.Hash
 }
@@ -228,6 +323,12 @@ type UnDelegateArgs struct {
 	Amount    *big.Int
 }
 
+type ReDelegateArgs struct {
+	FromCandidate common.Address
+	ToCandidate   common.Address
+	Amount        *big.Int
+}
+
 type RegisterArgs struct {
 	Pubkey     []byte
 	Signature  []byte
@@ -250,6 +351,10 @@ type WithdrawRewardArgs struct {
 	DelegateAddress common.Address
 }
 
+type SetAutoCompoundArgs struct {
+	Enable bool
+}
+
 type UnForbiddenArgs struct {
 }
 
@@ -257,6 +362,25 @@ type SetCommissionArgs struct {
 	Commission uint8
 }
 
+type ReportDoubleSignArgs struct {
+	Evidence []byte
+}
+
+type SubmitProposalArgs struct {
+	Param       string
+	Value       *big.Int
+	TargetEpoch uint64
+}
+
+type VoteProposalArgs struct {
+	Id      uint64
+	Approve bool
+}
+
+type DepositProposalArgs struct {
+	Id uint64
+}
+
 const jsonChainABI = `
 [
 	{
@@ -286,6 +410,45 @@ const jsonChainABI = `
 			}
 		]
 	},
+	{
+		"type": "function",
+		"name": "CreateSideChainWithForks",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "chainId",
+				"type": "string"
+			},
+			{
+				"name": "minValidators",
+				"type": "uint16"
+			},
+			{
+				"name": "minDepositAmount",
+				"type": "uint256"
+			},
+			{
+				"name": "startBlock",
+				"type": "uint256"
+			},
+			{
+				"name": "endBlock",
+				"type": "uint256"
+			},
+			{
+				"name": "constantinopleBlock",
+				"type": "uint256"
+			},
+			{
+				"name": "petersburgBlock",
+				"type": "uint256"
+			},
+			{
+				"name": "istanbulBlock",
+				"type": "uint256"
+			}
+		]
+	},
 	{
 		"type": "function",
 		"name": "JoinSideChain",
@@ -372,6 +535,47 @@ const jsonChainABI = `
 			}
 		]
 	},
+	{
+		"type": "function",
+		"name": "DeliverMessage",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "proof",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "CloseSideChain",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "chainId",
+				"type": "string"
+			},
+			{
+				"name": "haltHeight",
+				"type": "uint256"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "WithdrawFromClosedSideChain",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "chainId",
+				"type": "string"
+			},
+			{
+				"name": "proof",
+				"type": "bytes"
+			}
+		]
+	},
 	{
 		"type": "function",
 		"name": "VoteNextEpoch",
@@ -432,6 +636,25 @@ const jsonChainABI = `
 			}
 		]
 	},
+	{
+		"type": "function",
+		"name": "ReDelegate",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "fromCandidate",
+				"type": "address"
+			},
+			{
+				"name": "toCandidate",
+				"type": "address"
+			},
+			{
+				"name": "amount",
+				"type": "uint256"
+			}
+		]
+	},
 	{
 		"type": "function",
 		"name": "Register",
@@ -506,6 +729,17 @@ const jsonChainABI = `
 			}
 		]
 	},
+	{
+		"type": "function",
+		"name": "SetAutoCompound",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "enable",
+				"type": "bool"
+			}
+		]
+	},
 	{
 		"type": "function",
 		"name": "UnForbidden",
@@ -522,6 +756,62 @@ const jsonChainABI = `
 				"type": "uint8"
 			}
 		]
+	},
+	{
+		"type": "function",
+		"name": "ReportDoubleSign",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "evidence",
+				"type": "bytes"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "SubmitProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "param",
+				"type": "string"
+			},
+			{
+				"name": "value",
+				"type": "uint256"
+			},
+			{
+				"name": "targetEpoch",
+				"type": "uint64"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "VoteProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64"
+			},
+			{
+				"name": "approve",
+				"type": "bool"
+			}
+		]
+	},
+	{
+		"type": "function",
+		"name": "DepositProposal",
+		"constant": false,
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64"
+			}
+		]
 	}
 ]`
 
@@ -0,0 +1,685 @@
+package abi
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Gessiux/neatchain/chain/accounts/abi"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// EventName returns the name of the event logged by the function under the ChainContractMagicAddr
+func (t FunctionType) EventName() string {
+	switch t {
+	case CreateSideChain, CreateSideChainWithForks:
+		return "SideChainCreated"
+	case JoinSideChain:
+		return "SideChainJoined"
+	case DepositInMainChain:
+		return "DepositedInMainChain"
+	case DepositInSideChain:
+		return "DepositedInSideChain"
+	case WithdrawFromSideChain:
+		return "WithdrawnFromSideChain"
+	case WithdrawFromMainChain:
+		return "WithdrawnFromMainChain"
+	case SaveDataToMainChain:
+		return "DataSavedToMainChain"
+	case SetBlockReward:
+		return "BlockRewardSet"
+	case DeliverMessage:
+		return "MessageDelivered"
+	case CloseSideChain:
+		return "SideChainClosed"
+	case WithdrawFromClosedSideChain:
+		return "WithdrawnFromClosedSideChain"
+	case VoteNextEpoch:
+		return "EpochVoted"
+	case RevealVote:
+		return "VoteRevealed"
+	case Delegate:
+		return "Delegated"
+	case UnDelegate:
+		return "Undelegated"
+	case Register:
+		return "Registered"
+	case UnRegister:
+		return "Unregistered"
+	case EditValidator:
+		return "ValidatorEdited"
+	case WithdrawReward:
+		return "RewardWithdrawn"
+	case UnForbidden:
+		return "Unforbidden"
+	case SetCommission:
+		return "CommissionChanged"
+	case ReportDoubleSign:
+		return "DoubleSignReported"
+	case SubmitProposal:
+		return "ProposalSubmitted"
+	case VoteProposal:
+		return "ProposalVoted"
+	case DepositProposal:
+		return "ProposalDeposited"
+	case ReDelegate:
+		return "Redelegated"
+	case SetAutoCompound:
+		return "AutoCompoundSet"
+	default:
+		return ""
+	}
+}
+
+// PackEvent returns the topics and the data of the event logged by the function, the args are given
+// in the order of the event inputs
+func PackEvent(t FunctionType, args ...interface{}) ([]common.Hash, []byte, error) {
+	event, exist := ChainEventABI.Events[t.EventName()]
+	if !exist {
+		return nil, nil, fmt.Errorf("event of function %v not found", t)
+	}
+	if len(args) != len(event.Inputs) {
+		return nil, nil, fmt.Errorf("event %v argument count mismatch: %d for %d", event.Name, len(args), len(event.Inputs))
+	}
+
+	topics := []common.Hash{event.ID()}
+	var data []interface{}
+	for i, input := range event.Inputs {
+		if !input.Indexed {
+			data = append(data, args[i])
+			continue
+		}
+		// the indexed inputs are all static types, packed into one word
+		word, err := abi.Arguments{{Type: input.Type}}.Pack(args[i])
+		if err != nil {
+			return nil, nil, err
+		}
+		topics = append(topics, common.BytesToHash(word))
+	}
+
+	packed, err := event.Inputs.NonIndexed().Pack(data...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return topics, packed, nil
+}
+
+const jsonChainEventABI = `
+[
+	{
+		"type": "event",
+		"name": "SideChainCreated",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "minValidators",
+				"type": "uint16",
+				"indexed": false
+			},
+			{
+				"name": "minDepositAmount",
+				"type": "uint256",
+				"indexed": false
+			},
+			{
+				"name": "startBlock",
+				"type": "uint256",
+				"indexed": false
+			},
+			{
+				"name": "endBlock",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "SideChainJoined",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "DepositedInMainChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "DepositedInSideChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "txHash",
+				"type": "bytes32",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "WithdrawnFromSideChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "WithdrawnFromMainChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "txHash",
+				"type": "bytes32",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "DataSavedToMainChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "dataHash",
+				"type": "bytes32",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "BlockRewardSet",
+		"inputs": [
+			{
+				"name": "owner",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "reward",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "MessageDelivered",
+		"inputs": [
+			{
+				"name": "messageHash",
+				"type": "bytes32",
+				"indexed": true
+			},
+			{
+				"name": "sourceChain",
+				"type": "bytes32",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "SideChainClosed",
+		"inputs": [
+			{
+				"name": "owner",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "haltHeight",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "WithdrawnFromClosedSideChain",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "chainId",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "EpochVoted",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "voteHash",
+				"type": "bytes32",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "VoteRevealed",
+		"inputs": [
+			{
+				"name": "from",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Delegated",
+		"inputs": [
+			{
+				"name": "delegator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Undelegated",
+		"inputs": [
+			{
+				"name": "delegator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Registered",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			},
+			{
+				"name": "commission",
+				"type": "uint8",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Unregistered",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "ValidatorEdited",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "moniker",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "website",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "identity",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "details",
+				"type": "string",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "RewardWithdrawn",
+		"inputs": [
+			{
+				"name": "delegator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Unforbidden",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "CommissionChanged",
+		"inputs": [
+			{
+				"name": "candidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "commission",
+				"type": "uint8",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "DoubleSignReported",
+		"inputs": [
+			{
+				"name": "reporter",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "validator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "slashed",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "ProposalSubmitted",
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64",
+				"indexed": true
+			},
+			{
+				"name": "proposer",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "param",
+				"type": "string",
+				"indexed": false
+			},
+			{
+				"name": "value",
+				"type": "uint256",
+				"indexed": false
+			},
+			{
+				"name": "targetEpoch",
+				"type": "uint64",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "ProposalVoted",
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64",
+				"indexed": true
+			},
+			{
+				"name": "voter",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "approve",
+				"type": "bool",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "ProposalDeposited",
+		"inputs": [
+			{
+				"name": "id",
+				"type": "uint64",
+				"indexed": true
+			},
+			{
+				"name": "depositor",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "Redelegated",
+		"inputs": [
+			{
+				"name": "delegator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "fromCandidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "toCandidate",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "amount",
+				"type": "uint256",
+				"indexed": false
+			}
+		]
+	},
+	{
+		"type": "event",
+		"name": "AutoCompoundSet",
+		"inputs": [
+			{
+				"name": "delegator",
+				"type": "address",
+				"indexed": true
+			},
+			{
+				"name": "enable",
+				"type": "bool",
+				"indexed": false
+			}
+		]
+	}
+]`
+
+var ChainEventABI abi.ABI
+
+func init() {
+	var err error
+	ChainEventABI, err = abi.JSON(strings.NewReader(jsonChainEventABI))
+	if err != nil {
+		panic("fail to create the chain event ABI: " + err.Error())
+	}
+}
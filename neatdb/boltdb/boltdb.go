@@ -0,0 +1,340 @@
+// Package boltdb implements the key-value database layer based on BoltDB.
+package boltdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/utilities/common"
+	bolt "go.etcd.io/bbolt"
+)
+
+const (
+	// FileName is the name of the BoltDB file in the database directory.
+	FileName = "bolt.db"
+
+	// iteratorChunk is the number of items an iterator loads in one read transaction,
+	// a long living read transaction would block the writes growing the file.
+	iteratorChunk = 1024
+)
+
+var (
+	// bucket is the single bucket holding all the key-value pairs.
+	bucket = []byte("neatdb")
+
+	// errBoltdbNotFound is returned if a key is requested that is not found in
+	// the provided BoltDB database.
+	errBoltdbNotFound = errors.New("not found")
+)
+
+// Database is a persistent key-value store in a single BoltDB file. Apart from basic
+// data storage functionality it also supports batch writes and iterating over the
+// keyspace in binary-alphabetical order.
+type Database struct {
+	fn string   // filename for reporting
+	db *bolt.DB // BoltDB instance
+
+	log log.Logger // Contextual logger tracking the database path
+}
+
+// New returns a wrapped BoltDB object stored in the directory. BoltDB relies on the
+// page cache of the operating system, there's no cache to allocate.
+func New(dir string) (*Database, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	file := filepath.Join(dir, FileName)
+	db, err := bolt.Open(file, 0644, &bolt.Options{
+		Timeout:        time.Second,
+		NoFreelistSync: true,
+		FreelistType:   bolt.FreelistMapType,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := db.Update(func(tx *bolt.Tx) error {
+		_, err := tx.CreateBucketIfNotExists(bucket)
+		return err
+	}); err != nil {
+		db.Close()
+		return nil, err
+	}
+	return &Database{
+		fn:  file,
+		db:  db,
+		log: log.New("database", file),
+	}, nil
+}
+
+// Close flushes any pending data to disk and closes all io accesses to the
+// underlying key-value store.
+func (db *Database) Close() error {
+	return db.db.Close()
+}
+
+// lookup retrieves the value of the key, the value is only valid during the
+// transaction. Empty values are not told apart from missing keys by Bucket.Get.
+func lookup(tx *bolt.Tx, key []byte) ([]byte, bool) {
+	k, v := tx.Bucket(bucket).Cursor().Seek(key)
+	if k == nil || !bytes.Equal(k, key) {
+		return nil, false
+	}
+	return v, true
+}
+
+// Has retrieves if a key is present in the key-value store.
+func (db *Database) Has(key []byte) (bool, error) {
+	var has bool
+	err := db.db.View(func(tx *bolt.Tx) error {
+		_, has = lookup(tx, key)
+		return nil
+	})
+	return has, err
+}
+
+// Get retrieves the given key if it's present in the key-value store.
+func (db *Database) Get(key []byte) ([]byte, error) {
+	var value []byte
+	err := db.db.View(func(tx *bolt.Tx) error {
+		v, ok := lookup(tx, key)
+		if !ok {
+			return errBoltdbNotFound
+		}
+		value = append([]byte{}, v...)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return value, nil
+}
+
+// Put inserts the given value into the key-value store.
+func (db *Database) Put(key []byte, value []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Put(key, value)
+	})
+}
+
+// Delete removes the key from the key-value store.
+func (db *Database) Delete(key []byte) error {
+	return db.db.Update(func(tx *bolt.Tx) error {
+		return tx.Bucket(bucket).Delete(key)
+	})
+}
+
+// NewBatch creates a write-only key-value store that buffers changes to its host
+// database until a final write is called.
+func (db *Database) NewBatch() neatdb.Batch {
+	return &batch{
+		db: db,
+	}
+}
+
+// NewIterator creates a binary-alphabetical iterator over the entire keyspace
+// contained within the BoltDB database.
+func (db *Database) NewIterator() neatdb.Iterator {
+	return db.NewIteratorWithPrefix(nil)
+}
+
+// NewIteratorWithPrefix creates a binary-alphabetical iterator over a subset
+// of database content with a particular key prefix.
+func (db *Database) NewIteratorWithPrefix(prefix []byte) neatdb.Iterator {
+	return &iterator{
+		db:     db,
+		prefix: common.CopyBytes(prefix),
+		next:   append([]byte{}, prefix...),
+		index:  -1,
+	}
+}
+
+// Stat returns a particular internal stat of the database.
+func (db *Database) Stat(property string) (string, error) {
+	if property != "boltdb.stats" {
+		return "", errors.New("unknown property")
+	}
+	stats := db.db.Stats()
+	return fmt.Sprintf("Free pages: %d\nPending pages: %d\nFree page bytes: %d\nRead transactions: %d\nWrite transactions: %d\nWrite time: %v\n",
+		stats.FreePageN, stats.PendingPageN, stats.FreeAlloc, stats.TxN, stats.TxStats.Write, stats.TxStats.WriteTime), nil
+}
+
+// Compact is a noop, BoltDB reuses the pages freed by the deletions and doesn't
+// rearrange the data in place.
+func (db *Database) Compact(start []byte, limit []byte) error {
+	return nil
+}
+
+// Path returns the path to the database file.
+func (db *Database) Path() string {
+	return db.fn
+}
+
+// keyvalue is a key-value tuple tagged with a deletion field to allow creating
+// BoltDB write batches.
+type keyvalue struct {
+	key    []byte
+	value  []byte
+	delete bool
+}
+
+// batch is a write-only BoltDB batch that commits changes to its host database
+// in a single transaction when Write is called. A batch cannot be used concurrently.
+type batch struct {
+	db     *Database
+	writes []keyvalue
+	size   int
+}
+
+// Put inserts the given value into the batch for later committing.
+func (b *batch) Put(key, value []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), common.CopyBytes(value), false})
+	b.size += len(value)
+	return nil
+}
+
+// Delete inserts the a key removal into the batch for later committing.
+func (b *batch) Delete(key []byte) error {
+	b.writes = append(b.writes, keyvalue{common.CopyBytes(key), nil, true})
+	b.size++
+	return nil
+}
+
+// ValueSize retrieves the amount of data queued up for writing.
+func (b *batch) ValueSize() int {
+	return b.size
+}
+
+// Write flushes any accumulated data to disk.
+func (b *batch) Write() error {
+	if len(b.writes) == 0 {
+		return nil
+	}
+	return b.db.db.Update(func(tx *bolt.Tx) error {
+		bk := tx.Bucket(bucket)
+		for _, kv := range b.writes {
+			if kv.delete {
+				if err := bk.Delete(kv.key); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := bk.Put(kv.key, kv.value); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// Reset resets the batch for reuse.
+func (b *batch) Reset() {
+	b.writes = b.writes[:0]
+	b.size = 0
+}
+
+// Replay replays the batch contents.
+func (b *batch) Replay(w neatdb.Writer) error {
+	for _, kv := range b.writes {
+		if kv.delete {
+			if err := w.Delete(kv.key); err != nil {
+				return err
+			}
+			continue
+		}
+		if err := w.Put(kv.key, kv.value); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// iterator can walk over the (potentially partial) keyspace of a BoltDB database.
+// The items are loaded in chunks, every chunk in its own read transaction, so the
+// iterator doesn't see a consistent snapshot of the database.
+type iterator struct {
+	db     *Database
+	prefix []byte
+	next   []byte // First key of the next chunk, nil if no more chunk
+	keys   [][]byte
+	values [][]byte
+	index  int
+	err    error
+}
+
+// Next moves the iterator to the next key/value pair. It returns whether the
+// iterator is exhausted.
+func (it *iterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if it.index+1 < len(it.keys) {
+		it.index++
+		return true
+	}
+	it.keys, it.values, it.index = nil, nil, -1
+	if it.next == nil {
+		return false
+	}
+	if it.err = it.load(); it.err != nil || len(it.keys) == 0 {
+		return false
+	}
+	it.index = 0
+	return true
+}
+
+// load reads the next chunk of items.
+func (it *iterator) load() error {
+	return it.db.db.View(func(tx *bolt.Tx) error {
+		c := tx.Bucket(bucket).Cursor()
+
+		k, v := c.Seek(it.next)
+		for ; k != nil && bytes.HasPrefix(k, it.prefix) && len(it.keys) < iteratorChunk; k, v = c.Next() {
+			it.keys = append(it.keys, common.CopyBytes(k))
+			it.values = append(it.values, append([]byte{}, v...))
+		}
+		if k == nil || !bytes.HasPrefix(k, it.prefix) {
+			it.next = nil
+		} else {
+			it.next = common.CopyBytes(k)
+		}
+		return nil
+	})
+}
+
+// Error returns any accumulated error. Exhausting all the key/value pairs
+// is not considered to be an error.
+func (it *iterator) Error() error {
+	return it.err
+}
+
+// Key returns the key of the current key/value pair, or nil if done. The caller
+// should not modify the contents of the returned slice, and its contents may
+// change on the next call to Next.
+func (it *iterator) Key() []byte {
+	if it.index < 0 || it.index >= len(it.keys) {
+		return nil
+	}
+	return it.keys[it.index]
+}
+
+// Value returns the value of the current key/value pair, or nil if done. The
+// caller should not modify the contents of the returned slice, and its contents
+// may change on the next call to Next.
+func (it *iterator) Value() []byte {
+	if it.index < 0 || it.index >= len(it.values) {
+		return nil
+	}
+	return it.values[it.index]
+}
+
+// Release releases associated resources. Release should always succeed and can
+// be called multiple times without causing error.
+func (it *iterator) Release() {
+	it.keys, it.values, it.next, it.index = nil, nil, nil, -1
+}
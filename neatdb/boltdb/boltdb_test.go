@@ -0,0 +1,34 @@
+package boltdb
+
+import (
+	"io/ioutil"
+	"os"
+	"testing"
+
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/neatdb/dbtest"
+)
+
+func TestBoltDB(t *testing.T) {
+	t.Run("DatabaseSuite", func(t *testing.T) {
+		var dirs []string
+		defer func() {
+			for _, dir := range dirs {
+				os.RemoveAll(dir)
+			}
+		}()
+		dbtest.TestDatabaseSuite(t, func() neatdb.KeyValueStore {
+			dir, err := ioutil.TempDir("", "boltdb")
+			if err != nil {
+				t.Fatal(err)
+			}
+			dirs = append(dirs, dir)
+
+			db, err := New(dir)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return db
+		})
+	})
+}
@@ -0,0 +1,214 @@
+// Package dbtest implements a test suite run against every key-value store engine.
+package dbtest
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/Gessiux/neatchain/neatdb"
+)
+
+// TestDatabaseSuite runs a suite of tests against a KeyValueStore database
+// implementation.
+func TestDatabaseSuite(t *testing.T, New func() neatdb.KeyValueStore) {
+	t.Run("Iterator", func(t *testing.T) {
+		tests := []struct {
+			content map[string]string
+			prefix  string
+			order   []string
+		}{
+			// Empty databases should be iterable
+			{map[string]string{}, "", nil},
+			{map[string]string{}, "non-existent-prefix", nil},
+
+			// Single-item databases should be iterable
+			{map[string]string{"key": "val"}, "", []string{"key"}},
+			{map[string]string{"key": "val"}, "k", []string{"key"}},
+			{map[string]string{"key": "val"}, "l", nil},
+
+			// Multi-item databases should be fully iterable
+			{
+				map[string]string{"k1": "v1", "k5": "v5", "k2": "v2", "k4": "v4", "k3": "v3"},
+				"",
+				[]string{"k1", "k2", "k3", "k4", "k5"},
+			},
+			{
+				map[string]string{"k1": "v1", "k5": "v5", "k2": "v2", "k4": "v4", "k3": "v3"},
+				"k",
+				[]string{"k1", "k2", "k3", "k4", "k5"},
+			},
+			{
+				map[string]string{"k1": "v1", "k5": "v5", "k2": "v2", "k4": "v4", "k3": "v3"},
+				"l",
+				nil,
+			},
+			// Multi-item databases should be prefix-iterable
+			{
+				map[string]string{
+					"ka1": "va1", "ka5": "va5", "ka2": "va2", "ka4": "va4", "ka3": "va3",
+					"kb1": "vb1", "kb5": "vb5", "kb2": "vb2", "kb4": "vb4", "kb3": "vb3",
+				},
+				"ka",
+				[]string{"ka1", "ka2", "ka3", "ka4", "ka5"},
+			},
+			{
+				map[string]string{
+					"ka1": "va1", "ka5": "va5", "ka2": "va2", "ka4": "va4", "ka3": "va3",
+					"kb1": "vb1", "kb5": "vb5", "kb2": "vb2", "kb4": "vb4", "kb3": "vb3",
+				},
+				"kc",
+				nil,
+			},
+		}
+		for i, tt := range tests {
+			// Create the key-value data store
+			db := New()
+			for key, val := range tt.content {
+				if err := db.Put([]byte(key), []byte(val)); err != nil {
+					t.Fatalf("test %d: failed to insert item %s:%s into database: %v", i, key, val, err)
+				}
+			}
+			// Iterate over the database with the given configs and verify the results
+			it, idx := db.NewIteratorWithPrefix([]byte(tt.prefix)), 0
+			for it.Next() {
+				if !bytes.Equal(it.Key(), []byte(tt.order[idx])) {
+					t.Errorf("test %d: item %d: key mismatch: have %s, want %s", i, idx, string(it.Key()), tt.order[idx])
+				}
+				if !bytes.Equal(it.Value(), []byte(tt.content[tt.order[idx]])) {
+					t.Errorf("test %d: item %d: value mismatch: have %s, want %s", i, idx, string(it.Value()), tt.content[tt.order[idx]])
+				}
+				idx++
+			}
+			if err := it.Error(); err != nil {
+				t.Errorf("test %d: iteration failed: %v", i, err)
+			}
+			if idx != len(tt.order) {
+				t.Errorf("test %d: iteration terminated prematurely: have %d, want %d", i, idx, len(tt.order))
+			}
+			it.Release()
+			db.Close()
+		}
+	})
+
+	t.Run("KeyValueOperations", func(t *testing.T) {
+		db := New()
+		defer db.Close()
+
+		key := []byte("foo")
+		if got, err := db.Has(key); err != nil || got {
+			t.Errorf("wrong value: %t, %v", got, err)
+		}
+		if _, err := db.Get(key); err == nil {
+			t.Errorf("missing key retrieved")
+		}
+		value := []byte("hello world")
+		if err := db.Put(key, value); err != nil {
+			t.Fatalf("failed to put: %v", err)
+		}
+		if got, err := db.Has(key); err != nil || !got {
+			t.Errorf("wrong value: %t, %v", got, err)
+		}
+		if got, err := db.Get(key); err != nil || !bytes.Equal(got, value) {
+			t.Errorf("wrong value: %q, %v", got, err)
+		}
+		// Empty values are told apart from missing keys
+		if err := db.Put([]byte("empty"), nil); err != nil {
+			t.Fatalf("failed to put empty value: %v", err)
+		}
+		if got, err := db.Has([]byte("empty")); err != nil || !got {
+			t.Errorf("empty value missing: %t, %v", got, err)
+		}
+		if err := db.Delete(key); err != nil {
+			t.Fatalf("failed to delete: %v", err)
+		}
+		if got, err := db.Has(key); err != nil || got {
+			t.Errorf("deleted key present: %t, %v", got, err)
+		}
+	})
+
+	t.Run("Batch", func(t *testing.T) {
+		db := New()
+		defer db.Close()
+
+		b := db.NewBatch()
+		for _, k := range []string{"1", "2", "3", "4"} {
+			if err := b.Put([]byte(k), nil); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if has, err := db.Has([]byte("1")); err != nil || has {
+			t.Fatalf("batch written before Write: %t, %v", has, err)
+		}
+		if err := b.Write(); err != nil {
+			t.Fatal(err)
+		}
+		b.Reset()
+		if err := b.Delete([]byte("2")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Put([]byte("5"), []byte("five")); err != nil {
+			t.Fatal(err)
+		}
+		if err := b.Write(); err != nil {
+			t.Fatal(err)
+		}
+		var keys []string
+		it := db.NewIterator()
+		for it.Next() {
+			keys = append(keys, string(it.Key()))
+		}
+		it.Release()
+		if want := []string{"1", "3", "4", "5"}; !equalStrings(keys, want) {
+			t.Errorf("batch result mismatch: have %v, want %v", keys, want)
+		}
+		// Replaying the batch into another database applies the same changes
+		other := New()
+		defer other.Close()
+		if err := b.Replay(other); err != nil {
+			t.Fatalf("failed to replay batch: %v", err)
+		}
+		if got, err := other.Get([]byte("5")); err != nil || !bytes.Equal(got, []byte("five")) {
+			t.Errorf("replayed value mismatch: %q, %v", got, err)
+		}
+	})
+
+	t.Run("IteratorLarge", func(t *testing.T) {
+		db := New()
+		defer db.Close()
+
+		// Iterate over more items than loaded at once by the chunked iterators
+		b := db.NewBatch()
+		for i := 0; i < 5000; i++ {
+			b.Put([]byte{'k', byte(i >> 8), byte(i)}, []byte{byte(i)})
+		}
+		b.Put([]byte("l"), nil)
+		if err := b.Write(); err != nil {
+			t.Fatal(err)
+		}
+		it, count := db.NewIteratorWithPrefix([]byte("k")), 0
+		var prev []byte
+		for it.Next() {
+			if prev != nil && bytes.Compare(prev, it.Key()) >= 0 {
+				t.Fatalf("keys out of order: %x >= %x", prev, it.Key())
+			}
+			prev = append(prev[:0], it.Key()...)
+			count++
+		}
+		it.Release()
+		if count != 5000 {
+			t.Errorf("iterated item count mismatch: have %d, want %d", count, 5000)
+		}
+	})
+}
+
+func equalStrings(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -27,7 +27,6 @@ import (
 	"strings"
 
 	"github.com/Gessiux/neatchain/chain/core"
-	"github.com/Gessiux/neatchain/chain/core/datareduction"
 	"github.com/Gessiux/neatchain/chain/core/rawdb"
 	"github.com/Gessiux/neatchain/chain/core/state"
 	"github.com/Gessiux/neatchain/chain/core/types"
@@ -291,20 +290,15 @@ func (api *PrivateAdminAPI) ImportChain(file string) (bool, error) {
 	return true, nil
 }
 
+// PruneStateData is deprecated, the state is pruned online when --prune.retain is set.
 func (api *PrivateAdminAPI) PruneStateData(height *hexutil.Uint64) (bool, error) {
-	var blockNumber uint64
-	if height != nil && *height > 0 {
-		blockNumber = uint64(*height)
-	}
-
-	go api.eth.StartScanAndPrune(blockNumber)
-	return true, nil
+	log.Warn("admin_pruneStateData is deprecated and does nothing, use --prune.retain instead")
+	return false, nil
 }
 
-func (api *PrivateAdminAPI) LatestPruneState() (*datareduction.PruneStatus, error) {
-	status := datareduction.GetLatestStatus(api.eth.pruneDb)
-	status.LatestBlockNumber = api.eth.blockchain.CurrentHeader().Number.Uint64()
-	return status, nil
+// LatestPruneState reports the progress of the state pruning.
+func (api *PrivateAdminAPI) LatestPruneState() (*core.PruneStatus, error) {
+	return api.eth.blockchain.PruneStatus(), nil
 }
 
 // PublicDebugAPI is the collection of NeatChain full node APIs exposed
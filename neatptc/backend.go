@@ -21,6 +21,7 @@ import (
 	"errors"
 	"fmt"
 	"math/big"
+	"os"
 	"runtime"
 	"sync"
 	"sync/atomic"
@@ -31,7 +32,6 @@ import (
 	ntcBackend "github.com/Gessiux/neatchain/chain/consensus/neatcon"
 	"github.com/Gessiux/neatchain/chain/core"
 	"github.com/Gessiux/neatchain/chain/core/bloombits"
-	"github.com/Gessiux/neatchain/chain/core/datareduction"
 	"github.com/Gessiux/neatchain/chain/core/rawdb"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/chain/core/vm"
@@ -57,7 +57,6 @@ type LesServer interface {
 	Start(srvr *p2p.Server)
 	Stop()
 	Protocols() []p2p.Protocol
-	SetBloomBitsIndexer(bbIndexer *core.ChainIndexer)
 }
 
 // NeatChain implements the NEAT Chain full node service.
@@ -72,10 +71,10 @@ type NeatChain struct {
 	txPool          *core.TxPool
 	blockchain      *core.BlockChain
 	protocolManager *ProtocolManager
+	lesServer       LesServer
 
 	// DB interfaces
 	chainDb neatdb.Database // Block chain database
-	pruneDb neatdb.Database // Prune data database
 
 	eventMux       *event.TypeMux
 	engine         consensus.NeatCon
@@ -102,16 +101,28 @@ type NeatChain struct {
 func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 	cch core.CrossChainHelper, logger log.Logger, isTestnet bool) (*NeatChain, error) {
 
+	if config.SyncMode == downloader.LightSync {
+		return nil, errors.New("can't run neatptc.NeatChain in light sync mode, use light.LightNeatChain")
+	}
 	if !config.SyncMode.IsValid() {
 		return nil, fmt.Errorf("invalid sync mode %d", config.SyncMode)
 	}
-	chainDb, err := ctx.OpenDatabase("chaindata", config.DatabaseCache, config.DatabaseHandles, "neatchain/db/chaindata/")
+	chainDb, err := ctx.OpenDatabaseWithFreezer("chaindata", config.DatabaseCache, config.DatabaseHandles, config.DatabaseFreezer, "neatchain/db/chaindata/")
 	if err != nil {
 		return nil, err
 	}
-	pruneDb, err := ctx.OpenDatabase("prunedata", config.DatabaseCache, config.DatabaseHandles, "neatchain/db/prune/")
-	if err != nil {
-		return nil, err
+
+	if config.PruneStateData || config.PruneBlockData {
+		log.Warn("The PruneStateData and PruneBlockData options are deprecated and ignored, use TrieRetention instead")
+	}
+	// The offline data reduction is replaced by the online state pruning, drop its leftover database
+	if pruneDir := ctx.ResolvePath("prunedata"); pruneDir != "" {
+		if _, err := os.Stat(pruneDir); err == nil {
+			log.Info("Removing the obsolete data reduction database", "dir", pruneDir)
+			if err := os.RemoveAll(pruneDir); err != nil {
+				log.Warn("Failed to remove the obsolete data reduction database", "dir", pruneDir, "err", err)
+			}
+		}
 	}
 
 	isMainChain := params.IsMainChain(ctx.ChainId())
@@ -126,7 +137,6 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 	neatChain := &NeatChain{
 		config:         config,
 		chainDb:        chainDb,
-		pruneDb:        pruneDb,
 		chainConfig:    chainConfig,
 		eventMux:       ctx.EventMux,
 		accountManager: ctx.AccountManager,
@@ -163,6 +173,7 @@ func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context,
 			TrieDirtyLimit:    config.TrieDirtyCache,
 			TrieDirtyDisabled: config.NoPruning,
 			TrieTimeLimit:     config.TrieTimeout,
+			TrieRetention:     config.TrieRetention,
 		}
 	)
 
@@ -397,7 +408,15 @@ func (s *NeatChain) Downloader() *downloader.Downloader { return s.protocolManag
 // Protocols implements node.Service, returning all the currently configured
 // network protocols to start.
 func (s *NeatChain) Protocols() []p2p.Protocol {
-	return s.protocolManager.SubProtocols
+	if s.lesServer == nil {
+		return s.protocolManager.SubProtocols
+	}
+	return append(s.protocolManager.SubProtocols, s.lesServer.Protocols()...)
+}
+
+// AddLesServer registers the light server, it must be added before the node starts
+func (s *NeatChain) AddLesServer(ls LesServer) {
+	s.lesServer = ls
 }
 
 // Start implements node.Service, starting all internal goroutines needed by the
@@ -411,17 +430,24 @@ func (s *NeatChain) Start(srvr *p2p.Server) error {
 
 	// Figure out a max peers count based on the server limits
 	maxPeers := srvr.MaxPeers
+	if s.config.LightPeers > 0 {
+		if s.config.LightPeers >= srvr.MaxPeers {
+			return fmt.Errorf("invalid peer config: light peer count (%d) >= total peer count (%d)", s.config.LightPeers, srvr.MaxPeers)
+		}
+		maxPeers -= s.config.LightPeers
+	}
 
 	// Start the networking layer and the light server if requested
+	if s.lesServer != nil {
+		s.lesServer.Start(srvr)
+	}
 	s.protocolManager.Start(maxPeers)
 
 	// Start the Auto Mining Loop
 	go s.loopForMiningEvent()
 
-	// Start the Data Reduction
-	if s.config.PruneStateData && s.chainConfig.NeatChainId == "side_0" {
-		go s.StartScanAndPrune(0)
-	}
+	// Start the epoch state snapshots for the snap syncing nodes
+	go s.snapshotLoop()
 
 	return nil
 }
@@ -432,6 +458,9 @@ func (s *NeatChain) Stop() error {
 	s.bloomIndexer.Close()
 	s.blockchain.Stop()
 	s.protocolManager.Stop()
+	if s.lesServer != nil {
+		s.lesServer.Stop()
+	}
 	s.txPool.Stop()
 	s.miner.Stop()
 	s.engine.Close()
@@ -439,7 +468,6 @@ func (s *NeatChain) Stop() error {
 	s.eventMux.Stop()
 
 	s.chainDb.Close()
-	s.pruneDb.Close()
 	close(s.shutdownChan)
 
 	return nil
@@ -484,48 +512,3 @@ func (s *NeatChain) loopForMiningEvent() {
 		}
 	}
 }
-
-func (s *NeatChain) StartScanAndPrune(blockNumber uint64) {
-
-	if datareduction.StartPruning() {
-		log.Info("Data Reduction - Start")
-	} else {
-		log.Info("Data Reduction - Pruning is already running")
-		return
-	}
-
-	latestBlockNumber := s.blockchain.CurrentHeader().Number.Uint64()
-	if blockNumber == 0 || blockNumber >= latestBlockNumber {
-		blockNumber = latestBlockNumber
-		log.Infof("Data Reduction - Last block number %v", blockNumber)
-	} else {
-		log.Infof("Data Reduction - User defined Last block number %v", blockNumber)
-	}
-
-	ps := rawdb.ReadHeadScanNumber(s.pruneDb)
-	var scanNumber uint64
-	if ps != nil {
-		scanNumber = *ps
-	}
-
-	pp := rawdb.ReadHeadPruneNumber(s.pruneDb)
-	var pruneNumber uint64
-	if pp != nil {
-		pruneNumber = *pp
-	}
-	log.Infof("Data Reduction - Last scan number %v, prune number %v", scanNumber, pruneNumber)
-
-	pruneProcessor := datareduction.NewPruneProcessor(s.chainDb, s.pruneDb, s.blockchain, s.config.PruneBlockData)
-
-	lastScanNumber, lastPruneNumber := pruneProcessor.Process(blockNumber, scanNumber, pruneNumber)
-	log.Infof("Data Reduction - After prune, last number scan %v, prune number %v", lastScanNumber, lastPruneNumber)
-	if s.config.PruneBlockData {
-		for i := uint64(1); i < lastPruneNumber; i++ {
-			rawdb.DeleteBody(s.chainDb, rawdb.ReadCanonicalHash(s.chainDb, i), i)
-		}
-		log.Infof("deleted block from 1 to %v", lastPruneNumber)
-	}
-	log.Info("Data Reduction - Completed")
-
-	datareduction.StopPruning()
-}
@@ -34,6 +34,10 @@ import (
 	"github.com/Gessiux/neatchain/utilities/common/hexutil"
 )
 
+// DefaultPruneRetention is the number of recent blocks whose state is kept on
+// disk when pruning is enabled through the deprecated --prune flag.
+const DefaultPruneRetention = 90000
+
 // DefaultConfig contains default settings for use on the NEATChain main net.
 var DefaultConfig = Config{
 	//SyncMode: downloader.FastSync,
@@ -80,16 +84,24 @@ type Config struct {
 	NetworkId uint64 // Network ID to use for selecting peers to connect to
 	SyncMode  downloader.SyncMode
 
+	// Light server options
+	LightPeers int `toml:",omitempty"` // Maximum number of light clients to serve, zero disables the light server
+
+	// Snapshot options
+	StateSnapshot bool `toml:",omitempty"` // Produce epoch state snapshots even if not a validator
+
 	NoPruning bool // Whether to disable pruning and flush everything to disk
 
 	// Database options
 	SkipBcVersionCheck bool `toml:"-"`
 	DatabaseHandles    int  `toml:"-"`
 	DatabaseCache      int
+	DatabaseFreezer    string
 
 	TrieCleanCache int
 	TrieDirtyCache int
 	TrieTimeout    time.Duration
+	TrieRetention  uint64 // Number of recent blocks whose state is kept on disk in full gc mode, 0 disables pruning
 
 	// Mining-related options
 	Coinbase      common.Address `toml:",omitempty"`
@@ -116,7 +128,7 @@ type Config struct {
 	// Miscellaneous options
 	DocRoot string `toml:"-"`
 
-	// Data Reduction options
+	// Data Reduction options, deprecated and ignored in favour of TrieRetention
 	PruneStateData bool
 	PruneBlockData bool
 }
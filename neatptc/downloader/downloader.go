@@ -27,6 +27,7 @@ import (
 
 	"github.com/Gessiux/neatchain"
 	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/neatdb"
@@ -90,6 +91,7 @@ var (
 	errCancelContentProcessing = errors.New("content processing canceled (requested)")
 	errNoSyncActive            = errors.New("no sync active")
 	errTooOld                  = errors.New("peer doesn't speak recent enough protocol version (need version >= 62)")
+	errInvalidSnapshot         = errors.New("snapshot doesn't match the pivot block")
 )
 
 type Downloader struct {
@@ -134,6 +136,12 @@ type Downloader struct {
 	trackStateReq  chan *stateReq
 	stateCh        chan dataPack // [neatptc/63] Channel receiving inbound node state data
 
+	// for snapshot sync
+	snapshot       *snapshot.Manifest // Snapshot restoring the pivot state (per sync cycle)
+	snapSyncStart  chan *snapshotSync
+	snapManifestCh chan dataPack // Channel receiving inbound snapshot manifests
+	snapChunkCh    chan dataPack // Channel receiving inbound snapshot chunks
+
 	// Cancellation and termination
 	cancelPeer string        // Identifier of the peer currently being used as the master (cancel on drop)
 	cancelCh   chan struct{} // Channel to cancel mid-flight syncs
@@ -229,10 +237,15 @@ func New(mode SyncMode, stateDb neatdb.Database, mux *event.TypeMux, chain Block
 		},
 		trackStateReq: make(chan *stateReq),
 
+		snapSyncStart:  make(chan *snapshotSync),
+		snapManifestCh: make(chan dataPack, 1),
+		snapChunkCh:    make(chan dataPack),
+
 		logger: logger,
 	}
 	go dl.qosTuner()
 	go dl.stateFetcher()
+	go dl.snapshotFetcher()
 	return dl
 }
 
@@ -254,6 +267,8 @@ func (d *Downloader) Progress() neatchain.SyncProgress {
 		current = d.blockchain.CurrentBlock().NumberU64()
 	case FastSync:
 		current = d.blockchain.CurrentFastBlock().NumberU64()
+	case LightSync:
+		current = d.lightchain.CurrentHeader().Number.Uint64()
 	}
 	return neatchain.SyncProgress{
 		StartingBlock: d.syncStatsChainOrigin,
@@ -365,7 +380,7 @@ func (d *Downloader) synchronise(id string, hash common.Hash, td *big.Int, mode
 		default:
 		}
 	}
-	for _, ch := range []chan dataPack{d.headerCh, d.bodyCh, d.receiptCh} {
+	for _, ch := range []chan dataPack{d.headerCh, d.bodyCh, d.receiptCh, d.snapManifestCh} {
 		for empty := false; !empty; {
 			select {
 			case <-ch:
@@ -439,9 +454,25 @@ func (d *Downloader) syncWithPeer(p *peerConnection, hash common.Hash, td *big.I
 	d.syncStatsChainHeight = height
 	d.syncStatsLock.Unlock()
 
+	// Snap sync runs as fast sync, but the pivot is the block of the snapshot
+	d.snapshot = nil
+	if d.mode == SnapSync {
+		d.mode = FastSync
+		if d.snapshot, err = d.fetchSnapshotManifest(p, height); err != nil {
+			return err
+		}
+		if d.snapshot == nil {
+			p.log.Debug("No usable snapshot, falling back to fast sync")
+		}
+	}
 	// Ensure our origin point is below any fast sync pivot point
 	pivot := uint64(0)
-	if d.mode == FastSync {
+	if d.snapshot != nil {
+		pivot = d.snapshot.Number
+		if pivot <= origin {
+			origin = pivot - 1
+		}
+	} else if d.mode == FastSync {
 		if height <= uint64(fsMinFullBlocks) {
 			origin = 0
 		} else {
@@ -1163,12 +1194,16 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				hashes[i] = header.Hash()
 			}
 			lastHeader, lastFastBlock, lastBlock := d.lightchain.CurrentHeader().Number, common.Big0, common.Big0
-			lastFastBlock = d.blockchain.CurrentFastBlock().Number()
-			lastBlock = d.blockchain.CurrentBlock().Number()
+			if d.mode != LightSync {
+				lastFastBlock = d.blockchain.CurrentFastBlock().Number()
+				lastBlock = d.blockchain.CurrentBlock().Number()
+			}
 			d.lightchain.Rollback(hashes)
 			curFastBlock, curBlock := common.Big0, common.Big0
-			curFastBlock = d.blockchain.CurrentFastBlock().Number()
-			curBlock = d.blockchain.CurrentBlock().Number()
+			if d.mode != LightSync {
+				curFastBlock = d.blockchain.CurrentFastBlock().Number()
+				curBlock = d.blockchain.CurrentBlock().Number()
+			}
 			d.logger.Warn("Rolled back headers", "count", len(hashes),
 				"header", fmt.Sprintf("%d->%d", lastHeader, d.lightchain.CurrentHeader().Number),
 				"fast", fmt.Sprintf("%d->%d", lastFastBlock, curFastBlock),
@@ -1206,9 +1241,11 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// L: Sync begins, and finds common ancestor at 11
 				// L: Request new headers up from 11 (R's TD was higher, it must have something)
 				// R: Nothing to give
-				head := d.blockchain.CurrentBlock()
-				if !gotHeaders && td.Cmp(d.blockchain.GetTd(head.Hash(), head.NumberU64())) > 0 {
-					return errStallingPeer
+				if d.mode != LightSync {
+					head := d.blockchain.CurrentBlock()
+					if !gotHeaders && td.Cmp(d.blockchain.GetTd(head.Hash(), head.NumberU64())) > 0 {
+						return errStallingPeer
+					}
 				}
 				// If fast or light syncing, ensure promised headers are indeed delivered. This is
 				// needed to detect scenarios where an attacker feeds a bad pivot and then bails out
@@ -1217,7 +1254,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				// This check cannot be executed "as is" for full imports, since blocks may still be
 				// queued for processing when the header download completes. However, as long as the
 				// peer gave us something useful, we're already happy/progressed (above check).
-				if d.mode == FastSync {
+				if d.mode == FastSync || d.mode == LightSync {
 					head := d.lightchain.CurrentHeader()
 					if td.Cmp(d.lightchain.GetTd(head.Hash(), head.Number.Uint64())) > 0 {
 						return errStallingPeer
@@ -1245,7 +1282,7 @@ func (d *Downloader) processHeaders(origin uint64, pivot uint64, td *big.Int) er
 				chunk := headers[:limit]
 
 				// In case of header only syncing, validate the chunk immediately
-				if d.mode == FastSync {
+				if d.mode == FastSync || d.mode == LightSync {
 					// Collect the yet unknown headers to mark them as uncertain
 					unknown := make([]*types.Header, 0, len(headers))
 					for _, header := range chunk {
@@ -1358,8 +1395,14 @@ func (d *Downloader) importBlockResults(results []*fetchResult) error {
 // database. It also controls the synchronisation of state nodes of the pivot block.
 func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	// Start syncing state of the reported head block. This should get us most of
-	// the state of the pivot block.
-	stateSync := d.syncState(latest.Root)
+	// the state of the pivot block. With a snapshot, the pivot state is restored
+	// from the snapshot chunks instead.
+	var stateSync stateSyncer
+	if d.snapshot != nil {
+		stateSync = d.syncSnapshot(d.snapshot)
+	} else {
+		stateSync = d.syncState(latest.Root)
+	}
 	defer stateSync.Cancel()
 	go func() {
 		if err := stateSync.Wait(); err != nil && err != errCancelStateFetch {
@@ -1369,7 +1412,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 	// Figure out the ideal pivot block. Note, that this goalpost may move if the
 	// sync takes long enough for the chain head to move significantly.
 	pivot := uint64(0)
-	if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
+	if d.snapshot != nil {
+		pivot = d.snapshot.Number
+	} else if height := latest.Number.Uint64(); height > uint64(fsMinFullBlocks) {
 		pivot = height - uint64(fsMinFullBlocks)
 	}
 	// To cater for moving pivot points, track the pivot block and subsequently
@@ -1401,7 +1446,7 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			results = append(append([]*fetchResult{oldPivot}, oldTail...), results...)
 		}
 		// Split around the pivot block and process the two sides via fast/full sync
-		if atomic.LoadInt32(&d.committed) == 0 {
+		if atomic.LoadInt32(&d.committed) == 0 && d.snapshot == nil {
 			latest = results[len(results)-1].Header
 			if height := latest.Number.Uint64(); height > pivot+2*uint64(fsMinFullBlocks) {
 				d.logger.Warn("Pivot became stale, moving", "old", pivot, "new", height-uint64(fsMinFullBlocks))
@@ -1413,6 +1458,13 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			return err
 		}
 		if P != nil {
+			// The snapshot must be the state of the pivot block
+			if d.snapshot != nil {
+				if P.Header.Hash() != d.snapshot.BlockHash || P.Header.Root != d.snapshot.Root {
+					return errInvalidSnapshot
+				}
+				oldPivot = P
+			}
 			// If new pivot block found, cancel old state retrieval and restart
 			if oldPivot != P {
 				stateSync.Cancel()
@@ -1428,9 +1480,9 @@ func (d *Downloader) processFastSyncContent(latest *types.Header) error {
 			}
 			// Wait for completion, occasionally checking for pivot staleness
 			select {
-			case <-stateSync.done:
-				if stateSync.err != nil {
-					return stateSync.err
+			case <-stateSync.Done():
+				if err := stateSync.Wait(); err != nil {
+					return err
 				}
 				if err := d.commitPivotBlock(P); err != nil {
 					return err
@@ -1464,7 +1516,7 @@ func splitAroundPivot(pivot uint64, results []*fetchResult) (p *fetchResult, bef
 	return p, before, after
 }
 
-func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *stateSync) error {
+func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync stateSyncer) error {
 	// Check for any early termination requests
 	if len(results) == 0 {
 		return nil
@@ -1472,7 +1524,7 @@ func (d *Downloader) commitFastSyncData(results []*fetchResult, stateSync *state
 	select {
 	case <-d.quitCh:
 		return errCancelContentProcessing
-	case <-stateSync.done:
+	case <-stateSync.Done():
 		if err := stateSync.Wait(); err != nil {
 			return err
 		}
@@ -1531,6 +1583,17 @@ func (d *Downloader) DeliverNodeData(id string, data [][]byte) (err error) {
 	return d.deliver(id, d.stateCh, &statePack{id, data}, stateInMeter, stateDropMeter)
 }
 
+// DeliverSnapshotManifest injects the snapshot manifest received from a remote node,
+// an empty list if the node has no snapshot.
+func (d *Downloader) DeliverSnapshotManifest(id string, manifests []*snapshot.Manifest) (err error) {
+	return d.deliver(id, d.snapManifestCh, &manifestPack{id, manifests}, snapManifestInMeter, snapManifestDropMeter)
+}
+
+// DeliverSnapshotChunks injects a batch of snapshot chunks received from a remote node.
+func (d *Downloader) DeliverSnapshotChunks(id string, chunks [][]byte) (err error) {
+	return d.deliver(id, d.snapChunkCh, &chunkPack{id, chunks}, snapChunkInMeter, snapChunkDropMeter)
+}
+
 // deliver injects a new batch of data received from a remote node.
 func (d *Downloader) deliver(id string, destCh chan dataPack, packet dataPack, inMeter, dropMeter metrics.Meter) (err error) {
 	// Update the delivery metrics for both good and failed deliveries
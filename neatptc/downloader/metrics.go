@@ -40,4 +40,10 @@ var (
 
 	stateInMeter   = metrics.NewRegisteredMeter("eth/downloader/states/in", nil)
 	stateDropMeter = metrics.NewRegisteredMeter("eth/downloader/states/drop", nil)
+
+	snapManifestInMeter   = metrics.NewRegisteredMeter("eth/downloader/snapshot/manifests/in", nil)
+	snapManifestDropMeter = metrics.NewRegisteredMeter("eth/downloader/snapshot/manifests/drop", nil)
+	snapChunkInMeter      = metrics.NewRegisteredMeter("eth/downloader/snapshot/chunks/in", nil)
+	snapChunkDropMeter    = metrics.NewRegisteredMeter("eth/downloader/snapshot/chunks/drop", nil)
+	snapChunkTimeoutMeter = metrics.NewRegisteredMeter("eth/downloader/snapshot/chunks/timeout", nil)
 )
@@ -0,0 +1,283 @@
+package downloader
+
+import (
+	"sync"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+)
+
+// MaxSnapshotChunkFetch is the amount of snapshot chunks to be fetched per retrieval request
+var MaxSnapshotChunkFetch = 4
+
+// SnapshotPeer is a peer able to serve the epoch state snapshots
+type SnapshotPeer interface {
+	RequestSnapshotManifest(number uint64) error
+	RequestSnapshotChunks(hashes []common.Hash) error
+}
+
+// stateSyncer retrieves the state of the pivot block, either by trie nodes or from
+// the chunks of a snapshot.
+type stateSyncer interface {
+	Wait() error
+	Cancel() error
+	Done() <-chan struct{}
+}
+
+// fetchSnapshotManifest retrieves the latest snapshot manifest of the peer. No manifest
+// is returned if the peer can't serve snapshots or its snapshot is above the height.
+func (d *Downloader) fetchSnapshotManifest(p *peerConnection, height uint64) (*snapshot.Manifest, error) {
+	sp, ok := p.peer.(SnapshotPeer)
+	if !ok {
+		return nil, nil
+	}
+	p.log.Debug("Retrieving remote snapshot manifest")
+	go sp.RequestSnapshotManifest(0)
+
+	ttl := d.requestTTL()
+	timeout := time.After(ttl)
+	for {
+		select {
+		case <-d.cancelCh:
+			return nil, errCancelBlockFetch
+
+		case packet := <-d.snapManifestCh:
+			// Discard anything not from the origin peer
+			if packet.PeerId() != p.id {
+				d.logger.Debug("Received snapshot manifest from incorrect peer", "peer", packet.PeerId())
+				break
+			}
+			manifests := packet.(*manifestPack).manifests
+			if len(manifests) == 0 {
+				return nil, nil
+			}
+			manifest := manifests[0]
+			if manifest.Number == 0 || manifest.Number > height || len(manifest.Chunks) == 0 {
+				p.log.Debug("Unusable snapshot manifest", "number", manifest.Number, "height", height, "chunks", len(manifest.Chunks))
+				return nil, nil
+			}
+			p.log.Debug("Remote snapshot identified", "number", manifest.Number, "root", manifest.Root, "chunks", len(manifest.Chunks))
+			return manifest, nil
+
+		case <-timeout:
+			p.log.Debug("Waiting for snapshot manifest timed out", "elapsed", ttl)
+			return nil, nil
+		}
+	}
+}
+
+// syncSnapshot starts restoring the state from the chunks of the snapshot.
+func (d *Downloader) syncSnapshot(manifest *snapshot.Manifest) *snapshotSync {
+	s := newSnapshotSync(d, manifest)
+	select {
+	case d.snapSyncStart <- s:
+	case <-d.quitCh:
+		s.err = errCancelStateFetch
+		close(s.done)
+	}
+	return s
+}
+
+// snapshotFetcher runs the snapshot syncs, dropping the chunks delivered while no
+// sync is running.
+func (d *Downloader) snapshotFetcher() {
+	for {
+		select {
+		case s := <-d.snapSyncStart:
+			s.run()
+		case <-d.snapChunkCh:
+			// Ignore snapshot chunks while no sync is running.
+		case <-d.quitCh:
+			return
+		}
+	}
+}
+
+// chunkReq is a batch of snapshot chunks requested from a peer
+type chunkReq struct {
+	peer     *peerConnection
+	hashes   map[common.Hash]struct{}
+	deadline time.Time
+}
+
+// snapshotSync downloads the chunks of a snapshot from all the peers serving it, and
+// restores the state tries from them.
+type snapshotSync struct {
+	d        *Downloader
+	manifest *snapshot.Manifest
+
+	active   map[string]*chunkReq     // Currently in-flight requests by peer
+	inflight map[common.Hash]struct{} // Chunks currently requested
+	failed   map[string]struct{}      // Peers not serving the snapshot
+
+	cancel     chan struct{} // Channel to signal a termination request
+	cancelOnce sync.Once     // Ensures cancel only ever gets called once
+	done       chan struct{} // Channel to signal termination completion
+	err        error         // Any error hit during sync (set before completion)
+}
+
+func newSnapshotSync(d *Downloader, manifest *snapshot.Manifest) *snapshotSync {
+	return &snapshotSync{
+		d:        d,
+		manifest: manifest,
+		active:   make(map[string]*chunkReq),
+		inflight: make(map[common.Hash]struct{}),
+		failed:   make(map[string]struct{}),
+		cancel:   make(chan struct{}),
+		done:     make(chan struct{}),
+	}
+}
+
+func (s *snapshotSync) run() {
+	s.err = s.loop()
+	close(s.done)
+}
+
+// Wait blocks until the sync is done or canceled.
+func (s *snapshotSync) Wait() error {
+	<-s.done
+	return s.err
+}
+
+// Cancel cancels the sync and waits until it has shut down.
+func (s *snapshotSync) Cancel() error {
+	s.cancelOnce.Do(func() { close(s.cancel) })
+	return s.Wait()
+}
+
+// Done returns a channel closed once the sync has terminated.
+func (s *snapshotSync) Done() <-chan struct{} {
+	return s.done
+}
+
+func (s *snapshotSync) loop() error {
+	restorer, err := snapshot.NewRestorer(s.manifest, s.d.stateDB)
+	if err != nil {
+		return err
+	}
+	peerDrop := make(chan *peerConnection, 1024)
+	peerSub := s.d.peers.SubscribePeerDrops(peerDrop)
+	defer peerSub.Unsubscribe()
+
+	newPeer := make(chan *peerConnection, 1024)
+	newPeerSub := s.d.peers.SubscribeNewPeers(newPeer)
+	defer newPeerSub.Unsubscribe()
+
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+
+	total := len(s.manifest.Chunks)
+	for !restorer.Done() {
+		s.assignTasks(restorer.Missing())
+		if len(s.active) == 0 && len(s.failed) >= s.d.peers.Len() {
+			return errPeersUnavailable
+		}
+
+		select {
+		case <-newPeer:
+			// New peer arrived, try to assign it chunks
+
+		case p := <-peerDrop:
+			s.release(p.id)
+
+		case <-ticker.C:
+			now := time.Now()
+			for id, req := range s.active {
+				if now.After(req.deadline) {
+					req.peer.log.Debug("Snapshot chunk request timed out", "chunks", len(req.hashes))
+					snapChunkTimeoutMeter.Mark(int64(len(req.hashes)))
+					s.failed[id] = struct{}{}
+					s.release(id)
+				}
+			}
+
+		case packet := <-s.d.snapChunkCh:
+			req := s.active[packet.PeerId()]
+			if req == nil {
+				break
+			}
+			s.release(req.peer.id)
+
+			chunks := packet.(*chunkPack).chunks
+			if len(chunks) == 0 {
+				// The peer doesn't keep this snapshot anymore
+				s.failed[req.peer.id] = struct{}{}
+				break
+			}
+			for _, chunk := range chunks {
+				if _, ok := req.hashes[crypto.Keccak256Hash(chunk)]; !ok {
+					req.peer.log.Warn("Unrequested snapshot chunk, dropping peer")
+					s.failed[req.peer.id] = struct{}{}
+					s.d.dropPeer(req.peer.id)
+					break
+				}
+				if err := restorer.Process(chunk); err != nil {
+					req.peer.log.Warn("Invalid snapshot chunk, dropping peer", "err", err)
+					s.failed[req.peer.id] = struct{}{}
+					s.d.dropPeer(req.peer.id)
+					break
+				}
+			}
+			s.d.logger.Info("Imported snapshot chunks", "count", len(chunks), "restored", total-len(restorer.Missing()), "total", total)
+
+		case <-s.cancel:
+			return errCancelStateFetch
+
+		case <-s.d.cancelCh:
+			return errCancelStateFetch
+		}
+	}
+	return restorer.Finish()
+}
+
+// release frees the chunks requested from the peer for other peers
+func (s *snapshotSync) release(id string) {
+	req := s.active[id]
+	if req == nil {
+		return
+	}
+	for hash := range req.hashes {
+		delete(s.inflight, hash)
+	}
+	delete(s.active, id)
+}
+
+// assignTasks requests the missing chunks not in flight from the idle peers
+func (s *snapshotSync) assignTasks(missing []common.Hash) {
+	for _, p := range s.d.peers.AllPeers() {
+		if _, ok := s.active[p.id]; ok {
+			continue
+		}
+		if _, ok := s.failed[p.id]; ok {
+			continue
+		}
+		sp, ok := p.peer.(SnapshotPeer)
+		if !ok {
+			s.failed[p.id] = struct{}{}
+			continue
+		}
+		req := &chunkReq{peer: p, hashes: make(map[common.Hash]struct{}), deadline: time.Now().Add(s.d.requestTTL())}
+		var hashes []common.Hash
+		for _, hash := range missing {
+			if len(hashes) >= MaxSnapshotChunkFetch {
+				break
+			}
+			if _, ok := s.inflight[hash]; ok {
+				continue
+			}
+			hashes = append(hashes, hash)
+			req.hashes[hash] = struct{}{}
+			s.inflight[hash] = struct{}{}
+		}
+		if len(hashes) == 0 {
+			return
+		}
+		s.active[p.id] = req
+		if err := sp.RequestSnapshotChunks(hashes); err != nil {
+			s.failed[p.id] = struct{}{}
+			s.release(p.id)
+		}
+	}
+}
@@ -268,6 +268,11 @@ func (s *stateSync) Cancel() error {
 	return s.Wait()
 }
 
+// Done returns a channel closed once the sync has terminated.
+func (s *stateSync) Done() <-chan struct{} {
+	return s.done
+}
+
 // loop is the main event loop of a state trie sync. It it responsible for the
 // assignment of new tasks to peers (including sending it to them) as well as
 // for the processing of inbound data. Note, that the loop does not directly
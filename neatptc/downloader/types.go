@@ -19,6 +19,7 @@ package downloader
 import (
 	"fmt"
 
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
 	"github.com/Gessiux/neatchain/chain/core/types"
 )
 
@@ -77,3 +78,23 @@ type statePack struct {
 func (p *statePack) PeerId() string { return p.peerId }
 func (p *statePack) Items() int     { return len(p.states) }
 func (p *statePack) Stats() string  { return fmt.Sprintf("%d", len(p.states)) }
+
+// manifestPack is the snapshot manifest returned by a peer, empty if it has none.
+type manifestPack struct {
+	peerId    string
+	manifests []*snapshot.Manifest
+}
+
+func (p *manifestPack) PeerId() string { return p.peerId }
+func (p *manifestPack) Items() int     { return len(p.manifests) }
+func (p *manifestPack) Stats() string  { return fmt.Sprintf("%d", len(p.manifests)) }
+
+// chunkPack is a batch of snapshot chunks returned by a peer.
+type chunkPack struct {
+	peerId string
+	chunks [][]byte
+}
+
+func (p *chunkPack) PeerId() string { return p.peerId }
+func (p *chunkPack) Items() int     { return len(p.chunks) }
+func (p *chunkPack) Stats() string  { return fmt.Sprintf("%d", len(p.chunks)) }
@@ -17,6 +17,7 @@
 package neatptc
 
 import (
+	"crypto/ecdsa"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -30,8 +31,10 @@ import (
 
 	"github.com/Gessiux/neatchain/chain/consensus"
 	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/chain/log"
+	neatAbi "github.com/Gessiux/neatchain/neatabi/abi"
 	"github.com/Gessiux/neatchain/neatdb"
 	"github.com/Gessiux/neatchain/neatptc/downloader"
 	"github.com/Gessiux/neatchain/neatptc/fetcher"
@@ -72,11 +75,13 @@ func errResp(code errCode, format string, v ...interface{}) error {
 type ProtocolManager struct {
 	networkId uint64
 
-	fastSync  uint32 // Flag whether fast sync is enabled (gets disabled if we already have blocks)
-	acceptTxs uint32 // Flag whether we're considered synchronised (enables transaction processing)
+	fastSync     uint32              // Flag whether fast sync is enabled (gets disabled if we already have blocks)
+	fastSyncMode downloader.SyncMode // Mode of the initial sync, fast sync or snapshot sync
+	acceptTxs    uint32              // Flag whether we're considered synchronised (enables transaction processing)
 
 	txpool      txPool
 	blockchain  *core.BlockChain
+	chaindb     neatdb.Database
 	chainconfig *params.ChainConfig
 	maxPeers    int
 
@@ -124,6 +129,7 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 		eventMux:       mux,
 		txpool:         txpool,
 		blockchain:     blockchain,
+		chaindb:        chaindb,
 		chainconfig:    config,
 		peers:          newPeerSet(),
 		newPeerCh:      make(chan *peer),
@@ -141,19 +147,21 @@ func NewProtocolManager(config *params.ChainConfig, mode downloader.SyncMode, ne
 	}
 
 	// Figure out whether to allow fast sync or not
-	if mode == downloader.FastSync && blockchain.CurrentBlock().NumberU64() > 0 {
+	if (mode == downloader.FastSync || mode == downloader.SnapSync) && blockchain.CurrentBlock().NumberU64() > 0 {
 		manager.logger.Warn("Blockchain not empty, fast sync disabled")
 		mode = downloader.FullSync
 	}
-	if mode == downloader.FastSync {
+	manager.fastSyncMode = downloader.FastSync
+	if mode == downloader.FastSync || mode == downloader.SnapSync {
 		manager.fastSync = uint32(1)
+		manager.fastSyncMode = mode
 	}
 	protocol := engine.Protocol()
 	// Initiate a sub-protocol for every implemented version we can handle
 	manager.SubProtocols = make([]p2p.Protocol, 0, len(protocol.Versions))
 	for i, version := range protocol.Versions {
 		// Skip protocol version if incompatible with the mode of operation
-		if mode == downloader.FastSync && version < consensus.Eth63 {
+		if mode != downloader.FullSync && version < consensus.Eth63 {
 			continue
 		}
 		// Compatible; initialise the sub-protocol
@@ -772,6 +780,61 @@ func (pm *ProtocolManager) handleMsg(p *peer) error {
 				pm.logger.Debugf("TrieNodeData %x already existed", thash)
 			}
 		}
+
+	case p.version >= consensus.Eth63 && msg.Code == GetSnapshotManifestMsg:
+		var number uint64
+		if err := msg.Decode(&number); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		var manifests []*snapshot.Manifest
+		if manifest := snapshot.ReadManifest(pm.chaindb, number); manifest != nil {
+			manifests = append(manifests, manifest)
+		}
+		return p.SendSnapshotManifest(manifests)
+
+	case p.version >= consensus.Eth63 && msg.Code == SnapshotManifestMsg:
+		var manifests []*snapshot.Manifest
+		if err := msg.Decode(&manifests); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverSnapshotManifest(p.id, manifests); err != nil {
+			pm.logger.Debug("Failed to deliver snapshot manifest", "err", err)
+		}
+
+	case p.version >= consensus.Eth63 && msg.Code == GetSnapshotChunksMsg:
+		// Decode the retrieval message
+		msgStream := rlp.NewStream(msg.Payload, uint64(msg.Size))
+		if _, err := msgStream.List(); err != nil {
+			return err
+		}
+		// Gather chunks until the fetch or network limits is reached
+		var (
+			hash   common.Hash
+			bytes  int
+			chunks [][]byte
+		)
+		for bytes < softResponseLimit && len(chunks) < downloader.MaxSnapshotChunkFetch {
+			if err := msgStream.Decode(&hash); err == rlp.EOL {
+				break
+			} else if err != nil {
+				return errResp(ErrDecode, "msg %v: %v", msg, err)
+			}
+			if chunk := rawdb.ReadSnapshotChunk(pm.chaindb, hash); len(chunk) > 0 {
+				chunks = append(chunks, chunk)
+				bytes += len(chunk)
+			}
+		}
+		return p.SendSnapshotChunks(chunks)
+
+	case p.version >= consensus.Eth63 && msg.Code == SnapshotChunksMsg:
+		var chunks [][]byte
+		if err := msg.Decode(&chunks); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := pm.downloader.DeliverSnapshotChunks(p.id, chunks); err != nil {
+			pm.logger.Debug("Failed to deliver snapshot chunks", "err", err)
+		}
+
 	default:
 		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
 	}
@@ -847,6 +910,25 @@ func (pm *ProtocolManager) BroadcastMessage(msgcode uint64, data interface{}) {
 	pm.logger.Trace("Broadcast p2p message", "code", msgcode, "recipients", recipients, "msg", data)
 }
 
+// SendSystemTx signs the chain contract call data with the key and adds it into the local tx pool,
+// the function of the chain contract requires no gas, so the account doesn't need any balance
+func (pm *ProtocolManager) SendSystemTx(data []byte, prv *ecdsa.PrivateKey) (common.Hash, error) {
+	account := crypto.PubkeyToAddress(prv.PublicKey)
+	nonce := pm.txpool.State().GetNonce(account)
+
+	tx := types.NewTransaction(nonce, neatAbi.ChainContractMagicAddr, nil, 0, new(big.Int), data)
+	signer := types.MakeSigner(pm.chainconfig, pm.blockchain.CurrentBlock().Number())
+	signedTx, err := types.SignTx(tx, signer, prv)
+	if err != nil {
+		return common.Hash{}, err
+	}
+
+	if err := pm.txpool.AddLocal(signedTx); err != nil {
+		return common.Hash{}, err
+	}
+	return signedTx.Hash(), nil
+}
+
 func (pm *ProtocolManager) TryFixBadPreimages() {
 	// Record all preimages (Testing)
 	images := make(map[common.Hash][]byte)
@@ -29,6 +29,7 @@ import (
 
 	"github.com/Gessiux/neatchain/chain/core"
 	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/chain/core/vm"
 	"github.com/Gessiux/neatchain/neatdb"
@@ -107,6 +108,16 @@ func (p *testTxPool) AddRemotes(txs []*types.Transaction) []error {
 	return make([]error, len(txs))
 }
 
+// AddLocal appends a single transaction to the pool
+func (p *testTxPool) AddLocal(tx *types.Transaction) error {
+	return p.AddRemotes([]*types.Transaction{tx})[0]
+}
+
+// State is not tracked by the fake pool
+func (p *testTxPool) State() *state.ManagedState {
+	return nil
+}
+
 // Pending returns all the transactions known to the pool
 func (p *testTxPool) Pending() (map[common.Address]types.Transactions, error) {
 	p.lock.RLock()
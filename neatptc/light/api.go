@@ -0,0 +1,75 @@
+package light
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/internal/neatapi"
+	"github.com/Gessiux/neatchain/network/rpc"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/common/hexutil"
+)
+
+// PublicLightAPI provides the chain and state queries of the light client, every state
+// is proven against the root of a verified header.
+type PublicLightAPI struct {
+	lnc *LightNeatChain
+}
+
+// NewPublicLightAPI creates a new light client API.
+func NewPublicLightAPI(lnc *LightNeatChain) *PublicLightAPI {
+	return &PublicLightAPI{lnc}
+}
+
+// BlockNumber returns the number of the latest verified header.
+func (api *PublicLightAPI) BlockNumber() hexutil.Uint64 {
+	return hexutil.Uint64(api.lnc.lightchain.CurrentHeader().Number.Uint64())
+}
+
+// GetBalance returns the proven amount of wei for the given address in the state of the
+// given block number.
+func (api *PublicLightAPI) GetBalance(ctx context.Context, address common.Address, blockNr rpc.BlockNumber) (*hexutil.Big, error) {
+	header, err := api.headerByNumber(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	balance, err := api.lnc.GetBalance(ctx, address, header)
+	return (*hexutil.Big)(balance), err
+}
+
+// GetStorageAt returns the proven storage from the state at the given address, key and
+// block number.
+func (api *PublicLightAPI) GetStorageAt(ctx context.Context, address common.Address, key string, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	header, err := api.headerByNumber(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	res, err := api.lnc.GetStorageAt(ctx, address, common.HexToHash(key), header)
+	if err != nil {
+		return nil, err
+	}
+	return res[:], nil
+}
+
+// Call executes the given transaction on the proven state of the given block number.
+func (api *PublicLightAPI) Call(ctx context.Context, args neatapi.CallArgs, blockNr rpc.BlockNumber) (hexutil.Bytes, error) {
+	header, err := api.headerByNumber(blockNr)
+	if err != nil {
+		return nil, err
+	}
+	result, err := api.lnc.Call(ctx, args.From, args.To, uint64(args.Gas), args.GasPrice.ToInt(), args.Value.ToInt(), args.Data, header)
+	return (hexutil.Bytes)(result), err
+}
+
+// headerByNumber returns the verified header, the pending block is not known to the light client
+func (api *PublicLightAPI) headerByNumber(blockNr rpc.BlockNumber) (*types.Header, error) {
+	if blockNr == rpc.LatestBlockNumber || blockNr == rpc.PendingBlockNumber {
+		return api.lnc.lightchain.CurrentHeader(), nil
+	}
+	header := api.lnc.lightchain.GetHeaderByNumber(uint64(blockNr))
+	if header == nil {
+		return nil, fmt.Errorf("header #%d not found", blockNr)
+	}
+	return header, nil
+}
@@ -0,0 +1,335 @@
+package light
+
+import (
+	"context"
+	"math/big"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/consensus/neatcon"
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/neatptc/downloader"
+	"github.com/Gessiux/neatchain/network/node"
+	"github.com/Gessiux/neatchain/network/p2p"
+	"github.com/Gessiux/neatchain/network/rpc"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/event"
+	"gopkg.in/urfave/cli.v1"
+)
+
+// requestTimeout is the maximum time waiting for the proofs of a state request
+const requestTimeout = 10 * time.Second
+
+// Config contains the configuration options of the light client
+type Config struct {
+	// The genesis block, which is inserted if the database is empty.
+	Genesis *core.Genesis `toml:",omitempty"`
+
+	NetworkId uint64 // Network ID to use for selecting peers to connect to
+
+	DatabaseHandles int `toml:"-"`
+	DatabaseCache   int
+}
+
+// LightNeatChain implements the NEAT Chain light client service. It syncs the headers in
+// LightSync mode, and answers the state queries by the proofs of the light servers.
+type LightNeatChain struct {
+	config      *Config
+	chainConfig *params.ChainConfig
+
+	chainDb    neatdb.Database
+	lightchain *LightChain
+	downloader *downloader.Downloader
+	eventMux   *event.TypeMux
+
+	peers     *peerSet
+	protocols []p2p.Protocol
+
+	reqID   uint64
+	reqLock sync.Mutex
+	pending map[uint64]chan [][]byte
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	logger log.Logger
+}
+
+// New creates the light client of the chain, the validators of the NeatCon genesis epoch
+// are trusted and every later epoch is learned from the verified headers.
+func New(ctx *node.ServiceContext, config *Config, cliCtx *cli.Context, logger log.Logger, isTestnet bool) (*LightNeatChain, error) {
+	chainDb, err := ctx.OpenDatabase("lightchaindata", config.DatabaseCache, config.DatabaseHandles, "neatchain/db/lightchaindata/")
+	if err != nil {
+		return nil, err
+	}
+
+	isMainChain := params.IsMainChain(ctx.ChainId())
+
+	chainConfig, _, genesisErr := core.SetupGenesisBlockWithDefault(chainDb, config.Genesis, isMainChain, isTestnet)
+	if _, ok := genesisErr.(*params.ConfigCompatError); genesisErr != nil && !ok {
+		return nil, genesisErr
+	}
+	chainConfig.ChainLogger = logger
+
+	trusted, err := neatcon.GenesisEpoch(chainConfig, cliCtx)
+	if err != nil {
+		return nil, err
+	}
+	lightchain, err := NewLightChain(chainDb, chainConfig, ctx.ChainId(), trusted, logger)
+	if err != nil {
+		return nil, err
+	}
+
+	lnc := &LightNeatChain{
+		config:      config,
+		chainConfig: chainConfig,
+		chainDb:     chainDb,
+		lightchain:  lightchain,
+		eventMux:    ctx.EventMux,
+		peers:       newPeerSet(),
+		pending:     make(map[uint64]chan [][]byte),
+		quit:        make(chan struct{}),
+		logger:      logger,
+	}
+	lnc.downloader = downloader.New(downloader.LightSync, chainDb, lnc.eventMux, nil, lightchain, lnc.removePeer, logger)
+
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		lnc.protocols = append(lnc.protocols, p2p.Protocol{
+			Name:    protocolName,
+			Version: version,
+			Length:  protocolLengths[ProtocolVersions[i]],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				peer := newPeer(int(version), p, rw)
+				lnc.wg.Add(1)
+				defer lnc.wg.Done()
+				return lnc.handle(peer)
+			},
+		})
+	}
+	return lnc, nil
+}
+
+// Protocols implements node.Service, returning the nles protocols.
+func (s *LightNeatChain) Protocols() []p2p.Protocol {
+	return s.protocols
+}
+
+// APIs implements node.Service, returning the RPC API endpoints answered by proofs.
+func (s *LightNeatChain) APIs() []rpc.API {
+	return []rpc.API{
+		{
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   NewPublicLightAPI(s),
+			Public:    true,
+		}, {
+			Namespace: "eth",
+			Version:   "1.0",
+			Service:   downloader.NewPublicDownloaderAPI(s.downloader, s.eventMux),
+			Public:    true,
+		},
+	}
+}
+
+// Start implements node.Service.
+func (s *LightNeatChain) Start(srvr *p2p.Server) error {
+	s.logger.Info("Light client started", "head", s.lightchain.CurrentHeader().Number)
+	return nil
+}
+
+// Stop implements node.Service, terminating all internal goroutines.
+func (s *LightNeatChain) Stop() error {
+	close(s.quit)
+	s.downloader.Terminate()
+	s.peers.Close()
+	s.wg.Wait()
+	s.chainDb.Close()
+	s.logger.Info("Light client stopped")
+	return nil
+}
+
+// LightChain returns the verified header chain
+func (s *LightNeatChain) LightChain() *LightChain { return s.lightchain }
+
+// Downloader returns the header downloader
+func (s *LightNeatChain) Downloader() *downloader.Downloader { return s.downloader }
+
+func (s *LightNeatChain) removePeer(id string) {
+	peer := s.peers.Peer(id)
+	if peer == nil {
+		return
+	}
+	s.logger.Debug("Removing light server peer", "peer", id)
+	s.downloader.UnregisterPeer(id)
+	s.peers.Unregister(id)
+	peer.Disconnect(p2p.DiscUselessPeer)
+}
+
+func (s *LightNeatChain) handle(p *peer) error {
+	head := s.lightchain.CurrentHeader()
+	hash, number := head.Hash(), head.Number.Uint64()
+	if err := p.Handshake(s.config.NetworkId, s.lightchain.GetTd(hash, number), hash, s.lightchain.Genesis().Hash()); err != nil {
+		p.Log().Debug("Light handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		return err
+	}
+	defer s.removePeer(p.id)
+
+	if err := s.downloader.RegisterLightPeer(p.id, ethVersion, p); err != nil {
+		return err
+	}
+	go s.synchronise(p)
+
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a light server.
+func (s *LightNeatChain) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case AnnounceMsg:
+		var announce announceData
+		if err := msg.Decode(&announce); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		if announce.TD == nil {
+			return errResp(ErrDecode, "missing total difficulty")
+		}
+		p.SetHead(announce.Hash, announce.TD)
+		go s.synchronise(p)
+
+	case BlockHeadersMsg:
+		var headers []*types.Header
+		if err := msg.Decode(&headers); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		if err := s.downloader.DeliverHeaders(p.id, headers); err != nil {
+			p.Log().Debug("Failed to deliver headers", "err", err)
+		}
+
+	case StateProofsMsg:
+		var resp stateProofs
+		if err := msg.Decode(&resp); err != nil {
+			return errResp(ErrDecode, "msg %v: %v", msg, err)
+		}
+		s.reqLock.Lock()
+		ch, ok := s.pending[resp.ReqID]
+		delete(s.pending, resp.ReqID)
+		s.reqLock.Unlock()
+		if !ok {
+			return errResp(ErrUnexpectedResponse, "reqid %v", resp.ReqID)
+		}
+		ch <- resp.Nodes
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+	return nil
+}
+
+// synchronise syncs the headers from the peer if its chain is heavier
+func (s *LightNeatChain) synchronise(p *peer) {
+	head := s.lightchain.CurrentHeader()
+	td := s.lightchain.GetTd(head.Hash(), head.Number.Uint64())
+	pHead, pTd := p.Head()
+	if pTd.Cmp(td) <= 0 {
+		return
+	}
+	if err := s.downloader.Synchronise(p.id, pHead, pTd, downloader.LightSync); err != nil {
+		return
+	}
+	s.logger.Debug("Light chain synchronised", "number", s.lightchain.CurrentHeader().Number)
+}
+
+// retrieveState sends the state request to the best light server and answers it by the proofs
+func (s *LightNeatChain) retrieveState(ctx context.Context, req *stateReq, header *types.Header) ([]byte, error) {
+	p := s.peers.BestPeer()
+	if p == nil {
+		return nil, errNoPeers
+	}
+	req.ReqID = atomic.AddUint64(&s.reqID, 1)
+	req.BlockHash = header.Hash()
+
+	ch := make(chan [][]byte, 1)
+	s.reqLock.Lock()
+	s.pending[req.ReqID] = ch
+	s.reqLock.Unlock()
+	defer func() {
+		s.reqLock.Lock()
+		delete(s.pending, req.ReqID)
+		s.reqLock.Unlock()
+	}()
+
+	if err := p.RequestStateProofs(req); err != nil {
+		return nil, err
+	}
+	timeout := time.NewTimer(requestTimeout)
+	defer timeout.Stop()
+
+	select {
+	case nodes := <-ch:
+		return verifyStateRequest(req, header, nodes, s.lightchain.hc, s.chainConfig)
+	case <-timeout.C:
+		return nil, errRequestTimeout
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case <-s.quit:
+		return nil, errNoPeers
+	}
+}
+
+// GetBalance retrieves the proven balance of the account on the state of the header
+func (s *LightNeatChain) GetBalance(ctx context.Context, address common.Address, header *types.Header) (*big.Int, error) {
+	result, err := s.retrieveState(ctx, &stateReq{Kind: stateReqBalance, Address: address}, header)
+	if err != nil {
+		return nil, err
+	}
+	return new(big.Int).SetBytes(result), nil
+}
+
+// GetStorageAt retrieves the proven storage slot of the account on the state of the header
+func (s *LightNeatChain) GetStorageAt(ctx context.Context, address common.Address, key common.Hash, header *types.Header) (common.Hash, error) {
+	result, err := s.retrieveState(ctx, &stateReq{Kind: stateReqStorage, Address: address, Key: key}, header)
+	if err != nil {
+		return common.Hash{}, err
+	}
+	return common.BytesToHash(result), nil
+}
+
+// Call executes the message on the proven state of the header
+func (s *LightNeatChain) Call(ctx context.Context, from common.Address, to *common.Address, gas uint64, gasPrice, value *big.Int, data []byte, header *types.Header) ([]byte, error) {
+	req := &stateReq{
+		Kind:     stateReqCall,
+		Address:  from,
+		To:       to,
+		Gas:      gas,
+		GasPrice: gasPrice,
+		Value:    value,
+		Data:     data,
+	}
+	return s.retrieveState(ctx, req, header)
+}
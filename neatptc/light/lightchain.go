@@ -0,0 +1,165 @@
+package light
+
+import (
+	"encoding/binary"
+	"fmt"
+	"math/big"
+	"sync"
+
+	ep "github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatdb"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// epochPrefix + epoch number (uint64 big endian) -> epoch learned by the light chain
+var epochPrefix = []byte("light-epoch-")
+
+// LightChain is the header chain of the light client. Every header is checked by the
+// BLS aggregated commit of its epoch's validators before it is written.
+type LightChain struct {
+	hc       *core.HeaderChain
+	chainDb  neatdb.Database
+	verifier *HeaderVerifier
+
+	mu sync.Mutex // serializes the header insertion
+
+	logger log.Logger
+}
+
+// NewLightChain creates the light chain on top of the genesis in chainDb. The validators
+// of the trusted epoch are trusted, the following epochs are learned from the headers.
+func NewLightChain(chainDb neatdb.Database, config *params.ChainConfig, chainID string, trusted *ep.Epoch, logger log.Logger) (*LightChain, error) {
+	hc, err := core.NewHeaderChain(chainDb, config, nil, func() bool { return false })
+	if err != nil {
+		return nil, err
+	}
+	if head := rawdb.ReadHeadHeaderHash(chainDb); head != (common.Hash{}) {
+		if header := hc.GetHeaderByHash(head); header != nil {
+			hc.SetCurrentHeader(header)
+		}
+	}
+
+	lc := &LightChain{
+		hc:       hc,
+		chainDb:  chainDb,
+		verifier: NewHeaderVerifier(chainID, trusted),
+		logger:   logger,
+	}
+	// Reload the epochs learned before the restart
+	for number := trusted.Number + 1; ; number++ {
+		epoch := ep.FromBytes(readEpoch(chainDb, number))
+		if epoch == nil {
+			break
+		}
+		lc.verifier.epochs = append(lc.verifier.epochs, epoch)
+	}
+	return lc, nil
+}
+
+// InsertHeaderChain verifies and writes a batch of linked headers, it returns the index
+// of the failed header. The commit of every header is checked, the checkFreq is ignored
+// as the epoch transitions have to be followed.
+func (lc *LightChain) InsertHeaderChain(chain []*types.Header, checkFreq int) (int, error) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for i, header := range chain {
+		if i > 0 && (header.Number.Uint64() != chain[i-1].Number.Uint64()+1 || header.ParentHash != chain[i-1].Hash()) {
+			return i, fmt.Errorf("non contiguous insert: item %d is #%d [%x…], item %d is #%d [%x…] (parent [%x…])",
+				i-1, chain[i-1].Number, chain[i-1].Hash().Bytes()[:4], i, header.Number, header.Hash().Bytes()[:4], header.ParentHash[:4])
+		}
+		if lc.hc.HasHeader(header.Hash(), header.Number.Uint64()) {
+			continue
+		}
+
+		last := lc.verifier.epochs[len(lc.verifier.epochs)-1]
+		if err := lc.verifier.VerifyHeader(header); err != nil {
+			lc.logger.Debug("Invalid header", "number", header.Number, "hash", header.Hash(), "err", err)
+			return i, err
+		}
+		if epochs := lc.verifier.Epochs(); epochs[len(epochs)-1] != last {
+			learned := epochs[len(epochs)-1]
+			writeEpoch(lc.chainDb, learned)
+			lc.logger.Info("Learned next epoch", "number", learned.Number, "start", learned.StartBlock, "end", learned.EndBlock)
+		}
+
+		if _, err := lc.hc.WriteHeader(header); err != nil {
+			return i, err
+		}
+	}
+	return 0, nil
+}
+
+// Rollback removes the given headers from the head of the chain
+func (lc *LightChain) Rollback(chain []common.Hash) {
+	lc.mu.Lock()
+	defer lc.mu.Unlock()
+
+	for i := len(chain) - 1; i >= 0; i-- {
+		if head := lc.hc.CurrentHeader(); head.Hash() == chain[i] {
+			lc.hc.SetCurrentHeader(lc.hc.GetHeader(head.ParentHash, head.Number.Uint64()-1))
+		}
+	}
+}
+
+// HasHeader checks if the header is present in the chain
+func (lc *LightChain) HasHeader(hash common.Hash, number uint64) bool {
+	return lc.hc.HasHeader(hash, number)
+}
+
+// GetHeader retrieves a header by hash and number
+func (lc *LightChain) GetHeader(hash common.Hash, number uint64) *types.Header {
+	return lc.hc.GetHeader(hash, number)
+}
+
+// GetHeaderByHash retrieves a header by hash
+func (lc *LightChain) GetHeaderByHash(hash common.Hash) *types.Header {
+	return lc.hc.GetHeaderByHash(hash)
+}
+
+// GetHeaderByNumber retrieves a canonical header by number
+func (lc *LightChain) GetHeaderByNumber(number uint64) *types.Header {
+	return lc.hc.GetHeaderByNumber(number)
+}
+
+// CurrentHeader retrieves the head header of the chain
+func (lc *LightChain) CurrentHeader() *types.Header {
+	return lc.hc.CurrentHeader()
+}
+
+// GetTd retrieves the total difficulty of a header
+func (lc *LightChain) GetTd(hash common.Hash, number uint64) *big.Int {
+	return lc.hc.GetTd(hash, number)
+}
+
+// Genesis returns the genesis header of the chain
+func (lc *LightChain) Genesis() *types.Header {
+	return lc.hc.GetHeaderByNumber(0)
+}
+
+// Config returns the chain configuration
+func (lc *LightChain) Config() *params.ChainConfig {
+	return lc.hc.Config()
+}
+
+func epochKey(number uint64) []byte {
+	enc := make([]byte, 8)
+	binary.BigEndian.PutUint64(enc, number)
+	return append(append([]byte{}, epochPrefix...), enc...)
+}
+
+func readEpoch(db neatdb.Reader, number uint64) []byte {
+	data, _ := db.Get(epochKey(number))
+	return data
+}
+
+func writeEpoch(db neatdb.Writer, epoch *ep.Epoch) {
+	if err := db.Put(epochKey(epoch.Number), epoch.Bytes()); err != nil {
+		log.Crit("Failed to store epoch", "err", err)
+	}
+}
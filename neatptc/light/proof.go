@@ -0,0 +1,230 @@
+package light
+
+import (
+	"errors"
+	"math/big"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/core/vm"
+	"github.com/Gessiux/neatchain/chain/trie"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/common/math"
+	"github.com/Gessiux/neatchain/utilities/crypto"
+)
+
+// callTimeout is the maximum time the server executes a call
+const callTimeout = 5 * time.Second
+
+// errIteratorNotProvable is recorded if a trie is iterated, only the keys read could be proved
+var errIteratorNotProvable = errors.New("trie iteration can not be proved")
+
+// stateRecorder wraps a state database, it remembers the keys read from every trie and
+// the contract codes loaded. The server proves the recorded keys, the client checks that
+// nothing was missing from the proofs by the first error recorded.
+type stateRecorder struct {
+	state.Database
+
+	tries []*recordingTrie
+	codes map[common.Hash][]byte
+	err   error
+}
+
+func newStateRecorder(db state.Database) *stateRecorder {
+	return &stateRecorder{
+		Database: db,
+		codes:    make(map[common.Hash][]byte),
+	}
+}
+
+// recordingTrie is a trie remembering the keys read from it
+type recordingTrie struct {
+	state.Trie
+	root     common.Hash
+	keys     [][]byte
+	recorder *stateRecorder
+}
+
+func (t *recordingTrie) TryGet(key []byte) ([]byte, error) {
+	t.keys = append(t.keys, common.CopyBytes(key))
+	value, err := t.Trie.TryGet(key)
+	t.recorder.setError(err)
+	return value, err
+}
+
+func (t *recordingTrie) NodeIterator(startKey []byte) trie.NodeIterator {
+	t.recorder.setError(errIteratorNotProvable)
+	return t.Trie.NodeIterator(startKey)
+}
+
+func (r *stateRecorder) setError(err error) {
+	if r.err == nil {
+		r.err = err
+	}
+}
+
+func (r *stateRecorder) record(root common.Hash, tr state.Trie, err error) (state.Trie, error) {
+	if err != nil {
+		r.setError(err)
+		return nil, err
+	}
+	rt := &recordingTrie{Trie: tr, root: root, recorder: r}
+	r.tries = append(r.tries, rt)
+	return rt, nil
+}
+
+func (r *stateRecorder) OpenTrie(root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenTrie(root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) OpenStorageTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenStorageTrie(addrHash, root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) OpenTX1Trie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenTX1Trie(addrHash, root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) OpenTX3Trie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenTX3Trie(addrHash, root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) OpenProxiedTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenProxiedTrie(addrHash, root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) OpenRewardTrie(addrHash, root common.Hash) (state.Trie, error) {
+	tr, err := r.Database.OpenRewardTrie(addrHash, root)
+	return r.record(root, tr, err)
+}
+
+func (r *stateRecorder) CopyTrie(t state.Trie) state.Trie {
+	rt := t.(*recordingTrie)
+	tr, _ := r.record(rt.root, r.Database.CopyTrie(rt.Trie), nil)
+	return tr
+}
+
+func (r *stateRecorder) ContractCode(addrHash, codeHash common.Hash) ([]byte, error) {
+	code, err := r.Database.ContractCode(addrHash, codeHash)
+	if err != nil {
+		r.setError(err)
+		return nil, err
+	}
+	r.codes[codeHash] = code
+	return code, nil
+}
+
+func (r *stateRecorder) ContractCodeSize(addrHash, codeHash common.Hash) (int, error) {
+	code, err := r.ContractCode(addrHash, codeHash)
+	return len(code), err
+}
+
+// nodeSet collects the proof nodes by their hash
+type nodeSet map[common.Hash][]byte
+
+func (s nodeSet) Put(key []byte, value []byte) error {
+	s[common.BytesToHash(key)] = common.CopyBytes(value)
+	return nil
+}
+
+func (s nodeSet) Delete(key []byte) error {
+	delete(s, common.BytesToHash(key))
+	return nil
+}
+
+// proofs returns the trie nodes on the path of every key read, and the contract codes loaded
+func (r *stateRecorder) proofs() ([][]byte, error) {
+	set := make(nodeSet)
+	for _, rt := range r.tries {
+		tr, err := trie.NewSecure(rt.root, r.TrieDB())
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range rt.keys {
+			// The secure trie proves the hashed keys
+			if err := tr.Prove(crypto.Keccak256(key), 0, set); err != nil {
+				return nil, err
+			}
+		}
+	}
+	for hash, code := range r.codes {
+		set[hash] = code
+	}
+
+	nodes := make([][]byte, 0, len(set))
+	for _, node := range set {
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// executeStateRequest answers the state request on the state of the header. The server
+// runs it to record the proofs, the client runs it again on the proofs.
+func executeStateRequest(req *stateReq, statedb *state.StateDB, header *types.Header, chain core.ChainContext, config *params.ChainConfig) ([]byte, error) {
+	switch req.Kind {
+	case stateReqBalance:
+		return statedb.GetBalance(req.Address).Bytes(), nil
+
+	case stateReqStorage:
+		value := statedb.GetState(req.Address, req.Key)
+		return value[:], nil
+
+	case stateReqCall:
+		// The gas is capped by the block gas limit on both sides
+		gas := req.Gas
+		if gas == 0 || gas > header.GasLimit {
+			gas = header.GasLimit
+		}
+		gasPrice, value := new(big.Int), new(big.Int)
+		if req.GasPrice != nil {
+			gasPrice.Set(req.GasPrice)
+		}
+		if req.Value != nil {
+			value.Set(req.Value)
+		}
+		msg := types.NewMessage(req.Address, req.To, 0, value, gas, gasPrice, req.Data, false)
+		statedb.SetBalance(msg.From(), math.MaxBig256)
+
+		context := core.NewEVMContext(msg, header, chain, &header.Coinbase)
+		evm := vm.NewEVM(context, statedb, config, vm.Config{})
+		timer := time.AfterFunc(callTimeout, evm.Cancel)
+		defer timer.Stop()
+
+		gp := new(core.GasPool).AddGas(math.MaxUint64)
+		result, _, _, err := core.ApplyMessage(evm, msg, gp)
+		return result, err
+
+	default:
+		return nil, errUnknownRequest
+	}
+}
+
+// verifyStateRequest answers the state request on the state made of the proofs, the state
+// root is taken from the verified header. Any trie node or code missing from the proofs
+// fails the request.
+func verifyStateRequest(req *stateReq, header *types.Header, nodes [][]byte, chain core.ChainContext, config *params.ChainConfig) ([]byte, error) {
+	db := rawdb.NewMemoryDatabase()
+	for _, node := range nodes {
+		db.Put(crypto.Keccak256(node), node)
+	}
+
+	recorder := newStateRecorder(state.NewDatabase(db))
+	statedb, err := state.New(header.Root, recorder)
+	if err != nil {
+		return nil, errInvalidProof
+	}
+	result, err := executeStateRequest(req, statedb, header, chain, config)
+	if recorder.err != nil || statedb.Error() != nil {
+		return nil, errInvalidProof
+	}
+	return result, err
+}
@@ -0,0 +1,94 @@
+package light
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/params"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+var (
+	testAddress = common.HexToAddress("0x1000000000000000000000000000000000000001")
+	testKey     = common.HexToHash("0x01")
+	testValue   = common.HexToHash("0xbeef")
+)
+
+func newTestState(t *testing.T) (state.Database, *types.Header) {
+	sdb := state.NewDatabase(rawdb.NewMemoryDatabase())
+	statedb, _ := state.New(common.Hash{}, sdb)
+	statedb.SetBalance(testAddress, big.NewInt(1000))
+	statedb.SetState(testAddress, testKey, testValue)
+	for i := int64(0); i < 100; i++ {
+		statedb.SetBalance(common.BigToAddress(big.NewInt(i+100)), big.NewInt(i))
+	}
+	root, err := statedb.Commit(false)
+	if err != nil {
+		t.Fatalf("failed to commit state: %v", err)
+	}
+	if err := sdb.TrieDB().Commit(root, false); err != nil {
+		t.Fatalf("failed to commit trie: %v", err)
+	}
+	return sdb, &types.Header{Number: big.NewInt(1), Root: root, GasLimit: 8000000}
+}
+
+// proveStateRequest answers the state request like the server does
+func proveStateRequest(t *testing.T, sdb state.Database, header *types.Header, req *stateReq) [][]byte {
+	recorder := newStateRecorder(sdb)
+	statedb, err := state.New(header.Root, recorder)
+	if err != nil {
+		t.Fatalf("failed to open state: %v", err)
+	}
+	if _, err := executeStateRequest(req, statedb, header, nil, params.TestChainConfig); err != nil {
+		t.Fatalf("failed to execute request: %v", err)
+	}
+	nodes, err := recorder.proofs()
+	if err != nil {
+		t.Fatalf("failed to prove request: %v", err)
+	}
+	return nodes
+}
+
+func TestStateProofs(t *testing.T) {
+	sdb, header := newTestState(t)
+
+	req := &stateReq{Kind: stateReqBalance, Address: testAddress}
+	result, err := verifyStateRequest(req, header, proveStateRequest(t, sdb, header, req), nil, params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("failed to verify balance: %v", err)
+	}
+	if balance := new(big.Int).SetBytes(result); balance.Cmp(big.NewInt(1000)) != 0 {
+		t.Fatalf("balance mismatch: have %v, want %v", balance, 1000)
+	}
+
+	req = &stateReq{Kind: stateReqStorage, Address: testAddress, Key: testKey}
+	result, err = verifyStateRequest(req, header, proveStateRequest(t, sdb, header, req), nil, params.TestChainConfig)
+	if err != nil {
+		t.Fatalf("failed to verify storage: %v", err)
+	}
+	if value := common.BytesToHash(result); value != testValue {
+		t.Fatalf("storage mismatch: have %x, want %x", value, testValue)
+	}
+}
+
+func TestStateProofsMissingNodes(t *testing.T) {
+	sdb, header := newTestState(t)
+
+	req := &stateReq{Kind: stateReqStorage, Address: testAddress, Key: testKey}
+	nodes := proveStateRequest(t, sdb, header, req)
+	for i := range nodes {
+		partial := append(append([][]byte{}, nodes[:i]...), nodes[i+1:]...)
+		if _, err := verifyStateRequest(req, header, partial, nil, params.TestChainConfig); err != errInvalidProof {
+			t.Fatalf("proof without node %d: have %v, want %v", i, err, errInvalidProof)
+		}
+	}
+
+	// Proofs of another state root are rejected
+	other := &types.Header{Number: big.NewInt(1), Root: common.HexToHash("0xdead")}
+	if _, err := verifyStateRequest(req, other, nodes, nil, params.TestChainConfig); err != errInvalidProof {
+		t.Fatalf("proof of other root: have %v, want %v", err, errInvalidProof)
+	}
+}
@@ -0,0 +1,138 @@
+package light
+
+import (
+	"errors"
+	"fmt"
+	"math/big"
+
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// Constants to match up protocol versions and messages
+const (
+	nles1 = 1
+)
+
+// protocolName is the official short name of the light protocol used during capability negotiation.
+const protocolName = "nles"
+
+// ProtocolVersions are the supported versions of the nles protocol (first is primary).
+var ProtocolVersions = []uint{nles1}
+
+// protocolLengths are the number of implemented message corresponding to different protocol versions.
+var protocolLengths = map[uint]uint64{nles1: 6}
+
+const ProtocolMaxMsgSize = 10 * 1024 * 1024 // Maximum cap on the size of a protocol message
+
+// ethVersion is the neatptc protocol version the light peers are registered with in the downloader
+const ethVersion = 63
+
+// nles protocol message codes
+const (
+	StatusMsg          = 0x00
+	AnnounceMsg        = 0x01
+	GetBlockHeadersMsg = 0x02
+	BlockHeadersMsg    = 0x03
+	GetStateProofsMsg  = 0x04
+	StateProofsMsg     = 0x05
+)
+
+type errCode int
+
+const (
+	ErrMsgTooLarge = iota
+	ErrDecode
+	ErrInvalidMsgCode
+	ErrProtocolVersionMismatch
+	ErrNetworkIdMismatch
+	ErrGenesisBlockMismatch
+	ErrNoStatusMsg
+	ErrExtraStatusMsg
+	ErrUnexpectedResponse
+)
+
+func (e errCode) String() string {
+	return errorToString[int(e)]
+}
+
+var errorToString = map[int]string{
+	ErrMsgTooLarge:             "Message too long",
+	ErrDecode:                  "Invalid message",
+	ErrInvalidMsgCode:          "Invalid message code",
+	ErrProtocolVersionMismatch: "Protocol version mismatch",
+	ErrNetworkIdMismatch:       "NetworkId mismatch",
+	ErrGenesisBlockMismatch:    "Genesis block mismatch",
+	ErrNoStatusMsg:             "No status message",
+	ErrExtraStatusMsg:          "Extra status message",
+	ErrUnexpectedResponse:      "Unexpected response",
+}
+
+func errResp(code errCode, format string, v ...interface{}) error {
+	return fmt.Errorf("%v - %v", code, fmt.Sprintf(format, v...))
+}
+
+var (
+	errNoPeers        = errors.New("no light server peers")
+	errRequestTimeout = errors.New("light request timed out")
+	errUnknownBlock   = errors.New("unknown block")
+	errUnknownRequest = errors.New("unknown state request")
+	errInvalidProof   = errors.New("invalid state proof")
+)
+
+// statusData is the network packet for the status message.
+type statusData struct {
+	ProtocolVersion uint32
+	NetworkId       uint64
+	TD              *big.Int
+	CurrentBlock    common.Hash
+	GenesisBlock    common.Hash
+}
+
+// announceData is the network packet announcing a new head of the server.
+type announceData struct {
+	Hash   common.Hash
+	Number uint64
+	TD     *big.Int
+}
+
+// getBlockHeadersData represents a block header query, the origin is given by
+// the hash, or by the number if the hash is empty.
+type getBlockHeadersData struct {
+	Hash    common.Hash
+	Number  uint64
+	Amount  uint64 // Maximum number of headers to retrieve
+	Skip    uint64 // Blocks to skip between consecutive headers
+	Reverse bool   // Query direction (false = rising towards latest, true = falling towards genesis)
+}
+
+// Kinds of the state requests
+const (
+	stateReqBalance = iota
+	stateReqStorage
+	stateReqCall
+)
+
+// stateReq asks for the proofs of a state query on the state of a block. The server answers
+// the query on a state recording the trie nodes read, the client checks the answer by running
+// the query again on the state made of the proofs.
+type stateReq struct {
+	ReqID     uint64
+	BlockHash common.Hash
+	Kind      uint8
+	Address   common.Address // the account of the balance or storage, the sender of the call
+	Key       common.Hash    // the storage slot
+
+	// Call arguments
+	To       *common.Address `rlp:"nil"`
+	Gas      uint64
+	GasPrice *big.Int
+	Value    *big.Int
+	Data     []byte
+}
+
+// stateProofs is the network packet carrying the trie nodes and contract codes
+// needed to answer a state request.
+type stateProofs struct {
+	ReqID uint64
+	Nodes [][]byte
+}
@@ -0,0 +1,232 @@
+package light
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/state"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/chain/log"
+	"github.com/Gessiux/neatchain/neatptc/downloader"
+	"github.com/Gessiux/neatchain/network/p2p"
+	"github.com/Gessiux/neatchain/network/p2p/discover"
+	"github.com/Gessiux/neatchain/utilities/common"
+	"github.com/Gessiux/neatchain/utilities/event"
+)
+
+// Server serves the headers and the state proofs of a full node to the light clients
+// over the nles protocol.
+type Server struct {
+	blockchain *core.BlockChain
+	networkId  uint64
+	maxPeers   int
+
+	protocols []p2p.Protocol
+	peers     *peerSet
+
+	chainHeadCh  chan core.ChainHeadEvent
+	chainHeadSub event.Subscription
+
+	quit chan struct{}
+	wg   sync.WaitGroup
+
+	logger log.Logger
+}
+
+// NewServer creates the light server of the blockchain, at most maxPeers light clients are served
+func NewServer(blockchain *core.BlockChain, networkId uint64, maxPeers int, logger log.Logger) *Server {
+	s := &Server{
+		blockchain: blockchain,
+		networkId:  networkId,
+		maxPeers:   maxPeers,
+		peers:      newPeerSet(),
+		quit:       make(chan struct{}),
+		logger:     logger,
+	}
+	for i, version := range ProtocolVersions {
+		version := version // Closure for the run
+		s.protocols = append(s.protocols, p2p.Protocol{
+			Name:    protocolName,
+			Version: version,
+			Length:  protocolLengths[ProtocolVersions[i]],
+			Run: func(p *p2p.Peer, rw p2p.MsgReadWriter) error {
+				peer := newPeer(int(version), p, rw)
+				s.wg.Add(1)
+				defer s.wg.Done()
+				return s.handle(peer)
+			},
+			PeerInfo: func(id discover.NodeID) interface{} {
+				if p := s.peers.Peer(fmt.Sprintf("%x", id[:8])); p != nil {
+					hash, td := p.Head()
+					return map[string]interface{}{"version": p.version, "difficulty": td, "head": hash.Hex()}
+				}
+				return nil
+			},
+		})
+	}
+	return s
+}
+
+// Protocols returns the nles protocols served
+func (s *Server) Protocols() []p2p.Protocol {
+	return s.protocols
+}
+
+// Start announces the new heads of the blockchain to the light clients
+func (s *Server) Start(srvr *p2p.Server) {
+	s.chainHeadCh = make(chan core.ChainHeadEvent, 10)
+	s.chainHeadSub = s.blockchain.SubscribeChainHeadEvent(s.chainHeadCh)
+	go s.announceLoop()
+}
+
+// Stop disconnects the light clients
+func (s *Server) Stop() {
+	s.chainHeadSub.Unsubscribe()
+	close(s.quit)
+	s.peers.Close()
+	s.wg.Wait()
+	s.logger.Info("Light server stopped")
+}
+
+func (s *Server) announceLoop() {
+	for {
+		select {
+		case ev := <-s.chainHeadCh:
+			header := ev.Block.Header()
+			hash, number := header.Hash(), header.Number.Uint64()
+			td := s.blockchain.GetTd(hash, number)
+			for _, p := range s.peers.AllPeers() {
+				if err := p.SendAnnounce(hash, number, td); err != nil {
+					p.Log().Debug("Failed to announce head", "err", err)
+				}
+			}
+		case <-s.chainHeadSub.Err():
+			return
+		case <-s.quit:
+			return
+		}
+	}
+}
+
+func (s *Server) handle(p *peer) error {
+	if s.peers.Len() >= s.maxPeers {
+		return p2p.DiscTooManyPeers
+	}
+	p.Log().Debug("Light client connected", "name", p.Name())
+
+	head := s.blockchain.CurrentHeader()
+	hash, number := head.Hash(), head.Number.Uint64()
+	genesis := s.blockchain.Genesis()
+	if err := p.Handshake(s.networkId, s.blockchain.GetTd(hash, number), hash, genesis.Hash()); err != nil {
+		p.Log().Debug("Light handshake failed", "err", err)
+		return err
+	}
+	if err := s.peers.Register(p); err != nil {
+		return err
+	}
+	defer s.peers.Unregister(p.id)
+
+	for {
+		if err := s.handleMsg(p); err != nil {
+			p.Log().Debug("Light message handling failed", "err", err)
+			return err
+		}
+	}
+}
+
+// handleMsg is invoked whenever an inbound message is received from a light client.
+func (s *Server) handleMsg(p *peer) error {
+	msg, err := p.rw.ReadMsg()
+	if err != nil {
+		return err
+	}
+	if msg.Size > ProtocolMaxMsgSize {
+		return errResp(ErrMsgTooLarge, "%v > %v", msg.Size, ProtocolMaxMsgSize)
+	}
+	defer msg.Discard()
+
+	switch msg.Code {
+	case StatusMsg:
+		return errResp(ErrExtraStatusMsg, "uncontrolled status message")
+
+	case GetBlockHeadersMsg:
+		var query getBlockHeadersData
+		if err := msg.Decode(&query); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		return p.SendBlockHeaders(s.queryHeaders(&query))
+
+	case GetStateProofsMsg:
+		var req stateReq
+		if err := msg.Decode(&req); err != nil {
+			return errResp(ErrDecode, "%v: %v", msg, err)
+		}
+		nodes, err := s.stateProofs(&req)
+		if err != nil {
+			p.Log().Debug("Failed to prove state request", "reqid", req.ReqID, "err", err)
+		}
+		return p.SendStateProofs(req.ReqID, nodes)
+
+	default:
+		return errResp(ErrInvalidMsgCode, "%v", msg.Code)
+	}
+}
+
+// queryHeaders gathers the canonical headers of the query
+func (s *Server) queryHeaders(query *getBlockHeadersData) []*types.Header {
+	number := query.Number
+	if query.Hash != (common.Hash{}) {
+		origin := s.blockchain.GetHeaderByHash(query.Hash)
+		if origin == nil {
+			return nil
+		}
+		number = origin.Number.Uint64()
+		if canonical := s.blockchain.GetHeaderByNumber(number); canonical == nil || canonical.Hash() != query.Hash {
+			return []*types.Header{origin}
+		}
+	}
+
+	var headers []*types.Header
+	for len(headers) < int(query.Amount) && len(headers) < downloader.MaxHeaderFetch {
+		header := s.blockchain.GetHeaderByNumber(number)
+		if header == nil {
+			break
+		}
+		headers = append(headers, header)
+
+		if query.Reverse {
+			if number < query.Skip+1 {
+				break
+			}
+			number -= query.Skip + 1
+		} else {
+			if next := number + query.Skip + 1; next > number {
+				number = next
+			} else {
+				break
+			}
+		}
+	}
+	return headers
+}
+
+// stateProofs answers the state request on a recording state, and proves the trie nodes read
+func (s *Server) stateProofs(req *stateReq) ([][]byte, error) {
+	header := s.blockchain.GetHeaderByHash(req.BlockHash)
+	if header == nil {
+		return nil, errUnknownBlock
+	}
+	recorder := newStateRecorder(s.blockchain.StateCache())
+	statedb, err := state.New(header.Root, recorder)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := executeStateRequest(req, statedb, header, s.blockchain, s.blockchain.Config()); err == errUnknownRequest {
+		return nil, err
+	}
+	if recorder.err != nil {
+		return nil, recorder.err
+	}
+	return recorder.proofs()
+}
@@ -0,0 +1,128 @@
+package light
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sync"
+
+	ep "github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	ntcTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/core/types"
+)
+
+var (
+	// errInvalidExtra is returned if the NeatConExtra of the header can not be decoded
+	errInvalidExtra = errors.New("invalid neatcon extra data")
+	// errUnknownEpoch is returned if the validators of the header's epoch are not known yet
+	errUnknownEpoch = errors.New("unknown epoch")
+	// errInconsistentValidatorSet is returned if the header is not signed by the validators of its epoch
+	errInconsistentValidatorSet = errors.New("inconsistent validator set")
+	// errInvalidCommit is returned if the commit doesn't belong to the header
+	errInvalidCommit = errors.New("invalid commit")
+)
+
+// HeaderVerifier checks NeatCon headers by the BLS aggregated commit carried in NeatConExtra.
+// It starts from a trusted epoch and learns the validators of the following epochs from
+// the epoch bytes of the verified headers, so headers have to be verified in order.
+//
+// The commit signs the NeatConExtra of the proposal and the part set of the whole proposal
+// block, only the former could be checked from a header.
+type HeaderVerifier struct {
+	chainID string
+
+	mtx    sync.RWMutex
+	epochs []*ep.Epoch // known epochs in ascending order, the last one may be the proposed next epoch
+}
+
+// NewHeaderVerifier creates a verifier of the chain, trusting the validators of the given epoch
+func NewHeaderVerifier(chainID string, trusted *ep.Epoch) *HeaderVerifier {
+	return &HeaderVerifier{
+		chainID: chainID,
+		epochs:  []*ep.Epoch{trusted},
+	}
+}
+
+// VerifyHeader checks the commit of the header against the validators of its epoch.
+// If the header carries the next epoch, the next epoch is learned.
+func (v *HeaderVerifier) VerifyHeader(header *types.Header) error {
+	ncExtra, err := ntcTypes.ExtractNeatConExtra(header)
+	if err != nil {
+		return errInvalidExtra
+	}
+	if ncExtra.ChainID != v.chainID {
+		return fmt.Errorf("wrong chain id %q, expected %q", ncExtra.ChainID, v.chainID)
+	}
+	if ncExtra.Height != header.Number.Uint64() {
+		return fmt.Errorf("wrong height %v in extra data of header %v", ncExtra.Height, header.Number)
+	}
+
+	epoch := v.GetEpochByBlockNumber(ncExtra.Height)
+	if epoch == nil || epoch.Validators == nil {
+		return errUnknownEpoch
+	}
+
+	valSet := epoch.Validators
+	if !bytes.Equal(valSet.Hash(), ncExtra.ValidatorsHash) {
+		return errInconsistentValidatorSet
+	}
+
+	seenCommit := ncExtra.SeenCommit
+	if seenCommit == nil || !bytes.Equal(ncExtra.SeenCommitHash, seenCommit.Hash()) {
+		return errInvalidCommit
+	}
+	// NeedToSave and NeedToBroadcast are set once the block is committed,
+	// the commit signs the extra data as it was proposed
+	proposed := ncExtra.Copy()
+	proposed.NeedToSave, proposed.NeedToBroadcast = false, false
+	if !bytes.Equal(seenCommit.BlockID.Hash, proposed.Hash()) {
+		return errInvalidCommit
+	}
+
+	if err := valSet.VerifyCommit(ncExtra.ChainID, ncExtra.Height, seenCommit); err != nil {
+		return err
+	}
+
+	v.learnEpoch(epoch, ep.FromBytes(ncExtra.EpochBytes))
+	return nil
+}
+
+// learnEpoch remembers the next epoch carried by a verified header of the current epoch.
+// The next epoch is proposed at the beginning of the current epoch, the epoch end block
+// carries it again with the final validators.
+func (v *HeaderVerifier) learnEpoch(current, next *ep.Epoch) {
+	if next == nil || next.Number != current.Number+1 || next.StartBlock != current.EndBlock+1 {
+		return
+	}
+
+	v.mtx.Lock()
+	defer v.mtx.Unlock()
+
+	last := v.epochs[len(v.epochs)-1]
+	if last.Number == next.Number {
+		v.epochs[len(v.epochs)-1] = next
+	} else if last.Number == current.Number {
+		v.epochs = append(v.epochs, next)
+	}
+}
+
+// GetEpochByBlockNumber returns the known epoch the block belongs to, nil if unknown
+func (v *HeaderVerifier) GetEpochByBlockNumber(number uint64) *ep.Epoch {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+
+	for i := len(v.epochs) - 1; i >= 0; i-- {
+		if number >= v.epochs[i].StartBlock && number <= v.epochs[i].EndBlock {
+			return v.epochs[i]
+		}
+	}
+	return nil
+}
+
+// Epochs returns the known epochs in ascending order
+func (v *HeaderVerifier) Epochs() []*ep.Epoch {
+	v.mtx.RLock()
+	defer v.mtx.RUnlock()
+
+	return append([]*ep.Epoch(nil), v.epochs...)
+}
@@ -0,0 +1,177 @@
+package light
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	cmn "github.com/Gessiux/go-common"
+	"github.com/Gessiux/go-crypto"
+	"github.com/Gessiux/go-wire"
+	ep "github.com/Gessiux/neatchain/chain/consensus/neatcon/epoch"
+	ntcTypes "github.com/Gessiux/neatchain/chain/consensus/neatcon/types"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+const testChainID = "neatchain"
+
+type testValidators struct {
+	valSet *ntcTypes.ValidatorSet
+	keys   map[string]crypto.PrivKey
+}
+
+func newTestValidators(n int) *testValidators {
+	tv := &testValidators{keys: make(map[string]crypto.PrivKey)}
+	validators := make([]*ntcTypes.Validator, n)
+	for i := 0; i < n; i++ {
+		address := common.BigToAddress(big.NewInt(int64(i + 1)))
+		priv := ntcTypes.GenPrivValidatorKey(address)
+		validators[i] = ntcTypes.NewValidator(address.Bytes(), priv.PubKey, big.NewInt(100))
+		tv.keys[string(address.Bytes())] = priv.PrivKey
+	}
+	tv.valSet = ntcTypes.NewValidatorSet(validators)
+	return tv
+}
+
+func newTestEpoch(number, start, end uint64, tv *testValidators) *ep.Epoch {
+	return &ep.Epoch{
+		Number:         number,
+		RewardPerBlock: big.NewInt(0),
+		StartBlock:     start,
+		EndBlock:       end,
+		Validators:     tv.valSet,
+	}
+}
+
+// makeHeader creates the header of the block committed by the first signers of the validators
+func makeHeader(number, epochNumber uint64, tv *testValidators, signers int, epochBytes []byte) *types.Header {
+	ncExtra := &ntcTypes.NeatConExtra{
+		ChainID:        testChainID,
+		Height:         number,
+		Time:           time.Unix(1600000000+int64(number), 0).UTC(),
+		EpochNumber:    epochNumber,
+		ValidatorsHash: tv.valSet.Hash(),
+		EpochBytes:     epochBytes,
+	}
+	blockID := ntcTypes.BlockID{
+		Hash:        ncExtra.Hash(),
+		PartsHeader: ntcTypes.PartSetHeader{Total: 1, Hash: common.BigToHash(big.NewInt(int64(number))).Bytes()},
+	}
+	vote := &ntcTypes.Vote{BlockID: blockID, Height: number, Type: ntcTypes.VoteTypePrecommit}
+	signBytes := ntcTypes.SignBytes(testChainID, vote)
+
+	bitArray := cmn.NewBitArray(uint64(tv.valSet.Size()))
+	var sigs []*crypto.Signature
+	for i, val := range tv.valSet.Validators {
+		if i >= signers {
+			break
+		}
+		sig := tv.keys[string(val.Address)].Sign(signBytes)
+		sigs = append(sigs, &sig)
+		bitArray.SetIndex(uint64(i), true)
+	}
+	ncExtra.SeenCommit = &ntcTypes.Commit{
+		BlockID:  blockID,
+		Height:   number,
+		SignAggr: crypto.BLSSignatureAggregate(sigs),
+		BitArray: bitArray,
+	}
+	ncExtra.SeenCommitHash = ncExtra.SeenCommit.Hash()
+
+	// Set once the block is committed, not covered by the commit
+	ncExtra.NeedToSave = true
+
+	return &types.Header{
+		Number: new(big.Int).SetUint64(number),
+		Extra:  wire.BinaryBytes(*ncExtra),
+	}
+}
+
+func TestVerifyHeader(t *testing.T) {
+	tv := newTestValidators(4)
+	verifier := NewHeaderVerifier(testChainID, newTestEpoch(0, 0, 10, tv))
+
+	if err := verifier.VerifyHeader(makeHeader(1, 0, tv, 4, nil)); err != nil {
+		t.Fatalf("failed to verify header signed by all validators: %v", err)
+	}
+	if err := verifier.VerifyHeader(makeHeader(2, 0, tv, 3, nil)); err != nil {
+		t.Fatalf("failed to verify header signed by 3/4 validators: %v", err)
+	}
+	if err := verifier.VerifyHeader(makeHeader(3, 0, tv, 1, nil)); err == nil {
+		t.Fatalf("header signed by 1/4 validators verified")
+	}
+
+	// Headers signed by unknown validators
+	if err := verifier.VerifyHeader(makeHeader(4, 0, newTestValidators(4), 4, nil)); err != errInconsistentValidatorSet {
+		t.Fatalf("header of other validators: have %v, want %v", err, errInconsistentValidatorSet)
+	}
+
+	// Headers out of the known epochs
+	if err := verifier.VerifyHeader(makeHeader(11, 1, tv, 4, nil)); err != errUnknownEpoch {
+		t.Fatalf("header of unknown epoch: have %v, want %v", err, errUnknownEpoch)
+	}
+}
+
+func TestVerifyForgedHeader(t *testing.T) {
+	tv := newTestValidators(4)
+	verifier := NewHeaderVerifier(testChainID, newTestEpoch(0, 0, 10, tv))
+
+	// Replace the epoch bytes of a signed header
+	header := makeHeader(1, 0, tv, 4, nil)
+	ncExtra, _ := ntcTypes.ExtractNeatConExtra(header)
+	ncExtra.EpochBytes = newTestEpoch(1, 11, 20, newTestValidators(4)).Bytes()
+	header.Extra = wire.BinaryBytes(*ncExtra)
+	if err := verifier.VerifyHeader(header); err != errInvalidCommit {
+		t.Fatalf("forged epoch bytes: have %v, want %v", err, errInvalidCommit)
+	}
+	if len(verifier.Epochs()) != 1 {
+		t.Fatalf("epoch learned from forged header")
+	}
+
+	// Move a signed commit to another height
+	header = makeHeader(1, 0, tv, 4, nil)
+	header.Number = big.NewInt(2)
+	if err := verifier.VerifyHeader(header); err == nil {
+		t.Fatalf("header with wrong height verified")
+	}
+
+	// Headers of other chains
+	header = makeHeader(1, 0, tv, 4, nil)
+	ncExtra, _ = ntcTypes.ExtractNeatConExtra(header)
+	ncExtra.ChainID = "side_0"
+	header.Extra = wire.BinaryBytes(*ncExtra)
+	if err := verifier.VerifyHeader(header); err == nil {
+		t.Fatalf("header of other chain verified")
+	}
+}
+
+func TestVerifyEpochTransition(t *testing.T) {
+	tv0, tv1 := newTestValidators(4), newTestValidators(3)
+	verifier := NewHeaderVerifier(testChainID, newTestEpoch(0, 0, 10, tv0))
+
+	// The next epoch is proposed in the second block of the epoch
+	next := newTestEpoch(1, 11, 20, tv1)
+	if err := verifier.VerifyHeader(makeHeader(2, 0, tv0, 4, next.Bytes())); err != nil {
+		t.Fatalf("failed to verify header proposing next epoch: %v", err)
+	}
+	if epochs := verifier.Epochs(); len(epochs) != 2 || epochs[1].Number != 1 {
+		t.Fatalf("next epoch not learned: %v", epochs)
+	}
+
+	// Headers of the next epoch are signed by its validators
+	if err := verifier.VerifyHeader(makeHeader(11, 1, tv1, 3, nil)); err != nil {
+		t.Fatalf("failed to verify header of next epoch: %v", err)
+	}
+	if err := verifier.VerifyHeader(makeHeader(12, 1, tv0, 4, nil)); err != errInconsistentValidatorSet {
+		t.Fatalf("header of next epoch signed by previous validators: have %v, want %v", err, errInconsistentValidatorSet)
+	}
+
+	// An epoch not following the current one is ignored
+	if err := verifier.VerifyHeader(makeHeader(13, 1, tv1, 3, newTestEpoch(3, 30, 40, tv0).Bytes())); err != nil {
+		t.Fatalf("failed to verify header: %v", err)
+	}
+	if epochs := verifier.Epochs(); len(epochs) != 2 {
+		t.Fatalf("unexpected epoch learned: %v", epochs)
+	}
+}
@@ -138,3 +138,36 @@ func (rw *meteredMsgReadWriter) WriteMsg(msg p2p.Msg) error {
 	// Send the packet to the p2p layer
 	return rw.MsgReadWriter.WriteMsg(msg)
 }
+
+// NewChainRegistry creates the metrics registry of the chain, the metrics of each running chain
+// are kept apart from the process wide ones, so they could be reported with the chain label.
+func NewChainRegistry(neatChain *NeatChain) metrics.Registry {
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredFunctionalGauge("chain/head/block", reg, func() int64 {
+		return int64(neatChain.BlockChain().CurrentBlock().NumberU64())
+	})
+	metrics.NewRegisteredFunctionalGauge("chain/epoch", reg, func() int64 {
+		if ep := neatChain.Engine().GetEpoch(); ep != nil {
+			return int64(ep.Number)
+		}
+		return 0
+	})
+	metrics.NewRegisteredFunctionalGauge("chain/validators", reg, func() int64 {
+		if ep := neatChain.Engine().GetEpoch(); ep != nil {
+			return int64(ep.Validators.Size())
+		}
+		return 0
+	})
+	metrics.NewRegisteredFunctionalGauge("chain/peers", reg, func() int64 {
+		return int64(neatChain.protocolManager.peers.Len())
+	})
+	metrics.NewRegisteredFunctionalGauge("txpool/pending", reg, func() int64 {
+		pending, _ := neatChain.TxPool().Stats()
+		return int64(pending)
+	})
+	metrics.NewRegisteredFunctionalGauge("txpool/queued", reg, func() int64 {
+		_, queued := neatChain.TxPool().Stats()
+		return int64(queued)
+	})
+	return reg
+}
@@ -26,6 +26,7 @@ import (
 	"github.com/Gessiux/go-wire"
 	"github.com/Gessiux/neatchain/chain/consensus"
 
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/network/p2p"
 	"github.com/Gessiux/neatchain/utilities/common"
@@ -306,6 +307,31 @@ func (p *peer) RequestPreimages(hashes []common.Hash) error {
 	return p2p.Send(p.rw, GetPreImagesMsg, hashes)
 }
 
+// SendSnapshotManifest sends the requested snapshot manifest, an empty list if the
+// snapshot isn't available.
+func (p *peer) SendSnapshotManifest(manifests []*snapshot.Manifest) error {
+	return p2p.Send(p.rw, SnapshotManifestMsg, manifests)
+}
+
+// SendSnapshotChunks sends a batch of snapshot chunks, corresponding to the hashes
+// requested.
+func (p *peer) SendSnapshotChunks(chunks [][]byte) error {
+	return p2p.Send(p.rw, SnapshotChunksMsg, chunks)
+}
+
+// RequestSnapshotManifest fetches the manifest of the snapshot taken at the block, or
+// of the latest snapshot if number is zero.
+func (p *peer) RequestSnapshotManifest(number uint64) error {
+	p.Log().Debug("Fetching snapshot manifest", "number", number)
+	return p2p.Send(p.rw, GetSnapshotManifestMsg, number)
+}
+
+// RequestSnapshotChunks fetches a batch of snapshot chunks from a remote node.
+func (p *peer) RequestSnapshotChunks(hashes []common.Hash) error {
+	p.Log().Debug("Fetching batch of snapshot chunks", "count", len(hashes))
+	return p2p.Send(p.rw, GetSnapshotChunksMsg, hashes)
+}
+
 // Handshake executes the neatptc protocol handshake, negotiating version number,
 // network IDs, difficulties, head and genesis blocks.
 func (p *peer) Handshake(network uint64, td *big.Int, head common.Hash, genesis common.Hash) error {
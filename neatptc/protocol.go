@@ -22,6 +22,7 @@ import (
 	"math/big"
 
 	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/state"
 	"github.com/Gessiux/neatchain/chain/core/types"
 	"github.com/Gessiux/neatchain/utilities/common"
 	"github.com/Gessiux/neatchain/utilities/event"
@@ -70,6 +71,12 @@ const (
 	GetPreImagesMsg = 0x19
 	PreImagesMsg    = 0x1a
 	TrieNodeDataMsg = 0x1b
+
+	// Protocol messages of the epoch state snapshots
+	GetSnapshotManifestMsg = 0x1c
+	SnapshotManifestMsg    = 0x1d
+	GetSnapshotChunksMsg   = 0x1e
+	SnapshotChunksMsg      = 0x1f
 )
 
 type errCode int
@@ -109,6 +116,12 @@ type txPool interface {
 	// AddRemotes should add the given transactions to the pool.
 	AddRemotes([]*types.Transaction) []error
 
+	// AddLocal should add the given transaction created by the node itself to the pool.
+	AddLocal(*types.Transaction) error
+
+	// State should return the pool state including the pending nonces.
+	State() *state.ManagedState
+
 	// Pending should return pending transactions.
 	// The slice should be modifiable by the caller.
 	Pending() (map[common.Address]types.Transactions, error)
@@ -0,0 +1,69 @@
+package neatptc
+
+import (
+	"sync/atomic"
+	"time"
+
+	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/state/snapshot"
+	"github.com/Gessiux/neatchain/chain/core/types"
+	"github.com/Gessiux/neatchain/utilities/common"
+)
+
+// snapshotsKept is the number of epoch snapshots kept for the snap syncing nodes
+const snapshotsKept = 2
+
+// snapshotLoop takes a state snapshot at the end block of every epoch, if the node is a
+// validator of the epoch or the snapshots were requested.
+func (s *NeatChain) snapshotLoop() {
+	headCh := make(chan core.ChainHeadEvent, 10)
+	headSub := s.blockchain.SubscribeChainHeadEvent(headCh)
+	defer headSub.Unsubscribe()
+
+	var generating int32
+	for {
+		select {
+		case ev := <-headCh:
+			block := ev.Block
+			number := block.NumberU64()
+
+			ep := s.engine.GetEpoch()
+			if ep == nil {
+				continue
+			}
+			if ep = ep.GetEpochByBlockNumber(number); ep == nil || ep.EndBlock != number {
+				continue
+			}
+			if !s.config.StateSnapshot && !ep.Validators.HasAddress(s.engine.PrivateValidator().Bytes()) {
+				continue
+			}
+			if !atomic.CompareAndSwapInt32(&generating, 0, 1) {
+				s.chainConfig.ChainLogger.Warn("Previous state snapshot still generating, skipped", "number", number)
+				continue
+			}
+			// Keep the state of the block in memory until the snapshot is done
+			triedb := s.blockchain.StateCache().TrieDB()
+			triedb.Reference(block.Root(), common.Hash{})
+			go func() {
+				defer atomic.StoreInt32(&generating, 0)
+				defer triedb.Dereference(block.Root())
+				s.generateSnapshot(block)
+			}()
+
+		case <-headSub.Err():
+			return
+		}
+	}
+}
+
+// generateSnapshot dumps the state of the block and prunes the older snapshots
+func (s *NeatChain) generateSnapshot(block *types.Block) {
+	start := time.Now()
+	manifest, err := snapshot.Generate(s.blockchain.StateCache(), s.chainDb, block.NumberU64(), block.Hash(), block.Root())
+	if err != nil {
+		s.chainConfig.ChainLogger.Error("Failed to generate state snapshot", "number", block.NumberU64(), "err", err)
+		return
+	}
+	snapshot.Prune(s.chainDb, snapshotsKept)
+	s.chainConfig.ChainLogger.Info("State snapshot generated", "number", manifest.Number, "root", manifest.Root, "chunks", len(manifest.Chunks), "elapsed", common.PrettyDuration(time.Since(start)))
+}
@@ -73,6 +73,10 @@ type Config struct {
 	// in memory.
 	DataDir string
 
+	// DBEngine is the key-value store engine of the new databases, the existing
+	// ones are opened with the engine they were created with.
+	DBEngine string `toml:",omitempty"`
+
 	// Configuration of peer-to-peer networking.
 	P2P p2p.Config
 
@@ -424,6 +424,16 @@ func (n *Node) stopWS() {
 // Stop terminates a running node along with all it's services. In the node was
 // not started, an error is returned.
 func (n *Node) Stop() error {
+	return n.stop1(true)
+}
+
+// StopServices terminates the API and the services of the node, the p2p server shared with the
+// other chains keeps running
+func (n *Node) StopServices() error {
+	return n.stop1(false)
+}
+
+func (n *Node) stop1(stopServer bool) error {
 	n.lock.Lock()
 	defer n.lock.Unlock()
 
@@ -445,7 +455,9 @@ func (n *Node) Stop() error {
 			failure.Services[kind] = err
 		}
 	}
-	n.server.Stop()
+	if stopServer {
+		n.server.Stop()
+	}
 	n.services = nil
 	n.server = nil
 
@@ -595,7 +607,39 @@ func (n *Node) OpenDatabase(name string, cache, handles int, namespace string) (
 	if n.config.DataDir == "" {
 		return rawdb.NewMemoryDatabase(), nil
 	}
-	return rawdb.NewLevelDBDatabase(n.config.ResolvePath(name), cache, handles, namespace)
+	kvdb, err := rawdb.NewKeyValueStore(n.config.ResolvePath(name), n.config.DBEngine, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	return rawdb.NewDatabase(kvdb), nil
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (n *Node) OpenDatabaseWithFreezer(name string, cache, handles int, freezer, namespace string) (neatdb.Database, error) {
+	if n.config.DataDir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	}
+	root := n.config.ResolvePath(name)
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(root, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = n.config.ResolvePath(freezer)
+	}
+	kvdb, err := rawdb.NewKeyValueStore(root, n.config.DBEngine, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, freezer, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
+	return db, nil
 }
 
 // ResolvePath returns the absolute path of a resource in the instance directory.
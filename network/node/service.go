@@ -18,6 +18,7 @@ package node
 
 import (
 	"crypto/ecdsa"
+	"path/filepath"
 	"reflect"
 
 	"github.com/Gessiux/neatchain/chain/core/rawdb"
@@ -46,10 +47,38 @@ func (ctx *ServiceContext) OpenDatabase(name string, cache int, handles int, nam
 	if ctx.config.DataDir == "" {
 		return rawdb.NewMemoryDatabase(), nil
 	}
-	db, err := rawdb.NewLevelDBDatabase(ctx.config.ResolvePath(name), cache, handles, namespace)
+	kvdb, err := rawdb.NewKeyValueStore(ctx.config.ResolvePath(name), ctx.config.DBEngine, cache, handles, namespace)
 	if err != nil {
 		return nil, err
 	}
+	return rawdb.NewDatabase(kvdb), nil
+}
+
+// OpenDatabaseWithFreezer opens an existing database with the given name (or
+// creates one if no previous can be found) from within the node's data directory,
+// also attaching a chain freezer to it that moves ancient chain data from the
+// database to immutable append-only files. If the node is an ephemeral one, a
+// memory database is returned.
+func (ctx *ServiceContext) OpenDatabaseWithFreezer(name string, cache int, handles int, freezer string, namespace string) (neatdb.Database, error) {
+	if ctx.config.DataDir == "" {
+		return rawdb.NewMemoryDatabase(), nil
+	}
+	root := ctx.config.ResolvePath(name)
+	switch {
+	case freezer == "":
+		freezer = filepath.Join(root, "ancient")
+	case !filepath.IsAbs(freezer):
+		freezer = ctx.config.ResolvePath(freezer)
+	}
+	kvdb, err := rawdb.NewKeyValueStore(root, ctx.config.DBEngine, cache, handles, namespace)
+	if err != nil {
+		return nil, err
+	}
+	db, err := rawdb.NewDatabaseWithFreezer(kvdb, freezer, namespace)
+	if err != nil {
+		kvdb.Close()
+		return nil, err
+	}
 	return db, nil
 }
 
@@ -542,6 +542,25 @@ func (srv *Server) AddChildProtocolCaps(sideProtocols []Protocol) {
 	}
 }
 
+// RemoveChildProtocolCaps removes the protocols of the side chain stopped, the peers
+// connected later don't run them anymore
+func (srv *Server) RemoveChildProtocolCaps(sideProtocols []Protocol) {
+	for _, p := range sideProtocols {
+		for i, protocol := range srv.Protocols {
+			if protocol.Name == p.Name && protocol.Version == p.Version {
+				srv.Protocols = append(srv.Protocols[:i], srv.Protocols[i+1:]...)
+				break
+			}
+		}
+		for i, c := range srv.ourHandshake.Caps {
+			if c == p.cap() {
+				srv.ourHandshake.Caps = append(srv.ourHandshake.Caps[:i], srv.ourHandshake.Caps[i+1:]...)
+				break
+			}
+		}
+	}
+}
+
 func (srv *Server) startListening() error {
 	// Launch the TCP listener.
 	listener, err := net.Listen("tcp", srv.ListenAddr)
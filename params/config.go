@@ -66,7 +66,7 @@ var (
 		},
 	}
 
-	TestChainConfig = &ChainConfig{"", big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil}
+	TestChainConfig = &ChainConfig{"", big.NewInt(1), big.NewInt(0), big.NewInt(0), common.Hash{}, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil, nil, big.NewInt(0), big.NewInt(0), big.NewInt(0), nil, nil}
 	TestRules       = TestChainConfig.Rules(new(big.Int))
 )
 
@@ -96,6 +96,12 @@ type ChainConfig struct {
 
 	ByzantiumBlock      *big.Int `json:"byzantiumBlock,omitempty"`      // Byzantium switch block (nil = no fork, 0 = already on byzantium)
 	ConstantinopleBlock *big.Int `json:"constantinopleBlock,omitempty"` // Constantinople switch block (nil = no fork, 0 = already activated)
+	PetersburgBlock     *big.Int `json:"petersburgBlock,omitempty"`     // Petersburg switch block (nil = same as Constantinople)
+	IstanbulBlock       *big.Int `json:"istanbulBlock,omitempty"`       // Istanbul switch block (nil = no fork, 0 = already on istanbul)
+
+	CrossChainMessageBlock *big.Int `json:"crossChainMessageBlock,omitempty"` // Cross chain message switch block (nil = no fork, 0 = already activated)
+	ChainEventBlock        *big.Int `json:"chainEventBlock,omitempty"`        // Chain function event logs switch block (nil = no fork, 0 = already activated)
+	SideChainProofBlock    *big.Int `json:"sideChainProofBlock,omitempty"`    // Side chain proofs of epoch 0 verified switch block (nil = no fork, 0 = already activated)
 
 	// Various consensus engines
 	NeatCon *NeatConConfig `json:"neatcon,omitempty"`
@@ -114,8 +120,17 @@ func (c *NeatConConfig) String() string {
 	return "neatcon"
 }
 
-// Create a new Chain Config based on the Chain ID, for side chain creation purpose
-func NewSideChainConfig(sideChainID string) *ChainConfig {
+// SideChainForks is the activation heights of the EVM forks chosen by the creator of the side chain
+type SideChainForks struct {
+	ConstantinopleBlock *big.Int
+	PetersburgBlock     *big.Int
+	IstanbulBlock       *big.Int
+}
+
+// Create a new Chain Config based on the Chain ID, for side chain creation purpose.
+// The side chains created without the fork heights (nil forks) never activate the forks,
+// the others support the cross chain messages and the chain function events from the genesis
+func NewSideChainConfig(sideChainID string, forks *SideChainForks) *ChainConfig {
 	config := &ChainConfig{
 		NeatChainId:    sideChainID,
 		HomesteadBlock: big.NewInt(0),
@@ -131,6 +146,13 @@ func NewSideChainConfig(sideChainID string) *ChainConfig {
 			ProposerPolicy: 0,
 		},
 	}
+	if forks != nil {
+		config.ConstantinopleBlock = new(big.Int).Set(forks.ConstantinopleBlock)
+		config.PetersburgBlock = new(big.Int).Set(forks.PetersburgBlock)
+		config.IstanbulBlock = new(big.Int).Set(forks.IstanbulBlock)
+		config.CrossChainMessageBlock = big.NewInt(0)
+		config.ChainEventBlock = big.NewInt(0)
+	}
 
 	digest := crypto.Keccak256([]byte(config.NeatChainId))
 	config.ChainId = new(big.Int).SetBytes(digest[:])
@@ -147,7 +169,7 @@ func (c *ChainConfig) String() string {
 	default:
 		engine = "unknown"
 	}
-	return fmt.Sprintf("{NeatChainId: %s ChainID: %v Homestead: %v  EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Engine: %v}",
+	return fmt.Sprintf("{NeatChainId: %s ChainID: %v Homestead: %v  EIP150: %v EIP155: %v EIP158: %v Byzantium: %v Constantinople: %v Petersburg: %v Istanbul: %v CrossChainMessage: %v ChainEvent: %v SideChainProof: %v Engine: %v}",
 		c.NeatChainId,
 		c.ChainId,
 		c.HomesteadBlock,
@@ -156,6 +178,11 @@ func (c *ChainConfig) String() string {
 		c.EIP158Block,
 		c.ByzantiumBlock,
 		c.ConstantinopleBlock,
+		c.PetersburgBlock,
+		c.IstanbulBlock,
+		c.CrossChainMessageBlock,
+		c.ChainEventBlock,
+		c.SideChainProofBlock,
 		engine,
 	)
 }
@@ -185,6 +212,33 @@ func (c *ChainConfig) IsConstantinople(num *big.Int) bool {
 	return isForked(c.ConstantinopleBlock, num)
 }
 
+// IsPetersburg returns whether num is either
+// - equal to or greater than the PetersburgBlock fork block,
+// - OR is nil, and Constantinople is active
+func (c *ChainConfig) IsPetersburg(num *big.Int) bool {
+	return isForked(c.PetersburgBlock, num) || c.PetersburgBlock == nil && isForked(c.ConstantinopleBlock, num)
+}
+
+// IsIstanbul returns whether num is either equal to the Istanbul fork block or greater.
+func (c *ChainConfig) IsIstanbul(num *big.Int) bool {
+	return isForked(c.IstanbulBlock, num)
+}
+
+// IsCrossChainMessage returns whether num is either equal to the cross chain message fork block or greater.
+func (c *ChainConfig) IsCrossChainMessage(num *big.Int) bool {
+	return isForked(c.CrossChainMessageBlock, num)
+}
+
+// IsChainEvent returns whether num is either equal to the chain event fork block or greater.
+func (c *ChainConfig) IsChainEvent(num *big.Int) bool {
+	return isForked(c.ChainEventBlock, num)
+}
+
+// IsSideChainProof returns whether num is either equal to the side chain proof fork block or greater.
+func (c *ChainConfig) IsSideChainProof(num *big.Int) bool {
+	return isForked(c.SideChainProofBlock, num)
+}
+
 func (c *ChainConfig) IsEWASM(num *big.Int) bool {
 	return false
 }
@@ -207,6 +261,10 @@ func (c *ChainConfig) GasTable(num *big.Int) GasTable {
 		return GasTableHomestead
 	}
 	switch {
+	case c.IsIstanbul(num):
+		return GasTableIstanbul
+	case c.IsConstantinople(num):
+		return GasTableConstantinople
 	case c.IsEIP158(num):
 		return GasTableEIP158
 	case c.IsEIP150(num):
@@ -256,6 +314,21 @@ func (c *ChainConfig) checkCompatible(newcfg *ChainConfig, head *big.Int) *Confi
 	if isForkIncompatible(c.ConstantinopleBlock, newcfg.ConstantinopleBlock, head) {
 		return newCompatError("Constantinople fork block", c.ConstantinopleBlock, newcfg.ConstantinopleBlock)
 	}
+	if isForkIncompatible(c.PetersburgBlock, newcfg.PetersburgBlock, head) {
+		return newCompatError("Petersburg fork block", c.PetersburgBlock, newcfg.PetersburgBlock)
+	}
+	if isForkIncompatible(c.IstanbulBlock, newcfg.IstanbulBlock, head) {
+		return newCompatError("Istanbul fork block", c.IstanbulBlock, newcfg.IstanbulBlock)
+	}
+	if isForkIncompatible(c.CrossChainMessageBlock, newcfg.CrossChainMessageBlock, head) {
+		return newCompatError("CrossChainMessage fork block", c.CrossChainMessageBlock, newcfg.CrossChainMessageBlock)
+	}
+	if isForkIncompatible(c.ChainEventBlock, newcfg.ChainEventBlock, head) {
+		return newCompatError("ChainEvent fork block", c.ChainEventBlock, newcfg.ChainEventBlock)
+	}
+	if isForkIncompatible(c.SideChainProofBlock, newcfg.SideChainProofBlock, head) {
+		return newCompatError("SideChainProof fork block", c.SideChainProofBlock, newcfg.SideChainProofBlock)
+	}
 	return nil
 }
 
@@ -337,8 +410,8 @@ func (c *ChainConfig) Rules(num *big.Int) Rules {
 		IsEIP155:         c.IsEIP155(num),
 		IsEIP158:         c.IsEIP158(num),
 		IsByzantium:      c.IsByzantium(num),
-		IsConstantinople: false,
-		IsPetersburg:     false,
-		IsIstanbul:       false,
+		IsConstantinople: c.IsConstantinople(num),
+		IsPetersburg:     c.IsPetersburg(num),
+		IsIstanbul:       c.IsIstanbul(num),
 	}
 }
@@ -88,6 +88,20 @@ var (
 		Suicide:     5000,
 		ExpByte:     50,
 
+		CreateBySuicide: 25000,
+	}
+	// GasTableIstanbul contain the gas re-prices for
+	// the istanbul phase.
+	GasTableIstanbul = GasTable{
+		ExtcodeSize: 700,
+		ExtcodeCopy: 700,
+		ExtcodeHash: ExtcodeHashGasEIP1884,
+		Balance:     BalanceGasEIP1884,
+		SLoad:       SloadGasEIP1884,
+		Calls:       700,
+		Suicide:     5000,
+		ExpByte:     50,
+
 		CreateBySuicide: 25000,
 	}
 )
@@ -23,4 +23,10 @@ const (
 	// BloomBitsBlocks is the number of blocks a single bloom bit section vector
 	// contains.
 	BloomBitsBlocks uint64 = 4096
+
+	// ImmutabilityThreshold is the number of blocks after which a block is moved
+	// from the key-value store into the ancient store. NeatCon blocks are final
+	// once committed, the threshold only keeps the recent blocks in the faster
+	// key-value store.
+	ImmutabilityThreshold = 10000
 )
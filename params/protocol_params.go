@@ -134,6 +134,10 @@ const (
 	Bn256PairingBaseGasIstanbul      uint64 = 45000  // Base price for an elliptic curve pairing check
 	Bn256PairingPerPointGasByzantium uint64 = 80000  // Byzantium per-point price for an elliptic curve pairing check
 	Bn256PairingPerPointGasIstanbul  uint64 = 34000  // Per-point price for an elliptic curve pairing check
+
+	CrossChainMessageGas     uint64 = 30000 // Base price for sending a cross chain message to the outbox
+	CrossChainMessageWordGas uint64 = 200   // Per-word price of the input of a cross chain message
+	CrossChainInboxGas       uint64 = 200   // Price for reading the cross chain message being delivered
 )
 
 var (
@@ -0,0 +1,107 @@
+package prometheus
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/Gessiux/neatchain/utilities/metrics"
+)
+
+var (
+	typeGaugeTpl   = "# TYPE %s gauge\n"
+	typeSummaryTpl = "# TYPE %s summary\n"
+	keyValueTpl    = "%s%s %v\n"
+)
+
+// histogram and timer quantiles to report
+var quantiles = []float64{0.5, 0.75, 0.95, 0.99, 0.999, 0.9999}
+
+// collector is a collection of byte buffers that aggregate Prometheus reports
+// for different metric types.
+type collector struct {
+	buff  *bytes.Buffer
+	typed map[string]bool // metric families whose type line has been written
+}
+
+// newCollector creates a new Prometheus metric aggregator.
+func newCollector() *collector {
+	return &collector{
+		buff:  &bytes.Buffer{},
+		typed: make(map[string]bool),
+	}
+}
+
+// add writes the metric, it's labeled with the chain id unless the chain id is empty
+func (c *collector) add(name string, chainId string, i interface{}) {
+	switch m := i.(type) {
+	case metrics.Counter:
+		c.writeGauge(name, chainId, m.Count())
+	case metrics.Gauge:
+		c.writeGauge(name, chainId, m.Value())
+	case metrics.GaugeFloat64:
+		c.writeGauge(name, chainId, m.Value())
+	case metrics.Meter:
+		c.writeGauge(name, chainId, m.Count())
+	case metrics.Histogram:
+		c.writeSummary(name, chainId, m.Count(), quantiles, m.Percentiles(quantiles))
+	case metrics.Timer:
+		c.writeSummary(name, chainId, m.Count(), quantiles, m.Percentiles(quantiles))
+	case metrics.ResettingTimer:
+		// the snapshot resets the timer, so the values are reported once
+		snapshot := m.Snapshot()
+		values := snapshot.Values()
+		if len(values) == 0 {
+			return
+		}
+		pv := []float64{0.5, 0.95, 0.99}
+		ps := snapshot.Percentiles([]float64{50, 95, 99})
+		c.writeSummary(name, chainId, int64(len(values)), pv, []float64{float64(ps[0]), float64(ps[1]), float64(ps[2])})
+	}
+}
+
+func (c *collector) writeGauge(name string, chainId string, value interface{}) {
+	name = mutateKey(name)
+	c.writeType(typeGaugeTpl, name)
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, formatLabels(chainId, ""), value))
+}
+
+func (c *collector) writeSummary(name string, chainId string, count int64, pv []float64, ps []float64) {
+	name = mutateKey(name)
+	c.writeType(typeSummaryTpl, name)
+	for i := range pv {
+		quantile := strconv.FormatFloat(pv[i], 'f', -1, 64)
+		c.buff.WriteString(fmt.Sprintf(keyValueTpl, name, formatLabels(chainId, quantile), ps[i]))
+	}
+	c.buff.WriteString(fmt.Sprintf(keyValueTpl, name+"_count", formatLabels(chainId, ""), count))
+}
+
+// writeType writes the type line once per metric family, the samples of a family must be grouped together
+func (c *collector) writeType(tpl, name string) {
+	if c.typed[name] {
+		return
+	}
+	c.typed[name] = true
+	c.buff.WriteString(fmt.Sprintf(tpl, name))
+}
+
+// formatLabels formats the chain and quantile labels, the empty ones are omitted
+func formatLabels(chainId, quantile string) string {
+	var pairs []string
+	if chainId != "" {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", ChainLabel, chainId))
+	}
+	if quantile != "" {
+		pairs = append(pairs, fmt.Sprintf("quantile=%q", quantile))
+	}
+	if len(pairs) == 0 {
+		return ""
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+// mutateKey converts the metric name into a valid Prometheus metric name
+func mutateKey(key string) string {
+	return strings.NewReplacer("/", "_", "-", "_", ".", "_").Replace(key)
+}
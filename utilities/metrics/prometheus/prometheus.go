@@ -0,0 +1,79 @@
+// Package prometheus exposes the metrics registries in Prometheus text exposition format.
+package prometheus
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+
+	"github.com/Gessiux/neatchain/utilities/metrics"
+)
+
+// ChainLabel is the label of the metrics reported by each running chain
+const ChainLabel = "chain"
+
+var (
+	chainsLock sync.RWMutex
+	chains     = make(map[string]metrics.Registry)
+)
+
+// RegisterChain adds the metrics registry of a running chain, its metrics are reported with the chain label
+func RegisterChain(chainId string, reg metrics.Registry) {
+	chainsLock.Lock()
+	defer chainsLock.Unlock()
+	chains[chainId] = reg
+}
+
+// UnregisterChain removes the metrics registry of a stopped chain
+func UnregisterChain(chainId string) {
+	chainsLock.Lock()
+	defer chainsLock.Unlock()
+	delete(chains, chainId)
+}
+
+// Handler returns an HTTP handler which dumps the metrics of the registry
+// and of all the running chains in Prometheus format.
+func Handler(reg metrics.Registry) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Gather the registries, the process wide one has no chain label
+		registries := map[string]metrics.Registry{"": reg}
+		chainsLock.RLock()
+		for chainId, chainReg := range chains {
+			registries[chainId] = chainReg
+		}
+		chainsLock.RUnlock()
+
+		// Group the metrics by name, then by chain to avoid random listings
+		families := make(map[string]map[string]interface{})
+		for chainId, r := range registries {
+			r.Each(func(name string, i interface{}) {
+				if families[name] == nil {
+					families[name] = make(map[string]interface{})
+				}
+				families[name][chainId] = i
+			})
+		}
+
+		names := make([]string, 0, len(families))
+		for name := range families {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		c := newCollector()
+		for _, name := range names {
+			chainIds := make([]string, 0, len(families[name]))
+			for chainId := range families[name] {
+				chainIds = append(chainIds, chainId)
+			}
+			sort.Strings(chainIds)
+			for _, chainId := range chainIds {
+				c.add(name, chainId, families[name][chainId])
+			}
+		}
+		w.Header().Add("Content-Type", "text/plain; version=0.0.4")
+		w.Header().Add("Content-Length", fmt.Sprint(c.buff.Len()))
+		w.Write(c.buff.Bytes())
+	})
+}
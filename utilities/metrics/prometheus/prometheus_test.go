@@ -0,0 +1,63 @@
+package prometheus
+
+import (
+	"io/ioutil"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Gessiux/neatchain/utilities/metrics"
+)
+
+func TestHandler(t *testing.T) {
+	metrics.Enabled = true
+
+	reg := metrics.NewRegistry()
+	metrics.NewRegisteredCounter("test/counter", reg).Inc(12345)
+	metrics.NewRegisteredGaugeFloat64("test/gauge_float64", reg).Update(34567.89)
+	metrics.NewRegisteredMeter("test/meter", reg).Mark(9999999)
+	timer := metrics.NewRegisteredTimer("test/timer", reg)
+	timer.Update(20 * time.Millisecond)
+	resettingTimer := metrics.NewRegisteredResettingTimer("test/resetting_timer", reg)
+	resettingTimer.Update(10 * time.Millisecond)
+
+	sideA := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("chain/head/block", sideA).Update(100)
+	sideB := metrics.NewRegistry()
+	metrics.NewRegisteredGauge("chain/head/block", sideB).Update(200)
+	RegisterChain("side_a", sideA)
+	RegisterChain("side_b", sideB)
+	defer UnregisterChain("side_a")
+	defer UnregisterChain("side_b")
+
+	rec := httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics/prometheus", nil))
+	body, _ := ioutil.ReadAll(rec.Body)
+	output := string(body)
+
+	for _, want := range []string{
+		"# TYPE chain_head_block gauge\nchain_head_block{chain=\"side_a\"} 100\nchain_head_block{chain=\"side_b\"} 200\n",
+		"# TYPE test_counter gauge\ntest_counter 12345\n",
+		"test_gauge_float64 34567.89\n",
+		"test_meter 9999999\n",
+		"# TYPE test_timer summary\ntest_timer{quantile=\"0.5\"} 2e+07\n",
+		"test_timer_count 1\n",
+		"test_resetting_timer{quantile=\"0.99\"} 1e+07\n",
+		"test_resetting_timer_count 1\n",
+	} {
+		if !strings.Contains(output, want) {
+			t.Errorf("missing %q in output:\n%s", want, output)
+		}
+	}
+	if strings.Count(output, "# TYPE chain_head_block") != 1 {
+		t.Errorf("metric family type reported more than once:\n%s", output)
+	}
+
+	// the resetting timer is reset after reported
+	rec = httptest.NewRecorder()
+	Handler(reg).ServeHTTP(rec, httptest.NewRequest("GET", "/debug/metrics/prometheus", nil))
+	if strings.Contains(rec.Body.String(), "test_resetting_timer") {
+		t.Errorf("resetting timer reported twice")
+	}
+}
@@ -30,12 +30,14 @@ import (
 	"github.com/Gessiux/neatchain/chain/accounts/keystore"
 	"github.com/Gessiux/neatchain/chain/consensus"
 	"github.com/Gessiux/neatchain/chain/core"
+	"github.com/Gessiux/neatchain/chain/core/rawdb"
 	"github.com/Gessiux/neatchain/chain/core/vm"
 	"github.com/Gessiux/neatchain/chain/log"
 	"github.com/Gessiux/neatchain/neatdb"
 	neatptc "github.com/Gessiux/neatchain/neatptc"
 	"github.com/Gessiux/neatchain/neatptc/downloader"
 	"github.com/Gessiux/neatchain/neatptc/gasprice"
+	"github.com/Gessiux/neatchain/neatptc/light"
 	"github.com/Gessiux/neatchain/network/node"
 	"github.com/Gessiux/neatchain/network/p2p"
 	"github.com/Gessiux/neatchain/network/p2p/discover"
@@ -46,6 +48,7 @@ import (
 	"github.com/Gessiux/neatchain/utilities/common/fdlimit"
 	"github.com/Gessiux/neatchain/utilities/crypto"
 	"github.com/Gessiux/neatchain/utilities/metrics"
+	"github.com/Gessiux/neatchain/utilities/metrics/exp"
 	"gopkg.in/urfave/cli.v1"
 
 	// import neatcon config
@@ -112,6 +115,14 @@ var (
 		Usage: "Data directory for the databases and keystore",
 		Value: DirectoryString{node.DefaultDataDir()},
 	}
+	DBEngineFlag = cli.StringFlag{
+		Name:  "db.engine",
+		Usage: "Backing database implementation of the new databases ('leveldb' or 'boltdb'), the existing ones keep their own",
+	}
+	AncientFlag = DirectoryFlag{
+		Name:  "datadir.ancient",
+		Usage: "Data directory for ancient chain segments (default = inside chaindata)",
+	}
 	KeyStoreDirFlag = DirectoryFlag{
 		Name:  "keystore",
 		Usage: "Directory for the keystore (default = inside the datadir)",
@@ -151,11 +162,19 @@ var (
 
 	defaultSyncMode = neatptc.DefaultConfig.SyncMode
 	SyncModeFlag    = TextMarshalerFlag{
-		Name: "syncmode",
-		//Usage: `Blockchain sync mode ("fast", "full", or "light")`,
-		Usage: `Blockchain sync mode ("full")`,
+		Name:  "syncmode",
+		Usage: `Blockchain sync mode ("full", "light" or "snap")`,
 		Value: &defaultSyncMode,
 	}
+	SnapshotFlag = cli.BoolFlag{
+		Name:  "snapshot",
+		Usage: "Produce state snapshots at the epoch boundaries for the snap syncing nodes (validators always do)",
+	}
+	LightPeersFlag = cli.IntFlag{
+		Name:  "lightpeers",
+		Usage: "Maximum number of light clients to serve (0 = light server disabled)",
+		Value: neatptc.DefaultConfig.LightPeers,
+	}
 	GCModeFlag = cli.StringFlag{
 		Name:  "gcmode",
 		Usage: `Blockchain garbage collection mode ("full", "archive")`,
@@ -278,6 +297,11 @@ var (
 		Usage: "Password file to use for non-interactive password input",
 		Value: "",
 	}
+	ValidatorPasswordFileFlag = cli.StringFlag{
+		Name:  "validator.password",
+		Usage: "Password file to unlock the encrypted consensus key (priv_validator.json)",
+		Value: "",
+	}
 
 	VMEnableDebugFlag = cli.BoolFlag{
 		Name:  "vmdebug",
@@ -292,6 +316,20 @@ var (
 		Name:  metrics.MetricsEnabledFlag,
 		Usage: "Enable metrics collection and reporting",
 	}
+	// MetricsHTTPFlag defines the endpoint for a stand-alone metrics HTTP endpoint.
+	// Since the pprof service enables sensitive/vulnerable behavior, this allows a user
+	// to enable a public-OK metrics endpoint without having to worry about ALSO exposing
+	// other profiling behavior or information.
+	MetricsHTTPFlag = cli.StringFlag{
+		Name:  "metrics.addr",
+		Usage: "Enable stand-alone metrics HTTP server listening interface",
+		Value: "127.0.0.1",
+	}
+	MetricsPortFlag = cli.IntFlag{
+		Name:  "metrics.port",
+		Usage: "Metrics HTTP server listening port",
+		Value: 6060,
+	}
 
 	NoCompactionFlag = cli.BoolFlag{
 		Name:  "nocompaction",
@@ -453,7 +491,12 @@ var (
 	// Data Reduction Flag
 	PruneFlag = cli.BoolFlag{
 		Name:  "prune",
-		Usage: "Enable the Data Reduction feature, history state data will be pruned by default",
+		Usage: "Prune the history state data (deprecated, use --prune.retain)",
+	}
+	PruneRetainFlag = cli.Uint64Flag{
+		Name:  "prune.retain",
+		Usage: "Number of recent blocks whose state is kept on disk in full gc mode (0 = keep all)",
+		Value: neatptc.DefaultConfig.TrieRetention,
 	}
 
 	//for performance test
@@ -831,7 +874,15 @@ func setCoinbase(ctx *cli.Context, ks *keystore.KeyStore, cfg *neatptc.Config) {
 
 // MakePasswordList reads password lines from the file specified by the global --password flag.
 func MakePasswordList(ctx *cli.Context) []string {
-	path := ctx.GlobalString(PasswordFileFlag.Name)
+	return readPasswordList(ctx.GlobalString(PasswordFileFlag.Name))
+}
+
+// MakeValidatorPasswordList reads password lines from the file specified by the global --validator.password flag.
+func MakeValidatorPasswordList(ctx *cli.Context) []string {
+	return readPasswordList(ctx.GlobalString(ValidatorPasswordFileFlag.Name))
+}
+
+func readPasswordList(path string) []string {
 	if path == "" {
 		return nil
 	}
@@ -899,6 +950,13 @@ func SetNodeConfig(ctx *cli.Context, cfg *node.Config) {
 		cfg.DataDir = filepath.Join(cfg.GeneralDataDir, ctx.GlobalString(TestnetFlag.Name))
 	}
 
+	if ctx.GlobalIsSet(DBEngineFlag.Name) {
+		engine := ctx.GlobalString(DBEngineFlag.Name)
+		if engine != rawdb.EngineLevelDB && engine != rawdb.EngineBoltDB {
+			Fatalf("--%s must be either '%s' or '%s'", DBEngineFlag.Name, rawdb.EngineLevelDB, rawdb.EngineBoltDB)
+		}
+		cfg.DBEngine = engine
+	}
 	if ctx.GlobalIsSet(KeyStoreDirFlag.Name) {
 		cfg.KeyStoreDir = ctx.GlobalString(KeyStoreDirFlag.Name)
 	}
@@ -1016,6 +1074,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 		cfg.SyncMode = downloader.FastSync
 	}
 
+	if ctx.GlobalIsSet(SnapshotFlag.Name) {
+		cfg.StateSnapshot = ctx.GlobalBool(SnapshotFlag.Name)
+	}
+	if ctx.GlobalIsSet(LightPeersFlag.Name) {
+		cfg.LightPeers = ctx.GlobalInt(LightPeersFlag.Name)
+	}
+
 	if ctx.GlobalIsSet(NetworkIdFlag.Name) {
 		cfg.NetworkId = ctx.GlobalUint64(NetworkIdFlag.Name)
 	}
@@ -1024,6 +1089,9 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 		cfg.DatabaseCache = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheDatabaseFlag.Name) / 100
 	}
 	cfg.DatabaseHandles = makeDatabaseHandles()
+	if ctx.GlobalIsSet(AncientFlag.Name) {
+		cfg.DatabaseFreezer = ctx.GlobalString(AncientFlag.Name)
+	}
 
 	if gcmode := ctx.GlobalString(GCModeFlag.Name); gcmode != "full" && gcmode != "archive" {
 		Fatalf("--%s must be either 'full' or 'archive'", GCModeFlag.Name)
@@ -1067,8 +1135,13 @@ func SetEthConfig(ctx *cli.Context, stack *node.Node, cfg *neatptc.Config) {
 	}
 
 	// Data Reduction Config
-	cfg.PruneStateData = ctx.GlobalBool(PruneFlag.Name)
-	//cfg.PruneBlockData = ctx.GlobalBool(PruneBlockFlag.Name)
+	if ctx.GlobalBool(PruneFlag.Name) {
+		log.Warn("The flag --prune is deprecated and will be removed in the future, please use --prune.retain")
+		cfg.TrieRetention = neatptc.DefaultPruneRetention
+	}
+	if ctx.GlobalIsSet(PruneRetainFlag.Name) {
+		cfg.TrieRetention = ctx.GlobalUint64(PruneRetainFlag.Name)
+	}
 }
 
 func SetGeneralConfig(ctx *cli.Context) {
@@ -1078,15 +1151,26 @@ func SetGeneralConfig(ctx *cli.Context) {
 // registerIntService adds an NEAT Chain client to the stack.
 func RegisterIntService(stack *node.Node, cfg *neatptc.Config, cliCtx *cli.Context, cch core.CrossChainHelper) {
 	var err error
-	err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
-		//return NewBackend(ctx, cfg, cliCtx, pNode, cch)
-		fullNode, err := neatptc.New(ctx, cfg, cliCtx, cch, stack.GetLogger(), cliCtx.GlobalBool(TestnetFlag.Name))
-		//if fullNode != nil && cfg.LightServ > 0 {
-		//	ls, _ := les.NewLesServer(fullNode, cfg)
-		//	fullNode.AddLesServer(ls)
-		//}
-		return fullNode, err
-	})
+	if cfg.SyncMode == downloader.LightSync {
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			lightCfg := &light.Config{
+				Genesis:         cfg.Genesis,
+				NetworkId:       cfg.NetworkId,
+				DatabaseHandles: cfg.DatabaseHandles,
+				DatabaseCache:   cfg.DatabaseCache,
+			}
+			return light.New(ctx, lightCfg, cliCtx, stack.GetLogger(), cliCtx.GlobalBool(TestnetFlag.Name))
+		})
+	} else {
+		err = stack.Register(func(ctx *node.ServiceContext) (node.Service, error) {
+			//return NewBackend(ctx, cfg, cliCtx, pNode, cch)
+			fullNode, err := neatptc.New(ctx, cfg, cliCtx, cch, stack.GetLogger(), cliCtx.GlobalBool(TestnetFlag.Name))
+			if fullNode != nil && cfg.LightPeers > 0 {
+				fullNode.AddLesServer(light.NewServer(fullNode.BlockChain(), cfg.NetworkId, cfg.LightPeers, stack.GetLogger()))
+			}
+			return fullNode, err
+		})
+	}
 	if err != nil {
 		Fatalf("Failed to register the neatchain service: %v", err)
 	}
@@ -1117,6 +1201,19 @@ func RegisterIntService(stack *node.Node, cfg *neatptc.Config, cliCtx *cli.Conte
 //	}
 //}
 
+// SetupMetrics starts the stand-alone metrics HTTP server if metrics are enabled and the endpoint is requested
+func SetupMetrics(ctx *cli.Context) {
+	if !metrics.Enabled {
+		return
+	}
+	log.Info("Enabling metrics collection")
+	if ctx.GlobalIsSet(MetricsHTTPFlag.Name) || ctx.GlobalIsSet(MetricsPortFlag.Name) {
+		address := fmt.Sprintf("%s:%d", ctx.GlobalString(MetricsHTTPFlag.Name), ctx.GlobalInt(MetricsPortFlag.Name))
+		log.Info("Enabling stand-alone metrics HTTP endpoint", "address", address)
+		exp.Setup(address)
+	}
+}
+
 // MakeChainDatabase open an LevelDB using the flags passed to the client and will hard crash if it fails.
 func MakeChainDatabase(ctx *cli.Context, stack *node.Node) neatdb.Database {
 	var (
@@ -1124,7 +1221,7 @@ func MakeChainDatabase(ctx *cli.Context, stack *node.Node) neatdb.Database {
 		handles = makeDatabaseHandles()
 	)
 	name := "chaindata"
-	chainDb, err := stack.OpenDatabase(name, cache, handles, "")
+	chainDb, err := stack.OpenDatabaseWithFreezer(name, cache, handles, ctx.GlobalString(AncientFlag.Name), "")
 	if err != nil {
 		Fatalf("Could not open database: %v", err)
 	}
@@ -1167,6 +1264,7 @@ func MakeChain(ctx *cli.Context, stack *node.Node) (chain *core.BlockChain, chai
 		TrieDirtyLimit:    neatptc.DefaultConfig.TrieDirtyCache,
 		TrieDirtyDisabled: ctx.GlobalString(GCModeFlag.Name) == "archive",
 		TrieTimeLimit:     neatptc.DefaultConfig.TrieTimeout,
+		TrieRetention:     ctx.GlobalUint64(PruneRetainFlag.Name),
 	}
 	if ctx.GlobalIsSet(CacheFlag.Name) || ctx.GlobalIsSet(CacheTrieFlag.Name) {
 		cache.TrieCleanLimit = ctx.GlobalInt(CacheFlag.Name) * ctx.GlobalInt(CacheTrieFlag.Name) / 100